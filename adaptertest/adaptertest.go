@@ -0,0 +1,293 @@
+// Package adaptertest is a conformance suite for orm.QueryAdapter
+// implementations. It checks that an adapter composes conditions, maps
+// rows, reports errors and paginates the way the native adapter does, so
+// code written against the interface behaves the same on every backend.
+//
+// Seed a database with the Widget table and run the suite from a regular
+// test, passing a function that returns a fresh adapter on it:
+//
+//	func TestConformance(t *testing.T) {
+//		db := openTestDB(t)
+//		if err := adaptertest.Seed(context.Background(), db); err != nil {
+//			t.Fatal(err)
+//		}
+//		adaptertest.Run(t, func(t *testing.T) orm.QueryAdapter {
+//			return orm.NewSqlAdapter(db)
+//		})
+//	}
+//
+// The suite only reads; the table must hold exactly Fixtures while it runs.
+package adaptertest
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/godev90/orm"
+)
+
+// Widget is the model the suite queries.
+type Widget struct {
+	ID     int64   `sql:"column:id;primaryKey;clientKey"`
+	Name   string  `sql:"column:name"`
+	Color  *string `sql:"column:color"`
+	Weight int64   `sql:"column:weight"`
+}
+
+func (Widget) TableName() string {
+	return "adaptertest_widgets"
+}
+
+// Schema creates the Widget table, in SQL that PostgreSQL, MySQL and SQLite
+// all accept.
+const Schema = `CREATE TABLE adaptertest_widgets (
+	id INTEGER PRIMARY KEY,
+	name VARCHAR(64) NOT NULL,
+	color VARCHAR(16),
+	weight INTEGER NOT NULL
+)`
+
+// Fixtures returns the rows the suite expects in the Widget table, in id
+// order. Two of them have a NULL color.
+func Fixtures() []Widget {
+	color := func(c string) *string { return &c }
+	return []Widget{
+		{ID: 1, Name: "bolt", Color: color("red"), Weight: 5},
+		{ID: 2, Name: "nut", Weight: 2},
+		{ID: 3, Name: "gear", Color: color("blue"), Weight: 12},
+		{ID: 4, Name: "axle", Color: color("red"), Weight: 30},
+		{ID: 5, Name: "cog", Color: color("blue"), Weight: 8},
+		{ID: 6, Name: "shaft", Weight: 20},
+	}
+}
+
+// Seed (re)creates the Widget table on db and inserts Fixtures.
+func Seed(ctx context.Context, db *sql.DB) error {
+	if _, err := db.ExecContext(ctx, "DROP TABLE IF EXISTS adaptertest_widgets"); err != nil {
+		return err
+	}
+	if _, err := db.ExecContext(ctx, Schema); err != nil {
+		return err
+	}
+
+	fixtures := Fixtures()
+	models := make([]orm.Tabler, len(fixtures))
+	for i := range fixtures {
+		models[i] = &fixtures[i]
+	}
+	return orm.WithTransaction(ctx, db, func(tx *orm.SqlTransactionAdapter) error {
+		return tx.BulkInsert(models)
+	})
+}
+
+// Run runs the suite against the adapters newAdapter returns, one per
+// check. The adapter must be on a database seeded as Seed does.
+func Run(t *testing.T, newAdapter func(t *testing.T) orm.QueryAdapter) {
+	widgets := func(t *testing.T) orm.QueryAdapter {
+		t.Helper()
+		q := newAdapter(t)
+		if q == nil {
+			t.Fatal("adaptertest: newAdapter returned nil")
+		}
+		return q.UseModel(&Widget{})
+	}
+
+	for _, c := range checks {
+		t.Run(c.name, func(t *testing.T) {
+			c.run(t, widgets(t))
+		})
+	}
+}
+
+// checks are the suite's cases, run in order.
+var checks = []struct {
+	name string
+	run  func(t *testing.T, q orm.QueryAdapter)
+}{
+	{"Where/And", func(t *testing.T, q orm.QueryAdapter) {
+		expectIDs(t, q.Where("color = ?", "red").Where("weight > ?", 10).Order("id"), 4)
+	}},
+	{"Where/Or", func(t *testing.T, q orm.QueryAdapter) {
+		expectIDs(t, q.Where("weight < ?", 3).Or("weight > ?", 25).Order("id"), 2, 4)
+	}},
+	{"Where/In", func(t *testing.T, q orm.QueryAdapter) {
+		expectIDs(t, q.Where("id IN ?", []int64{1, 3, 5}).Order("id"), 1, 3, 5)
+	}},
+	{"Where/Null", func(t *testing.T, q orm.QueryAdapter) {
+		expectIDs(t, q.Where("color IS NULL").Order("id"), 2, 6)
+	}},
+	{"Where/Immutable", func(t *testing.T, q orm.QueryAdapter) {
+		base := q.Where("weight > ?", 4).Order("id")
+		expectIDs(t, base.Where("color = ?", "red"), 1, 4)
+		expectIDs(t, base.Where("color = ?", "blue"), 3, 5)
+		expectIDs(t, base, 1, 3, 4, 5, 6)
+	}},
+	{"Where/Scopes", func(t *testing.T, q orm.QueryAdapter) {
+		heavy := func(q orm.QueryAdapter) orm.QueryAdapter { return q.Where("weight >= ?", 12) }
+		expectIDs(t, q.Scopes(heavy).Where("color IS NOT NULL").Order("id"), 3, 4)
+	}},
+
+	{"Scan/Structs", func(t *testing.T, q orm.QueryAdapter) {
+		var got []Widget
+		if err := q.Order("id").Scan(&got); err != nil {
+			t.Fatalf("Scan: %v", err)
+		}
+		expectWidgets(t, got, Fixtures())
+	}},
+	{"Scan/Pointers", func(t *testing.T, q orm.QueryAdapter) {
+		var got []*Widget
+		if err := q.Order("id").Scan(&got); err != nil {
+			t.Fatalf("Scan: %v", err)
+		}
+		want := Fixtures()
+		if len(got) != len(want) {
+			t.Fatalf("Scan into []*Widget: got %d rows, want %d", len(got), len(want))
+		}
+		for i, w := range got {
+			if w == nil {
+				t.Fatalf("Scan into []*Widget: row %d is nil", i)
+			}
+			expectWidgets(t, []Widget{*w}, want[i:i+1])
+		}
+	}},
+	{"Scan/Select", func(t *testing.T, q orm.QueryAdapter) {
+		var got []Widget
+		if err := q.Select([]string{"id", "name"}).Where("id = ?", 3).Scan(&got); err != nil {
+			t.Fatalf("Scan: %v", err)
+		}
+		expectWidgets(t, got, []Widget{{ID: 3, Name: "gear"}})
+	}},
+	{"Scan/Empty", func(t *testing.T, q orm.QueryAdapter) {
+		var got []Widget
+		if err := q.Where("weight > ?", 1000).Scan(&got); err != nil {
+			t.Fatalf("Scan with no matching rows: %v, want nil", err)
+		}
+		if len(got) != 0 {
+			t.Errorf("Scan with no matching rows: got %d rows, want none", len(got))
+		}
+	}},
+	{"Scan/Struct", func(t *testing.T, q orm.QueryAdapter) {
+		var got Widget
+		if err := q.Where("id = ?", 6).Scan(&got); err != nil {
+			t.Fatalf("Scan into a struct: %v", err)
+		}
+		expectWidgets(t, []Widget{got}, Fixtures()[5:6])
+	}},
+	{"First", func(t *testing.T, q orm.QueryAdapter) {
+		var got Widget
+		if err := q.Where("color = ?", "blue").Order("weight").First(&got); err != nil {
+			t.Fatalf("First: %v", err)
+		}
+		expectWidgets(t, []Widget{got}, Fixtures()[4:5])
+	}},
+
+	{"Errors/FirstNotFound", func(t *testing.T, q orm.QueryAdapter) {
+		var got Widget
+		expectNotFound(t, "First", q.Where("id = ?", -1).First(&got))
+	}},
+	{"Errors/ScanNotFound", func(t *testing.T, q orm.QueryAdapter) {
+		var got Widget
+		expectNotFound(t, "Scan into a struct", q.Where("id = ?", -1).Scan(&got))
+	}},
+	{"Errors/NotPointer", func(t *testing.T, q orm.QueryAdapter) {
+		err := q.Scan([]Widget{})
+		if err == nil {
+			t.Error("Scan into a non-pointer: got nil error")
+		}
+	}},
+
+	{"Pagination/LimitOffset", func(t *testing.T, q orm.QueryAdapter) {
+		expectIDs(t, q.Order("id").Limit(2).Offset(2), 3, 4)
+	}},
+	{"Pagination/Order", func(t *testing.T, q orm.QueryAdapter) {
+		expectIDs(t, q.Order("weight DESC").Limit(3), 4, 6, 3)
+	}},
+	{"Pagination/PastEnd", func(t *testing.T, q orm.QueryAdapter) {
+		expectIDs(t, q.Order("id").Limit(2).Offset(10))
+	}},
+	{"Pagination/Count", func(t *testing.T, q orm.QueryAdapter) {
+		// the count of a page's query is the total, not the page size
+		expectCount(t, q.Where("color IS NOT NULL"), 4)
+		expectCount(t, q.Where("color IS NOT NULL").Order("id").Limit(2).Offset(2), 4)
+		expectCount(t, q.Where("weight > ?", 1000), 0)
+	}},
+	{"Pagination/Clone", func(t *testing.T, q orm.QueryAdapter) {
+		base := q.Where("weight > ?", 4).Order("id")
+		page := base.Clone().Limit(2)
+		expectIDs(t, page, 1, 3)
+		expectIDs(t, page.Offset(2), 4, 5)
+		expectCount(t, base, 5)
+	}},
+}
+
+// expectIDs scans q and fails t unless it returns the rows ids, in order.
+func expectIDs(t *testing.T, q orm.QueryAdapter, ids ...int64) {
+	t.Helper()
+	var got []Widget
+	if err := q.Scan(&got); err != nil {
+		sqlStr, args := q.ToSQL()
+		t.Fatalf("Scan: %v\n%s %v", err, sqlStr, args)
+	}
+
+	gotIDs := make([]int64, len(got))
+	for i, w := range got {
+		gotIDs[i] = w.ID
+	}
+	if len(ids) == 0 && len(gotIDs) == 0 {
+		return
+	}
+	if !reflect.DeepEqual(gotIDs, ids) {
+		sqlStr, args := q.ToSQL()
+		t.Errorf("got ids %v, want %v\n%s %v", gotIDs, ids, sqlStr, args)
+	}
+}
+
+// expectCount counts q and fails t unless it is n.
+func expectCount(t *testing.T, q orm.QueryAdapter, n int64) {
+	t.Helper()
+	var got int64
+	if err := q.Count(&got); err != nil {
+		t.Fatalf("Count: %v", err)
+	}
+	if got != n {
+		sqlStr, args := q.ToSQL()
+		t.Errorf("Count: got %d, want %d\n%s %v", got, n, sqlStr, args)
+	}
+}
+
+// expectWidgets fails t unless got and want hold the same rows.
+func expectWidgets(t *testing.T, got, want []Widget) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("got %d rows, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if !reflect.DeepEqual(got[i], want[i]) {
+			t.Errorf("row %d: got %s, want %s", i, format(got[i]), format(want[i]))
+		}
+	}
+}
+
+// expectNotFound fails t unless err is the orm's not-found error, which
+// matches orm.ErrNotFound and sql.ErrNoRows alike.
+func expectNotFound(t *testing.T, op string, err error) {
+	t.Helper()
+	switch {
+	case err == nil:
+		t.Errorf("%s with no matching row: got nil error, want orm.ErrNotFound", op)
+	case !errors.Is(err, orm.ErrNotFound) || !errors.Is(err, sql.ErrNoRows):
+		t.Errorf("%s with no matching row: got %v, want an error matching orm.ErrNotFound and sql.ErrNoRows", op, err)
+	}
+}
+
+func format(w Widget) string {
+	color := "NULL"
+	if w.Color != nil {
+		color = *w.Color
+	}
+	return fmt.Sprintf("{ID:%d Name:%q Color:%s Weight:%d}", w.ID, w.Name, color, w.Weight)
+}
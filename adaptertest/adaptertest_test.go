@@ -0,0 +1,39 @@
+package adaptertest
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/godev90/orm"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// openSQLite returns a seeded in-memory SQLite database. SQLite takes the
+// MySQL flavor's ? placeholders and backquoted identifiers.
+func openSQLite(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("sqlite3", "file::memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// every connection to :memory: is a database of its own
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+	if err := db.Ping(); err != nil {
+		t.Skipf("sqlite unavailable, go-sqlite3 needs cgo: %v", err)
+	}
+
+	orm.SetFlavor(db, orm.FlavorMySQL)
+	if err := Seed(context.Background(), db); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestConformance(t *testing.T) {
+	db := openSQLite(t)
+	Run(t, func(t *testing.T) orm.QueryAdapter {
+		return orm.NewSqlAdapter(db)
+	})
+}
@@ -0,0 +1,109 @@
+package orm
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/godev90/validator/faults"
+)
+
+var (
+	errInvalidBatchKey = fmt.Errorf("orm: invalid batch key")
+	ErrInvalidBatchKey = faults.New(errInvalidBatchKey, &faults.ErrAttr{
+		Code: http.StatusInternalServerError,
+		Messages: []faults.LangPackage{
+			{
+				Tag:     faults.English,
+				Message: "orm: batch key %s: %s",
+			},
+		},
+	})
+)
+
+// conditionGrouper is implemented by the adapters, whose grouped method
+// parenthesizes a chain's conditions before more are added.
+type conditionGrouper interface {
+	grouped() QueryAdapter
+}
+
+// AggregateInBatches runs the grouped query q a page of batchSize groups at
+// a time, ordered by the group key column key, and calls fn with each page,
+// so GROUP BY results over millions of keys are processed without holding
+// them all:
+//
+//	q := adapter.UseModel(&Order{}).
+//		Select([]string{"customer_id", "COUNT(*) AS orders", "SUM(total) AS spent"}).
+//		GroupBy([]string{"customer_id"})
+//
+//	err := orm.AggregateInBatches(q, "customer_id", 10000, func(batch []CustomerTotal) error {
+//		return publish(batch)
+//	})
+//
+// Pages are keyset-paginated (WHERE key > last ORDER BY key LIMIT
+// batchSize), so each one is a cheap index range on key rather than an
+// OFFSET scan; q must group by key alone and leave ordering and limits to
+// it. T must map key to a field, from which the last key of a page is
+// read. The group whose key is NULL, if any, comes last in a page of its
+// own. A non-nil error from fn stops the iteration and is returned.
+func AggregateInBatches[T any](q QueryAdapter, key string, batchSize int, fn func(batch []T) error) error {
+	if batchSize <= 0 {
+		batchSize = defaultBulkChunk
+	}
+	cols, err := SanitizeColumnNames([]string{key})
+	if err != nil {
+		return ErrInvalidBatchKey.Render(key, err.Error())
+	}
+	key = cols[0]
+
+	// a key > last bound over a wider group list would skip groups
+	if groups := q.Describe().GroupBy; len(groups) != 1 || !strings.EqualFold(groups[0], key) {
+		return ErrInvalidBatchKey.Render(key, fmt.Sprintf("query groups by %v, not by it alone", groups))
+	}
+
+	t := reflect.TypeOf((*T)(nil)).Elem()
+	if t.Kind() != reflect.Struct {
+		return ErrInvalidBatchKey.Render(key, fmt.Sprintf("%s is not a struct", t))
+	}
+	field, ok := cachedFieldMap(t)[normalize(key)]
+	if !ok {
+		return ErrInvalidBatchKey.Render(key, fmt.Sprintf("%s has no field for it", t.Name()))
+	}
+
+	// the caller's ORs must not reach past the keyset bound
+	if g, ok := q.(conditionGrouper); ok {
+		q = g.grouped()
+	}
+	page := whereTrusted(q, key+" IS NOT NULL").UnsafeOrder(key).Limit(batchSize)
+	for query := page; ; {
+		var batch []T
+		if err := query.Scan(&batch); err != nil {
+			return err
+		}
+		if len(batch) > 0 {
+			if err := fn(batch); err != nil {
+				return err
+			}
+		}
+		if len(batch) < batchSize {
+			break
+		}
+
+		last, err := field.value(reflect.ValueOf(batch[len(batch)-1]).FieldByIndex(field.Index))
+		if err != nil {
+			return err
+		}
+		query = whereTrusted(page, key+" > ?", last)
+	}
+
+	// key > last never matches NULL, so its group is fetched on its own
+	var nulls []T
+	if err := whereTrusted(q, key+" IS NULL").Scan(&nulls); err != nil {
+		return err
+	}
+	if len(nulls) == 0 {
+		return nil
+	}
+	return fn(nulls)
+}
@@ -0,0 +1,89 @@
+package orm
+
+import (
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/godev90/validator/faults"
+)
+
+type batchOrder struct {
+	ID         int64  `sql:"column:id;primaryKey"`
+	CustomerID int64  `sql:"column:customer_id"`
+	Status     string `sql:"column:status"`
+}
+
+func (batchOrder) TableName() string { return "orders" }
+
+type customerTotal struct {
+	CustomerID int64 `sql:"column:customer_id"`
+	Orders     int64 `sql:"column:orders"`
+}
+
+func TestAggregateInBatchesKeepsOrInsideKeysetBound(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	SetFlavor(db, FlavorPostgres)
+
+	const base = `SELECT customer_id, COUNT(*) AS orders FROM "orders" ` +
+		`WHERE (status = $1 OR (status = $2)) AND customer_id IS NOT NULL`
+	mock.ExpectQuery(base+` GROUP BY customer_id ORDER BY customer_id LIMIT 2`).
+		WithArgs("paid", "shipped").
+		WillReturnRows(sqlmock.NewRows([]string{"customer_id", "orders"}).AddRow(1, 3).AddRow(2, 5))
+	mock.ExpectQuery(base+` AND customer_id > $3 GROUP BY customer_id ORDER BY customer_id LIMIT 2`).
+		WithArgs("paid", "shipped", int64(2)).
+		WillReturnRows(sqlmock.NewRows([]string{"customer_id", "orders"}).AddRow(7, 1))
+	mock.ExpectQuery(`SELECT customer_id, COUNT(*) AS orders FROM "orders" `+
+		`WHERE (status = $1 OR (status = $2)) AND customer_id IS NULL GROUP BY customer_id`).
+		WithArgs("paid", "shipped").
+		WillReturnRows(sqlmock.NewRows([]string{"customer_id", "orders"}).AddRow(nil, 4))
+
+	q := NewSqlAdapter(db).UseModel(&batchOrder{}).
+		Select([]string{"customer_id", "COUNT(*) AS orders"}).
+		Where("status = ?", "paid").
+		Or("status = ?", "shipped").
+		GroupBy([]string{"customer_id"})
+
+	var got []customerTotal
+	err = AggregateInBatches(q, "customer_id", 2, func(batch []customerTotal) error {
+		got = append(got, batch...)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 4 || got[2].CustomerID != 7 || got[3].Orders != 4 {
+		t.Errorf("got %+v, want customers 1, 2 and 7, then the NULL group", got)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestAggregateInBatchesRejectsOtherGroups(t *testing.T) {
+	db, _, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	SetFlavor(db, FlavorPostgres)
+
+	q := NewSqlAdapter(db).UseModel(&batchOrder{}).
+		Select([]string{"customer_id", "COUNT(*) AS orders"})
+	for name, chain := range map[string]QueryAdapter{
+		"ungrouped":    q,
+		"wider groups": q.GroupBy([]string{"customer_id", "status"}),
+		"other group":  q.GroupBy([]string{"status"}),
+	} {
+		err := AggregateInBatches(chain, "customer_id", 2, func([]customerTotal) error {
+			t.Errorf("%s: fn called", name)
+			return nil
+		})
+		if !faults.Is(err, ErrInvalidBatchKey) {
+			t.Errorf("%s: err = %v, want ErrInvalidBatchKey", name, err)
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package orm
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"unicode/utf8"
+
+	"github.com/godev90/validator/faults"
+)
+
+var (
+	errAnonymize = fmt.Errorf("orm: invalid anonymization rule")
+	ErrAnonymize = faults.New(errAnonymize, &faults.ErrAttr{
+		Code: http.StatusInternalServerError,
+		Messages: []faults.LangPackage{
+			{
+				Tag:     faults.English,
+				Message: "orm: anonymize %s.%s: %s",
+			},
+		},
+	})
+)
+
+// Anonymizer replaces the value of a field on export. It gets the field's
+// Go value and returns one of the same type, or nil for the zero value.
+type Anonymizer func(value any) any
+
+// AnonymizePolicy maps columns to the Anonymizer DumpAnonymized applies to
+// them. It overrides anonymize tags; a nil Anonymizer exports the column
+// as it is.
+type AnonymizePolicy map[string]Anonymizer
+
+// Anonymizers are the rules an anonymize tag can name:
+//
+//	Email string `sql:"column:email;anonymize:email"`
+//
+// null exports the zero value (NULL for pointers and sql.Null*). hash,
+// email and mask rewrite text (string, *string, sql.NullString) and leave
+// other types as they are: hash to a hex digest, email to
+// user-<digest>@example.invalid, mask to the first character followed by
+// ***. Digests are keyed with a secret drawn when the process starts, so
+// equal values get equal digests within one run (joins and uniqueness
+// survive) but can't be looked up.
+var Anonymizers = map[string]Anonymizer{
+	"null": func(any) any { return nil },
+	"hash": anonymizeText(digest),
+	"email": anonymizeText(func(s string) string {
+		return "user-" + digest(s) + "@example.invalid"
+	}),
+	"mask": anonymizeText(func(s string) string {
+		if s == "" {
+			return s
+		}
+		_, n := utf8.DecodeRuneInString(s)
+		return s[:n] + "***"
+	}),
+}
+
+var digestKey = func() []byte {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		panic(err)
+	}
+	return key
+}()
+
+// digest returns the first 16 hex digits of the keyed hash of s.
+func digest(s string) string {
+	mac := hmac.New(sha256.New, digestKey)
+	mac.Write([]byte(s))
+	return hex.EncodeToString(mac.Sum(nil))[:16]
+}
+
+// anonymizeText lifts fn over the text types; other values pass through.
+func anonymizeText(fn func(string) string) Anonymizer {
+	return func(v any) any {
+		switch s := v.(type) {
+		case string:
+			return fn(s)
+		case *string:
+			if s == nil {
+				return s
+			}
+			out := fn(*s)
+			return &out
+		case sql.NullString:
+			if !s.Valid {
+				return s
+			}
+			return sql.NullString{String: fn(s.String), Valid: true}
+		}
+		return v
+	}
+}
+
+// DumpAnonymized is DumpModel with the anonymize tags of model and policy
+// applied to the exported values, so production-shaped data can seed a
+// staging database without leaking personal data:
+//
+//	policy := orm.AnonymizePolicy{
+//		"phone": func(any) any { return "+10000000000" },
+//		"email": orm.Anonymizers["email"],
+//	}
+//	n, err := adapter.(*orm.SqlQueryAdapter).DumpAnonymized(&User{}, w, policy)
+//
+// DumpModel applies the tags as well; policy may be nil.
+func (q *SqlQueryAdapter) DumpAnonymized(model Tabler, w io.Writer, policy AnonymizePolicy) (int, error) {
+	return dumpModel(q.UseModel(model), model, w, policy)
+}
+
+// DumpAnonymized is DumpModel with anonymization; see
+// SqlQueryAdapter.DumpAnonymized.
+func (g *GormAdapter) DumpAnonymized(model Tabler, w io.Writer, policy AnonymizePolicy) (int, error) {
+	return dumpModel(g.UseModel(model), model, w, policy)
+}
+
+// anonymizers returns the rule of each of fields, by position, from policy
+// or their anonymize tag; nil for fields exported as they are.
+func anonymizers(t reflect.Type, fields []modelField, policy AnonymizePolicy) ([]Anonymizer, error) {
+	rules := make([]Anonymizer, len(fields))
+	for i, f := range fields {
+		if fn, ok := policy[f.column]; ok {
+			rules[i] = fn
+			continue
+		}
+		name := tagOption(f.Tag.Get("sql"), "anonymize")
+		if name == "" {
+			continue
+		}
+		fn, ok := Anonymizers[name]
+		if !ok {
+			return nil, ErrAnonymize.Render(t.Name(), f.Name, fmt.Sprintf("unknown rule %q", name))
+		}
+		rules[i] = fn
+	}
+	return rules, nil
+}
+
+// anonymized returns the export value of field v under rule.
+func anonymized(t reflect.Type, f modelField, v reflect.Value, rule Anonymizer) (any, error) {
+	out := rule(v.Interface())
+	if out == nil {
+		return reflect.Zero(v.Type()).Interface(), nil
+	}
+	ov := reflect.ValueOf(out)
+	switch {
+	case ov.Type().AssignableTo(v.Type()):
+		return out, nil
+	case ov.Type().ConvertibleTo(v.Type()) && ov.Kind() == v.Kind():
+		return ov.Convert(v.Type()).Interface(), nil
+	}
+	return nil, ErrAnonymize.Render(t.Name(), f.Name, fmt.Sprintf("rule returned %T, want %s", out, v.Type()))
+}
@@ -0,0 +1,111 @@
+package orm
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/godev90/validator/faults"
+)
+
+// QueryBudget caps the number of statements and the cumulative database
+// time spent on behalf of one request. Attach it with WithQueryBudget.
+type QueryBudget struct {
+	MaxQueries  int           // 0 means unlimited
+	MaxDuration time.Duration // 0 means unlimited
+
+	// Enforce rejects statements once the budget is spent. When false the
+	// overrun is only logged (once per budget) and the statement runs.
+	Enforce bool
+
+	mu      sync.Mutex
+	queries int
+	elapsed time.Duration
+	warned  bool
+}
+
+type budgetCtxKey struct{}
+
+var (
+	errQueryBudgetExceeded = fmt.Errorf("orm: query budget exceeded")
+	ErrQueryBudgetExceeded = faults.New(errQueryBudgetExceeded, &faults.ErrAttr{
+		Code: http.StatusServiceUnavailable,
+		Messages: []faults.LangPackage{
+			{
+				Tag:     faults.English,
+				Message: "orm: query budget exceeded (%d queries, %s)",
+			},
+		},
+	})
+)
+
+// WithQueryBudget returns a context whose statements are counted against b.
+func WithQueryBudget(ctx context.Context, b *QueryBudget) context.Context {
+	return context.WithValue(ctx, budgetCtxKey{}, b)
+}
+
+// QueryBudgetFromContext returns the budget attached to ctx, if any.
+func QueryBudgetFromContext(ctx context.Context) *QueryBudget {
+	if ctx == nil {
+		return nil
+	}
+	b, _ := ctx.Value(budgetCtxKey{}).(*QueryBudget)
+	return b
+}
+
+// Usage reports the statements executed and database time spent so far.
+func (b *QueryBudget) Usage() (queries int, elapsed time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.queries, b.elapsed
+}
+
+func (b *QueryBudget) exceeded() bool {
+	return (b.MaxQueries > 0 && b.queries >= b.MaxQueries) ||
+		(b.MaxDuration > 0 && b.elapsed >= b.MaxDuration)
+}
+
+// admit decides whether one more statement may run.
+func (b *QueryBudget) admit() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !b.exceeded() {
+		return nil
+	}
+
+	if b.Enforce {
+		return ErrQueryBudgetExceeded.Render(b.queries, b.elapsed)
+	}
+
+	if !b.warned {
+		b.warned = true
+		log.Printf("WARNING: query budget exceeded (%d queries, %s)", b.queries, b.elapsed)
+	}
+	return nil
+}
+
+func (b *QueryBudget) spend(d time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.queries++
+	b.elapsed += d
+}
+
+func budgetInterceptor(c *queryCall, next func() error) error {
+	b := QueryBudgetFromContext(c.ctx)
+	if b == nil || c.dryRun {
+		return next()
+	}
+
+	if err := b.admit(); err != nil {
+		return err
+	}
+
+	err := next()
+	b.spend(c.elapsed)
+	return err
+}
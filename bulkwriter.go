@@ -0,0 +1,114 @@
+package orm
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+const defaultBulkChunk = 500
+
+// BulkWriter writes large sets of models in chunks, each in a transaction
+// of its own, at a bounded rate, so backfills can run against a production
+// database without starving its regular traffic:
+//
+//	w := orm.NewBulkWriter(db, 1000)
+//	w.RowsPerSecond = 5000
+//	w.Pause = 100 * time.Millisecond
+//	n, err := w.Write(ctx, rows)
+//
+// The limits are averages over the whole Write: a chunk starts once the
+// rows and statements written so far fit the rates, and no sooner than
+// Pause after the previous chunk committed.
+type BulkWriter struct {
+	ChunkSize           int           // rows per chunk; 500 when zero
+	RowsPerSecond       float64       // 0 for no row limit
+	StatementsPerSecond float64       // chunks per second; 0 for no limit
+	Pause               time.Duration // minimum gap between chunks
+
+	// WriteChunk writes one chunk in tx; BulkInsert when nil. Set it to
+	// write differently, e.g. an upsert on the same pacing.
+	WriteChunk func(tx *SqlTransactionAdapter, chunk []Tabler) error
+
+	db *sql.DB
+}
+
+// NewBulkWriter returns an unthrottled writer to db in chunks of chunkSize
+// rows.
+func NewBulkWriter(db *sql.DB, chunkSize int) *BulkWriter {
+	return &BulkWriter{db: db, ChunkSize: chunkSize}
+}
+
+// Write writes models chunk by chunk and returns how many were committed.
+// It stops at the first failing chunk, whose transaction is rolled back,
+// or when ctx is done; the chunks before it stay committed.
+func (w *BulkWriter) Write(ctx context.Context, models []Tabler) (int, error) {
+	if w.db == nil {
+		return 0, ErrNilPointer
+	}
+	size := w.ChunkSize
+	if size <= 0 {
+		size = defaultBulkChunk
+	}
+	write := w.WriteChunk
+	if write == nil {
+		write = func(tx *SqlTransactionAdapter, chunk []Tabler) error {
+			return tx.BulkInsert(chunk)
+		}
+	}
+
+	start := time.Now()
+	var written, stmts int
+	for written < len(models) {
+		chunk := models[written:min(written+size, len(models))]
+
+		err := WithTransaction(ctx, w.db, func(tx *SqlTransactionAdapter) error {
+			return write(tx, chunk)
+		})
+		if err != nil {
+			return written, err
+		}
+		written += len(chunk)
+		stmts++
+		if written == len(models) {
+			break
+		}
+
+		if err := sleepCtx(ctx, w.wait(start, written, stmts)); err != nil {
+			return written, err
+		}
+	}
+	return written, nil
+}
+
+// wait returns how long to hold off the next chunk, after rows and stmts
+// were written since start, to stay within the limits.
+func (w *BulkWriter) wait(start time.Time, rows, stmts int) time.Duration {
+	next := time.Now().Add(w.Pause)
+	if w.RowsPerSecond > 0 {
+		if t := start.Add(time.Duration(float64(rows) / w.RowsPerSecond * float64(time.Second))); t.After(next) {
+			next = t
+		}
+	}
+	if w.StatementsPerSecond > 0 {
+		if t := start.Add(time.Duration(float64(stmts) / w.StatementsPerSecond * float64(time.Second))); t.After(next) {
+			next = t
+		}
+	}
+	return time.Until(next)
+}
+
+// sleepCtx waits for d, or until ctx is done.
+func sleepCtx(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
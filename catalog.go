@@ -0,0 +1,272 @@
+package orm
+
+import (
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Catalog describes models as their struct tags map them, for data
+// dictionaries, schema reviews and API docs. It marshals to JSON as is.
+type Catalog struct {
+	Models []CatalogModel `json:"models"`
+}
+
+// CatalogModel is one model of a Catalog.
+type CatalogModel struct {
+	Name      string            `json:"name"` // Go type name
+	Table     string            `json:"table"`
+	Columns   []CatalogColumn   `json:"columns"` // in field order
+	Relations []CatalogRelation `json:"relations,omitempty"`
+	Indexes   []CatalogIndex    `json:"indexes,omitempty"`
+}
+
+// CatalogColumn is a column of a CatalogModel. Type and Format are the
+// JSON schema type of its values ("string", "integer", "number",
+// "boolean", "object" or "array") and, where it narrows them, their format
+// ("date-time", "uuid", "int32", "int64", "float", "double", "byte").
+type CatalogColumn struct {
+	Name         string `json:"name"`
+	Field        string `json:"field"`
+	JSON         string `json:"json,omitempty"` // the name filters and sorts take, see CachedSqlTablerAllowedFields
+	GoType       string `json:"goType"`
+	Type         string `json:"type"`
+	Format       string `json:"format,omitempty"`
+	SQLType      string `json:"sqlType,omitempty"` // from a type: tag
+	Nullable     bool   `json:"nullable"`
+	PrimaryKey   bool   `json:"primaryKey,omitempty"`
+	Default      string `json:"default,omitempty"`
+	Filterable   bool   `json:"filterable"` // allowed by the model's ColumnPolicy
+	Sortable     bool   `json:"sortable"`
+	Sensitive    bool   `json:"sensitive,omitempty"`
+	LegacyColumn string `json:"legacyColumn,omitempty"`
+}
+
+// CatalogRelation is a relation of a CatalogModel; see Preload for the
+// kinds and keys.
+type CatalogRelation struct {
+	Name      string `json:"name"`
+	Kind      string `json:"kind"`
+	Model     string `json:"model"`
+	Table     string `json:"table"`
+	LocalKey  string `json:"localKey"`
+	RemoteKey string `json:"remoteKey"`
+	JoinTable string `json:"joinTable,omitempty"`
+}
+
+// CatalogIndex is an index declared by index / uniqueIndex tags.
+type CatalogIndex struct {
+	Name    string   `json:"name"`
+	Unique  bool     `json:"unique,omitempty"`
+	Columns []string `json:"columns"`
+}
+
+var (
+	catalogMu     sync.Mutex
+	catalogModels []Tabler
+)
+
+// RegisterModels adds models to those ModelCatalog describes by default,
+// typically from the init functions of the packages declaring them. A model
+// type registered twice is kept once.
+func RegisterModels(models ...Tabler) {
+	catalogMu.Lock()
+	defer catalogMu.Unlock()
+	for _, m := range models {
+		t := reflect.TypeOf(m)
+		if !slices.ContainsFunc(catalogModels, func(r Tabler) bool { return reflect.TypeOf(r) == t }) {
+			catalogModels = append(catalogModels, m)
+		}
+		rememberModel(m)
+	}
+}
+
+// ModelCatalog describes models, or the registered ones when none are
+// given, sorted by table:
+//
+//	orm.RegisterModels(&User{}, &Order{})
+//
+//	catalog, err := orm.ModelCatalog()
+//	json.NewEncoder(w).Encode(catalog)
+//
+// Relations and indexes are checked as Preload and CreateIndexes would, and
+// their errors returned.
+func ModelCatalog(models ...Tabler) (*Catalog, error) {
+	if len(models) == 0 {
+		catalogMu.Lock()
+		models = slices.Clone(catalogModels)
+		catalogMu.Unlock()
+	}
+
+	catalog := &Catalog{Models: []CatalogModel{}}
+	for _, model := range models {
+		m, err := catalogModel(model)
+		if err != nil {
+			return nil, err
+		}
+		catalog.Models = append(catalog.Models, m)
+	}
+	sort.SliceStable(catalog.Models, func(i, j int) bool {
+		return catalog.Models[i].Table < catalog.Models[j].Table
+	})
+	return catalog, nil
+}
+
+func catalogModel(model Tabler) (CatalogModel, error) {
+	val, err := modelStruct(model, false)
+	if err != nil {
+		return CatalogModel{}, err
+	}
+	t := val.Type()
+	m := CatalogModel{Name: t.Name(), Table: model.TableName(), Columns: []CatalogColumn{}}
+
+	jsonNames := map[string]string{}
+	for name, col := range CachedSqlTablerAllowedFields(model) {
+		jsonNames[strings.ToLower(col)] = name
+	}
+	policy := ModelColumnPolicy(model)
+
+	for _, f := range modelFields(t) {
+		tag := f.Tag.Get("sql")
+		typ, format := catalogType(f)
+		m.Columns = append(m.Columns, CatalogColumn{
+			Name:         f.column,
+			Field:        f.Name,
+			JSON:         jsonNames[strings.ToLower(f.column)],
+			GoType:       f.Type.String(),
+			Type:         typ,
+			Format:       format,
+			SQLType:      tagOption(tag, "type"),
+			Nullable:     catalogNullable(f),
+			PrimaryKey:   f.pk,
+			Default:      tagOption(tag, "default"),
+			Filterable:   policy.AllowColumn(ColumnFilter, f.column) == nil,
+			Sortable:     policy.AllowColumn(ColumnOrder, f.column) == nil,
+			Sensitive:    tagFlag(tag, "sensitive"),
+			LegacyColumn: f.legacy,
+		})
+	}
+
+	rels, err := relationsOf(t)
+	if err != nil {
+		return CatalogModel{}, err
+	}
+	for _, name := range sortedKeys(rels, nil) {
+		rel := rels[name]
+		m.Relations = append(m.Relations, CatalogRelation{
+			Name:      rel.name,
+			Kind:      rel.kind,
+			Model:     rel.elem.Name(),
+			Table:     rel.model.TableName(),
+			LocalKey:  rel.localKey,
+			RemoteKey: rel.remoteKey,
+			JoinTable: rel.joinTable,
+		})
+	}
+
+	indexes, err := indexesOf(t, m.Table)
+	if err != nil {
+		return CatalogModel{}, err
+	}
+	for _, idx := range indexes {
+		m.Indexes = append(m.Indexes, CatalogIndex{Name: idx.name, Unique: idx.unique, Columns: idx.columns})
+	}
+	return m, nil
+}
+
+// catalogNullable reports whether f holds NULL: pointers and sql.Null*
+// types do, unless tagged notNull.
+func catalogNullable(f modelField) bool {
+	tag := f.Tag.Get("sql")
+	if tagFlag(tag, "notNull") || tagFlag(tag, "not null") || f.pk {
+		return false
+	}
+	if f.Type.Kind() == reflect.Ptr {
+		return true
+	}
+	return strings.HasPrefix(f.Type.String(), "sql.Null") || f.Type == gormDeletedAtT
+}
+
+// catalogType returns the JSON schema type and format of f's values.
+func catalogType(f modelField) (typ, format string) {
+	t := f.Type
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if f.timeFormat != "" {
+		return "string", "date-time" // scanned and encoded as time.Time
+	}
+
+	switch t {
+	case timeT, nullTimeT, gormDeletedAtT:
+		return "string", "date-time"
+	case nullStringT:
+		return "string", ""
+	case nullIntT:
+		return "integer", "int64"
+	case nullInt32T, nullInt16T:
+		return "integer", "int32"
+	case nullFloatT:
+		return "number", "double"
+	case nullBoolT:
+		return "boolean", ""
+	case uuidT:
+		return "string", "uuid"
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return "boolean", ""
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16:
+		return "integer", "int32"
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint32, reflect.Uint64:
+		return "integer", "int64"
+	case reflect.Float32:
+		return "number", "float"
+	case reflect.Float64:
+		return "number", "double"
+	case reflect.String:
+		return "string", ""
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return "string", "byte"
+		}
+		return "array", ""
+	case reflect.Map, reflect.Struct:
+		return "object", ""
+	}
+	return "string", ""
+}
+
+// OpenAPIParameter is an OpenAPI 3 query parameter.
+type OpenAPIParameter struct {
+	Name   string        `json:"name"`
+	In     string        `json:"in"`
+	Schema OpenAPISchema `json:"schema"`
+}
+
+// OpenAPISchema is the schema of an OpenAPIParameter.
+type OpenAPISchema struct {
+	Type   string `json:"type"`
+	Format string `json:"format,omitempty"`
+}
+
+// FilterParameters returns a query parameter, named by its JSON name, for
+// each filterable column of m that has one, to document list endpoints
+// whose filters are bound by JSON name.
+func (m CatalogModel) FilterParameters() []OpenAPIParameter {
+	params := []OpenAPIParameter{}
+	for _, c := range m.Columns {
+		if !c.Filterable || c.JSON == "" || c.Sensitive {
+			continue
+		}
+		params = append(params, OpenAPIParameter{
+			Name:   c.JSON,
+			In:     "query",
+			Schema: OpenAPISchema{Type: c.Type, Format: c.Format},
+		})
+	}
+	return params
+}
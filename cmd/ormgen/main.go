@@ -0,0 +1,224 @@
+// Command ormgen generates reflection-free row scanners for orm models.
+//
+// Add a directive next to the models and run go generate:
+//
+//	//go:generate go run github.com/godev90/orm/cmd/ormgen -type User,Order
+//
+// For every listed struct it emits a ScanRow(rows *sql.Rows) error method,
+// which SqlQueryAdapter uses instead of reflection, a <Type>Columns slice
+// holding the mapped column names and a <Type>Column<Field> constant per
+// column for building joins with orm.Col. Columns follow the same rules as the
+// adapter: the sql tag ("column:x" or a bare name), else the snake_cased
+// field name; sql:"-" and unexported fields are skipped. Every field is read
+// through orm.Nullable, so values convert as they would reflectively; queries
+// the scanner can't read exactly (embedded or nested structs, epoch, empty
+// string and legacy column settings, non-default chain options) fall back to
+// reflection.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"log"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+type (
+	model struct {
+		name   string
+		fields []field
+	}
+
+	field struct {
+		name   string
+		column string
+	}
+)
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("ormgen: ")
+
+	typeNames := flag.String("type", "", "comma-separated list of model type names (required)")
+	output := flag.String("output", "", "output file name; default <first type>_scan_gen.go")
+	flag.Parse()
+
+	if *typeNames == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	dir := "."
+	if args := flag.Args(); len(args) > 0 {
+		dir = args[0]
+	}
+
+	types := strings.Split(*typeNames, ",")
+	pkgName, models, err := loadModels(dir, types)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	src, err := generate(pkgName, models)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	name := *output
+	if name == "" {
+		name = strings.ToLower(types[0]) + "_scan_gen.go"
+	}
+	if err := os.WriteFile(filepath.Join(dir, name), src, 0o644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// loadModels parses the non-test Go files in dir and collects the requested
+// struct types.
+func loadModels(dir string, names []string) (string, []model, error) {
+	fset := token.NewFileSet()
+	pkgs, err := parser.ParseDir(fset, dir, func(fi os.FileInfo) bool {
+		return !strings.HasSuffix(fi.Name(), "_test.go")
+	}, 0)
+	if err != nil {
+		return "", nil, err
+	}
+	if len(pkgs) != 1 {
+		return "", nil, fmt.Errorf("expected one package in %s, found %d", dir, len(pkgs))
+	}
+
+	var pkg *ast.Package
+	for _, p := range pkgs {
+		pkg = p
+	}
+
+	structs := map[string]*ast.StructType{}
+	for _, f := range pkg.Files {
+		ast.Inspect(f, func(n ast.Node) bool {
+			if ts, ok := n.(*ast.TypeSpec); ok {
+				if st, ok := ts.Type.(*ast.StructType); ok {
+					structs[ts.Name.Name] = st
+				}
+			}
+			return true
+		})
+	}
+
+	models := make([]model, 0, len(names))
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		st, ok := structs[name]
+		if !ok {
+			return "", nil, fmt.Errorf("struct type %s not found in %s", name, dir)
+		}
+		models = append(models, model{name: name, fields: structFields(st)})
+	}
+	return pkg.Name, models, nil
+}
+
+func structFields(st *ast.StructType) []field {
+	var out []field
+	for _, f := range st.Fields.List {
+		var tag reflect.StructTag
+		if f.Tag != nil {
+			if s, err := strconv.Unquote(f.Tag.Value); err == nil {
+				tag = reflect.StructTag(s)
+			}
+		}
+		if tag.Get("sql") == "-" {
+			continue
+		}
+
+		for _, n := range f.Names {
+			if !n.IsExported() {
+				continue
+			}
+
+			col := columnFromTag(tag.Get("sql"))
+			if col == "" {
+				col = toSnake(n.Name)
+			}
+			out = append(out, field{
+				name:   n.Name,
+				column: strings.ToLower(col),
+			})
+		}
+	}
+	return out
+}
+
+// columnFromTag mirrors the adapter's sql tag parsing.
+func columnFromTag(tag string) string {
+	const prefix = "column:"
+	if strings.Contains(tag, prefix) {
+		for _, p := range strings.Split(tag, ";") {
+			if strings.HasPrefix(p, prefix) {
+				return strings.TrimPrefix(p, prefix)
+			}
+		}
+	} else if !strings.Contains(tag, ":") {
+		return tag
+	}
+	return ""
+}
+
+func toSnake(s string) string {
+	var out []rune
+	for i, r := range s {
+		if i > 0 && r >= 'A' && r <= 'Z' {
+			out = append(out, '_')
+		}
+		out = append(out, r)
+	}
+	return strings.ToLower(string(out))
+}
+
+func generate(pkgName string, models []model) ([]byte, error) {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "// Code generated by ormgen; DO NOT EDIT.\n\n")
+	fmt.Fprintf(&b, "package %s\n\n", pkgName)
+	fmt.Fprintf(&b, "import (\n\t\"database/sql\"\n\t\"strings\"\n\n\t\"github.com/godev90/orm\"\n)\n\n")
+
+	for _, m := range models {
+		fmt.Fprintf(&b, "// Column names of %s, for orm.Col.\nconst (\n", m.name)
+		for _, f := range m.fields {
+			fmt.Fprintf(&b, "\t%sColumn%s = %s\n", m.name, f.name, strconv.Quote(f.column))
+		}
+		b.WriteString(")\n\n")
+
+		fmt.Fprintf(&b, "// %sColumns lists the columns %s maps, in field order.\n", m.name, m.name)
+		fmt.Fprintf(&b, "var %sColumns = []string{", m.name)
+		for i, f := range m.fields {
+			if i > 0 {
+				b.WriteString(", ")
+			}
+			b.WriteString(strconv.Quote(f.column))
+		}
+		b.WriteString("}\n\n")
+
+		fmt.Fprintf(&b, "// ScanRow implements orm.RowScanner.\n")
+		fmt.Fprintf(&b, "func (m *%s) ScanRow(rows *sql.Rows) error {\n", m.name)
+		b.WriteString("\tcols, err := rows.Columns()\n\tif err != nil {\n\t\treturn err\n\t}\n\n")
+		b.WriteString("\tdest := make([]any, len(cols))\n")
+		b.WriteString("\tfor i, col := range cols {\n")
+		b.WriteString("\t\tif j := strings.LastIndexByte(col, '.'); j >= 0 {\n\t\t\tcol = col[j+1:]\n\t\t}\n")
+		b.WriteString("\t\tswitch strings.ToLower(strings.Trim(col, \"`\\\"\")) {\n")
+		for _, f := range m.fields {
+			fmt.Fprintf(&b, "\t\tcase %s:\n", strconv.Quote(f.column))
+			fmt.Fprintf(&b, "\t\t\tdest[i] = orm.Nullable(&m.%s)\n", f.name)
+		}
+		b.WriteString("\t\tdefault:\n\t\t\tdest[i] = new(any)\n\t\t}\n\t}\n")
+		b.WriteString("\treturn rows.Scan(dest...)\n}\n\n")
+	}
+
+	return format.Source(b.Bytes())
+}
@@ -0,0 +1,385 @@
+package orm
+
+import (
+	"bytes"
+	"cmp"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/godev90/validator/faults"
+)
+
+var (
+	errCompare = fmt.Errorf("orm: cannot compare tables")
+	ErrCompare = faults.New(errCompare, &faults.ErrAttr{
+		Code: http.StatusBadRequest,
+		Messages: []faults.LangPackage{
+			{
+				Tag:     faults.English,
+				Message: "orm: compare %s: %s",
+			},
+		},
+	})
+)
+
+// maxRowDiffs caps the differences a TableDiff lists; its counts go on.
+const maxRowDiffs = 1000
+
+// RowDiffKind says how a row differs between the sides of CompareTables.
+type RowDiffKind string
+
+const (
+	RowMissing RowDiffKind = "missing" // in src, not in dst
+	RowExtra   RowDiffKind = "extra"   // in dst, not in src
+	RowChanged RowDiffKind = "changed" // in both, with other values
+)
+
+// RowDiff is one row that differs between src and dst.
+type RowDiff struct {
+	Kind    RowDiffKind
+	Key     []any    // the values of the key columns
+	Columns []string // the columns that differ, for RowChanged
+	Src     Tabler   // the row in src; nil for RowExtra
+	Dst     Tabler   // the row in dst; nil for RowMissing
+}
+
+// TableDiff is the result of CompareTables.
+type TableDiff struct {
+	Table   string
+	SrcRows int // rows read from src
+	DstRows int // rows read from dst
+	Missing int
+	Extra   int
+	Changed int
+	Diffs   []RowDiff // the first 1000 differences, in key order
+}
+
+// Equal reports whether src and dst hold the same rows.
+func (d *TableDiff) Equal() bool {
+	return d.Missing == 0 && d.Extra == 0 && d.Changed == 0
+}
+
+// CompareTables reads the rows of model's table from src and dst, both
+// ordered by keyCols (the primary key when empty), and reports the rows
+// missing from dst, the extra ones in it and the ones whose columns
+// differ, to verify a migration or a replica:
+//
+//	diff, err := orm.CompareTables(
+//		orm.NewSqlAdapter(primary), orm.NewSqlAdapter(replica),
+//		&Order{}, []string{"id"})
+//	if err == nil && !diff.Equal() {
+//		log.Printf("%d missing, %d extra, %d changed", diff.Missing, diff.Extra, diff.Changed)
+//	}
+//
+// The sides are read concurrently and merged as they stream, so only the
+// differences are held (gorm reads a side in full first). Conditions set
+// on src and dst apply, e.g. Where("created_at < ?", cutoff) to leave out
+// rows still being written. Values compare after Value (driver.Valuer),
+// times with time.Equal. Both databases must sort the key the same way:
+// use numeric keys or a binary collation, or a side read out of order
+// fails with ErrCompare.
+func CompareTables(src, dst QueryAdapter, model Tabler, keyCols []string) (*TableDiff, error) {
+	val, err := modelStruct(model, false)
+	if err != nil {
+		return nil, err
+	}
+	t := val.Type()
+	fields := modelFields(t)
+	table := model.TableName()
+
+	var keys []modelField
+	if len(keyCols) == 0 {
+		for _, f := range fields {
+			if f.pk {
+				keys = append(keys, f)
+				keyCols = append(keyCols, f.column)
+			}
+		}
+		if len(keys) == 0 {
+			return nil, ErrCompare.Render(table, "no key columns and no primary key")
+		}
+	} else {
+		fm := cachedFieldMap(t)
+		for _, col := range keyCols {
+			f, ok := fm[strings.ToLower(col)]
+			if !ok {
+				return nil, ErrCompare.Render(table, fmt.Sprintf("%s has no column %q", t.Name(), col))
+			}
+			keys = append(keys, *f)
+		}
+	}
+	order := strings.Join(keyCols, ", ")
+
+	done := make(chan struct{})
+	srcRows, dstRows := make(chan reflect.Value, 64), make(chan reflect.Value, 64)
+	srcErr, dstErr := make(chan error, 1), make(chan error, 1)
+	go func() { srcErr <- streamRows(src.UseModel(model).Order(order), t, srcRows, done) }()
+	go func() { dstErr <- streamRows(dst.UseModel(model).Order(order), t, dstRows, done) }()
+
+	diff := &TableDiff{Table: table}
+	mergeErr := mergeRows(diff, table, keys, fields, srcRows, dstRows)
+	close(done)
+	// let the readers see done and finish
+	for range srcRows {
+	}
+	for range dstRows {
+	}
+
+	for _, err := range []error{mergeErr, <-srcErr, <-dstErr} {
+		if err != nil && !errors.Is(err, errCompareStopped) {
+			return nil, err
+		}
+	}
+	return diff, nil
+}
+
+var errCompareStopped = errors.New("orm: compare stopped")
+
+// streamRows scans the rows of q into copies of t sent to out, which it
+// closes when done, unless done is closed first.
+func streamRows(q QueryAdapter, t reflect.Type, out chan<- reflect.Value, done <-chan struct{}) error {
+	defer close(out)
+	send := func(dest any) error {
+		row := reflect.New(t)
+		row.Elem().Set(reflect.Indirect(reflect.ValueOf(dest)))
+		select {
+		case out <- row:
+			// sent; keep it out of the result
+			return ErrSkipRow
+		case <-done:
+			return errCompareStopped
+		}
+	}
+	rows := reflect.New(reflect.SliceOf(t))
+	return q.OnRow(send).Scan(rows.Interface())
+}
+
+// mergeRows walks the ordered rows of both sides and records their
+// differences in diff.
+func mergeRows(diff *TableDiff, table string, keys, fields []modelField, srcRows, dstRows <-chan reflect.Value) error {
+	var prevSrc, prevDst []any
+	next := func(ch <-chan reflect.Value, prev *[]any, side string, count *int) (reflect.Value, []any, error) {
+		row, ok := <-ch
+		if !ok {
+			return reflect.Value{}, nil, nil
+		}
+		key, err := rowKey(row.Elem(), keys)
+		if err != nil {
+			return reflect.Value{}, nil, err
+		}
+		if *prev != nil && compareKeys(*prev, key) > 0 {
+			return reflect.Value{}, nil, ErrCompare.Render(table, fmt.Sprintf("%s rows are not in key order at %v; check the key's collation", side, key))
+		}
+		*prev = key
+		*count++
+		return row, key, nil
+	}
+
+	srow, skey, err := next(srcRows, &prevSrc, "src", &diff.SrcRows)
+	if err != nil {
+		return err
+	}
+	drow, dkey, err := next(dstRows, &prevDst, "dst", &diff.DstRows)
+	if err != nil {
+		return err
+	}
+
+	for srow.IsValid() || drow.IsValid() {
+		c := 0
+		switch {
+		case !drow.IsValid():
+			c = -1
+		case !srow.IsValid():
+			c = 1
+		default:
+			c = compareKeys(skey, dkey)
+		}
+
+		switch {
+		case c < 0:
+			diff.Missing++
+			diff.add(RowDiff{Kind: RowMissing, Key: skey, Src: asTabler(srow)})
+		case c > 0:
+			diff.Extra++
+			diff.add(RowDiff{Kind: RowExtra, Key: dkey, Dst: asTabler(drow)})
+		default:
+			cols, err := changedColumns(srow.Elem(), drow.Elem(), fields)
+			if err != nil {
+				return err
+			}
+			if len(cols) > 0 {
+				diff.Changed++
+				diff.add(RowDiff{Kind: RowChanged, Key: skey, Columns: cols, Src: asTabler(srow), Dst: asTabler(drow)})
+			}
+		}
+
+		if c <= 0 {
+			if srow, skey, err = next(srcRows, &prevSrc, "src", &diff.SrcRows); err != nil {
+				return err
+			}
+		}
+		if c >= 0 {
+			if drow, dkey, err = next(dstRows, &prevDst, "dst", &diff.DstRows); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (d *TableDiff) add(rd RowDiff) {
+	if len(d.Diffs) < maxRowDiffs {
+		d.Diffs = append(d.Diffs, rd)
+	}
+}
+
+func asTabler(row reflect.Value) Tabler {
+	t, _ := row.Interface().(Tabler)
+	return t
+}
+
+// rowKey returns the bound values of the key fields of row.
+func rowKey(row reflect.Value, keys []modelField) ([]any, error) {
+	key := make([]any, len(keys))
+	for i, f := range keys {
+		v, err := f.value(row.FieldByIndex(f.Index))
+		if err != nil {
+			return nil, err
+		}
+		key[i] = v
+	}
+	return key, nil
+}
+
+// changedColumns returns the columns of fields whose values differ between
+// the rows a and b.
+func changedColumns(a, b reflect.Value, fields []modelField) ([]string, error) {
+	var cols []string
+	for _, f := range fields {
+		av, err := f.value(a.FieldByIndex(f.Index))
+		if err != nil {
+			return nil, err
+		}
+		bv, err := f.value(b.FieldByIndex(f.Index))
+		if err != nil {
+			return nil, err
+		}
+		if compareValues(av, bv) != 0 {
+			cols = append(cols, f.column)
+		}
+	}
+	return cols, nil
+}
+
+func compareKeys(a, b []any) int {
+	for i := range a {
+		if c := compareValues(a[i], b[i]); c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+// compareValues orders two bound values: NULL first, numbers by value
+// whatever their Go type, text and bytes bytewise, times by instant. Values
+// of other or mismatched types compare by their printed form.
+func compareValues(a, b any) int {
+	a, b = derefValue(a), derefValue(b)
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return -1
+	case b == nil:
+		return 1
+	}
+
+	av, bv := reflect.ValueOf(a), reflect.ValueOf(b)
+	if c, ok := compareNumbers(av, bv); ok {
+		return c
+	}
+	switch x := a.(type) {
+	case time.Time:
+		if y, ok := b.(time.Time); ok {
+			return x.Compare(y)
+		}
+	case []byte:
+		if y, ok := b.([]byte); ok {
+			return bytes.Compare(x, y)
+		}
+	case bool:
+		if y, ok := b.(bool); ok {
+			return cmp.Compare(boolInt(x), boolInt(y))
+		}
+	}
+	if av.Kind() == reflect.String && bv.Kind() == reflect.String {
+		return strings.Compare(av.String(), bv.String())
+	}
+	if reflect.DeepEqual(a, b) {
+		return 0
+	}
+	return strings.Compare(fmt.Sprint(a), fmt.Sprint(b))
+}
+
+// derefValue follows pointers; nil pointers are nil.
+func derefValue(v any) any {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return nil
+		}
+		rv = rv.Elem()
+	}
+	if !rv.IsValid() {
+		return nil
+	}
+	return rv.Interface()
+}
+
+// compareNumbers orders a and b when both are numbers, exactly for
+// integers of any size and sign.
+func compareNumbers(a, b reflect.Value) (int, bool) {
+	switch {
+	case a.CanInt() && b.CanInt():
+		return cmp.Compare(a.Int(), b.Int()), true
+	case a.CanUint() && b.CanUint():
+		return cmp.Compare(a.Uint(), b.Uint()), true
+	case a.CanInt() && b.CanUint():
+		if a.Int() < 0 {
+			return -1, true
+		}
+		return cmp.Compare(uint64(a.Int()), b.Uint()), true
+	case a.CanUint() && b.CanInt():
+		c, _ := compareNumbers(b, a)
+		return -c, true
+	}
+	af, aok := floatOf(a)
+	bf, bok := floatOf(b)
+	if !aok || !bok {
+		return 0, false
+	}
+	return cmp.Compare(af, bf), true
+}
+
+func floatOf(v reflect.Value) (float64, bool) {
+	switch {
+	case v.CanInt():
+		return float64(v.Int()), true
+	case v.CanUint():
+		return float64(v.Uint()), true
+	case v.CanFloat():
+		return v.Float(), true
+	}
+	return 0, false
+}
+
+func boolInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -4,10 +4,12 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"fmt"
 	"reflect"
 	"regexp"
 	"strings"
 	"sync"
+	"unicode"
 )
 
 // Constants for security validation
@@ -17,6 +19,7 @@ const (
 	maxOrderByLen     = 256
 	maxJoinClauseLen  = 512
 	maxWhereClauseLen = 1024
+	maxCommentLen     = 256
 )
 
 // Security validation patterns
@@ -27,21 +30,19 @@ var (
 	// Order by pattern (column ASC/DESC, with optional table prefix)
 	orderByPattern = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*(\.[a-zA-Z_][a-zA-Z0-9_]*)?\s*(ASC|DESC)?(\s*,\s*[a-zA-Z_][a-zA-Z0-9_]*(\.[a-zA-Z_][a-zA-Z0-9_]*)?\s*(ASC|DESC)?)*$`)
 
-	// Basic JOIN pattern validation
-	joinPattern = regexp.MustCompile(`^(INNER|LEFT|RIGHT|FULL\s+OUTER)?\s*JOIN\s+[a-zA-Z_][a-zA-Z0-9_]*(\s+AS\s+[a-zA-Z_][a-zA-Z0-9_]*)?\s+ON\s+.+$`)
-
 	// Column name validation
 	columnNamePattern = regexp.MustCompile(`^[a-zA-Z0-9_]+$`)
 )
 
 // Security errors
 var (
-	ErrInvalidIdentifier = errors.New("orm: invalid SQL identifier")
-	ErrInvalidOrderBy    = errors.New("orm: invalid ORDER BY clause")
-	ErrInvalidJoinClause = errors.New("orm: invalid JOIN clause")
-	ErrInvalidColumnName = errors.New("orm: invalid column name")
-	ErrIdentifierTooLong = errors.New("orm: identifier too long")
-	ErrSuspiciousPattern = errors.New("orm: suspicious SQL pattern detected")
+	ErrInvalidIdentifier   = errors.New("orm: invalid SQL identifier")
+	ErrInvalidOrderBy      = errors.New("orm: invalid ORDER BY clause")
+	ErrInvalidJoinClause   = errors.New("orm: invalid JOIN clause")
+	ErrInvalidColumnName   = errors.New("orm: invalid column name")
+	ErrInvalidHavingClause = errors.New("orm: invalid HAVING clause")
+	ErrIdentifierTooLong   = errors.New("orm: identifier too long")
+	ErrSuspiciousPattern   = errors.New("orm: suspicious SQL pattern detected")
 )
 
 // Common suspicious patterns for validation
@@ -60,13 +61,81 @@ var (
 func validateSuspiciousPatterns(input string, patterns []string) error {
 	upperInput := strings.ToUpper(input)
 	for _, pattern := range patterns {
-		if strings.Contains(upperInput, pattern) {
+		if containsPattern(upperInput, pattern) {
 			return ErrSuspiciousPattern
 		}
 	}
 	return nil
 }
 
+// containsPattern matches keyword patterns as whole words, so identifiers
+// such as deleted_at or updated_by don't trip DELETE / UPDATE. Symbol
+// patterns ("--", ";") and prefixes ("PG_", "SYS.") match anywhere, since
+// pg_sleep or sys.users must still be caught.
+func containsPattern(upperInput, pattern string) bool {
+	if !isKeywordPattern(pattern) {
+		return strings.Contains(upperInput, pattern)
+	}
+	for from := 0; ; {
+		i := strings.Index(upperInput[from:], pattern)
+		if i < 0 {
+			return false
+		}
+		i += from
+		end := i + len(pattern)
+
+		if (i == 0 || !isWordByte(upperInput[i-1])) && (end == len(upperInput) || !isWordByte(upperInput[end])) {
+			return true
+		}
+		from = i + 1
+	}
+}
+
+// isKeywordPattern reports whether pattern is a whole keyword, such as
+// DELETE or INFORMATION_SCHEMA, rather than a symbol or a prefix ending in
+// "_" or ".".
+func isKeywordPattern(pattern string) bool {
+	for i := 0; i < len(pattern); i++ {
+		if !isWordByte(pattern[i]) {
+			return false
+		}
+	}
+	return pattern[len(pattern)-1] != '_'
+}
+
+func isWordByte(c byte) bool {
+	return c == '_' || c >= '0' && c <= '9' || c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z'
+}
+
+// sanitizeComment makes free text safe to embed in /* ... */: comment
+// delimiters are removed, control characters and runs of whitespace become
+// one space, and the result is capped at maxCommentLen bytes.
+func sanitizeComment(text string) string {
+	for strings.Contains(text, "*/") || strings.Contains(text, "/*") {
+		text = strings.ReplaceAll(text, "*/", "")
+		text = strings.ReplaceAll(text, "/*", "")
+	}
+	text = strings.Join(strings.FieldsFunc(text, func(r rune) bool {
+		return unicode.IsSpace(r) || unicode.IsControl(r)
+	}), " ")
+
+	if len(text) > maxCommentLen {
+		text = strings.ToValidUTF8(text[:maxCommentLen], "")
+	}
+	return text
+}
+
+// joinComment adds text to an existing statement comment.
+func joinComment(existing, text string) string {
+	if text = sanitizeComment(text); text == "" {
+		return existing
+	}
+	if existing == "" {
+		return text
+	}
+	return existing + "; " + text
+}
+
 func validateLength(input string, maxLen int, errType error) error {
 	if len(input) > maxLen {
 		return errType
@@ -79,6 +148,20 @@ type (
 		TableName() string
 	}
 
+	// DefaultScoper is implemented by models whose invariant conditions
+	// (e.g. archived = false) must apply to every query built on them.
+	// The scope is applied when the statement is built; Unscoped skips it.
+	DefaultScoper interface {
+		DefaultScope() ScopeFunc
+	}
+
+	// RowScanner is implemented by models with a generated scanner (see
+	// cmd/ormgen). SqlQueryAdapter calls it for each row instead of mapping
+	// columns through reflection.
+	RowScanner interface {
+		ScanRow(rows *sql.Rows) error
+	}
+
 	QueryAdapter interface {
 		WithContext(ctx context.Context) QueryAdapter
 		Count(target *int64) error
@@ -91,6 +174,8 @@ type (
 		Model() Tabler
 		UseModel(Tabler) QueryAdapter
 		Join(joinClause string, args ...any) QueryAdapter
+		JoinModel(model Tabler, on JoinOn) QueryAdapter
+		Joins(relation string) QueryAdapter
 		Scopes(fs ...ScopeFunc) QueryAdapter
 		Where(query any, args ...any) QueryAdapter
 		Or(query any, args ...any) QueryAdapter
@@ -98,8 +183,33 @@ type (
 		GroupBy(groupbys []string) QueryAdapter
 		Having(havings []string, args ...any) QueryAdapter
 		Clone() QueryAdapter
+		ToSQL() (string, []any)
+		Describe() QueryDescription
+		DryRun(rec *StatementRecorder) QueryAdapter
+		Explain(analyze bool) (string, error)
+		PrepareStmt() QueryAdapter
+		Unscoped() QueryAdapter
+		WithDefaultScope(fs ...ScopeFunc) QueryAdapter
+		OnRow(fn RowFunc) QueryAdapter
+		Preload(relation string, scopes ...ScopeFunc) QueryAdapter
+		WithTablePrefix(prefix string) QueryAdapter
+		Comment(text string) QueryAdapter
+		PlanCache(mode PlanCacheMode) QueryAdapter
+		// StrictValidation makes rejected clauses fail the chain instead
+		// of being dropped; Error returns the first one.
+		StrictValidation() QueryAdapter
+		Error() error
+		// Debug logs every statement of the chain, see DebugOn.
+		Debug() QueryAdapter
 		Driver() driverFlavor
+		// DB returns the *sql.DB statements run on, for dropping down to
+		// database/sql; nil for adapters not built on it (pgx). See also
+		// SqlQueryAdapter.Tx, GormAdapter.GormDB, PgxAdapter.Pool and
+		// SqlxAdapter.Sqlx.
 		DB() *sql.DB
+		// Stats reports the connection pool statements run on, e.g. for a
+		// health endpoint; zero when there is none.
+		Stats() sql.DBStats
 
 		// Safe methods for backward compatibility and explicit safety
 		SafeOrder(order string) QueryAdapter
@@ -143,6 +253,13 @@ func validateOrderByFormat(orderBy string) error {
 	return nil
 }
 
+// ValidateJoinClause parses joinClause as one or more joins: INNER, LEFT,
+// RIGHT, FULL [OUTER] or CROSS JOIN of a table, with an optional alias and
+// an ON condition or USING column list. Conditions follow the HAVING
+// grammar (see ValidateHavingClause) and may use ? placeholders. A simple
+// sub-select (SELECT ... FROM ... [WHERE] [GROUP BY] [HAVING]) is accepted
+// as the joined source only after AllowJoinSubselects. System catalogs
+// (information_schema, pg_*, mysql.*, sys.*) can't be referenced anywhere.
 func ValidateJoinClause(joinClause string) error {
 	if len(joinClause) == 0 {
 		return nil
@@ -152,16 +269,12 @@ func ValidateJoinClause(joinClause string) error {
 		return err
 	}
 
-	if err := validateSuspiciousPatterns(joinClause, commonSuspiciousPatterns); err != nil {
+	if err := validateSuspiciousPatterns(joinClause, databaseMetadataPatterns); err != nil {
 		return err
 	}
 
-	return validateJoinFormat(joinClause)
-}
-
-func validateJoinFormat(joinClause string) error {
-	if !joinPattern.MatchString(strings.TrimSpace(joinClause)) {
-		return ErrInvalidJoinClause
+	if err := validateJoinSQL(joinClause); err != nil {
+		return fmt.Errorf("%w %q: %v", ErrInvalidJoinClause, joinClause, err)
 	}
 	return nil
 }
@@ -214,11 +327,21 @@ func ValidateIdentifier(identifier string) error {
 	return nil
 }
 
+// SanitizeColumnNames validates plain column names. Dialect-quoted names
+// ("user", `order`, "u"."name") are accepted too and come back with their
+// quoting normalized; see SanitizeSelectFields.
 func SanitizeColumnNames(columns []string) ([]string, error) {
 	sanitized := make([]string, 0, len(columns))
 
 	for _, col := range columns {
 		trimmed := strings.TrimSpace(col)
+		if quoted, ok, err := normalizeQuotedIdent(trimmed); ok {
+			if err != nil {
+				return nil, err
+			}
+			sanitized = append(sanitized, quoted)
+			continue
+		}
 		if err := ValidateColumnName(trimmed); err != nil {
 			return nil, err
 		}
@@ -258,10 +381,21 @@ func validateStringSliceParameter(slice []string) ([]string, error) {
 func sanitizeSelectField(field string) (string, error) {
 	trimmed := strings.TrimSpace(field)
 
-	// Allow wildcard
+	// Allow wildcard, alone or for one table of a join (u.*)
 	if trimmed == "*" {
 		return trimmed, nil
 	}
+	if table, ok := strings.CutSuffix(trimmed, ".*"); ok {
+		table, err := sanitizeSelectField(table)
+		if err != nil || table == "*" {
+			return "", ErrInvalidColumnName
+		}
+		return table + ".*", nil
+	}
+
+	if quoted, ok, err := normalizeQuotedIdent(trimmed); ok {
+		return quoted, err
+	}
 
 	// Validate table.column format
 	return validateTableColumnFormat(trimmed)
@@ -280,7 +414,43 @@ func validateTableColumnFormat(field string) (string, error) {
 	}
 
 	return field, nil
-} // Enhanced ValidateAndSanitizeParameter with better type handling
+}
+
+// normalizeQuotedIdent validates a column, optionally table-qualified, with
+// at least one part quoted for its dialect ("user"."name", `order`). Each
+// quoted part must still be a plain identifier inside the quotes, and all
+// quoted parts must use the same quote character. The result has the
+// whitespace around its parts removed. ok is false when nothing is quoted.
+func normalizeQuotedIdent(ident string) (normalized string, ok bool, err error) {
+	if !strings.ContainsAny(ident, "`\"") {
+		return "", false, nil
+	}
+
+	parts := strings.Split(ident, ".")
+	if len(parts) > 2 {
+		return "", true, ErrInvalidColumnName
+	}
+
+	var quote byte
+	for i, part := range parts {
+		part = strings.TrimSpace(part)
+		parts[i] = part
+
+		if len(part) >= 2 && (part[0] == '"' || part[0] == '`') && part[len(part)-1] == part[0] {
+			if quote != 0 && part[0] != quote {
+				return "", true, ErrInvalidColumnName
+			}
+			quote = part[0]
+			part = part[1 : len(part)-1]
+		}
+		if err := ValidateIdentifier(part); err != nil {
+			return "", true, err
+		}
+	}
+	return strings.Join(parts, "."), true, nil
+}
+
+// Enhanced ValidateAndSanitizeParameter with better type handling
 func ValidateAndSanitizeParameter(param interface{}) (interface{}, error) {
 	if param == nil {
 		return nil, nil
@@ -341,21 +511,19 @@ func SanitizeSelectFields(fields []string) ([]string, error) {
 
 	return sanitized, nil
 } // Validate HAVING clause
+// ValidateHavingClause checks each HAVING condition against an expression
+// grammar rather than the WHERE blacklist: columns, numbers, ? placeholders,
+// aggregate functions (COUNT, SUM, AVG, MIN, MAX and a few scalar helpers),
+// comparison, arithmetic and logical operators, IN, BETWEEN, LIKE and
+// IS [NOT] NULL. String literals, comments, statement separators and
+// sub-selects are rejected; values belong in placeholders.
 func ValidateHavingClause(having []string) error {
 	for _, clause := range having {
-		if err := ValidateWhereClause(clause); err != nil {
+		if err := validateLength(clause, maxWhereClauseLen, ErrInvalidHavingClause); err != nil {
 			return err
 		}
-
-		// Additional validation for HAVING clauses
-		upperClause := strings.ToUpper(clause)
-		if !strings.Contains(upperClause, "COUNT") &&
-			!strings.Contains(upperClause, "SUM") &&
-			!strings.Contains(upperClause, "AVG") &&
-			!strings.Contains(upperClause, "MIN") &&
-			!strings.Contains(upperClause, "MAX") {
-			// HAVING clauses typically use aggregate functions
-			// But we'll allow other clauses too for flexibility
+		if err := validateSQLExpr(clause, aggregateFuncs); err != nil {
+			return fmt.Errorf("%w %q: %v", ErrInvalidHavingClause, clause, err)
 		}
 	}
 	return nil
@@ -395,6 +563,7 @@ func SafeHavingClauses(clauses []string) ([]string, error) {
 type FieldMapCache struct {
 	gormCache sync.Map
 	sqlCache  sync.Map
+	scanCache sync.Map
 }
 
 var fieldMapCache = &FieldMapCache{}
@@ -440,6 +609,10 @@ func ClearFieldMapCache() {
 		fieldMapCache.sqlCache.Delete(key)
 		return true
 	})
+	fieldMapCache.scanCache.Range(func(key, value interface{}) bool {
+		fieldMapCache.scanCache.Delete(key)
+		return true
+	})
 }
 
 func applyScopes(a QueryAdapter, fs ...ScopeFunc) QueryAdapter {
@@ -499,15 +672,37 @@ func extractAllowedFields(model Tabler, tagName string) map[string]string {
 	}
 
 	fields := make(map[string]string)
+	addAllowedFields(fields, t, tagName, "")
+	return fields
+}
+
+// addAllowedFields adds the fields of the struct type t to fields. The
+// fields of anonymous structs are promoted, as encoding/json does, with the
+// struct's embeddedPrefix in front of their columns; outer fields win.
+func addAllowedFields(fields map[string]string, t reflect.Type, tagName, prefix string) {
+	var embedded []reflect.StructField
 	for i := 0; i < t.NumField(); i++ {
 		field := t.Field(i)
+		if field.Anonymous && field.Tag.Get("json") == "" && isEmbedded(field) {
+			embedded = append(embedded, field)
+			continue
+		}
 		jsonName, columnName := extractFieldMapping(field, tagName)
 
-		if columnName != "" && isValidColumnName(columnName) {
-			fields[jsonName] = columnName
+		if columnName != "" && isValidColumnName(prefix+columnName) {
+			fields[jsonName] = prefix + columnName
+		}
+	}
+
+	for _, field := range embedded {
+		inner := make(map[string]string)
+		addAllowedFields(inner, field.Type, tagName, prefix+tagOption(field.Tag.Get(tagName), "embeddedPrefix"))
+		for jsonName, columnName := range inner {
+			if _, ok := fields[jsonName]; !ok {
+				fields[jsonName] = columnName
+			}
 		}
 	}
-	return fields
 }
 
 func extractFieldMapping(field reflect.StructField, tagName string) (jsonName, columnName string) {
@@ -522,12 +717,38 @@ func extractFieldMapping(field reflect.StructField, tagName string) (jsonName, c
 	return jsonName, columnName
 }
 
-func isValidColumnName(columnName string) bool {
-	return columnNamePattern.MatchString(columnName)
-}
+// primaryKeyColumn returns the column of the field tagged primaryKey in
+// either the sql or gorm tag, falling back to "id".
+func primaryKeyColumn(model Tabler) string {
+	t := reflect.TypeOf(model)
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return "id"
+	}
 
-var debug = false
+	for _, f := range modelFields(t) {
+		if f.pk {
+			return f.column
+		}
+	}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
 
-func DebugOn() {
-	debug = true
+		if tag := field.Tag.Get("gorm"); strings.Contains(tag, "primaryKey") {
+			if col := extractColumnFromTag(tag, columnTagPrefix); col != "" {
+				return col
+			}
+			return toSnake(field.Name)
+		}
+	}
+	return "id"
+}
+
+func isValidColumnName(columnName string) bool {
+	return columnNamePattern.MatchString(columnName)
 }
@@ -0,0 +1,73 @@
+package orm
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestValidateWhereClauseMetadata(t *testing.T) {
+	rejected := []string{
+		"pg_sleep(10)",
+		"id = 1 AND pg_sleep(10) IS NULL",
+		"pg_read_file('/etc/passwd') IS NOT NULL",
+		"EXISTS (SELECT 1 FROM information_schema.tables)",
+		"id IN (SELECT id FROM sys.users)",
+		"mysql.user IS NOT NULL",
+		"deleted_at IS NULL; DROP TABLE users",
+	}
+	for _, clause := range rejected {
+		if err := ValidateWhereClause(clause); !errors.Is(err, ErrSuspiciousPattern) {
+			t.Errorf("ValidateWhereClause(%q) = %v, want ErrSuspiciousPattern", clause, err)
+		}
+	}
+
+	accepted := []string{
+		"deleted_at IS NULL",
+		"updated_by = ?",
+		"created_at > ? AND selected = ?",
+		"status = ?",
+	}
+	for _, clause := range accepted {
+		if err := ValidateWhereClause(clause); err != nil {
+			t.Errorf("ValidateWhereClause(%q) = %v, want nil", clause, err)
+		}
+	}
+}
+
+func TestValidateJoinClauseMetadata(t *testing.T) {
+	rejected := []string{
+		"JOIN pg_user u ON u.usesysid = users.id",
+		"LEFT JOIN information_schema.tables t ON t.table_name = users.name",
+		"JOIN INFORMATION_SCHEMA.COLUMNS c ON c.column_name = users.name",
+	}
+	for _, clause := range rejected {
+		if err := ValidateJoinClause(clause); err == nil {
+			t.Errorf("ValidateJoinClause(%q) = nil, want an error", clause)
+		}
+	}
+
+	if err := ValidateJoinClause("LEFT JOIN orders ON orders.user_id = users.id"); err != nil {
+		t.Errorf("ValidateJoinClause(orders) = %v, want nil", err)
+	}
+}
+
+func TestContainsPattern(t *testing.T) {
+	tests := []struct {
+		input, pattern string
+		want           bool
+	}{
+		{"DELETED_AT IS NULL", "DELETE", false},
+		{"UPDATED_BY = ?", "UPDATE", false},
+		{"X; DELETE FROM T", "DELETE", true},
+		{"PG_SLEEP(10)", "PG_", true},
+		{"XPG_SLEEP(10)", "PG_", true},
+		{"SYS.USERS", "SYS.", true},
+		{"INFORMATION_SCHEMA.TABLES", "INFORMATION_SCHEMA", true},
+		{"A--B", "--", true},
+	}
+	for _, tt := range tests {
+		if got := containsPattern(tt.input, tt.pattern); got != tt.want {
+			t.Errorf("containsPattern(%q, %q) = %v, want %v", tt.input, tt.pattern, got, tt.want)
+		}
+	}
+}
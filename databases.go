@@ -0,0 +1,125 @@
+package orm
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+
+	"github.com/godev90/validator/faults"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jmoiron/sqlx"
+	"gorm.io/gorm"
+)
+
+var (
+	errUnknownDatabase = fmt.Errorf("orm: unknown database")
+	ErrUnknownDatabase = faults.New(errUnknownDatabase, &faults.ErrAttr{
+		Code: http.StatusInternalServerError,
+		Messages: []faults.LangPackage{
+			{
+				Tag:     faults.English,
+				Message: "orm: no database registered as %q",
+			},
+		},
+	})
+
+	errInvalidDatabase = fmt.Errorf("orm: invalid database")
+	ErrInvalidDatabase = faults.New(errInvalidDatabase, &faults.ErrAttr{
+		Code: http.StatusInternalServerError,
+		Messages: []faults.LangPackage{
+			{
+				Tag:     faults.English,
+				Message: "orm: cannot register %q: %s",
+			},
+		},
+	})
+)
+
+var (
+	databasesMu sync.RWMutex
+	databases   = map[string]QueryAdapter{}
+)
+
+// Register makes db available to Use under name, replacing what was
+// registered under it before, so services talking to several databases
+// look them up instead of passing handles around:
+//
+//	orm.Register("primary", primaryDB)
+//	orm.Register("reporting", orm.NewSqlAdapter(reportingDB).WithTablePrefix("rpt_"))
+//
+//	var totals []Total
+//	err := orm.Use("reporting").UseModel(&Total{}).Scan(&totals)
+//
+// db is a *sql.DB, *sqlx.DB, *gorm.DB or *pgxpool.Pool, wrapped in the
+// matching adapter, or a QueryAdapter configured beforehand, whose settings
+// (table prefix, default scopes, replicas, ...) every Use then starts from.
+func Register(name string, db any) error {
+	if name == "" {
+		return faultError{err: ErrInvalidDatabase.Render(name, "empty name")}
+	}
+
+	var adapter QueryAdapter
+	switch v := db.(type) {
+	case *sql.DB:
+		if v != nil {
+			adapter = NewSqlAdapter(v)
+		}
+	case *sqlx.DB:
+		if v != nil {
+			adapter = NewSqlxAdapter(v)
+		}
+	case *gorm.DB:
+		if v != nil {
+			adapter = NewGormAdapter(v)
+		}
+	case *pgxpool.Pool:
+		if v != nil {
+			adapter = NewPgxAdapter(v)
+		}
+	case QueryAdapter:
+		adapter = v
+	default:
+		if db != nil {
+			return faultError{err: ErrInvalidDatabase.Render(name, fmt.Sprintf("unsupported %T", db))}
+		}
+	}
+	if adapter == nil {
+		return faultError{err: ErrInvalidDatabase.Render(name, "nil database")}
+	}
+
+	databasesMu.Lock()
+	defer databasesMu.Unlock()
+	databases[name] = adapter
+	return nil
+}
+
+// Use returns the adapter registered as name. Chains copy the adapter as
+// they go, so callers build on it independently. For a name that was never
+// registered it returns a chain whose Error and finishers report
+// ErrUnknownDatabase.
+func Use(name string) QueryAdapter {
+	databasesMu.RLock()
+	adapter, ok := databases[name]
+	databasesMu.RUnlock()
+	if ok {
+		return adapter
+	}
+
+	q := newBuilder(FlavorMySQL)
+	q.err = faultError{err: ErrUnknownDatabase.Render(name)}
+	return q
+}
+
+// Databases returns the registered names, sorted.
+func Databases() []string {
+	databasesMu.RLock()
+	defer databasesMu.RUnlock()
+	names := make([]string, 0, len(databases))
+	for name := range databases {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,204 @@
+package orm
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// ChangeColumnDefault is a column whose DEFAULT differs from its tag.
+const ChangeColumnDefault ChangeKind = "set default"
+
+// columnDefaultsQueries list the columns of the table named by the single
+// argument with their DEFAULT expressions, NULL for none.
+var columnDefaultsQueries = map[driverFlavor]string{
+	FlavorPostgres: `SELECT column_name, column_default
+FROM information_schema.columns
+WHERE table_schema = current_schema() AND table_name = ?`,
+
+	FlavorMySQL: `SELECT COLUMN_NAME, COLUMN_DEFAULT
+FROM information_schema.COLUMNS
+WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ?`,
+
+	FlavorOracle: `SELECT column_name, data_default
+FROM user_tab_columns
+WHERE table_name = ?`,
+}
+
+// SyncDefaults sets the DEFAULT of every column whose model field is
+// tagged with default: to the tagged expression, where the live schema has
+// another one or none, so defaults declared in code and in the database
+// don't drift apart:
+//
+//	type Order struct {
+//		Status string `sql:"column:status;default:'pending'"`
+//	}
+//
+//	err := orm.SyncDefaults(db, &Order{})
+//
+// Fields without default: are left alone, as are tables and columns that
+// don't exist yet; see AutoMigrate. DiffDefaults returns the same changes
+// for WriteMigration instead.
+func SyncDefaults(db *sql.DB, models ...Tabler) error {
+	return SyncDefaultsContext(context.Background(), db, models...)
+}
+
+// SyncDefaultsContext is SyncDefaults with a context for its statements.
+func SyncDefaultsContext(ctx context.Context, db *sql.DB, models ...Tabler) error {
+	changes, err := DiffDefaults(ctx, db, models...)
+	if err != nil {
+		return err
+	}
+	flavor := detectFlavor(db)
+	for _, c := range changes {
+		if err := execAdmin(ctx, db, flavor, c.Up); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DiffDefaults compares the default: tags of models with the column
+// defaults of their tables on db and returns a ChangeColumnDefault for
+// each column that differs. Its Down restores the previous default.
+//
+// Expressions are compared loosely, as catalogs spell them differently
+// from the tag: case, type casts ('x'::text), enclosing parentheses and
+// the quotes MySQL drops are ignored.
+func DiffDefaults(ctx context.Context, db *sql.DB, models ...Tabler) ([]SchemaChange, error) {
+	if db == nil {
+		return nil, ErrNilPointer
+	}
+	flavor := detectFlavor(db)
+
+	var changes []SchemaChange
+	for _, model := range models {
+		val, err := modelStruct(model, false)
+		if err != nil {
+			return nil, err
+		}
+		table := model.TableName()
+
+		live, err := columnDefaults(ctx, db, flavor, table)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, f := range modelFields(val.Type()) {
+			want := columnDefault(f)
+			if want == "" {
+				continue
+			}
+			have, ok := live[strings.ToLower(f.column)]
+			if !ok || normalizeDefault(have.String) == normalizeDefault(want) {
+				continue
+			}
+
+			down := dropDefaultSQL(flavor, table, f.column)
+			if have.Valid {
+				down = setDefaultSQL(flavor, table, f.column, strings.TrimSpace(have.String))
+			}
+			changes = append(changes, SchemaChange{
+				Kind:   ChangeColumnDefault,
+				Table:  table,
+				Column: f.column,
+				Up:     setDefaultSQL(flavor, table, f.column, want),
+				Down:   down,
+			})
+		}
+	}
+	return changes, nil
+}
+
+// columnDefaults returns the DEFAULT expressions of table's columns by
+// lower-cased column name. A table the catalog doesn't know has none.
+func columnDefaults(ctx context.Context, db *sql.DB, flavor driverFlavor, table string) (map[string]sql.NullString, error) {
+	name := table
+	if flavor == FlavorOracle {
+		name = strings.ToUpper(table)
+	}
+	query := rebind(flavor, columnDefaultsQueries[flavor])
+	args := []any{name}
+
+	defaults := map[string]sql.NullString{}
+	err := runQuery(&queryCall{ctx: ctx, query: query, args: args, flavor: flavor}, func(query string) error {
+		rows, err := db.QueryContext(ctx, query, args...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var col string
+			var def sql.NullString
+			if err := rows.Scan(&col, &def); err != nil {
+				return err
+			}
+			defaults[strings.ToLower(col)] = def
+		}
+		return rows.Err()
+	})
+	return defaults, err
+}
+
+// setDefaultSQL renders the statement setting the DEFAULT of column.
+func setDefaultSQL(flavor driverFlavor, table, column, expr string) string {
+	if flavor == FlavorOracle {
+		return fmt.Sprintf("ALTER TABLE %s MODIFY (%s DEFAULT %s)", quoteIdent(flavor, table), quoteIdent(flavor, column), expr)
+	}
+	return fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s SET DEFAULT %s", quoteIdent(flavor, table), quoteIdent(flavor, column), expr)
+}
+
+// dropDefaultSQL renders the statement removing the DEFAULT of column.
+func dropDefaultSQL(flavor driverFlavor, table, column string) string {
+	if flavor == FlavorOracle {
+		// Oracle has no DROP DEFAULT; a NULL default is the same
+		return setDefaultSQL(flavor, table, column, "NULL")
+	}
+	return fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s DROP DEFAULT", quoteIdent(flavor, table), quoteIdent(flavor, column))
+}
+
+// normalizeDefault reduces a DEFAULT expression to a form that compares
+// equal across the tag and the catalogs: lower case, without type casts,
+// enclosing parentheses, string quotes or a trailing ().
+func normalizeDefault(expr string) string {
+	s := strings.TrimSpace(expr)
+	for {
+		prev := s
+		if i := strings.LastIndex(s, "::"); i > 0 && !strings.Contains(s[i:], "'") {
+			s = strings.TrimSpace(s[:i])
+		}
+		if enclosed(s) {
+			s = strings.TrimSpace(s[1 : len(s)-1])
+		}
+		if s == prev {
+			break
+		}
+	}
+	if len(s) >= 2 && s[0] == '\'' && s[len(s)-1] == '\'' {
+		s = strings.ReplaceAll(s[1:len(s)-1], "''", "'")
+	}
+	return strings.TrimSuffix(strings.ToLower(s), "()")
+}
+
+// enclosed reports whether s is wrapped in one pair of parentheses, as in
+// "(1 + 2)" but not "(1) + (2)".
+func enclosed(s string) bool {
+	if len(s) < 2 || s[0] != '(' || s[len(s)-1] != ')' {
+		return false
+	}
+	depth := 0
+	for i, r := range s {
+		switch r {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 && i < len(s)-1 {
+				return false
+			}
+		}
+	}
+	return depth == 0
+}
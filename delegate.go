@@ -0,0 +1,172 @@
+package orm
+
+import "context"
+
+// builtAdapter supplies the builder methods of adapters that build their
+// SQL with a SqlQueryAdapter but run it elsewhere (PgxAdapter, SqlxAdapter).
+// Each builder call returns a copy re-wrapped in the outer adapter through
+// wrap, so chains keep the outer adapter's finishers.
+type builtAdapter struct {
+	b    *SqlQueryAdapter
+	wrap func(b *SqlQueryAdapter) QueryAdapter
+}
+
+// newBuilder returns the builder state of a fresh adapter.
+func newBuilder(flavor driverFlavor) *SqlQueryAdapter {
+	return &SqlQueryAdapter{
+		ctx:      context.Background(),
+		flavor:   flavor,
+		fields:   []string{"*"},
+		scopes:   []ScopeFunc{},
+		joins:    []string{},
+		joinArgs: []any{},
+		wheres:   []string{},
+		orWheres: []string{},
+	}
+}
+
+// rewrap wraps a builder returned by one of the SqlQueryAdapter methods.
+func (a builtAdapter) rewrap(q QueryAdapter) QueryAdapter {
+	if sq, ok := q.(*SqlQueryAdapter); ok {
+		return a.wrap(sq)
+	}
+	return q
+}
+
+func (a builtAdapter) grouped() QueryAdapter {
+	return a.rewrap(a.b.grouped())
+}
+
+func (a builtAdapter) WithContext(ctx context.Context) QueryAdapter {
+	return a.rewrap(a.b.WithContext(ctx))
+}
+
+func (a builtAdapter) UseModel(m Tabler) QueryAdapter {
+	return a.rewrap(a.b.UseModel(m))
+}
+
+func (a builtAdapter) Model() Tabler {
+	return a.b.Model()
+}
+
+func (a builtAdapter) Where(cond any, args ...any) QueryAdapter {
+	return a.rewrap(a.b.Where(cond, args...))
+}
+
+func (a builtAdapter) trustedWhere(cond any, args ...any) QueryAdapter {
+	return a.rewrap(a.b.trustedWhere(cond, args...))
+}
+
+func (a builtAdapter) Or(cond any, args ...any) QueryAdapter {
+	return a.rewrap(a.b.Or(cond, args...))
+}
+
+func (a builtAdapter) Join(joinClause string, args ...any) QueryAdapter {
+	return a.rewrap(a.b.Join(joinClause, args...))
+}
+
+func (a builtAdapter) JoinModel(model Tabler, on JoinOn) QueryAdapter {
+	return a.rewrap(a.b.JoinModel(model, on))
+}
+
+func (a builtAdapter) Select(sel []string) QueryAdapter {
+	return a.rewrap(a.b.Select(sel))
+}
+
+func (a builtAdapter) GroupBy(cols []string) QueryAdapter {
+	return a.rewrap(a.b.GroupBy(cols))
+}
+
+func (a builtAdapter) Having(cols []string, args ...any) QueryAdapter {
+	return a.rewrap(a.b.Having(cols, args...))
+}
+
+func (a builtAdapter) Limit(l int) QueryAdapter {
+	return a.rewrap(a.b.Limit(l))
+}
+
+func (a builtAdapter) Offset(o int) QueryAdapter {
+	return a.rewrap(a.b.Offset(o))
+}
+
+func (a builtAdapter) Order(order string) QueryAdapter {
+	return a.rewrap(a.b.Order(order))
+}
+
+func (a builtAdapter) Scopes(fs ...ScopeFunc) QueryAdapter {
+	out := a.wrap(a.b)
+	for _, f := range fs {
+		if f != nil {
+			out = f(out)
+		}
+	}
+	return out
+}
+
+func (a builtAdapter) Clone() QueryAdapter {
+	return a.wrap(a.b.clone())
+}
+
+func (a builtAdapter) ToSQL() (string, []any) {
+	return a.b.ToSQL()
+}
+
+func (a builtAdapter) DryRun(rec *StatementRecorder) QueryAdapter {
+	return a.rewrap(a.b.DryRun(rec))
+}
+
+func (a builtAdapter) Unscoped() QueryAdapter {
+	return a.rewrap(a.b.Unscoped())
+}
+
+func (a builtAdapter) WithDefaultScope(fs ...ScopeFunc) QueryAdapter {
+	return a.rewrap(a.b.WithDefaultScope(fs...))
+}
+
+func (a builtAdapter) Comment(text string) QueryAdapter {
+	return a.rewrap(a.b.Comment(text))
+}
+
+func (a builtAdapter) SafeOrder(order string) QueryAdapter {
+	return a.rewrap(a.b.SafeOrder(order))
+}
+
+func (a builtAdapter) SafeJoin(joinClause string, args ...any) QueryAdapter {
+	return a.rewrap(a.b.SafeJoin(joinClause, args...))
+}
+
+func (a builtAdapter) SafeSelect(selections []string) QueryAdapter {
+	return a.rewrap(a.b.SafeSelect(selections))
+}
+
+func (a builtAdapter) SafeGroupBy(groupbys []string) QueryAdapter {
+	return a.rewrap(a.b.SafeGroupBy(groupbys))
+}
+
+func (a builtAdapter) SafeHaving(havings []string, args ...any) QueryAdapter {
+	return a.rewrap(a.b.SafeHaving(havings, args...))
+}
+
+func (a builtAdapter) UnsafeOrder(order string) QueryAdapter {
+	return a.rewrap(a.b.UnsafeOrder(order))
+}
+
+func (a builtAdapter) UnsafeJoin(joinClause string, args ...any) QueryAdapter {
+	return a.rewrap(a.b.UnsafeJoin(joinClause, args...))
+}
+
+func (a builtAdapter) UnsafeSelect(selections []string) QueryAdapter {
+	return a.rewrap(a.b.UnsafeSelect(selections))
+}
+
+func (a builtAdapter) UnsafeGroupBy(groupbys []string) QueryAdapter {
+	return a.rewrap(a.b.UnsafeGroupBy(groupbys))
+}
+
+func (a builtAdapter) UnsafeHaving(havings []string, args ...any) QueryAdapter {
+	return a.rewrap(a.b.UnsafeHaving(havings, args...))
+}
+
+func (a builtAdapter) PlanCache(mode PlanCacheMode) QueryAdapter {
+	return a.rewrap(a.b.PlanCache(mode))
+}
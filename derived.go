@@ -0,0 +1,260 @@
+package orm
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/godev90/validator/faults"
+)
+
+var (
+	errInvalidRule = fmt.Errorf("orm: invalid derived rule")
+	ErrInvalidRule = faults.New(errInvalidRule, &faults.ErrAttr{
+		Code: http.StatusInternalServerError,
+		Messages: []faults.LangPackage{
+			{
+				Tag:     faults.English,
+				Message: "orm: rule on %s: %s",
+			},
+		},
+	})
+)
+
+// WriteEvent is a kind of write a DerivedRule runs after.
+type WriteEvent uint8
+
+const (
+	WriteInsert WriteEvent = 1 << iota // Create, BulkInsert
+	WriteUpdate                        // Update, Patch
+	WriteDelete                        // Delete, HardDelete
+
+	WriteAny = WriteInsert | WriteUpdate | WriteDelete
+)
+
+// DerivedRule keeps a derived column, a total or a count of rows, in sync
+// with the writes of a source model, in the transaction of the write, for
+// schemas that can't use database triggers:
+//
+//	err := orm.RegisterRules(
+//		orm.OnWrite(&OrderItem{}).
+//			Recompute(&Order{}, "total = (SELECT COALESCE(SUM(price * qty), 0) FROM order_items WHERE order_items.order_id = orders.id)").
+//			By("id", "order_id"),
+//	)
+//
+// After SqlTransactionAdapter writes an OrderItem, the orders row whose id
+// is the item's order_id is recomputed with
+// UPDATE orders SET <set> WHERE id = ?, once per distinct key of a batch.
+// Without By every row of the target is recomputed. The key is read from
+// the written model as it is after the write; an Update or Patch also
+// recomputes the row of the key it had before, so moving a row to another
+// parent recomputes both.
+type DerivedRule struct {
+	source    reflect.Type
+	events    WriteEvent
+	target    Tabler
+	set       string
+	targetKey string
+	sourceKey string
+}
+
+// OnInsert starts a rule run after inserts of model.
+func OnInsert(model Tabler) *DerivedRule {
+	return newRule(model, WriteInsert)
+}
+
+// OnUpdate starts a rule run after updates of model.
+func OnUpdate(model Tabler) *DerivedRule {
+	return newRule(model, WriteUpdate)
+}
+
+// OnDelete starts a rule run after deletes of model.
+func OnDelete(model Tabler) *DerivedRule {
+	return newRule(model, WriteDelete)
+}
+
+// OnWrite starts a rule run after every write of model.
+func OnWrite(model Tabler) *DerivedRule {
+	return newRule(model, WriteAny)
+}
+
+func newRule(model Tabler, events WriteEvent) *DerivedRule {
+	t := reflect.TypeOf(model)
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return &DerivedRule{source: t, events: events}
+}
+
+// Recompute sets the table of target with set, the SET clause of an UPDATE
+// (column = expression, ...), and returns r.
+func (r *DerivedRule) Recompute(target Tabler, set string) *DerivedRule {
+	r.target, r.set = target, set
+	return r
+}
+
+// By limits the recomputed rows to those whose targetColumn equals the
+// written model's sourceColumn, and returns r.
+func (r *DerivedRule) By(targetColumn, sourceColumn string) *DerivedRule {
+	r.targetKey, r.sourceKey = targetColumn, sourceColumn
+	return r
+}
+
+// derivedRules maps a source struct type to the rules its writes run.
+var derivedRules sync.Map // reflect.Type -> []*DerivedRule
+
+// RegisterRules checks rules and starts running them after the writes of
+// their source models.
+func RegisterRules(rules ...*DerivedRule) error {
+	for _, r := range rules {
+		if r.source == nil || r.source.Kind() != reflect.Struct {
+			return ErrInvalidRule.Render(fmt.Sprint(r.source), "source must be a struct model")
+		}
+		if r.target == nil || strings.TrimSpace(r.set) == "" {
+			return ErrInvalidRule.Render(r.source.Name(), "Recompute needs a target and a SET clause")
+		}
+		if r.targetKey != "" {
+			if err := ValidateColumnName(r.targetKey); err != nil {
+				return ErrInvalidRule.Render(r.source.Name(), err.Error())
+			}
+			if _, ok := cachedFieldMap(r.source)[strings.ToLower(r.sourceKey)]; !ok {
+				return ErrInvalidRule.Render(r.source.Name(), fmt.Sprintf("no column %q", r.sourceKey))
+			}
+		}
+
+		existing, _ := derivedRules.Load(r.source)
+		list, _ := existing.([]*DerivedRule)
+		derivedRules.Store(r.source, append(append([]*DerivedRule(nil), list...), r))
+	}
+	return nil
+}
+
+// rulesOf returns the rules registered for the type of model.
+func rulesOf(model Tabler) ([]*DerivedRule, reflect.Type, error) {
+	val, err := modelStruct(model, false)
+	if err != nil {
+		return nil, nil, err
+	}
+	cached, ok := derivedRules.Load(val.Type())
+	if !ok {
+		return nil, val.Type(), nil
+	}
+	return cached.([]*DerivedRule), val.Type(), nil
+}
+
+// storedRuleKeys adds to keys the keys src's keyed WriteUpdate rules
+// recompute by, read from src's row as it is stored, and returns them.
+// Read before an update, they let runRules recompute the parent a row moves
+// away from; read after a Patch, the one it moves to. keys is returned
+// unchanged when src's type has no such rule.
+func (q *SqlTransactionAdapter) storedRuleKeys(src Tabler, keys map[*DerivedRule][]any) (map[*DerivedRule][]any, error) {
+	rules, t, err := rulesOf(src)
+	if err != nil || !slices.ContainsFunc(rules, func(r *DerivedRule) bool {
+		return r.events&WriteUpdate != 0 && r.targetKey != ""
+	}) {
+		return keys, err
+	}
+
+	val, _ := modelStruct(src, false)
+	var pk *modelField
+	for _, f := range modelFields(t) {
+		if f.pk {
+			pk = &f
+			break
+		}
+	}
+	if pk == nil {
+		return keys, nil
+	}
+	pkVal, err := pk.value(val.FieldByIndex(pk.Index))
+	if err != nil {
+		return keys, err
+	}
+
+	rows := reflect.New(reflect.SliceOf(t))
+	err = whereTrusted(q.Query().UseModel(src).Unscoped(), quoteIdent(q.flavor, pk.column)+" = ?", pkVal).
+		Limit(1).Scan(rows.Interface())
+	if err != nil || rows.Elem().Len() == 0 {
+		return keys, err
+	}
+	row := rows.Elem().Index(0)
+
+	if keys == nil {
+		keys = map[*DerivedRule][]any{}
+	}
+	for _, r := range rules {
+		if r.events&WriteUpdate == 0 || r.targetKey == "" {
+			continue
+		}
+		field := cachedFieldMap(t)[strings.ToLower(r.sourceKey)]
+		key, err := field.value(row.FieldByIndex(field.Index))
+		if err != nil {
+			return keys, err
+		}
+		keys[r] = append(keys[r], key)
+	}
+	return keys, nil
+}
+
+// runRules runs the rules of the models' type registered for event, after
+// a write of models. stored holds keys storedRuleKeys read around an
+// update, recomputed along with those of models.
+func (q *SqlTransactionAdapter) runRules(event WriteEvent, stored map[*DerivedRule][]any, models ...Tabler) error {
+	if len(models) == 0 {
+		return nil
+	}
+	rules, t, err := rulesOf(models[0])
+	if err != nil {
+		return err
+	}
+
+	for _, r := range rules {
+		if r.events&event == 0 {
+			continue
+		}
+
+		query := fmt.Sprintf("UPDATE %s SET %s", quoteIdent(q.flavor, q.tableName(r.target)), r.set)
+		if r.targetKey == "" {
+			if err := q.exec(rebind(q.flavor, query), nil); err != nil {
+				return err
+			}
+			continue
+		}
+		query = rebind(q.flavor, query+fmt.Sprintf(" WHERE %s = ?", quoteIdent(q.flavor, r.targetKey)))
+
+		field := cachedFieldMap(t)[strings.ToLower(r.sourceKey)]
+		keys := make([]any, 0, len(models)+len(stored[r]))
+		for _, m := range models {
+			v, err := modelStruct(m, false)
+			if err != nil {
+				return err
+			}
+			key, err := field.value(v.FieldByIndex(field.Index))
+			if err != nil {
+				return err
+			}
+			keys = append(keys, key)
+		}
+		keys = append(keys, stored[r]...)
+
+		seen := map[any]bool{}
+		for _, key := range keys {
+			if key == nil {
+				continue
+			}
+			if reflect.TypeOf(key).Comparable() {
+				if seen[key] {
+					continue
+				}
+				seen[key] = true
+			}
+			if err := q.exec(query, []any{key}); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
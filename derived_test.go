@@ -0,0 +1,71 @@
+package orm
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/godev90/validator/faults"
+)
+
+type ruleOrder struct {
+	ID    int64 `sql:"column:id;primaryKey"`
+	Total int64 `sql:"column:total"`
+}
+
+func (ruleOrder) TableName() string { return "rule_orders" }
+
+type ruleItem struct {
+	ID          int64 `sql:"column:id;primaryKey"`
+	RuleOrderID int64 `sql:"column:rule_order_id"`
+	Price       int64 `sql:"column:price"`
+}
+
+func (ruleItem) TableName() string { return "rule_items" }
+
+func TestDerivedRuleRecomputesPreviousParent(t *testing.T) {
+	err := RegisterRules(OnUpdate(&ruleItem{}).Recompute(&ruleOrder{}, "total = 0").By("id", "rule_order_id"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	SetFlavor(db, FlavorPostgres)
+
+	const stored = `SELECT * FROM "rule_items" WHERE "id" = $1 LIMIT 1`
+	const recompute = `UPDATE "rule_orders" SET total = 0 WHERE "id" = $1`
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(stored).WithArgs(int64(5)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "rule_order_id", "price"}).AddRow(5, 1, 10))
+	mock.ExpectExec(`UPDATE "rule_items" SET "rule_order_id" = $1, "price" = $2 WHERE "id" = $3`).
+		WithArgs(int64(2), int64(10), int64(5)).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(recompute).WithArgs(int64(2)).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(recompute).WithArgs(int64(1)).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	err = WithTransaction(context.Background(), db, func(tx *SqlTransactionAdapter) error {
+		return tx.Update(&ruleItem{ID: 5, RuleOrderID: 2, Price: 10})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestRegisterRulesErrors(t *testing.T) {
+	for name, r := range map[string]*DerivedRule{
+		"no target": OnWrite(&ruleItem{}),
+		"no column": OnWrite(&ruleItem{}).Recompute(&ruleOrder{}, "total = 0").By("id", "order_ref"),
+	} {
+		if err := RegisterRules(r); !faults.Is(err, ErrInvalidRule) {
+			t.Errorf("%s: RegisterRules = %v, want ErrInvalidRule", name, err)
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package orm
+
+import (
+	"slices"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// QueryDescription is a snapshot of a query's shape, for middleware,
+// validators and tests that need to inspect a query without parsing its
+// SQL. The slices are copies; changing them doesn't affect the query.
+//
+// Conditions are described as given, with the model's DefaultScope
+// applied; the soft-delete filter is added when the statement is built and
+// is only reflected in Unscoped. With the gorm adapter, conditions other
+// than plain strings are rendered by gorm, with its placeholders.
+type QueryDescription struct {
+	Table  string
+	Model  Tabler
+	Fields []string // selected columns; "*" when none were chosen
+
+	Joins    []string
+	JoinArgs []any
+
+	Wheres    []string // AND-ed conditions
+	WhereArgs []any
+	Ors       []string // OR-ed conditions
+	OrArgs    []any
+
+	GroupBy    []string
+	Havings    []string
+	HavingArgs []any
+
+	OrderBy  string
+	Limit    *int
+	Offset   *int
+	Unscoped bool
+}
+
+// Describe returns the shape of the query built so far.
+func (q *SqlQueryAdapter) Describe() QueryDescription {
+	s := q.withDefaultScopes()
+	d := QueryDescription{
+		Table:      s.table,
+		Model:      s.model,
+		Fields:     slices.Clone(s.fields),
+		Joins:      slices.Clone(s.joins),
+		JoinArgs:   slices.Clone(s.joinArgs),
+		Wheres:     slices.Clone(s.wheres),
+		WhereArgs:  slices.Clone(s.whereArgs),
+		Ors:        slices.Clone(s.orWheres),
+		OrArgs:     slices.Clone(s.orArgs),
+		GroupBy:    slices.Clone(s.groups),
+		Havings:    slices.Clone(s.havings),
+		HavingArgs: slices.Clone(s.havingArgs),
+		OrderBy:    s.orderBy,
+		Unscoped:   q.unscoped,
+	}
+	if s.limit != nil {
+		l := *s.limit
+		d.Limit = &l
+	}
+	if s.offset != nil {
+		o := *s.offset
+		d.Offset = &o
+	}
+	if len(d.Fields) == 0 {
+		d.Fields = []string{"*"}
+	}
+	return d
+}
+
+func (a builtAdapter) Describe() QueryDescription {
+	return a.b.Describe()
+}
+
+// Describe returns the shape of the query built so far, read from gorm's
+// statement clauses.
+func (g *GormAdapter) Describe() QueryDescription {
+	stmt := g.withDefaultScopes().db.Statement
+	d := QueryDescription{
+		Table:    stmt.Table,
+		Model:    g.model,
+		Fields:   slices.Clone(stmt.Selects),
+		Unscoped: g.unscoped,
+	}
+	if d.Table == "" && g.model != nil {
+		d.Table = g.model.TableName()
+	}
+	if len(d.Fields) == 0 {
+		d.Fields = []string{"*"}
+	}
+
+	for _, j := range stmt.Joins {
+		d.Joins = append(d.Joins, j.Name)
+		d.JoinArgs = append(d.JoinArgs, j.Conds...)
+	}
+
+	if c, ok := stmt.Clauses["WHERE"]; ok {
+		if where, ok := c.Expression.(clause.Where); ok {
+			for _, e := range where.Exprs {
+				if or, ok := e.(clause.OrConditions); ok {
+					for _, oe := range or.Exprs {
+						sql, args := gormExprSQL(g.db, d.Table, oe)
+						d.Ors = append(d.Ors, sql)
+						d.OrArgs = append(d.OrArgs, args...)
+					}
+					continue
+				}
+				sql, args := gormExprSQL(g.db, d.Table, e)
+				d.Wheres = append(d.Wheres, sql)
+				d.WhereArgs = append(d.WhereArgs, args...)
+			}
+		}
+	}
+
+	if c, ok := stmt.Clauses["GROUP BY"]; ok {
+		if group, ok := c.Expression.(clause.GroupBy); ok {
+			for _, col := range group.Columns {
+				d.GroupBy = append(d.GroupBy, col.Name)
+			}
+			for _, e := range group.Having {
+				sql, args := gormExprSQL(g.db, d.Table, e)
+				d.Havings = append(d.Havings, sql)
+				d.HavingArgs = append(d.HavingArgs, args...)
+			}
+		}
+	}
+
+	if c, ok := stmt.Clauses["ORDER BY"]; ok {
+		if order, ok := c.Expression.(clause.OrderBy); ok {
+			for i, col := range order.Columns {
+				if i > 0 {
+					d.OrderBy += ", "
+				}
+				d.OrderBy += col.Column.Name
+				if col.Desc {
+					d.OrderBy += " DESC"
+				}
+			}
+		}
+	}
+
+	if c, ok := stmt.Clauses["LIMIT"]; ok {
+		if limit, ok := c.Expression.(clause.Limit); ok {
+			if limit.Limit != nil {
+				l := *limit.Limit
+				d.Limit = &l
+			}
+			if limit.Offset > 0 {
+				o := limit.Offset
+				d.Offset = &o
+			}
+		}
+	}
+	return d
+}
+
+// gormExprSQL renders one gorm condition. Raw conditions keep their text
+// and arguments; others are built by gorm for db's dialect.
+func gormExprSQL(db *gorm.DB, table string, e clause.Expression) (string, []any) {
+	if expr, ok := e.(clause.Expr); ok {
+		return expr.SQL, slices.Clone(expr.Vars)
+	}
+	stmt := &gorm.Statement{DB: db, Table: table, Clauses: map[string]clause.Clause{}}
+	e.Build(stmt)
+	return stmt.SQL.String(), stmt.Vars
+}
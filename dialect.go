@@ -0,0 +1,132 @@
+package orm
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// plainIdent matches a bare or schema-qualified identifier; anything else
+// (aliases, expressions, already quoted names) is emitted untouched.
+var plainIdent = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_$]*(\.[A-Za-z_][A-Za-z0-9_$]*)?$`)
+
+// quoteIdent quotes a table or column name the builder emits itself, so
+// reserved words such as order or user work as names. Oracle names are
+// upper-cased first, which is how it resolves unquoted identifiers.
+func quoteIdent(flavor driverFlavor, name string) string {
+	if !plainIdent.MatchString(name) {
+		return name
+	}
+
+	parts := strings.Split(name, ".")
+	for i, p := range parts {
+		switch flavor {
+		case FlavorPostgres:
+			parts[i] = `"` + p + `"`
+		case FlavorOracle:
+			parts[i] = `"` + strings.ToUpper(p) + `"`
+		default:
+			parts[i] = "`" + p + "`"
+		}
+	}
+	return strings.Join(parts, ".")
+}
+
+// rebind rewrites the builder's "?" placeholders into the flavor's native
+// form: $1.. for Postgres, :1.. for Oracle. Question marks inside quoted
+// literals and identifiers are left alone.
+func rebind(flavor driverFlavor, query string) string {
+	var prefix string
+	switch flavor {
+	case FlavorPostgres:
+		prefix = "$"
+	case FlavorOracle:
+		prefix = ":"
+	default:
+		return query
+	}
+
+	var b strings.Builder
+	b.Grow(len(query) + 8)
+
+	n := 0
+	var quote byte
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"' || c == '`':
+			quote = c
+		case c == '?':
+			n++
+			b.WriteString(prefix)
+			b.WriteString(strconv.Itoa(n))
+			continue
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
+
+// limitClause renders LIMIT/OFFSET, or Oracle's OFFSET .. ROWS FETCH NEXT
+// .. ROWS ONLY (12c+).
+func limitClause(flavor driverFlavor, limit, offset *int) string {
+	var sb strings.Builder
+
+	if flavor == FlavorOracle {
+		if offset != nil {
+			fmt.Fprintf(&sb, " OFFSET %d ROWS", *offset)
+		}
+		if limit != nil {
+			fmt.Fprintf(&sb, " FETCH NEXT %d ROWS ONLY", *limit)
+		}
+		return sb.String()
+	}
+
+	if limit != nil {
+		fmt.Fprintf(&sb, " LIMIT %d", *limit)
+	}
+	if offset != nil {
+		fmt.Fprintf(&sb, " OFFSET %d", *offset)
+	}
+	return sb.String()
+}
+
+// sequenceNextVal renders the expression drawing the next value from a
+// sequence named in a `sequence:<name>` tag.
+func sequenceNextVal(flavor driverFlavor, seq string) string {
+	if flavor == FlavorOracle {
+		return seq + ".NEXTVAL"
+	}
+	return "nextval('" + strings.ReplaceAll(seq, "'", "''") + "')"
+}
+
+// tagOption returns the value of key in a ;-separated sql tag
+// ("column:id;primaryKey;sequence:users_seq").
+func tagOption(tag, key string) string {
+	for _, p := range strings.Split(tag, ";") {
+		if v, ok := strings.CutPrefix(strings.TrimSpace(p), key+":"); ok {
+			return v
+		}
+	}
+	return ""
+}
+
+// flagOptions are the bare sql tag options, which a tag without column: must
+// not be mistaken for a column name.
+var flagOptions = []string{"autoCreateTime", "autoUpdateTime", "softDelete", "keepEmpty", "emptyAsNull", "embedded", "hasMany", "hasOne", "belongsTo", "notNull", "not null", "index", "uniqueIndex", "jobStatus", "jobRunAt", "jobAttempts", "jobError", "sensitive", "clientKey"}
+
+// tagFlag reports whether a ;-separated sql tag holds the bare option key
+// ("column:created_at;autoCreateTime").
+func tagFlag(tag, key string) bool {
+	for _, p := range strings.Split(tag, ";") {
+		if strings.TrimSpace(p) == key {
+			return true
+		}
+	}
+	return false
+}
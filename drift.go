@@ -0,0 +1,192 @@
+package orm
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/godev90/validator/faults"
+)
+
+var (
+	errModelDrift = fmt.Errorf("orm: models don't match the schema")
+	ErrModelDrift = faults.New(errModelDrift, &faults.ErrAttr{
+		Code: http.StatusInternalServerError,
+		Messages: []faults.LangPackage{
+			{
+				Tag:     faults.English,
+				Message: "orm: models don't match the schema: %s",
+			},
+		},
+	})
+)
+
+// typeClass groups column and field types that scan into each other.
+type typeClass int
+
+const (
+	classUnknown typeClass = iota
+	classNumber
+	classText
+	classBool
+	classTime
+	classBinary
+	classUUID
+	classJSON
+)
+
+// ValidateModels checks that every column the models map exists in its
+// table on db with a type its field can scan, so a deploy against an
+// outdated schema fails at startup rather than Scan leaving fields zero:
+//
+//	if err := orm.ValidateModels(db, &User{}, &Order{}); err != nil {
+//		log.Fatal(err)
+//	}
+//
+// All problems are reported in one ErrModelDrift, as "orders.total: no
+// such column" or "users.age: int64 field on a VARCHAR column". Types are
+// compared by kind (number, text, time, ...); string and []byte fields,
+// sql.Scanner implementations and column types the driver doesn't name
+// are not checked. Extra columns are fine.
+func ValidateModels(db *sql.DB, models ...Tabler) error {
+	return ValidateModelsContext(context.Background(), db, models...)
+}
+
+// ValidateModelsContext is ValidateModels with a context for its queries.
+func ValidateModelsContext(ctx context.Context, db *sql.DB, models ...Tabler) error {
+	if db == nil {
+		return ErrNilPointer
+	}
+	flavor := detectFlavor(db)
+
+	var problems []string
+	for _, model := range models {
+		val, err := modelStruct(model, false)
+		if err != nil {
+			return err
+		}
+		table := model.TableName()
+
+		cols, err := tableColumns(ctx, db, flavor, table)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", table, err))
+			continue
+		}
+		byName := make(map[string]*sql.ColumnType, len(cols))
+		for _, c := range cols {
+			byName[strings.ToLower(c.Name())] = c
+		}
+
+		for _, f := range modelFields(val.Type()) {
+			col, ok := byName[strings.ToLower(f.column)]
+			if !ok {
+				problems = append(problems, fmt.Sprintf("%s.%s: no such column", table, f.column))
+				continue
+			}
+			if !scansInto(columnClass(col.DatabaseTypeName()), fieldClass(f)) {
+				problems = append(problems, fmt.Sprintf("%s.%s: %s field on a %s column", table, f.column, f.Type, col.DatabaseTypeName()))
+			}
+		}
+	}
+
+	if len(problems) > 0 {
+		return faultError{err: ErrModelDrift.Render(strings.Join(problems, "; "))}
+	}
+	return nil
+}
+
+// scansInto reports whether a column of class col can fill a field of
+// class field. Unknown classes on either side are given the benefit of
+// the doubt.
+func scansInto(col, field typeClass) bool {
+	if col == classUnknown || field == classUnknown || col == field {
+		return true
+	}
+	switch field {
+	case classNumber:
+		// MySQL keeps booleans in TINYINT
+		return col == classBool
+	case classBool:
+		// MySQL BOOLEAN is TINYINT(1), Oracle's NUMBER(1)
+		return col == classNumber
+	case classUUID:
+		return col == classText || col == classBinary
+	case classJSON:
+		return col == classText || col == classBinary
+	}
+	return false
+}
+
+// fieldClass returns the class of f's Go type. Strings, byte slices and
+// types scanning themselves take anything and are unknown.
+func fieldClass(f modelField) typeClass {
+	t := f.Type
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if f.timeFormat != "" {
+		// epoch counts
+		return classNumber
+	}
+
+	switch t {
+	case timeT, nullTimeT, gormDeletedAtT:
+		return classTime
+	case nullIntT, nullInt32T, nullInt16T, nullFloatT:
+		return classNumber
+	case nullBoolT:
+		return classBool
+	case uuidT:
+		return classUUID
+	}
+	if reflect.PointerTo(t).Implements(scannerT) {
+		return classUnknown
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return classBool
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return classNumber
+	case reflect.Map, reflect.Struct:
+		return classJSON
+	}
+	return classUnknown
+}
+
+// columnClass returns the class of a column from the type name its driver
+// reports (lib/pq and pgx, go-sql-driver/mysql, godror and go-ora).
+func columnClass(name string) typeClass {
+	name = strings.ToUpper(strings.TrimSpace(name))
+	name = strings.TrimPrefix(name, "UNSIGNED ")
+	if i := strings.IndexByte(name, '('); i >= 0 {
+		name = name[:i]
+	}
+
+	switch name {
+	case "INT", "INT2", "INT4", "INT8", "INTEGER", "SMALLINT", "MEDIUMINT", "BIGINT", "TINYINT",
+		"SERIAL", "BIGSERIAL", "NUMERIC", "DECIMAL", "NUMBER", "FLOAT", "FLOAT4", "FLOAT8",
+		"REAL", "DOUBLE", "DOUBLE PRECISION", "BINARY_FLOAT", "BINARY_DOUBLE", "YEAR", "MONEY":
+		return classNumber
+	case "CHAR", "BPCHAR", "VARCHAR", "VARCHAR2", "NCHAR", "NVARCHAR2", "TEXT", "TINYTEXT",
+		"MEDIUMTEXT", "LONGTEXT", "CLOB", "NCLOB", "LONG", "CITEXT", "ENUM", "SET", "NAME":
+		return classText
+	case "BOOL", "BOOLEAN", "BIT":
+		return classBool
+	case "DATE", "DATETIME", "TIMESTAMP", "TIMESTAMPTZ", "TIME", "TIMETZ",
+		"TIMESTAMP WITH TIME ZONE", "TIMESTAMP WITH LOCAL TIME ZONE":
+		return classTime
+	case "BYTEA", "BLOB", "TINYBLOB", "MEDIUMBLOB", "LONGBLOB", "BINARY", "VARBINARY", "RAW", "LONG RAW":
+		return classBinary
+	case "UUID":
+		return classUUID
+	case "JSON", "JSONB":
+		return classJSON
+	}
+	return classUnknown
+}
@@ -0,0 +1,52 @@
+package orm
+
+import "sync"
+
+type (
+	// Statement is a built SQL statement together with its bound arguments.
+	Statement struct {
+		SQL  string
+		Args []any
+	}
+
+	// StatementRecorder collects the statements built by a DryRun adapter.
+	// It is safe for concurrent use.
+	StatementRecorder struct {
+		mu         sync.Mutex
+		statements []Statement
+	}
+)
+
+func (r *StatementRecorder) record(sqlStr string, args []any) {
+	if r == nil {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.statements = append(r.statements, Statement{SQL: sqlStr, Args: append([]any(nil), args...)})
+}
+
+// Statements returns a copy of the recorded statements in execution order.
+func (r *StatementRecorder) Statements() []Statement {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Statement(nil), r.statements...)
+}
+
+// Last returns the most recently recorded statement.
+func (r *StatementRecorder) Last() (Statement, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.statements) == 0 {
+		return Statement{}, false
+	}
+	return r.statements[len(r.statements)-1], true
+}
+
+// Reset discards all recorded statements.
+func (r *StatementRecorder) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.statements = nil
+}
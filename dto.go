@@ -0,0 +1,138 @@
+package orm
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/godev90/validator/faults"
+	"gorm.io/gorm"
+)
+
+// Scanning into a DTO: a query built with UseModel(&User{}) may be scanned
+// into a smaller struct (UserSummary, []UserSummary) whose fields are a
+// subset of the model's columns. When no Select was given, the SELECT list
+// is narrowed to the DTO's columns so only those travel over the wire.
+
+var (
+	errDTOColumn = fmt.Errorf("orm: dto column not in model")
+	ErrDTOColumn = faults.New(errDTOColumn, &faults.ErrAttr{
+		Code: http.StatusInternalServerError,
+		Messages: []faults.LangPackage{
+			{
+				Tag:     faults.English,
+				Message: "orm: %s maps column [%s], which %s does not have",
+			},
+		},
+	})
+)
+
+// dtoType returns the struct type behind dest (*T, *[]T, *[]*T) when it is a
+// DTO for model: a mapped struct of a different type than the model.
+func dtoType(dest any, model Tabler) (reflect.Type, bool) {
+	if model == nil {
+		return nil, false
+	}
+
+	t := reflect.TypeOf(dest)
+	if t == nil || t.Kind() != reflect.Ptr {
+		return nil, false
+	}
+	t = t.Elem()
+	if t.Kind() == reflect.Slice {
+		t = t.Elem()
+	}
+	if !isStructElem(t) {
+		return nil, false
+	}
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	mt := reflect.TypeOf(model)
+	if mt.Kind() == reflect.Ptr {
+		mt = mt.Elem()
+	}
+	if t == mt {
+		return nil, false
+	}
+	return t, true
+}
+
+// fieldColumns lists the columns t maps, in field order.
+func fieldColumns(t reflect.Type) []string {
+	fields := modelFields(t)
+	cols := make([]string, len(fields))
+	for i, f := range fields {
+		cols[i] = strings.ToLower(f.column)
+	}
+	return cols
+}
+
+// narrowTo selects only the columns of dest when it is a DTO of the model
+// and the query still selects *. Every DTO column must exist on the model.
+func (q *SqlQueryAdapter) narrowTo(dest any) (*SqlQueryAdapter, error) {
+	if len(q.fields) != 1 || q.fields[0] != "*" {
+		return q, nil
+	}
+	dt, ok := dtoType(dest, q.model)
+	if !ok {
+		return q, nil
+	}
+
+	model := cachedFieldMap(reflect.Indirect(reflect.ValueOf(q.model)).Type())
+	cols := fieldColumns(dt)
+	fields := make([]string, len(cols))
+	for i, col := range cols {
+		if _, ok := model[col]; !ok {
+			return nil, ErrDTOColumn.Render(dt.Name(), col, q.table)
+		}
+
+		// qualify once joins are present, their columns may share names
+		if len(q.joins) > 0 {
+			col = q.table + "." + col
+		}
+		fields[i] = quoteIdent(q.flavor, col)
+	}
+
+	cp := q.clone()
+	cp.fields = fields
+	return cp, nil
+}
+
+// narrowTo is the gorm counterpart of SqlQueryAdapter.narrowTo; columns are
+// resolved through gorm's schema so its naming rules and tags apply.
+func (g *GormAdapter) narrowTo(dest any) (*GormAdapter, error) {
+	if len(g.db.Statement.Selects) > 0 {
+		return g, nil
+	}
+	dt, ok := dtoType(dest, g.model)
+	if !ok {
+		return g, nil
+	}
+
+	model := &gorm.Statement{DB: g.db}
+	if err := model.Parse(g.model); err != nil {
+		return nil, err
+	}
+	dto := &gorm.Statement{DB: g.db}
+	if err := dto.Parse(reflect.New(dt).Interface()); err != nil {
+		return nil, err
+	}
+
+	table := model.Schema.Table
+	if g.db.Statement.Table != "" {
+		// a table prefix (WithTablePrefix) renames it
+		table = g.db.Statement.Table
+	}
+
+	cols := make([]string, 0, len(dto.Schema.DBNames))
+	for _, col := range dto.Schema.DBNames {
+		if _, ok := model.Schema.FieldsByDBName[col]; !ok {
+			return nil, ErrDTOColumn.Render(dt.Name(), col, table)
+		}
+		cols = append(cols, table+"."+col)
+	}
+	return g.with(g.db.Select(cols)), nil
+}
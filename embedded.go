@@ -0,0 +1,123 @@
+package orm
+
+import (
+	"reflect"
+	"slices"
+	"sync"
+)
+
+// modelField is a column-mapped field of a model struct. Fields of embedded
+// structs are flattened into the model: Index is the path from the model
+// struct (for reflect.Value.FieldByIndex) and column carries the
+// embeddedPrefix of the structs it sits in.
+type modelField struct {
+	reflect.StructField
+	column string
+	pk     bool
+	empty  EmptyStringPolicy // the keepEmpty/emptyAsNull policy plus one, or 0
+
+	timeFormat string // epoch format of a time field, see epochFormats
+
+	legacy    string // legacyColumn: the column being renamed to column
+	renamedTo string // of a copy under the legacy column, see legacyFields
+}
+
+// modelFieldCache maps a struct type to its modelFields.
+var modelFieldCache sync.Map // reflect.Type -> []modelField
+
+// modelFields returns the column-mapped fields of the struct type t in
+// field order. Anonymous struct fields, and struct fields tagged embedded,
+// are flattened in their place, their columns prefixed with the
+// embeddedPrefix tag option:
+//
+//	type BaseModel struct {
+//		ID        int64     `sql:"column:id;primaryKey"`
+//		CreatedAt time.Time `sql:"column:created_at"`
+//	}
+//
+//	type Invoice struct {
+//		BaseModel
+//		Audit Audit `sql:"embedded;embeddedPrefix:audit_"`
+//	}
+//
+// As with Go's promoted fields, a column mapped at a shallower depth hides
+// the same column further down.
+func modelFields(t reflect.Type) []modelField {
+	if cached, ok := modelFieldCache.Load(t); ok {
+		return cached.([]modelField)
+	}
+
+	all := appendModelFields(nil, t, nil, "")
+	fields := make([]modelField, 0, len(all))
+	for _, f := range all {
+		hidden := slices.ContainsFunc(all, func(o modelField) bool {
+			return o.column == f.column && len(o.Index) < len(f.Index)
+		})
+		if !hidden {
+			fields = append(fields, f)
+		}
+	}
+
+	modelFieldCache.Store(t, fields)
+	return fields
+}
+
+func appendModelFields(out []modelField, t reflect.Type, index []int, prefix string) []modelField {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("sql")
+		if tag == "-" || isRelationTag(tag) {
+			continue
+		}
+
+		path := append(slices.Clip(index), i)
+		if isEmbedded(f) {
+			out = appendModelFields(out, f.Type, path, prefix+tagOption(tag, "embeddedPrefix"))
+			continue
+		}
+		if f.PkgPath != "" {
+			continue
+		}
+
+		col, pk := parseColumnTag(f)
+		if col == "" {
+			col = toSnake(f.Name)
+		}
+		legacy := tagOption(tag, "legacyColumn")
+		if legacy != "" {
+			legacy = prefix + legacy
+		}
+		if tagFlag(tag, "sensitive") {
+			markSensitive(prefix + col)
+			if legacy != "" {
+				markSensitive(legacy)
+			}
+		}
+		f.Index = path
+		out = append(out, modelField{
+			StructField: f,
+			column:      prefix + col,
+			pk:          pk,
+			empty:       emptyOverride(tag),
+			timeFormat:  epochFormat(f),
+			legacy:      legacy,
+		})
+	}
+	return out
+}
+
+// isEmbedded reports whether the fields of f's struct are flattened into
+// its model: f is an anonymous struct field without a column of its own,
+// or is tagged embedded. Structs that scan as one value (time.Time,
+// sql.Scanner types) are never flattened.
+func isEmbedded(f reflect.StructField) bool {
+	if f.Type.Kind() != reflect.Struct || !isStructElem(f.Type) {
+		return false
+	}
+	tag := f.Tag.Get("sql")
+	if tagFlag(tag, "embedded") {
+		return true
+	}
+	col, _ := parseColumnTag(f)
+	return f.Anonymous && col == ""
+}
@@ -0,0 +1,60 @@
+package orm
+
+import (
+	"database/sql"
+	"reflect"
+)
+
+// EmptyStringPolicy decides how SqlQueryAdapter scans empty text values.
+type EmptyStringPolicy int
+
+const (
+	// EmptyAsNull reads empty and blank text as NULL: the field gets its
+	// zero value, nil for pointers and Valid false for sql.Null* types.
+	// It is the default.
+	EmptyAsNull EmptyStringPolicy = iota
+	// EmptyAsValue keeps empty text in fields that hold text (strings,
+	// pointers to strings, []byte and sql.NullString), so it survives a
+	// round trip. Other fields still read it as their zero value.
+	EmptyAsValue
+)
+
+var nullStringT = reflect.TypeOf(sql.NullString{})
+
+// UseEmptyStrings sets how this chain scans empty text; fields tagged
+// keepEmpty or emptyAsNull keep their own policy.
+func (q *SqlQueryAdapter) UseEmptyStrings(policy EmptyStringPolicy) QueryAdapter {
+	cp := q.clone()
+	cp.emptyStrings = policy
+	return cp
+}
+
+// emptyOverride returns the policy set by the keepEmpty and emptyAsNull
+// options of a field's sql tag plus one, or 0 when the field follows the
+// adapter.
+func emptyOverride(tag string) EmptyStringPolicy {
+	switch {
+	case tagFlag(tag, "keepEmpty"):
+		return EmptyAsValue + 1
+	case tagFlag(tag, "emptyAsNull"):
+		return EmptyAsNull + 1
+	}
+	return 0
+}
+
+// holdsText reports whether fields of type t can hold empty text as a
+// value distinct from NULL.
+func holdsText(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch {
+	case t.Kind() == reflect.String:
+		return true
+	case t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8:
+		return true
+	case t == nullStringT:
+		return true
+	}
+	return false
+}
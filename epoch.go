@@ -0,0 +1,154 @@
+package orm
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"log"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+// epochFormats are the timeFormat tag values of time fields stored as
+// integer epoch counts: sql:"column:created;timeFormat:unixmilli".
+var epochFormats = []string{"unix", "unixmilli", "unixmicro", "unixnano"}
+
+// epochFormat returns the timeFormat option of a field's sql tag, or ""
+// when it has none. Unknown formats are logged and ignored.
+func epochFormat(f reflect.StructField) string {
+	format := tagOption(f.Tag.Get("sql"), "timeFormat")
+	if format == "" {
+		return ""
+	}
+	for _, known := range epochFormats {
+		if format == known {
+			return format
+		}
+	}
+	log.Printf("WARNING: %s: unknown timeFormat %q", f.Name, format)
+	return ""
+}
+
+// epochTime converts the epoch count n in format to a time.
+func epochTime(format string, n int64) time.Time {
+	switch format {
+	case "unixmilli":
+		return time.UnixMilli(n)
+	case "unixmicro":
+		return time.UnixMicro(n)
+	case "unixnano":
+		return time.Unix(0, n)
+	}
+	return time.Unix(n, 0)
+}
+
+// epochCount converts t to an epoch count in format.
+func epochCount(format string, t time.Time) int64 {
+	switch format {
+	case "unixmilli":
+		return t.UnixMilli()
+	case "unixmicro":
+		return t.UnixMicro()
+	case "unixnano":
+		return t.UnixNano()
+	}
+	return t.Unix()
+}
+
+// assignEpoch sets the time.Time or sql.NullTime field from the epoch
+// count in raw.
+func assignEpoch(field reflect.Value, raw any, format string) error {
+	var t time.Time
+	switch v := toScalar(raw).(type) {
+	case time.Time:
+		// the driver already converted it
+		t = v
+	case int64:
+		t = epochTime(format, v)
+	case float64:
+		t = epochTime(format, int64(v))
+	case string:
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return ErrParseTimeFailed.Render(v)
+		}
+		t = epochTime(format, n)
+	default:
+		return ErrParseFailed.Render(v, "time")
+	}
+
+	if field.Type() == nullTimeT {
+		field.Set(reflect.ValueOf(sql.NullTime{Time: t, Valid: true}))
+	} else {
+		field.Set(reflect.ValueOf(t))
+	}
+	return nil
+}
+
+// epochValue returns the value written for a time field with an epoch
+// format: the count, or nil for a nil pointer or an invalid sql.NullTime.
+// Values of other fields, or without a format, are returned as they are.
+func epochValue(format string, v any) any {
+	if format == "" {
+		return v
+	}
+	switch t := v.(type) {
+	case time.Time:
+		return epochCount(format, t)
+	case *time.Time:
+		if t == nil {
+			return nil
+		}
+		return epochCount(format, *t)
+	case sql.NullTime:
+		if !t.Valid {
+			return nil
+		}
+		return epochCount(format, t.Time)
+	}
+	return v
+}
+
+// value returns what a write binds for the field v of this column; see
+// bindValue. Value methods with a pointer receiver are found on fields
+// held by value too.
+func (f *modelField) value(v reflect.Value) (any, error) {
+	x := v.Interface()
+	if v.Kind() != reflect.Ptr && !v.Type().Implements(valuerT) && reflect.PointerTo(v.Type()).Implements(valuerT) {
+		p := reflect.New(v.Type())
+		p.Elem().Set(v)
+		x = p.Interface()
+	}
+	return bindValue(f.timeFormat, x)
+}
+
+var valuerT = reflect.TypeOf((*driver.Valuer)(nil)).Elem()
+
+// bindValue returns what a write binds for v in a column with the epoch
+// format: the count for time values with one, the result of Value for
+// driver.Valuer implementations (enums, encrypted strings, decimals), so
+// interceptors and dry runs see what the driver gets, and v otherwise. A
+// nil pointer to a type whose Value has a value receiver binds NULL, as in
+// database/sql.
+func bindValue(format string, v any) (any, error) {
+	v = epochValue(format, v)
+	valuer, ok := v.(driver.Valuer)
+	if !ok {
+		return v, nil
+	}
+	if rv := reflect.ValueOf(v); rv.Kind() == reflect.Ptr && rv.IsNil() && rv.Type().Elem().Implements(valuerT) {
+		return nil, nil
+	}
+	return valuer.Value()
+}
+
+// epochScanner scans an epoch column into the time field it wraps, for
+// adapters that scan through sql.Scanner (pgx).
+type epochScanner struct {
+	field reflect.Value
+	opts  assignOpts
+}
+
+func (s *epochScanner) Scan(src any) error {
+	return convertAssign(s.field, src, s.opts)
+}
@@ -0,0 +1,217 @@
+package orm
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/godev90/validator/faults"
+	"gorm.io/gorm"
+)
+
+// statusClientClosedRequest is the de facto status of a request its client
+// gave up on.
+const statusClientClosedRequest = 499
+
+// The classes of failed statements. A statement error matches the one it
+// falls in with errors.Is, as well as ErrQueryFailed, and carries its code,
+// so retry policies and HTTP mappers can act on the class. The driver error
+// stays reachable with errors.As.
+var (
+	// ErrTimeout is a statement that ran out of time: its context deadline,
+	// a statement or lock timeout of the server. It is ErrQueryTimeout.
+	ErrTimeout = ErrQueryTimeout
+
+	errCanceled = fmt.Errorf("orm: query canceled")
+	// ErrCanceled is a statement whose context was canceled, or that the
+	// server was asked to cancel.
+	ErrCanceled = faults.New(errCanceled, &faults.ErrAttr{
+		Code: statusClientClosedRequest,
+		Messages: []faults.LangPackage{
+			{
+				Tag:     faults.English,
+				Message: "orm: query canceled after %s (timeout %s): %v",
+			},
+		},
+	})
+
+	errConnClosed = fmt.Errorf("orm: connection lost")
+	// ErrConnClosed is a statement whose connection failed or was closed;
+	// it may or may not have run.
+	ErrConnClosed = faults.New(errConnClosed, &faults.ErrAttr{
+		Code: http.StatusServiceUnavailable,
+		Messages: []faults.LangPackage{
+			{
+				Tag:     faults.English,
+				Message: "orm: connection lost after %s (timeout %s): %v",
+			},
+		},
+	})
+
+	errConstraint = fmt.Errorf("orm: constraint violated")
+	// ErrConstraint is a write rejected by a unique, foreign key, not null
+	// or check constraint.
+	ErrConstraint = faults.New(errConstraint, &faults.ErrAttr{
+		Code: http.StatusConflict,
+		Messages: []faults.LangPackage{
+			{
+				Tag:     faults.English,
+				Message: "orm: constraint violated: %[3]v",
+			},
+		},
+	})
+
+	errSyntax = fmt.Errorf("orm: invalid statement")
+	// ErrSyntax is a statement the server can't parse, or that names tables
+	// or columns that don't exist.
+	ErrSyntax = faults.New(errSyntax, &faults.ErrAttr{
+		Code: http.StatusInternalServerError,
+		Messages: []faults.LangPackage{
+			{
+				Tag:     faults.English,
+				Message: "orm: invalid statement: %[3]v",
+			},
+		},
+	})
+)
+
+// errorClass returns the class of err, a statement run under ctx failed
+// with, or ErrQueryFailed when it fits none.
+func errorClass(ctx context.Context, err error) faults.Error {
+	switch {
+	case errors.Is(err, context.DeadlineExceeded) || errors.Is(ctx.Err(), context.DeadlineExceeded):
+		return ErrTimeout
+	case errors.Is(err, context.Canceled) || errors.Is(ctx.Err(), context.Canceled):
+		return ErrCanceled
+	case errors.Is(err, gorm.ErrDuplicatedKey) || errors.Is(err, gorm.ErrForeignKeyViolated) || errors.Is(err, gorm.ErrCheckConstraintViolated):
+		return ErrConstraint
+	}
+
+	if state := sqlState(err); state != "" {
+		return stateClass(state, err)
+	}
+	if code, ok := mysqlErrorNumber(err); ok {
+		return mysqlClass(code)
+	}
+	if code, ok := oracleErrorCode(err); ok {
+		return oracleClass(code)
+	}
+
+	var netErr net.Error
+	switch {
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return ErrTimeout
+	case errors.Is(err, driver.ErrBadConn), errors.Is(err, sql.ErrConnDone),
+		errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF),
+		errors.Is(err, syscall.ECONNRESET), errors.Is(err, syscall.ECONNREFUSED), errors.Is(err, syscall.EPIPE),
+		errors.As(err, new(*net.OpError)):
+		return ErrConnClosed
+	}
+	return ErrQueryFailed
+}
+
+// sqlState returns the SQLSTATE of a lib/pq or pgx error, "" for others.
+func sqlState(err error) string {
+	for _, d := range pgDrivers {
+		if state := d.sqlState(err); state != "" {
+			return state
+		}
+	}
+	var e interface{ SQLState() string }
+	if errors.As(err, &e) {
+		return e.SQLState()
+	}
+	return ""
+}
+
+// stateClass classifies a PostgreSQL SQLSTATE.
+func stateClass(state string, err error) faults.Error {
+	switch {
+	case state == "57014":
+		// query_canceled: by statement_timeout, or on request
+		if strings.Contains(err.Error(), "timeout") {
+			return ErrTimeout
+		}
+		return ErrCanceled
+	case state == "55P03":
+		// lock_not_available: lock_timeout or NOWAIT
+		return ErrTimeout
+	case strings.HasPrefix(state, "08"), state == "57P01", state == "57P02", state == "57P03":
+		return ErrConnClosed
+	case strings.HasPrefix(state, "23"):
+		return ErrConstraint
+	case strings.HasPrefix(state, "42"):
+		return ErrSyntax
+	}
+	return ErrQueryFailed
+}
+
+// mysqlErrorNumber returns the number of a go-sql-driver/mysql
+// *MySQLError, found by shape so the driver needn't be imported.
+func mysqlErrorNumber(err error) (uint16, bool) {
+	for ; err != nil; err = errors.Unwrap(err) {
+		v := reflect.ValueOf(err)
+		if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct || v.Elem().Type().Name() != "MySQLError" {
+			continue
+		}
+		if n := v.Elem().FieldByName("Number"); n.IsValid() && n.Kind() == reflect.Uint16 {
+			return uint16(n.Uint()), true
+		}
+	}
+	return 0, false
+}
+
+func mysqlClass(code uint16) faults.Error {
+	switch code {
+	case 1317: // ER_QUERY_INTERRUPTED
+		return ErrCanceled
+	case 1205, 3024: // lock wait timeout, max_execution_time
+		return ErrTimeout
+	case 1053, 2006, 2013: // server shutdown, gone away, lost connection
+		return ErrConnClosed
+	case 1048, 1062, 1216, 1217, 1364, 1451, 1452, 1557, 1586, 3819:
+		return ErrConstraint
+	case 1054, 1064, 1146, 1149:
+		return ErrSyntax
+	}
+	return ErrQueryFailed
+}
+
+var oraCode = regexp.MustCompile(`\bORA-(\d{5})\b`)
+
+// oracleErrorCode returns the ORA- code an error message starts its
+// description with.
+func oracleErrorCode(err error) (int, bool) {
+	m := oraCode.FindStringSubmatch(err.Error())
+	if m == nil {
+		return 0, false
+	}
+	code, _ := strconv.Atoi(m[1])
+	return code, true
+}
+
+func oracleClass(code int) faults.Error {
+	switch code {
+	case 1013: // user requested cancel
+		return ErrCanceled
+	case 30006, 51, 4021: // resource busy, lock timeouts
+		return ErrTimeout
+	case 3113, 3114, 3135, 12537, 12541, 12170:
+		return ErrConnClosed
+	case 1, 1400, 1407, 2290, 2291, 2292:
+		return ErrConstraint
+	case 900, 904, 905, 906, 907, 908, 911, 913, 917, 921, 923, 933, 936, 942:
+		return ErrSyntax
+	}
+	return ErrQueryFailed
+}
@@ -0,0 +1,68 @@
+package orm
+
+import (
+	"database/sql"
+
+	"github.com/godev90/validator/faults"
+	"gorm.io/gorm"
+)
+
+// faultError lets a faults.Error take part in errors.Is and errors.As. A
+// bare faults.Error holds a map, so it isn't comparable and
+// errors.Is(err, ErrNotFound) would never match it.
+type faultError struct {
+	err     faults.Error
+	aliases []error // foreign sentinels that mean the same thing
+}
+
+// errRecordNotFound is what every finisher returns when a struct
+// destination gets no row. It matches ErrNotFound, sql.ErrNoRows and
+// gorm.ErrRecordNotFound.
+var errRecordNotFound error = faultError{
+	err:     ErrNotFound,
+	aliases: []error{sql.ErrNoRows, gorm.ErrRecordNotFound},
+}
+
+func (e faultError) Error() string {
+	return e.err.Error()
+}
+
+func (e faultError) Code() faults.ErrCode {
+	return e.err.Code()
+}
+
+func (e faultError) LocalizedError(tag faults.LanguageTag) string {
+	return e.err.LocalizedError(tag)
+}
+
+func (e faultError) Unwrap() error {
+	return e.err
+}
+
+func (e faultError) Is(target error) bool {
+	if t, ok := target.(faultError); ok {
+		target = t.err
+	}
+	if faults.Is(e.err, target) {
+		return true
+	}
+
+	for _, a := range e.aliases {
+		// faults.Is, as faults.Error values can't be compared with ==
+		if faults.Is(a, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// queryError is a faultError raised for a failed statement. Besides the
+// fault it unwraps to the driver error that caused it.
+type queryError struct {
+	faultError
+	cause error
+}
+
+func (e queryError) Unwrap() []error {
+	return []error{e.faultError, e.cause}
+}
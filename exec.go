@@ -0,0 +1,131 @@
+package orm
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/godev90/validator/faults"
+	"gorm.io/gorm"
+)
+
+var (
+	errQueryTimeout = fmt.Errorf("orm: query timed out")
+	ErrQueryTimeout = faults.New(errQueryTimeout, &faults.ErrAttr{
+		Code: http.StatusGatewayTimeout,
+		Messages: []faults.LangPackage{
+			{
+				Tag:     faults.English,
+				Message: "orm: query timed out after %s (timeout %s): %v",
+			},
+		},
+	})
+
+	errQueryFailed = fmt.Errorf("orm: query failed")
+	ErrQueryFailed = faults.New(errQueryFailed, &faults.ErrAttr{
+		Code: http.StatusInternalServerError,
+		Messages: []faults.LangPackage{
+			{
+				Tag:     faults.English,
+				Message: "orm: query failed after %s (timeout %s): %v",
+			},
+		},
+	})
+)
+
+type (
+	// queryCall describes one statement on its way to the driver.
+	queryCall struct {
+		ctx    context.Context
+		query  string
+		args   []any
+		flavor driverFlavor
+		dryRun bool // built but not sent; logging and budgets skip it
+
+		// prepared statements are sent without statement tags, which would
+		// make each of them a new entry of the statement cache
+		prepared bool
+		debug    bool // logged whatever the sampling, see SqlQueryAdapter.Debug
+
+		elapsed time.Duration
+	}
+
+	// interceptor wraps the execution of a statement; it must call next
+	// exactly once unless it rejects the call.
+	interceptor func(c *queryCall, next func() error) error
+)
+
+// interceptors is the chain every statement passes through, outermost first.
+var interceptors = []interceptor{
+	logInterceptor,
+	budgetInterceptor,
+	statsInterceptor,
+}
+
+// runQuery executes fn through the interceptor chain. fn performs the actual
+// driver call of query, c.query with the statement tags of c.ctx appended
+// (see WithStatementTags); adapters whose SQL is only known afterwards may
+// fill c.query from inside fn.
+func runQuery(c *queryCall, fn func(query string) error) error {
+	if c.ctx == nil {
+		c.ctx = context.Background()
+	}
+	if !c.dryRun && !c.prepared {
+		c.query = tagStatement(c.ctx, c.query)
+	}
+
+	call := func() error {
+		start := time.Now()
+		err := fn(c.query)
+		c.elapsed = time.Since(start)
+		return c.annotate(err, start)
+	}
+
+	for i := len(interceptors) - 1; i >= 0; i-- {
+		ic, next := interceptors[i], call
+		call = func() error { return ic(c, next) }
+	}
+	return call()
+}
+
+// annotate wraps a driver error in the fault a translator gives it (see
+// ErrDuplicateKey), its class (see ErrConstraint) or ErrQueryFailed. The
+// messages name how long the statement ran and the deadline it had, so a
+// timeout reads differently from a dropped connection. The driver error is
+// still reachable through errors.Is and errors.As. Not-found results and
+// errors the orm raised itself are returned as they are.
+func (c *queryCall) annotate(err error, start time.Time) error {
+	if err == nil || c.dryRun || errors.Is(err, sql.ErrNoRows) || errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+	if _, ok := err.(interface{ Code() faults.ErrCode }); ok {
+		return err
+	}
+
+	timeout := "none"
+	if deadline, ok := c.ctx.Deadline(); ok {
+		timeout = deadline.Sub(start).Round(time.Millisecond).String()
+	}
+	elapsed := c.elapsed.Round(time.Microsecond)
+
+	class := errorClass(c.ctx, err)
+	fault := class.Render(elapsed, timeout, err)
+	if f, ok := translateError(err); ok {
+		fault = f
+	} else if f, ok := driverFault(err); ok {
+		fault = f.Render(elapsed, timeout, err)
+	}
+
+	// the fault also matches its class, and all but timeouts ErrQueryFailed
+	aliases := []error{class}
+	if !faults.Is(class, ErrTimeout) {
+		aliases = append(aliases, ErrQueryFailed)
+	}
+	return queryError{
+		faultError: faultError{err: fault, aliases: aliases},
+		cause:      err,
+	}
+}
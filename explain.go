@@ -0,0 +1,114 @@
+package orm
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+)
+
+// explainPrefix returns the EXPLAIN keyword for flavor: EXPLAIN or EXPLAIN
+// ANALYZE on MySQL and Postgres, and EXPLAIN PLAN FOR on Oracle, whatever
+// analyze says.
+func explainPrefix(flavor driverFlavor, analyze bool) string {
+	if flavor == FlavorOracle {
+		// Oracle has no ANALYZE variant; actual statistics need
+		// DBMS_XPLAN.DISPLAY_CURSOR on an executed statement
+		return "EXPLAIN PLAN FOR "
+	}
+	if analyze {
+		return "EXPLAIN ANALYZE "
+	}
+	return "EXPLAIN "
+}
+
+// explainQuery runs the EXPLAIN form of sqlStr and renders the plan as text.
+// Single-column plans (Postgres, MySQL ANALYZE, Oracle) are returned line by
+// line; tabular plans get a header row and tab-separated columns.
+func explainQuery(ctx context.Context, db *sql.DB, flavor driverFlavor, sqlStr string, args []any, analyze bool) (string, error) {
+	if db == nil {
+		return "", ErrNilPointer
+	}
+
+	query := explainPrefix(flavor, analyze) + sqlStr
+	if flavor == FlavorOracle {
+		return explainOracle(ctx, db, query, args)
+	}
+
+	var rows *sql.Rows
+	err := runQuery(&queryCall{ctx: ctx, query: query, args: args, flavor: flavor}, func(query string) (err error) {
+		rows, err = db.QueryContext(ctx, query, args...)
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+
+	return renderPlan(rows)
+}
+
+// explainOracle runs EXPLAIN PLAN, which only fills PLAN_TABLE, and reads
+// the plan back through DBMS_XPLAN on the same session.
+func explainOracle(ctx context.Context, db *sql.DB, query string, args []any) (string, error) {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+
+	var rows *sql.Rows
+	err = runQuery(&queryCall{ctx: ctx, query: query, args: args, flavor: FlavorOracle}, func(query string) error {
+		if _, err := conn.ExecContext(ctx, query, args...); err != nil {
+			return err
+		}
+
+		var err error
+		rows, err = conn.QueryContext(ctx, "SELECT PLAN_TABLE_OUTPUT FROM TABLE(DBMS_XPLAN.DISPLAY())")
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+
+	return renderPlan(rows)
+}
+
+func renderPlan(rows *sql.Rows) (string, error) {
+	cols, err := rows.Columns()
+	if err != nil {
+		return "", err
+	}
+
+	var lines []string
+	if len(cols) > 1 {
+		lines = append(lines, strings.Join(cols, "\t"))
+	}
+
+	raw := make([]sql.NullString, len(cols))
+	holders := make([]any, len(cols))
+	for i := range holders {
+		holders[i] = &raw[i]
+	}
+
+	for rows.Next() {
+		if err := rows.Scan(holders...); err != nil {
+			return "", err
+		}
+
+		vals := make([]string, len(raw))
+		for i, v := range raw {
+			if v.Valid {
+				vals[i] = v.String
+			} else {
+				vals[i] = "NULL"
+			}
+		}
+		lines = append(lines, strings.Join(vals, "\t"))
+	}
+
+	if err := rows.Err(); err != nil {
+		return "", err
+	}
+	return strings.Join(lines, "\n"), nil
+}
@@ -0,0 +1,22 @@
+package orm
+
+import "testing"
+
+func TestExplainPrefix(t *testing.T) {
+	tests := []struct {
+		flavor  driverFlavor
+		analyze bool
+		want    string
+	}{
+		{FlavorPostgres, false, "EXPLAIN "},
+		{FlavorPostgres, true, "EXPLAIN ANALYZE "},
+		{FlavorMySQL, true, "EXPLAIN ANALYZE "},
+		{FlavorOracle, false, "EXPLAIN PLAN FOR "},
+		{FlavorOracle, true, "EXPLAIN PLAN FOR "},
+	}
+	for _, tt := range tests {
+		if got := explainPrefix(tt.flavor, tt.analyze); got != tt.want {
+			t.Errorf("explainPrefix(%d, %t) = %q, want %q", tt.flavor, tt.analyze, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,289 @@
+package orm
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"reflect"
+	"strings"
+
+	"github.com/godev90/validator/faults"
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	errFixture = fmt.Errorf("orm: invalid fixture")
+	ErrFixture = faults.New(errFixture, &faults.ErrAttr{
+		Code: http.StatusInternalServerError,
+		Messages: []faults.LangPackage{
+			{
+				Tag:     faults.English,
+				Message: "orm: fixture %s: %s",
+			},
+		},
+	})
+)
+
+// FixtureLoader loads rows from YAML or JSON files into the tables of its
+// models, for integration tests and demo environments. A file maps table
+// names to lists of rows, each row mapping columns to values:
+//
+//	users:
+//	  - _ref: alice          # label the row
+//	    name: Alice
+//	    email: alice@example.com
+//	orders:
+//	  - user_id: $alice      # alice's primary key
+//	    total: 120
+//
+// Tables are loaded in file order, rows in list order. A string "$label"
+// stands for the primary key of the row labelled so by _ref, which must
+// come earlier; "$$" escapes a literal leading "$". Rows are written with
+// BulkInsert, except labelled rows whose key the database generates, which
+// are written with Create to learn it.
+type FixtureLoader struct {
+	db     *sql.DB
+	tables map[string]reflect.Type
+	refs   map[string]any
+}
+
+type fixtureFile struct {
+	name string
+	data []byte
+}
+
+// NewFixtureLoader returns a loader writing to db the tables of models.
+func NewFixtureLoader(db *sql.DB, models ...Tabler) *FixtureLoader {
+	l := &FixtureLoader{db: db, tables: map[string]reflect.Type{}, refs: map[string]any{}}
+	for _, m := range models {
+		if t := reflect.TypeOf(m); t != nil {
+			for t.Kind() == reflect.Ptr {
+				t = t.Elem()
+			}
+			l.tables[m.TableName()] = t
+		}
+	}
+	return l
+}
+
+// Load loads the fixture files at paths, all in one transaction: either
+// every row is written or none is.
+func (l *FixtureLoader) Load(ctx context.Context, paths ...string) error {
+	files := make([]fixtureFile, len(paths))
+	for i, p := range paths {
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		files[i] = fixtureFile{name: p, data: data}
+	}
+	return l.load(ctx, files)
+}
+
+// LoadFS is Load reading from fsys, e.g. an embed.FS of testdata.
+func (l *FixtureLoader) LoadFS(ctx context.Context, fsys fs.FS, paths ...string) error {
+	files := make([]fixtureFile, len(paths))
+	for i, p := range paths {
+		data, err := fs.ReadFile(fsys, p)
+		if err != nil {
+			return err
+		}
+		files[i] = fixtureFile{name: p, data: data}
+	}
+	return l.load(ctx, files)
+}
+
+// Ref returns the primary key of the row labelled label by a successful
+// load, for tests that query what they loaded.
+func (l *FixtureLoader) Ref(label string) (any, bool) {
+	key, ok := l.refs[label]
+	return key, ok
+}
+
+func (l *FixtureLoader) load(ctx context.Context, files []fixtureFile) error {
+	if l.db == nil {
+		return ErrNilPointer
+	}
+
+	// labels of earlier loads resolve; this load's only count if it commits
+	refs := make(map[string]any, len(l.refs))
+	for k, v := range l.refs {
+		refs[k] = v
+	}
+
+	err := WithTransaction(ctx, l.db, func(tx *SqlTransactionAdapter) error {
+		for _, f := range files {
+			var doc yaml.Node
+			if err := yaml.Unmarshal(f.data, &doc); err != nil {
+				return ErrFixture.Render(f.name, err)
+			}
+			if len(doc.Content) == 0 {
+				continue
+			}
+			root := doc.Content[0]
+			if root.Kind != yaml.MappingNode {
+				return ErrFixture.Render(f.name, "want a mapping of table names to rows")
+			}
+
+			for i := 0; i+1 < len(root.Content); i += 2 {
+				table := root.Content[i].Value
+				t, ok := l.tables[table]
+				if !ok {
+					return ErrFixture.Render(f.name, fmt.Sprintf("no model for table %q", table))
+				}
+				rows := root.Content[i+1]
+				if rows.Kind != yaml.SequenceNode {
+					return ErrFixture.Render(f.name, fmt.Sprintf("%s: want a list of rows", table))
+				}
+				if err := loadFixtureRows(tx, f.name, table, t, rows.Content, refs); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	if err == nil {
+		l.refs = refs
+	}
+	return err
+}
+
+// loadFixtureRows writes the rows of one table, of model type t, and
+// records the keys of labelled rows in refs.
+func loadFixtureRows(tx *SqlTransactionAdapter, file, table string, t reflect.Type, rows []*yaml.Node, refs map[string]any) error {
+	fields := cachedFieldMap(t)
+	var pk *modelField
+	for _, f := range modelFields(t) {
+		if f.pk {
+			if pk != nil {
+				pk = nil // composite keys can't be referenced
+				break
+			}
+			pk = &f
+		}
+	}
+
+	// bulkInsert keeps the keys of a batch only if every row has one, so
+	// rows with and without keys go in separate batches
+	var pending []Tabler
+	var pendingKeyed bool
+	flush := func() error {
+		if len(pending) == 0 {
+			return nil
+		}
+		err := tx.bulkInsert(pending, pendingKeyed)
+		pending = nil
+		return err
+	}
+	add := func(model Tabler, keyed bool) error {
+		if len(pending) > 0 && keyed != pendingKeyed {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+		pending, pendingKeyed = append(pending, model), keyed
+		return nil
+	}
+
+	for n, node := range rows {
+		fail := func(msg string) error {
+			return ErrFixture.Render(file, fmt.Sprintf("%s row %d: %s", table, n+1, msg))
+		}
+
+		var row map[string]any
+		if err := node.Decode(&row); err != nil {
+			return fail(err.Error())
+		}
+		label, _ := row["_ref"].(string)
+		delete(row, "_ref")
+
+		ptr := reflect.New(t)
+		model, ok := ptr.Interface().(Tabler)
+		if !ok {
+			return fail(fmt.Sprintf("*%s is not a Tabler", t.Name()))
+		}
+		for col, raw := range row {
+			f, ok := fields[strings.ToLower(col)]
+			if !ok {
+				return fail(fmt.Sprintf("%s has no column %q", t.Name(), col))
+			}
+			if err := setFixtureField(ptr.Elem().FieldByIndex(f.Index), f, raw, refs); err != nil {
+				return fail(fmt.Sprintf("%s: %v", col, err))
+			}
+		}
+
+		var key reflect.Value
+		if pk != nil {
+			key = ptr.Elem().FieldByIndex(pk.Index)
+		}
+		keyed := key.IsValid() && !key.IsZero()
+
+		if label == "" {
+			if err := add(model, keyed); err != nil {
+				return err
+			}
+			continue
+		}
+		if _, dup := refs[label]; dup {
+			return fail(fmt.Sprintf("label %q is already taken", label))
+		}
+		if pk == nil {
+			return fail(fmt.Sprintf("_ref needs %s to have a single primary key", t.Name()))
+		}
+
+		if keyed {
+			ref, err := pk.value(key)
+			if err != nil {
+				return err
+			}
+			refs[label] = ref
+			if err := add(model, keyed); err != nil {
+				return err
+			}
+			continue
+		}
+		// the database generates the key; Create reads it back
+		if err := flush(); err != nil {
+			return err
+		}
+		if err := tx.Create(model); err != nil {
+			return err
+		}
+		ref, err := pk.value(key)
+		if err != nil {
+			return err
+		}
+		refs[label] = ref
+	}
+	return flush()
+}
+
+// setFixtureField sets field f from a decoded fixture value: references
+// are resolved, and lists and maps go through JSON into the field.
+func setFixtureField(field reflect.Value, f *modelField, raw any, refs map[string]any) error {
+	switch v := raw.(type) {
+	case string:
+		if rest, ok := strings.CutPrefix(v, "$$"); ok {
+			raw = "$" + rest
+		} else if label, ok := strings.CutPrefix(v, "$"); ok {
+			key, ok := refs[label]
+			if !ok {
+				return fmt.Errorf("unknown reference %q", v)
+			}
+			raw = key
+		}
+	case int:
+		raw = int64(v)
+	case []any, map[string]any:
+		b, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		return json.Unmarshal(b, field.Addr().Interface())
+	}
+	return convertAssign(field, raw, assignOpts{column: f.column, keepEmpty: true})
+}
@@ -0,0 +1,199 @@
+package orm
+
+import "strings"
+
+// clauseKeywords start a new line in FormatSQL. Multi-word keywords are
+// matched word by word, longest first.
+var clauseKeywords = [][]string{
+	{"select"}, {"from"}, {"where"}, {"group", "by"}, {"having"},
+	{"order", "by"}, {"limit"}, {"offset"}, {"fetch"}, {"returning"},
+	{"values"}, {"set"}, {"union", "all"}, {"union"}, {"intersect"}, {"except"},
+	{"insert", "into"}, {"update"}, {"delete", "from"},
+	{"left", "outer", "join"}, {"right", "outer", "join"}, {"full", "outer", "join"},
+	{"inner", "join"}, {"left", "join"}, {"right", "join"}, {"full", "join"},
+	{"cross", "join"}, {"join"},
+}
+
+// FormatSQL lays a statement out for debug logs and error reports: each
+// clause and join on its own line, AND/OR conditions indented below it and
+// sub-selects indented one level deeper. Literals, quoted identifiers and
+// comments are kept as they are; only whitespace changes.
+func FormatSQL(sqlStr string) string {
+	toks := formatTokens(sqlStr)
+
+	var buf []byte
+	level, line := 0, 0 // indent of the current clause and of the current line
+	lineStart := 0
+	// for each open paren: the level to restore and the indent of its line
+	// when it opened a sub-select, or -1
+	var parens [][2]int
+	between := false
+
+	newline := func(indent int) {
+		if len(buf) > lineStart && strings.TrimSpace(string(buf[lineStart:])) == "" {
+			// nothing was written on the current line: reuse it
+			buf = buf[:lineStart]
+		} else if len(buf) > 0 {
+			buf = append(buf, '\n')
+			lineStart = len(buf)
+		}
+		buf = append(buf, strings.Repeat("  ", indent)...)
+		line = indent
+	}
+	// write appends t, keeping the space before it in the source unless it
+	// starts a line.
+	write := func(t string, spaced bool) {
+		if spaced && strings.TrimSpace(string(buf[lineStart:])) != "" {
+			buf = append(buf, ' ')
+		}
+		buf = append(buf, t...)
+		if strings.HasPrefix(t, "--") {
+			// a line comment runs to the end of the line
+			newline(level + 1)
+		}
+	}
+
+	for i := 0; i < len(toks); i++ {
+		t := toks[i]
+		switch t.text {
+		case "(":
+			write(t.text, t.spaced)
+			if i+1 < len(toks) && strings.EqualFold(toks[i+1].text, "select") {
+				parens = append(parens, [2]int{level, line})
+				level = line + 1
+			} else {
+				parens = append(parens, [2]int{-1, 0})
+			}
+			continue
+		case ")":
+			if n := len(parens); n > 0 {
+				if p := parens[n-1]; p[0] >= 0 {
+					level = p[0]
+					newline(p[1])
+				}
+				parens = parens[:n-1]
+			}
+			write(t.text, t.spaced)
+			continue
+		}
+
+		if kw := matchKeyword(toks[i:]); kw > 0 {
+			newline(level)
+			words := make([]string, kw)
+			for j := range words {
+				words[j] = toks[i+j].text
+			}
+			write(strings.Join(words, " "), true)
+			i += kw - 1
+			between = false
+			continue
+		}
+
+		switch strings.ToLower(t.text) {
+		case "between":
+			between = true
+		case "and":
+			if between {
+				between = false
+				break
+			}
+			newline(level + 1)
+		case "or":
+			newline(level + 1)
+		}
+		write(t.text, t.spaced)
+	}
+	return strings.TrimRight(string(buf), " \n")
+}
+
+// matchKeyword returns how many of toks form a clause keyword, or 0.
+func matchKeyword(toks []formatToken) int {
+	for _, kw := range clauseKeywords {
+		if len(kw) > len(toks) {
+			continue
+		}
+		ok := true
+		for j, w := range kw {
+			if !strings.EqualFold(toks[j].text, w) {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			return len(kw)
+		}
+	}
+	return 0
+}
+
+// formatToken is a token of a statement and whether whitespace preceded it.
+type formatToken struct {
+	text   string
+	spaced bool
+}
+
+// formatTokens splits a statement into words, quoted literals and
+// identifiers, comments, parentheses and commas, dropping whitespace.
+func formatTokens(s string) []formatToken {
+	var toks []formatToken
+	spaced := false
+	add := func(t string) {
+		toks = append(toks, formatToken{text: t, spaced: spaced})
+		spaced = false
+	}
+
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			spaced = true
+			i++
+		case c == '(' || c == ')' || c == ',':
+			add(string(c))
+			i++
+		case c == '\'' || c == '"' || c == '`':
+			j := i + 1
+			for j < len(s) {
+				if s[j] == c {
+					// a doubled quote escapes itself
+					if j+1 < len(s) && s[j+1] == c {
+						j += 2
+						continue
+					}
+					break
+				}
+				j++
+			}
+			j = min(j+1, len(s))
+			add(s[i:j])
+			i = j
+		case strings.HasPrefix(s[i:], "/*"):
+			j := strings.Index(s[i+2:], "*/")
+			if j < 0 {
+				j = len(s)
+			} else {
+				j += i + 4
+			}
+			add(s[i:j])
+			i = j
+		case strings.HasPrefix(s[i:], "--"):
+			j := strings.IndexByte(s[i:], '\n')
+			if j < 0 {
+				j = len(s)
+			} else {
+				j += i
+			}
+			add(s[i:j])
+			i = j
+		default:
+			j := i
+			for j < len(s) && !strings.ContainsRune(" \t\n\r(),'\"`", rune(s[j])) &&
+				!strings.HasPrefix(s[j:], "/*") && !strings.HasPrefix(s[j:], "--") {
+				j++
+			}
+			add(s[i:j])
+			i = j
+		}
+	}
+	return toks
+}
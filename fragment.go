@@ -0,0 +1,102 @@
+package orm
+
+import (
+	"fmt"
+	"log"
+)
+
+// Fragment is a named, reusable bundle of Where/Join/Order clauses, e.g. a
+// "visible to user" or "billable items" filter shared across repositories.
+// Clauses are validated when they are added, so applying a fragment never
+// fails; a rejected clause is left out and reported by Err.
+//
+// Fragments are immutable: every builder method returns a copy, so a
+// package-level fragment can be extended and applied concurrently.
+type Fragment struct {
+	name  string
+	steps []ScopeFunc
+	err   error
+}
+
+// NewFragment starts an empty fragment. The name is used in log and error
+// messages.
+func NewFragment(name string) *Fragment {
+	return &Fragment{name: name}
+}
+
+// Name returns the fragment's name.
+func (f *Fragment) Name() string {
+	return f.name
+}
+
+// Err returns the first clause rejected while building the fragment.
+func (f *Fragment) Err() error {
+	return f.err
+}
+
+// Where adds a condition. String conditions go through ValidateWhereClause.
+func (f *Fragment) Where(cond any, args ...any) *Fragment {
+	if s, ok := cond.(string); ok {
+		if err := ValidateWhereClause(s); err != nil {
+			return f.reject("WHERE", s, err)
+		}
+	}
+	return f.with(func(q QueryAdapter) QueryAdapter { return q.Where(cond, args...) })
+}
+
+// Join adds a JOIN clause validated by ValidateJoinClause.
+func (f *Fragment) Join(joinClause string, args ...any) *Fragment {
+	if err := ValidateJoinClause(joinClause); err != nil {
+		return f.reject("JOIN", joinClause, err)
+	}
+	return f.with(func(q QueryAdapter) QueryAdapter { return q.Join(joinClause, args...) })
+}
+
+// Order adds an ORDER BY clause validated by ValidateOrderBy.
+func (f *Fragment) Order(order string) *Fragment {
+	if err := ValidateOrderBy(order); err != nil {
+		return f.reject("ORDER BY", order, err)
+	}
+	return f.with(func(q QueryAdapter) QueryAdapter { return q.Order(order) })
+}
+
+// Include appends the clauses of other fragments.
+func (f *Fragment) Include(others ...*Fragment) *Fragment {
+	cp := f
+	for _, o := range others {
+		if o == nil {
+			continue
+		}
+		cp = cp.with(o.steps...)
+		if cp.err == nil && o.err != nil {
+			cp.err = o.err
+		}
+	}
+	return cp
+}
+
+// Apply adds the fragment's clauses to q.
+func (f *Fragment) Apply(q QueryAdapter) QueryAdapter {
+	return applyScopes(q, f.steps...)
+}
+
+// Scope returns the fragment as a ScopeFunc for QueryAdapter.Scopes.
+func (f *Fragment) Scope() ScopeFunc {
+	return f.Apply
+}
+
+func (f *Fragment) with(steps ...ScopeFunc) *Fragment {
+	cp := *f
+	cp.steps = append(append([]ScopeFunc(nil), f.steps...), steps...)
+	return &cp
+}
+
+func (f *Fragment) reject(kind, clause string, err error) *Fragment {
+	log.Printf("WARNING: fragment %q: invalid %s clause %q: %v", f.name, kind, clause, err)
+
+	cp := *f
+	if cp.err == nil {
+		cp.err = fmt.Errorf("orm: fragment %q: %w", f.name, err)
+	}
+	return &cp
+}
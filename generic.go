@@ -0,0 +1,115 @@
+package orm
+
+import (
+	"reflect"
+)
+
+// Find scans every row matched by q into a []T. When q has no model yet,
+// T itself is used as the model, so callers get a typed result without
+// passing an untyped destination.
+func Find[T Tabler](q QueryAdapter) ([]T, error) {
+	var out []T
+	if err := useModelOf[T](q).Scan(&out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// First returns the first row matched by q as a T, or ErrNotFound.
+func First[T Tabler](q QueryAdapter) (T, error) {
+	var zero T
+
+	out := newModel[T]()
+	if reflect.TypeOf(out).Kind() == reflect.Ptr {
+		if err := useModelOf[T](q).First(out); err != nil {
+			return zero, err
+		}
+		return out, nil
+	}
+
+	if err := useModelOf[T](q).First(&out); err != nil {
+		return zero, err
+	}
+	return out, nil
+}
+
+// Pluck selects a single column from T's table and scans it into a []V.
+// The column is sanitized the same way Select fields are.
+func Pluck[T Tabler, V any](q QueryAdapter, column string) ([]V, error) {
+	fields, err := SanitizeSelectFields([]string{column})
+	if err != nil {
+		return nil, err
+	}
+
+	var out []V
+	if err := useModelOf[T](q).UnsafeSelect(fields).Scan(&out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// useModelOf binds T as the model of q unless a model is already set.
+func useModelOf[T Tabler](q QueryAdapter) QueryAdapter {
+	if q.Model() != nil {
+		return q
+	}
+	return q.UseModel(newModel[T]())
+}
+
+// newModel returns a usable T: a freshly allocated struct when T is a
+// pointer type, the zero value otherwise.
+func newModel[T Tabler]() T {
+	var zero T
+	t := reflect.TypeOf(&zero).Elem()
+	if t.Kind() == reflect.Ptr {
+		return reflect.New(t.Elem()).Interface().(T)
+	}
+	return zero
+}
+
+// ExistsByIDs reports which of ids exist in model's table using a single
+// IN query on the primary key, instead of one First call per id.
+func ExistsByIDs[K comparable](q QueryAdapter, model Tabler, ids []K) (map[K]bool, error) {
+	result := make(map[K]bool, len(ids))
+	keys := make([]K, 0, len(ids))
+	for _, id := range ids {
+		if _, seen := result[id]; !seen {
+			result[id] = false
+			keys = append(keys, id)
+		}
+	}
+
+	if len(keys) == 0 {
+		return result, nil
+	}
+
+	pk := primaryKeyColumn(model)
+	if err := ValidateColumnName(pk); err != nil {
+		return nil, err
+	}
+
+	var found []K
+	err := q.UseModel(model).
+		UnsafeSelect([]string{pk}).
+		Where(pk+" IN ?", keys).
+		Scan(&found)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, id := range found {
+		result[id] = true
+	}
+	return result, nil
+}
+
+// FindAs scans the rows matched by q into a []D, where D is a lightweight
+// view of q's model (set with UseModel). Only D's columns are selected
+// unless q already has a Select.
+func FindAs[D any](q QueryAdapter) ([]D, error) {
+	var out []D
+	if err := q.Scan(&out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
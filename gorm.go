@@ -4,48 +4,108 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"fmt"
+	"reflect"
+	"slices"
 	"strings"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type GormAdapter struct {
 	db    *gorm.DB
 	model Tabler
+
+	recorder  *StatementRecorder
+	unscoped  bool
+	scoped    bool // default scopes already applied, see withDefaultScopes
+	planCache PlanCacheMode
+
+	defaultScopes []ScopeFunc // applied to every statement, see WithDefaultScope
+	rowFuncs      []RowFunc   // run over scanned rows, see OnRow
+	tablePrefix   string      // put in front of model tables, see WithTablePrefix
+	preloads      []preload
+
+	strict  bool  // see StrictValidation
+	err     error // first clause rejected by a strict chain
+	debug   bool  // see Debug
+	trusted bool  // conditions skip column policies, while default scopes apply
+	filters []policyFilter
 }
 
 func NewGormAdapter(db *gorm.DB) QueryAdapter {
 	return &GormAdapter{db: db}
 }
 
+// with returns a copy of g bound to db, carrying the adapter settings along.
+func (g *GormAdapter) with(db *gorm.DB) *GormAdapter {
+	cp := *g
+	cp.db = db
+	return &cp
+}
+
+// WithContext binds ctx to the query. When ctx carries a transaction (see
+// TxToContext) begun on the gorm DB's own *sql.DB, the query runs in it.
 func (g *GormAdapter) WithContext(ctx context.Context) QueryAdapter {
-	return &GormAdapter{db: g.db.WithContext(ctx), model: g.model}
+	db := g.db.WithContext(ctx)
+	if tx, ok := TxFromContext(ctx); ok {
+		if sqlDB, err := g.db.DB(); err == nil && sqlDB == tx.db {
+			db.Statement.ConnPool = tx.tx
+		}
+	}
+	return g.with(db)
 }
 
 func (g *GormAdapter) UseModel(m Tabler) QueryAdapter {
-	return &GormAdapter{db: g.db.Model(m), model: m}
+	m = addressableModel(m)
+	cp := g.with(g.db.Model(m))
+	if g.tablePrefix != "" {
+		cp.db = cp.db.Table(g.tablePrefix + m.TableName())
+	}
+	cp.model = m
+	rememberModel(m)
+	return cp
+}
+
+// addressableModel turns a value model (User{}) into a pointer to a copy
+// (&User{}) so gorm can parse and write to it.
+func addressableModel(m Tabler) Tabler {
+	val := reflect.ValueOf(m)
+	if !val.IsValid() || val.Kind() != reflect.Struct {
+		return m
+	}
+
+	ptr := reflect.New(val.Type())
+	ptr.Elem().Set(val)
+	if t, ok := ptr.Interface().(Tabler); ok {
+		return t
+	}
+	return m
 }
 
 func (g *GormAdapter) Model() Tabler {
 	return g.model
 }
 
+// Where adds an AND condition, checking its columns against their models'
+// column policies like SqlQueryAdapter.Where; map conditions are checked by
+// their keys.
 func (g *GormAdapter) Where(query any, args ...any) QueryAdapter {
+	return g.filter(fmt.Sprintf("WHERE condition %q", toString(query)), query).trustedWhere(query, args...)
+}
+
+func (g *GormAdapter) trustedWhere(query any, args ...any) QueryAdapter {
 	if other, ok := query.(*GormAdapter); ok {
-		return &GormAdapter{
-			db:    g.db.Where(other.db),
-			model: g.model,
-		}
+		return g.with(g.db.Where(other.db))
 	}
 
-	return &GormAdapter{
-		db:    g.db.Where(query, args...),
-		model: g.model,
-	}
+	return g.with(g.db.Where(query, args...))
 }
 
 func (g *GormAdapter) Or(query any, args ...any) QueryAdapter {
-	return &GormAdapter{db: g.db.Or(query, args...), model: g.model}
+	cp := g.filter(fmt.Sprintf("OR condition %q", toString(query)), query)
+	return cp.with(cp.db.Or(query, args...))
 }
 
 func (g *GormAdapter) Select(fields []string) QueryAdapter {
@@ -53,9 +113,9 @@ func (g *GormAdapter) Select(fields []string) QueryAdapter {
 	sanitized, err := SanitizeSelectFields(fields)
 	if err != nil {
 		// Return adapter unchanged if sanitization fails
-		return g
+		return g.reject(fmt.Sprintf("SELECT fields %q", fields), err)
 	}
-	return &GormAdapter{db: g.db.Select(sanitized), model: g.model}
+	return g.with(g.db.Select(sanitized))
 }
 
 func (g *GormAdapter) GroupBy(fields []string) QueryAdapter {
@@ -63,91 +123,310 @@ func (g *GormAdapter) GroupBy(fields []string) QueryAdapter {
 	sanitized, err := SanitizeColumnNames(fields)
 	if err != nil {
 		// Return adapter unchanged if sanitization fails
-		return g
+		return g.reject(fmt.Sprintf("GROUP BY fields %q", fields), err)
 	}
-	return &GormAdapter{db: g.db.Group(strings.Join(sanitized, ",")), model: g.model}
+	return g.with(g.db.Group(strings.Join(sanitized, ",")))
 }
 
 func (g *GormAdapter) Having(fields []string, args ...any) QueryAdapter {
 	// Automatically validate having clauses for safety
 	if err := ValidateHavingClause(fields); err != nil {
 		// Return adapter unchanged if validation fails
-		return g
+		return g.reject(fmt.Sprintf("HAVING fields %q", fields), err)
+	}
+	for _, cond := range fields {
+		g = g.filter(fmt.Sprintf("HAVING condition %q", cond), cond)
 	}
-	return &GormAdapter{db: g.db.Having(strings.Join(fields, ","), args...), model: g.model}
+	return g.with(g.db.Having(strings.Join(fields, ","), args...))
 }
 
 func (g *GormAdapter) Limit(limit int) QueryAdapter {
-	return &GormAdapter{db: g.db.Limit(limit), model: g.model}
+	return g.with(g.db.Limit(limit))
 }
 
 func (g *GormAdapter) Offset(offset int) QueryAdapter {
-	return &GormAdapter{db: g.db.Offset(offset), model: g.model}
+	return g.with(g.db.Offset(offset))
 }
 
 func (g *GormAdapter) Order(order string) QueryAdapter {
 	// Automatically validate order clause for safety
 	if err := ValidateOrderBy(order); err != nil {
 		// Return adapter unchanged if validation fails
-		return g
+		return g.reject(fmt.Sprintf("ORDER BY clause %q", order), err)
 	}
-	return &GormAdapter{db: g.db.Order(order), model: g.model}
+	return g.with(g.db.Order(order))
 }
 
 func (g *GormAdapter) Clone() QueryAdapter {
-	return &GormAdapter{db: g.db.Session(&gorm.Session{NewDB: true}), model: g.model}
+	return g.with(g.db.Session(&gorm.Session{NewDB: true}))
 }
 
 func (g *GormAdapter) Join(joinClause string, args ...any) QueryAdapter {
 	// Automatically validate join clause for safety
 	if err := ValidateJoinClause(joinClause); err != nil {
 		// Return adapter unchanged if validation fails
-		return g
+		return g.reject(fmt.Sprintf("JOIN clause %q", joinClause), err)
 	}
-	return &GormAdapter{db: g.db.Joins(joinClause, args...), model: g.model}
+	return g.with(g.db.Joins(joinClause, args...))
 }
 
 func (g *GormAdapter) Scopes(fs ...ScopeFunc) QueryAdapter {
-	db := g.db
+	cur := g
 
 	for _, f := range fs {
-		tmpAdp := &GormAdapter{db: db, model: g.model}
+		tmpAdp := cur.with(cur.db)
 
 		res := f(tmpAdp)
 
 		// only for gorm adapter
 		if ga, ok := res.(*GormAdapter); ok {
-			db = ga.db
+			cur = ga
 		}
 	}
 
-	return &GormAdapter{db: db, model: g.model}
+	return cur.with(cur.db)
 }
 
+// Count counts the rows the chain matches, ignoring Limit and Offset like
+// SqlQueryAdapter.Count, so the query of a page also counts its total.
 func (g *GormAdapter) Count(target *int64) error {
-	return g.db.Session(&gorm.Session{}).Count(target).Error
+	return g.run(func(db *gorm.DB) *gorm.DB {
+		return db.Session(&gorm.Session{}).Limit(-1).Offset(-1).Count(target)
+	})
 }
 
+// Scan fills a slice (left empty when nothing matches) or a single struct,
+// which reports ErrNotFound when no row matched.
 func (g *GormAdapter) Scan(dest any) error {
-	if debug {
-		return g.db.Debug().Find(dest).Error
+	g, err := g.withTable(dest).narrowTo(dest)
+	if err != nil {
+		return err
 	}
 
-	return g.db.Find(dest).Error
+	var affected int64
+	err = g.run(func(db *gorm.DB) *gorm.DB {
+		tx := db.Find(dest)
+		affected = tx.RowsAffected
+		return tx
+	})
+
+	if err != nil || g.db.DryRun {
+		return err
+	}
+	if affected == 0 && isStructDest(dest) {
+		return errRecordNotFound
+	}
+	if err := filterRows(g.rowFuncs, dest); err != nil {
+		return err
+	}
+	if err := preloadRelations(g, dest, g.preloads); err != nil {
+		return err
+	}
+	return afterFind(g.db.Statement.Context, dest)
 }
 
 func (g *GormAdapter) First(dest any) (err error) {
-	if debug {
-		err = g.db.Debug().First(dest).Error
-	} else {
-		err = g.db.First(dest).Error
+	if g, err = g.withTable(dest).narrowTo(dest); err != nil {
+		return err
 	}
 
+	err = g.run(func(db *gorm.DB) *gorm.DB {
+		return db.First(dest)
+	})
+
 	if errors.Is(err, gorm.ErrRecordNotFound) {
-		return ErrNotFound
+		return errRecordNotFound
+	}
+	if err != nil || g.db.DryRun {
+		return err
+	}
+	if err := filterRows(g.rowFuncs, dest); err != nil {
+		return err
+	}
+	if err := preloadRelations(g, dest, g.preloads); err != nil {
+		return err
+	}
+	return afterFind(g.db.Statement.Context, dest)
+}
+
+// run executes a finisher through the interceptor chain. The statement is
+// only known once gorm has built it, so it is filled in afterwards.
+func (g *GormAdapter) run(fn func(db *gorm.DB) *gorm.DB) error {
+	if err := g.failure(); err != nil {
+		return err
+	}
+	c := &queryCall{ctx: g.db.Statement.Context, flavor: g.Driver(), dryRun: g.db.DryRun, debug: g.debug}
+	return runQuery(c, func(string) error {
+		exec := func(db *gorm.DB) error {
+			tx := g.record(fn(db))
+			c.query, c.args = tx.Statement.SQL.String(), tx.Statement.Vars
+			return tx.Error
+		}
+
+		db := g.withDefaultScopes().db
+		if tags := statementTagComment(c.ctx); tags != "" && !c.dryRun {
+			db = db.Clauses(commentClause(tags))
+		}
+		if g.planCache != "" && c.flavor == FlavorPostgres && !c.dryRun {
+			return g.planCacheTx(db, exec)
+		}
+		return exec(db)
+	})
+}
+
+// ToSQL returns the SELECT statement gorm would build for a Find on the
+// current model, without executing it.
+func (g *GormAdapter) ToSQL() (string, []any) {
+	stmt := g.withDefaultScopes().db.Session(&gorm.Session{DryRun: true}).Find(g.dryRunDest()).Statement
+	return stmt.SQL.String(), stmt.Vars
+}
+
+// DryRun returns an adapter whose finishers build statements and record
+// them in rec (which may be nil) instead of executing them.
+func (g *GormAdapter) DryRun(rec *StatementRecorder) QueryAdapter {
+	cp := g.with(g.db.Session(&gorm.Session{DryRun: true}))
+	cp.recorder = rec
+	return cp
+}
+
+// Explain returns the database's query plan for the built statement.
+// With analyze the statement is actually executed to collect timings.
+func (g *GormAdapter) Explain(analyze bool) (string, error) {
+	if err := g.failure(); err != nil {
+		return "", err
 	}
+	sqlStr, args := g.ToSQL()
+	return explainQuery(g.db.Statement.Context, g.DB(), g.Driver(), sqlStr, args, analyze)
+}
 
-	return err
+// PrepareStmt enables gorm's prepared statement cache for this chain.
+func (g *GormAdapter) PrepareStmt() QueryAdapter {
+	return g.with(g.db.Session(&gorm.Session{PrepareStmt: true}))
+}
+
+// Comment adds a human-readable /* text */ to the statement, placed after
+// the SELECT list. The text is sanitized; repeated calls are joined by "; ".
+func (g *GormAdapter) Comment(text string) QueryAdapter {
+	if sanitizeComment(text) == "" {
+		return g
+	}
+	return g.with(g.db.Clauses(commentClause(text)))
+}
+
+// commentClause rides on the SELECT clause as its AfterExpression, which
+// gorm keeps when it later fills in the select list.
+type commentClause string
+
+func (c commentClause) ModifyStatement(stmt *gorm.Statement) {
+	sel := stmt.Clauses["SELECT"]
+	prev, _ := sel.AfterExpression.(commentClause)
+	sel.AfterExpression = commentClause(joinComment(string(prev), string(c)))
+	stmt.Clauses["SELECT"] = sel
+}
+
+func (c commentClause) Build(builder clause.Builder) {
+	builder.WriteString("/* " + string(c) + " */")
+}
+
+// Unscoped disables the model's DefaultScope and soft-delete filter (and
+// gorm's own) for this chain. Scopes added with WithDefaultScope, such as
+// TenantScope, still apply.
+func (g *GormAdapter) Unscoped() QueryAdapter {
+	cp := g.with(g.db.Session(&gorm.Session{}).Unscoped())
+	cp.unscoped = true
+	return cp
+}
+
+// WithDefaultScope adds scopes applied to every statement run from this
+// adapter and the chains derived from it, after the model's DefaultScope.
+// Unscoped doesn't skip them: they belong to the adapter, not the model.
+func (g *GormAdapter) WithDefaultScope(fs ...ScopeFunc) QueryAdapter {
+	cp := g.with(g.db)
+	cp.defaultScopes = append(slices.Clip(g.defaultScopes), fs...)
+	return cp
+}
+
+// withDefaultScopes applies the model's DefaultScope and the adapter's
+// default scopes once, on a copy. Unscoped skips only the model's, and
+// the soft-delete filter.
+func (g *GormAdapter) withDefaultScopes() *GormAdapter {
+	if g.scoped {
+		return g
+	}
+
+	// a fresh session keeps the scope's conditions out of g's statement
+	cp := g.with(groupConditions(g.db.Session(&gorm.Session{})))
+	cp.scoped, cp.trusted = true, true
+	if s, ok := g.model.(DefaultScoper); ok && !g.unscoped {
+		if scoped, ok := s.DefaultScope()(cp).(*GormAdapter); ok {
+			cp = scoped
+		}
+	}
+	for _, f := range g.defaultScopes {
+		if f == nil {
+			continue
+		}
+		if scoped, ok := f(cp).(*GormAdapter); ok {
+			cp = scoped
+		}
+	}
+	// gorm filters its own DeletedAt type; other deleted_at columns here
+	if sd, ok := softDeleteOf(g.model); ok && !sd.gormManaged && !g.unscoped {
+		cp = cp.with(cp.db.Where(clause.Eq{
+			Column: clause.Column{Table: clause.CurrentTable, Name: sd.column},
+			Value:  nil,
+		}))
+	}
+	cp.trusted = g.trusted
+	return cp
+}
+
+// groupConditions wraps the WHERE conditions of db in parentheses when they
+// contain an OR, so that conditions added by default scopes restrict all of
+// them rather than only the last AND term.
+func groupConditions(db *gorm.DB) *gorm.DB {
+	c, ok := db.Statement.Clauses["WHERE"]
+	if !ok {
+		return db
+	}
+	where, ok := c.Expression.(clause.Where)
+	if !ok || !slices.ContainsFunc(where.Exprs, func(e clause.Expression) bool {
+		_, or := e.(clause.OrConditions)
+		return or
+	}) {
+		return db
+	}
+
+	db = db.Clauses() // a statement of its own, safe to change
+	c.Expression = clause.Where{Exprs: []clause.Expression{clause.AndConditions{Exprs: where.Exprs}}}
+	db.Statement.Clauses["WHERE"] = c
+	return db
+}
+
+// grouped returns a copy of g whose conditions, ORs included, form one
+// condition; see SqlQueryAdapter.grouped.
+func (g *GormAdapter) grouped() QueryAdapter {
+	return g.with(groupConditions(g.db.Session(&gorm.Session{})))
+}
+
+// record stores the statement of a finished dry-run call.
+func (g *GormAdapter) record(tx *gorm.DB) *gorm.DB {
+	if g.recorder != nil && tx.DryRun {
+		g.recorder.record(tx.Statement.SQL.String(), tx.Statement.Vars)
+	}
+	return tx
+}
+
+func (g *GormAdapter) dryRunDest() any {
+	if g.model == nil {
+		return &[]map[string]any{}
+	}
+
+	t := reflect.TypeOf(g.model)
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return reflect.New(reflect.SliceOf(t)).Interface()
 }
 
 func (g *GormAdapter) Driver() driverFlavor {
@@ -155,17 +434,37 @@ func (g *GormAdapter) Driver() driverFlavor {
 	return detectFlavor(sqlDB)
 }
 
+// DB returns the *sql.DB under gorm's connection pool, or nil when the pool
+// isn't one (inside a gorm transaction, for example).
 func (g *GormAdapter) DB() *sql.DB {
 	sqlDB, _ := g.db.DB()
 	return sqlDB
 }
 
+// Stats returns the pool statistics of DB, or zero when it is nil.
+func (g *GormAdapter) Stats() sql.DBStats {
+	if sqlDB := g.DB(); sqlDB != nil {
+		return sqlDB.Stats()
+	}
+	return sql.DBStats{}
+}
+
+// GormDB returns the *gorm.DB the adapter wraps, carrying the model and
+// conditions of the chain so far. Start from
+// GormDB().Session(&gorm.Session{NewDB: true}) for a statement without them.
+func (g *GormAdapter) GormDB() *gorm.DB {
+	return g.db
+}
+
 // Enhanced security methods implementation
 func (g *GormAdapter) SafeOrder(order string) QueryAdapter {
 	// Validate the order clause first
 	if err := ValidateOrderBy(order); err != nil {
 		// Return adapter unchanged on validation error
-		return g
+		return g.reject(fmt.Sprintf("ORDER BY clause %q", order), err)
+	}
+	if err := checkOrderPolicy(g.columnScope(), order); err != nil {
+		return g.reject(fmt.Sprintf("ORDER BY clause %q", order), err)
 	}
 	return g.Order(order)
 }
@@ -174,7 +473,7 @@ func (g *GormAdapter) SafeJoin(joinClause string, args ...any) QueryAdapter {
 	// Validate the join clause first
 	if err := ValidateJoinClause(joinClause); err != nil {
 		// Return adapter unchanged on validation error
-		return g
+		return g.reject(fmt.Sprintf("JOIN clause %q", joinClause), err)
 	}
 	return g.Join(joinClause, args...)
 }
@@ -184,7 +483,10 @@ func (g *GormAdapter) SafeSelect(selections []string) QueryAdapter {
 	sanitized, err := SanitizeSelectFields(selections)
 	if err != nil {
 		// Return adapter unchanged on error
-		return g
+		return g.reject(fmt.Sprintf("SELECT fields %q", selections), err)
+	}
+	if err := checkSelectPolicy(g.columnScope(), sanitized); err != nil {
+		return g.reject(fmt.Sprintf("SELECT fields %q", selections), err)
 	}
 	return g.Select(sanitized)
 }
@@ -194,7 +496,7 @@ func (g *GormAdapter) SafeGroupBy(groupbys []string) QueryAdapter {
 	sanitized, err := SanitizeColumnNames(groupbys)
 	if err != nil {
 		// Return adapter unchanged on error
-		return g
+		return g.reject(fmt.Sprintf("GROUP BY fields %q", groupbys), err)
 	}
 	return g.GroupBy(sanitized)
 }
@@ -203,28 +505,28 @@ func (g *GormAdapter) SafeHaving(havings []string, args ...any) QueryAdapter {
 	// Validate the having clauses
 	if err := ValidateHavingClause(havings); err != nil {
 		// Return adapter unchanged on error
-		return g
+		return g.reject(fmt.Sprintf("HAVING fields %q", havings), err)
 	}
 	return g.Having(havings, args...)
 }
 
 // Unsafe methods for advanced users who want to bypass validation
 func (g *GormAdapter) UnsafeOrder(order string) QueryAdapter {
-	return &GormAdapter{db: g.db.Order(order), model: g.model}
+	return g.with(g.db.Order(order))
 }
 
 func (g *GormAdapter) UnsafeJoin(joinClause string, args ...any) QueryAdapter {
-	return &GormAdapter{db: g.db.Joins(joinClause, args...), model: g.model}
+	return g.with(g.db.Joins(joinClause, args...))
 }
 
 func (g *GormAdapter) UnsafeSelect(selections []string) QueryAdapter {
-	return &GormAdapter{db: g.db.Select(selections), model: g.model}
+	return g.with(g.db.Select(selections))
 }
 
 func (g *GormAdapter) UnsafeGroupBy(groupbys []string) QueryAdapter {
-	return &GormAdapter{db: g.db.Group(strings.Join(groupbys, ",")), model: g.model}
+	return g.with(g.db.Group(strings.Join(groupbys, ",")))
 }
 
 func (g *GormAdapter) UnsafeHaving(havings []string, args ...any) QueryAdapter {
-	return &GormAdapter{db: g.db.Having(strings.Join(havings, ","), args...), model: g.model}
+	return g.with(g.db.Having(strings.Join(havings, ","), args...))
 }
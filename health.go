@@ -0,0 +1,225 @@
+package orm
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"sync"
+	"time"
+)
+
+// HealthState is the last known reachability of a database.
+type HealthState int
+
+const (
+	HealthUnknown HealthState = iota
+	HealthUp
+	HealthDown
+)
+
+const (
+	defaultProbeInterval = 30 * time.Second
+	defaultProbeTimeout  = 5 * time.Second
+)
+
+// HealthProber pings a database in the background and keeps MinIdle
+// connections open, so the first request after a quiet period doesn't pay
+// for reconnecting. The pool only retains them if db.SetMaxIdleConns is at
+// least MinIdle, and never beyond db.SetMaxOpenConns. A probe that times
+// out waiting on a busy pool doesn't count as a failure.
+type HealthProber struct {
+	Interval time.Duration // between probes; 30s when zero
+	Timeout  time.Duration // per probe; 5s when zero
+	MinIdle  int           // connections to keep warm; 0 only pings
+
+	// OnStateChange is called after every transition, e.g. to feed metrics.
+	// Transitions are logged either way.
+	OnStateChange func(from, to HealthState, err error)
+
+	db *sql.DB
+
+	mu      sync.Mutex
+	state   HealthState
+	lastErr error
+	cancel  context.CancelFunc
+	done    chan struct{}
+}
+
+// NewHealthProber returns a stopped prober for db.
+func NewHealthProber(db *sql.DB, interval time.Duration) *HealthProber {
+	return &HealthProber{db: db, Interval: interval}
+}
+
+func (s HealthState) String() string {
+	switch s {
+	case HealthUp:
+		return "up"
+	case HealthDown:
+		return "down"
+	default:
+		return "unknown"
+	}
+}
+
+// Start probes once immediately and then every Interval until Stop is called
+// or ctx is done. Starting a running prober is a no-op.
+func (p *HealthProber) Start(ctx context.Context) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.cancel != nil {
+		return
+	}
+
+	ctx, p.cancel = context.WithCancel(ctx)
+	p.done = make(chan struct{})
+	go p.loop(ctx, p.done)
+}
+
+// Stop ends the background loop and waits for an in-flight probe to finish.
+func (p *HealthProber) Stop() {
+	p.mu.Lock()
+	cancel, done := p.cancel, p.done
+	p.cancel, p.done = nil, nil
+	p.mu.Unlock()
+
+	if cancel != nil {
+		cancel()
+		<-done
+	}
+}
+
+// State returns the result of the latest probe and its error, if any.
+func (p *HealthProber) State() (HealthState, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.state, p.lastErr
+}
+
+func (p *HealthProber) loop(ctx context.Context, done chan struct{}) {
+	defer close(done)
+
+	interval := p.Interval
+	if interval <= 0 {
+		interval = defaultProbeInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		p.Probe(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Probe pings the database, warms MinIdle connections and records the
+// resulting state.
+func (p *HealthProber) Probe(ctx context.Context) error {
+	if p.db == nil {
+		return ErrNilPointer
+	}
+
+	timeout := p.Timeout
+	if timeout <= 0 {
+		timeout = defaultProbeTimeout
+	}
+	probeCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	before := p.db.Stats()
+	err := p.db.PingContext(probeCtx)
+	if err == nil {
+		err = p.warm(probeCtx)
+	}
+
+	if err != nil && ctx.Err() != nil {
+		// the caller is shutting down; not a health signal
+		return err
+	}
+	if p.waitedForPool(before, err) {
+		// every connection is busy serving requests; not a health signal
+		return nil
+	}
+
+	p.record(err)
+	return err
+}
+
+// warm opens connections one at a time until MinIdle are idle. Idle
+// connections are handed out first, so those checked out are held until
+// enough new ones are open. It stops rather than wait on the pool: when
+// MaxOpenConns is reached, or when a checkout had to wait because requests
+// hold every connection.
+func (p *HealthProber) warm(ctx context.Context) error {
+	if p.MinIdle <= 0 {
+		return nil
+	}
+
+	var conns []*sql.Conn
+	defer func() {
+		for _, c := range conns {
+			c.Close()
+		}
+	}()
+
+	for {
+		stats := p.db.Stats()
+		if len(conns)+stats.Idle >= p.MinIdle {
+			return nil
+		}
+		if stats.Idle == 0 && stats.MaxOpenConnections > 0 && stats.OpenConnections >= stats.MaxOpenConnections {
+			return nil // only a connection a request returns could be had
+		}
+
+		c, err := p.db.Conn(ctx)
+		if err != nil {
+			if p.waitedForPool(stats, err) {
+				return nil
+			}
+			return err
+		}
+		conns = append(conns, c)
+
+		if err := c.PingContext(ctx); err != nil {
+			return err
+		}
+	}
+}
+
+// waitedForPool reports whether err is a timeout spent waiting for a free
+// connection since before was taken, rather than one reaching the database.
+func (p *HealthProber) waitedForPool(before sql.DBStats, err error) bool {
+	return errors.Is(err, context.DeadlineExceeded) && p.db.Stats().WaitCount > before.WaitCount
+}
+
+func (p *HealthProber) record(err error) {
+	to := HealthUp
+	if err != nil {
+		to = HealthDown
+	}
+
+	p.mu.Lock()
+	from := p.state
+	p.state, p.lastErr = to, err
+	p.mu.Unlock()
+
+	if from == to {
+		return
+	}
+
+	if err != nil {
+		log.Printf("WARNING: database health %s -> %s: %v", from, to, err)
+	} else {
+		log.Printf("database health %s -> %s", from, to)
+	}
+
+	if p.OnStateChange != nil {
+		p.OnStateChange(from, to, err)
+	}
+}
@@ -0,0 +1,45 @@
+package orm
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestHealthProberBusyPool(t *testing.T) {
+	db, _, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(2)
+	db.SetMaxIdleConns(4)
+
+	ctx := context.Background()
+	busy1, _ := db.Conn(ctx)
+	busy2, _ := db.Conn(ctx)
+
+	p := NewHealthProber(db, time.Minute)
+	p.Timeout = 20 * time.Millisecond
+	p.MinIdle = 4
+	if err := p.Probe(ctx); err != nil {
+		t.Fatalf("probe on a busy pool = %v, want nil", err)
+	}
+	if state, _ := p.State(); state != HealthUnknown {
+		t.Errorf("state after a busy-pool probe = %s, want unknown", state)
+	}
+
+	busy1.Close()
+	busy2.Close()
+	if err := p.Probe(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if state, _ := p.State(); state != HealthUp {
+		t.Errorf("state = %s, want up", state)
+	}
+	if stats := db.Stats(); stats.OpenConnections > 2 || stats.Idle != 2 {
+		t.Errorf("open %d, idle %d; want 2 idle within MaxOpenConns", stats.OpenConnections, stats.Idle)
+	}
+}
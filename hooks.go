@@ -0,0 +1,120 @@
+package orm
+
+import (
+	"context"
+	"reflect"
+)
+
+type (
+	// BeforeCreator is implemented by models that default or validate their
+	// fields before SqlTransactionAdapter.Create or BulkInsert writes them.
+	// An error aborts the insert.
+	BeforeCreator interface {
+		BeforeCreate(ctx context.Context) error
+	}
+
+	// AfterCreator is implemented by models that act once their row was
+	// inserted; the primary key is set by then. An error is returned from
+	// Create, and the caller's transaction should be rolled back.
+	AfterCreator interface {
+		AfterCreate(ctx context.Context) error
+	}
+
+	// BeforeUpdater is implemented by models that validate themselves before
+	// SqlTransactionAdapter.Update or Patch. Changes it makes to the model
+	// are written by Update; Patch only writes its field map.
+	BeforeUpdater interface {
+		BeforeUpdate(ctx context.Context) error
+	}
+
+	// AfterUpdater is implemented by models that act once their row was
+	// updated by Update or Patch.
+	AfterUpdater interface {
+		AfterUpdate(ctx context.Context) error
+	}
+
+	// AfterFinder is implemented by models that derive fields once loaded by
+	// Scan or First, for each row of a slice destination.
+	AfterFinder interface {
+		AfterFind(ctx context.Context) error
+	}
+)
+
+var afterFinderT = reflect.TypeOf((*AfterFinder)(nil)).Elem()
+
+// beforeCreate runs the BeforeCreate hook of each model implementing it.
+func beforeCreate(ctx context.Context, models ...Tabler) error {
+	for _, m := range models {
+		if h, ok := m.(BeforeCreator); ok {
+			if err := h.BeforeCreate(ctx); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// afterCreate runs the AfterCreate hook of each model implementing it.
+func afterCreate(ctx context.Context, models ...Tabler) error {
+	for _, m := range models {
+		if h, ok := m.(AfterCreator); ok {
+			if err := h.AfterCreate(ctx); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func beforeUpdate(ctx context.Context, m Tabler) error {
+	if h, ok := m.(BeforeUpdater); ok {
+		return h.BeforeUpdate(ctx)
+	}
+	return nil
+}
+
+func afterUpdate(ctx context.Context, m Tabler) error {
+	if h, ok := m.(AfterUpdater); ok {
+		return h.AfterUpdate(ctx)
+	}
+	return nil
+}
+
+// afterFind runs the AfterFind hook on dest, a pointer to a model or to a
+// slice of models or model pointers, stopping at the first error.
+func afterFind(ctx context.Context, dest any) error {
+	val := reflect.ValueOf(dest)
+	if val.Kind() != reflect.Ptr || val.IsNil() {
+		return nil
+	}
+	if h, ok := dest.(AfterFinder); ok {
+		return h.AfterFind(ctx)
+	}
+
+	slice := val.Elem()
+	if slice.Kind() != reflect.Slice {
+		return nil
+	}
+
+	elemT := slice.Type().Elem()
+	byPtr := elemT.Kind() == reflect.Ptr
+	if !byPtr && !reflect.PointerTo(elemT).Implements(afterFinderT) ||
+		byPtr && !elemT.Implements(afterFinderT) {
+		return nil
+	}
+
+	for i := 0; i < slice.Len(); i++ {
+		elem := slice.Index(i)
+		if byPtr {
+			if elem.IsNil() {
+				continue
+			}
+		} else {
+			elem = elem.Addr()
+		}
+		if err := elem.Interface().(AfterFinder).AfterFind(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
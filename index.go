@@ -0,0 +1,174 @@
+package orm
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/godev90/validator/faults"
+)
+
+var (
+	errInvalidIndex = fmt.Errorf("orm: invalid index")
+	ErrInvalidIndex = faults.New(errInvalidIndex, &faults.ErrAttr{
+		Code: http.StatusInternalServerError,
+		Messages: []faults.LangPackage{
+			{
+				Tag:     faults.English,
+				Message: "orm: %s: index %s is tagged both index and uniqueIndex",
+			},
+		},
+	})
+)
+
+// tableIndex is an index declared by index / uniqueIndex sql tags.
+type tableIndex struct {
+	name    string
+	unique  bool
+	columns []string
+}
+
+// indexQueries list the index names of the table named by the single
+// argument.
+var indexQueries = map[driverFlavor]string{
+	FlavorPostgres: `SELECT indexname FROM pg_indexes WHERE schemaname = current_schema() AND tablename = ?`,
+	FlavorMySQL:    `SELECT DISTINCT INDEX_NAME FROM information_schema.STATISTICS WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ?`,
+	FlavorOracle:   `SELECT index_name FROM user_indexes WHERE table_name = ?`,
+}
+
+// CreateIndexes creates the indexes the models' sql tags declare and their
+// tables don't have yet:
+//
+//	type User struct {
+//		Email    string `sql:"column:email;uniqueIndex"`                 // idx_users_email
+//		TenantID int64  `sql:"column:tenant_id;index:idx_users_tenant"`  // composite,
+//		Status   string `sql:"column:status;index:idx_users_tenant"`     // in field order
+//	}
+//
+//	err := orm.CreateIndexes(db, &User{})
+//
+// index and uniqueIndex without a name index the field's column alone as
+// idx_<table>_<column>; fields sharing a name form one composite index.
+// Indexes are matched by name only, so an existing index keeps its columns.
+func CreateIndexes(db *sql.DB, models ...Tabler) error {
+	return CreateIndexesContext(context.Background(), db, models...)
+}
+
+// CreateIndexesContext is CreateIndexes with a context for its statements.
+func CreateIndexesContext(ctx context.Context, db *sql.DB, models ...Tabler) error {
+	if db == nil {
+		return ErrNilPointer
+	}
+	flavor := detectFlavor(db)
+
+	for _, model := range models {
+		val, err := modelStruct(model, false)
+		if err != nil {
+			return err
+		}
+		table := model.TableName()
+
+		indexes, err := indexesOf(val.Type(), table)
+		if err != nil {
+			return err
+		}
+		if len(indexes) == 0 {
+			continue
+		}
+		existing, err := indexNames(ctx, db, flavor, table)
+		if err != nil {
+			return err
+		}
+
+		for _, idx := range indexes {
+			if existing[strings.ToLower(idx.name)] {
+				continue
+			}
+			if err := execAdmin(ctx, db, flavor, createIndexSQL(flavor, table, idx)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// indexesOf returns the indexes declared on the fields of the model type t
+// for table, in the order their first field appears.
+func indexesOf(t reflect.Type, table string) ([]tableIndex, error) {
+	var indexes []tableIndex
+	byName := map[string]int{}
+
+	for _, f := range modelFields(t) {
+		tag := f.Tag.Get("sql")
+		for _, opt := range []string{"index", "uniqueIndex"} {
+			name := tagOption(tag, opt)
+			if name == "" && !tagFlag(tag, opt) {
+				continue
+			}
+			if name == "" {
+				name = "idx_" + strings.ReplaceAll(table, ".", "_") + "_" + f.column
+			}
+			unique := opt == "uniqueIndex"
+
+			i, ok := byName[name]
+			if !ok {
+				byName[name] = len(indexes)
+				indexes = append(indexes, tableIndex{name: name, unique: unique})
+				i = len(indexes) - 1
+			}
+			if indexes[i].unique != unique {
+				return nil, ErrInvalidIndex.Render(t.Name(), name)
+			}
+			indexes[i].columns = append(indexes[i].columns, f.column)
+		}
+	}
+	return indexes, nil
+}
+
+// indexNames returns the lower-cased names of table's indexes.
+func indexNames(ctx context.Context, db *sql.DB, flavor driverFlavor, table string) (map[string]bool, error) {
+	name := table
+	if i := strings.LastIndex(name, "."); i >= 0 {
+		name = name[i+1:]
+	}
+	if flavor == FlavorOracle {
+		name = strings.ToUpper(name)
+	}
+	query := rebind(flavor, indexQueries[flavor])
+	args := []any{name}
+
+	names := map[string]bool{}
+	err := runQuery(&queryCall{ctx: ctx, query: query, args: args, flavor: flavor}, func(query string) error {
+		rows, err := db.QueryContext(ctx, query, args...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var n string
+			if err := rows.Scan(&n); err != nil {
+				return err
+			}
+			names[strings.ToLower(n)] = true
+		}
+		return rows.Err()
+	})
+	return names, err
+}
+
+// createIndexSQL renders the CREATE INDEX statement of idx on table.
+func createIndexSQL(flavor driverFlavor, table string, idx tableIndex) string {
+	cols := make([]string, len(idx.columns))
+	for i, c := range idx.columns {
+		cols[i] = quoteIdent(flavor, c)
+	}
+	kind := "INDEX"
+	if idx.unique {
+		kind = "UNIQUE INDEX"
+	}
+	return fmt.Sprintf("CREATE %s %s ON %s (%s)", kind, quoteIdent(flavor, idx.name), quoteIdent(flavor, table), strings.Join(cols, ", "))
+}
@@ -0,0 +1,83 @@
+package orm
+
+import (
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// insertDefault is the default: tag of a field, applied on insert when the
+// field holds its zero value.
+type insertDefault struct {
+	field   modelField
+	literal reflect.Value // the tag's literal in the field's type, if it is one
+	expr    string        // the tag's SQL expression otherwise
+}
+
+var insertDefaultsCache sync.Map // reflect.Type -> []insertDefault
+
+var numericLiteral = regexp.MustCompile(`^[-+]?(\d+\.?\d*|\.\d+)([eE][-+]?\d+)?$`)
+
+// insertDefaultsOf returns the fields of t with a default: tag, except
+// primary keys, whose defaults pick a key generator (see tagIDGenerator).
+func insertDefaultsOf(t reflect.Type) []insertDefault {
+	if cached, ok := insertDefaultsCache.Load(t); ok {
+		return cached.([]insertDefault)
+	}
+
+	var defs []insertDefault
+	for _, f := range modelFields(t) {
+		def := strings.TrimSpace(tagOption(f.Tag.Get("sql"), "default"))
+		if f.pk || def == "" || slices.Contains(keyDefaults, def) || strings.EqualFold(def, "null") {
+			continue
+		}
+		d := insertDefault{field: f, expr: def}
+		if raw, ok := defaultLiteral(def); ok {
+			v := reflect.New(f.Type).Elem()
+			if convertAssign(v, raw, assignOpts{column: f.column, keepEmpty: true, timeFormat: f.timeFormat}) == nil {
+				d.literal = v
+			}
+		}
+		defs = append(defs, d)
+	}
+
+	insertDefaultsCache.Store(t, defs)
+	return defs
+}
+
+// defaultLiteral returns the value of a quoted string, number or boolean
+// default; false for any other SQL expression.
+func defaultLiteral(def string) (string, bool) {
+	if len(def) >= 2 && def[0] == '\'' && def[len(def)-1] == '\'' {
+		return strings.ReplaceAll(def[1:len(def)-1], "''", "'"), true
+	}
+	if numericLiteral.MatchString(def) || strings.EqualFold(def, "true") || strings.EqualFold(def, "false") {
+		return def, true
+	}
+	return "", false
+}
+
+// applyInsertDefaults sets the zero fields of the model struct val whose
+// default is a literal, and returns val (a copy when it can't be set) and
+// the SQL expressions to insert in place of the other zero fields, by
+// column.
+func applyInsertDefaults(val reflect.Value) (reflect.Value, map[string]string) {
+	var exprs map[string]string
+	for _, d := range insertDefaultsOf(val.Type()) {
+		if !val.FieldByIndex(d.field.Index).IsZero() {
+			continue
+		}
+		if d.literal.IsValid() {
+			val = settable(val)
+			val.FieldByIndex(d.field.Index).Set(d.literal)
+			continue
+		}
+		if exprs == nil {
+			exprs = map[string]string{}
+		}
+		exprs[d.field.column] = d.expr
+	}
+	return val, exprs
+}
@@ -0,0 +1,235 @@
+package orm
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/godev90/validator/faults"
+)
+
+// Job statuses kept in a jobStatus column.
+const (
+	JobPending = "pending"
+	JobDone    = "done"
+	JobFailed  = "failed"
+)
+
+var (
+	errInvalidJob = fmt.Errorf("orm: invalid job model")
+	ErrInvalidJob = faults.New(errInvalidJob, &faults.ErrAttr{
+		Code: http.StatusInternalServerError,
+		Messages: []faults.LangPackage{
+			{
+				Tag:     faults.English,
+				Message: "orm: job %s: %s",
+			},
+		},
+	})
+)
+
+// jobFields are the fields of a job model tagged with its queue columns.
+type jobFields struct {
+	status   *modelField
+	runAt    *modelField
+	attempts *modelField
+	error    *modelField
+	pk       *modelField
+}
+
+var jobFieldsCache sync.Map // reflect.Type -> jobFields
+
+func jobFieldsOf(t reflect.Type) jobFields {
+	if cached, ok := jobFieldsCache.Load(t); ok {
+		return cached.(jobFields)
+	}
+
+	var jf jobFields
+	for _, f := range modelFields(t) {
+		tag := f.Tag.Get("sql")
+		switch {
+		case tagFlag(tag, "jobStatus"):
+			jf.status = &f
+		case tagFlag(tag, "jobRunAt"):
+			jf.runAt = &f
+		case tagFlag(tag, "jobAttempts"):
+			jf.attempts = &f
+		case tagFlag(tag, "jobError"):
+			jf.error = &f
+		}
+		if f.pk && jf.pk == nil {
+			jf.pk = &f
+		}
+	}
+
+	jobFieldsCache.Store(t, jf)
+	return jf
+}
+
+var errJobsClaimed = errors.New("orm: jobs claimed")
+
+// ClaimJobs locks up to n rows of model's job table that no other
+// transaction holds, with SELECT ... FOR UPDATE SKIP LOCKED, and returns
+// them as new models, so workers sharing the table each take different
+// jobs:
+//
+//	type Job struct {
+//		ID       int64     `sql:"column:id;primaryKey"`
+//		Kind     string    `sql:"column:kind"`
+//		Status   string    `sql:"column:status;jobStatus"`
+//		RunAt    time.Time `sql:"column:run_at;jobRunAt"`
+//		Attempts int       `sql:"column:attempts;jobAttempts"`
+//		Error    string    `sql:"column:last_error;jobError"`
+//	}
+//
+//	err := orm.WithTransaction(ctx, db, func(tx *orm.SqlTransactionAdapter) error {
+//		jobs, err := tx.ClaimJobs(&Job{}, 10, nil)
+//		...
+//		return tx.CompleteJob(job)
+//	})
+//
+// The rows stay locked until the transaction ends; if the worker dies, the
+// jobs are free again. A jobStatus field limits the claim to JobPending
+// rows, a jobRunAt field to rows due by now, claimed earliest first; scope
+// narrows it further. MySQL needs 8.0 for SKIP LOCKED.
+func (q *SqlTransactionAdapter) ClaimJobs(model Tabler, n int, scope ScopeFunc) ([]Tabler, error) {
+	if n <= 0 {
+		return nil, nil
+	}
+	val, err := modelStruct(model, false)
+	if err != nil {
+		return nil, err
+	}
+	t := val.Type()
+	jf := jobFieldsOf(t)
+
+	query := q.Query().UseModel(model)
+	var order []string
+	if jf.status != nil {
+		query = whereTrusted(query, jf.status.column+" = ?", JobPending)
+	}
+	if jf.runAt != nil {
+		query = whereTrusted(query, "("+jf.runAt.column+" IS NULL OR "+jf.runAt.column+" <= ?)", time.Now())
+		order = append(order, jf.runAt.column)
+	}
+	if jf.pk != nil {
+		order = append(order, jf.pk.column)
+	}
+	if len(order) > 0 {
+		query = query.Order(strings.Join(order, ", "))
+	}
+	if scope != nil {
+		query = scope(query)
+	}
+
+	b, ok := query.(*SqlQueryAdapter)
+	if !ok {
+		return nil, ErrInvalidJob.Render(t.Name(), fmt.Sprintf("scope returned a %T", query))
+	}
+	b = b.clone()
+	b.lock = " FOR UPDATE SKIP LOCKED"
+	// Oracle can't lock a FETCH FIRST query; it locks rows as they are
+	// fetched, so the scan stops after n there
+	if q.flavor != FlavorOracle {
+		b.limit = &n
+	}
+
+	var jobs []Tabler
+	claim := func(dest any) error {
+		row := reflect.New(t)
+		row.Elem().Set(reflect.Indirect(reflect.ValueOf(dest)))
+		job, ok := row.Interface().(Tabler)
+		if !ok {
+			return ErrTablerNotImplemented
+		}
+		if jobs = append(jobs, job); len(jobs) >= n {
+			return errJobsClaimed
+		}
+		return ErrSkipRow
+	}
+
+	err = b.OnRow(claim).Scan(reflect.New(reflect.SliceOf(t)).Interface())
+	if err != nil && !errors.Is(err, errJobsClaimed) {
+		return nil, err
+	}
+	return jobs, nil
+}
+
+// CompleteJob marks job JobDone, or deletes its row when the model has no
+// jobStatus field.
+func (q *SqlTransactionAdapter) CompleteJob(job Tabler) error {
+	val, err := modelStruct(job, true)
+	if err != nil {
+		return err
+	}
+	jf := jobFieldsOf(val.Type())
+	if jf.status == nil {
+		return q.HardDelete(job)
+	}
+
+	patch := map[string]any{}
+	if err := setJobField(val, jf.status, JobDone, patch); err != nil {
+		return err
+	}
+	return q.Patch(job, patch)
+}
+
+// FailJob records a failed run of job: its jobAttempts field is
+// incremented and its jobError field set to cause. With a zero retryAt
+// the job is marked JobFailed for good; otherwise it stays pending and is
+// due again at retryAt, which needs a jobRunAt field. Return nil from the
+// transaction afterwards, or the record is rolled back with it.
+func (q *SqlTransactionAdapter) FailJob(job Tabler, cause error, retryAt time.Time) error {
+	val, err := modelStruct(job, true)
+	if err != nil {
+		return err
+	}
+	t := val.Type()
+	jf := jobFieldsOf(t)
+
+	switch {
+	case retryAt.IsZero() && jf.status == nil:
+		return ErrInvalidJob.Render(t.Name(), "failing a job for good needs a jobStatus field")
+	case !retryAt.IsZero() && jf.runAt == nil:
+		return ErrInvalidJob.Render(t.Name(), "retrying a job needs a jobRunAt field")
+	case jf.attempts != nil && !val.FieldByIndex(jf.attempts.Index).CanInt():
+		return ErrInvalidJob.Render(t.Name(), "jobAttempts must be an integer")
+	}
+
+	patch := map[string]any{}
+	if jf.attempts != nil {
+		f := val.FieldByIndex(jf.attempts.Index)
+		f.SetInt(f.Int() + 1)
+		patch[jf.attempts.column] = f.Interface()
+	}
+	if jf.error != nil && cause != nil {
+		if err := setJobField(val, jf.error, cause.Error(), patch); err != nil {
+			return err
+		}
+	}
+	if retryAt.IsZero() {
+		err = setJobField(val, jf.status, JobFailed, patch)
+	} else {
+		err = setJobField(val, jf.runAt, retryAt, patch)
+	}
+	if err != nil {
+		return err
+	}
+
+	return q.Patch(job, patch)
+}
+
+// setJobField sets field f of the job struct val to v and records it in
+// patch.
+func setJobField(val reflect.Value, f *modelField, v any, patch map[string]any) error {
+	field := val.FieldByIndex(f.Index)
+	if err := convertAssign(field, v, assignOpts{column: f.column, keepEmpty: true, timeFormat: f.timeFormat}); err != nil {
+		return err
+	}
+	patch[f.column] = field.Interface()
+	return nil
+}
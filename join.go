@@ -0,0 +1,209 @@
+package orm
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+type (
+	// JoinColumn names a column of a model, e.g. Col(&Order{}, OrderColumnUserID)
+	// with the constant generated by ormgen.
+	JoinColumn struct {
+		model Tabler
+		name  string
+	}
+
+	// JoinOn is the ON condition of a JoinModel call: one or more column
+	// equalities joined with AND. It is built only from model columns, so
+	// the rendered clause needs no further validation.
+	JoinOn struct {
+		kind  string
+		pairs [][2]JoinColumn
+	}
+)
+
+// Col refers to column of model m. The name is checked against the model's
+// mapped columns when the join is rendered.
+func Col(m Tabler, column string) JoinColumn {
+	return JoinColumn{model: m, name: strings.ToLower(column)}
+}
+
+// On starts an inner join condition left = right.
+func On(left, right JoinColumn) JoinOn {
+	return JoinOn{kind: "INNER JOIN", pairs: [][2]JoinColumn{{left, right}}}
+}
+
+// And adds another equality to the condition.
+func (o JoinOn) And(left, right JoinColumn) JoinOn {
+	o.pairs = append(append([][2]JoinColumn(nil), o.pairs...), [2]JoinColumn{left, right})
+	return o
+}
+
+// Left turns the join into a LEFT JOIN.
+func (o JoinOn) Left() JoinOn {
+	o.kind = "LEFT JOIN"
+	return o
+}
+
+// render builds the JOIN clause for model, with prefix in front of every
+// table. Every column must be mapped by its model and every equality must
+// involve the joined model.
+func (o JoinOn) render(flavor driverFlavor, prefix string, model Tabler) (string, error) {
+	if model == nil || len(o.pairs) == 0 {
+		return "", ErrInvalidJoinClause
+	}
+
+	table := model.TableName()
+
+	var sb strings.Builder
+	sb.WriteString(o.kind)
+	sb.WriteByte(' ')
+	sb.WriteString(quoteIdent(flavor, prefix+table))
+	sb.WriteString(" ON ")
+
+	for i, p := range o.pairs {
+		if p[0].model.TableName() != table && p[1].model.TableName() != table {
+			return "", fmt.Errorf("%w: condition %d does not reference %s", ErrInvalidJoinClause, i+1, table)
+		}
+
+		if i > 0 {
+			sb.WriteString(" AND ")
+		}
+		for j, c := range p {
+			col, err := c.qualified(flavor, prefix)
+			if err != nil {
+				return "", err
+			}
+			if j > 0 {
+				sb.WriteString(" = ")
+			}
+			sb.WriteString(col)
+		}
+	}
+	return sb.String(), nil
+}
+
+func (c JoinColumn) qualified(flavor driverFlavor, prefix string) (string, error) {
+	if c.model == nil {
+		return "", ErrInvalidJoinClause
+	}
+
+	table := c.model.TableName()
+	if _, ok := cachedFieldMap(reflect.Indirect(reflect.ValueOf(c.model)).Type())[c.name]; !ok {
+		return "", fmt.Errorf("%w: %s has no column %q", ErrInvalidJoinClause, table, c.name)
+	}
+	return quoteIdent(flavor, prefix+table) + "." + quoteIdent(flavor, c.name), nil
+}
+
+func (q *SqlQueryAdapter) JoinModel(model Tabler, on JoinOn) QueryAdapter {
+	clause, err := on.render(q.flavor, q.tablePrefix, model)
+	if err != nil {
+		return q.reject(fmt.Sprintf("JOIN on %T", model), err)
+	}
+	rememberModel(model)
+	return q.UnsafeJoin(clause)
+}
+
+func (g *GormAdapter) JoinModel(model Tabler, on JoinOn) QueryAdapter {
+	clause, err := on.render(g.Driver(), g.tablePrefix, model)
+	if err != nil {
+		return g.reject(fmt.Sprintf("JOIN on %T", model), err)
+	}
+	rememberModel(model)
+	return g.UnsafeJoin(clause)
+}
+
+// Joins LEFT JOINs the relation of the chain's model named by relation (see
+// Preload for the relation tags), with the ON condition read from its keys
+// and the related table aliased after the relation:
+//
+//	// SELECT * FROM `orders` LEFT JOIN `customers` `customer`
+//	//   ON `customer`.`id` = `orders`.`customer_id` WHERE customer.country = ?
+//	adapter.UseModel(&Order{}).Joins("Customer").Where("customer.country = ?", "NL")
+//
+// The alias is the snake-cased relation name; in a dotted path
+// ("Customer.Profile") the names are joined with __ ("customer__profile"),
+// so prefix__column results scan into the nested structs. A many2many
+// relation also joins its join table, aliased <alias>_link. Soft-deleted
+// related rows are left out of the join unless the chain is Unscoped. An
+// unknown relation is logged and the chain returned unchanged.
+func (q *SqlQueryAdapter) Joins(relation string) QueryAdapter {
+	clause, err := relationJoin(q.flavor, q.tablePrefix, q.model, relation, q.unscoped)
+	if err != nil {
+		return q.reject(fmt.Sprintf("JOIN of relation %q", relation), err)
+	}
+	return q.UnsafeJoin(clause)
+}
+
+func (a builtAdapter) Joins(relation string) QueryAdapter {
+	return a.rewrap(a.b.Joins(relation))
+}
+
+// Joins LEFT JOINs a relation read from sql tags; see SqlQueryAdapter.Joins.
+func (g *GormAdapter) Joins(relation string) QueryAdapter {
+	clause, err := relationJoin(g.Driver(), g.tablePrefix, g.model, relation, g.unscoped)
+	if err != nil {
+		return g.reject(fmt.Sprintf("JOIN of relation %q", relation), err)
+	}
+	return g.UnsafeJoin(clause)
+}
+
+// relationJoin renders the JOIN clauses of the relation path of model.
+func relationJoin(flavor driverFlavor, prefix string, model Tabler, path string, unscoped bool) (string, error) {
+	if model == nil {
+		return "", fmt.Errorf("%w: no model to join %s from", ErrInvalidJoinClause, path)
+	}
+
+	t := reflect.Indirect(reflect.ValueOf(model)).Type()
+	from := prefix + model.TableName()
+	alias := ""
+	var clauses []string
+	for _, name := range strings.Split(path, ".") {
+		rels, err := relationsOf(t)
+		if err != nil {
+			return "", err
+		}
+		rel, ok := rels[name]
+		if !ok {
+			return "", ErrUnknownRelation.Render(t.Name(), name)
+		}
+
+		if alias != "" {
+			alias += nestedSep
+		}
+		alias += toSnake(name)
+
+		// the model side of the ON condition
+		left := from + "." + rel.localKey
+		if rel.kind == "many2many" {
+			link := alias + "_link"
+			clauses = append(clauses, fmt.Sprintf("LEFT JOIN %s ON %s = %s",
+				aliasedTable(flavor, prefix+rel.joinTable, link),
+				quoteIdent(flavor, link+"."+rel.joinLocal),
+				quoteIdent(flavor, left),
+			))
+			left = link + "." + rel.joinRemote
+		}
+
+		on := quoteIdent(flavor, alias+"."+rel.remoteKey) + " = " + quoteIdent(flavor, left)
+		if sd, ok := softDeleteOf(rel.model); ok && !unscoped {
+			on += " AND " + quoteIdent(flavor, alias+"."+sd.column) + " IS NULL"
+		}
+		clauses = append(clauses, fmt.Sprintf("LEFT JOIN %s ON %s",
+			aliasedTable(flavor, prefix+rel.model.TableName(), alias), on))
+		rememberModel(rel.model)
+
+		t, from = rel.elem, alias
+	}
+	return strings.Join(clauses, " "), nil
+}
+
+// aliasedTable renders table with alias, leaving the alias out when it is
+// the table's name. No AS, which Oracle doesn't accept for tables.
+func aliasedTable(flavor driverFlavor, table, alias string) string {
+	if alias == table {
+		return quoteIdent(flavor, table)
+	}
+	return quoteIdent(flavor, table) + " " + quoteIdent(flavor, alias)
+}
@@ -0,0 +1,274 @@
+package orm
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/godev90/validator/faults"
+	"github.com/google/uuid"
+)
+
+type (
+	// IDGenerator produces primary keys client-side, on insert. NewID returns
+	// a string (or a fmt.Stringer, such as uuid.UUID) for string keys, or an
+	// integer for integer keys; other values must be assignable to the key
+	// field.
+	IDGenerator interface {
+		NewID() (any, error)
+	}
+
+	// IDGeneratorFunc adapts a plain function to IDGenerator.
+	IDGeneratorFunc func() (any, error)
+
+	// ModelIDGenerator is implemented by models that choose their own key
+	// generator; it takes precedence over the key's tag and over the
+	// generator set on the transaction. Returning nil opts the model out of
+	// the transaction's generator, leaving its keys to the database.
+	ModelIDGenerator interface {
+		IDGenerator() IDGenerator
+	}
+)
+
+func (f IDGeneratorFunc) NewID() (any, error) {
+	return f()
+}
+
+var (
+	errIDType = fmt.Errorf("orm: generated id does not fit the key")
+	ErrIDType = faults.New(errIDType, &faults.ErrAttr{
+		Code: http.StatusInternalServerError,
+		Messages: []faults.LangPackage{
+			{
+				Tag:     faults.English,
+				Message: "orm: generated id of type %T does not fit primary key %s",
+			},
+		},
+	})
+)
+
+var uuidT = reflect.TypeOf(uuid.UUID{})
+
+// uuidGenerator generates UUIDs of the given version (4 or 7).
+type uuidGenerator int
+
+func (g uuidGenerator) NewID() (any, error) {
+	if g == 7 {
+		return uuid.NewV7()
+	}
+	return uuid.New(), nil
+}
+
+// tagIDGenerator returns the generator the primary key field f asks for:
+// uuid.UUID fields and strings tagged default:uuid get a random v4,
+// default:uuidv7 a time-ordered v7.
+func tagIDGenerator(f reflect.StructField) IDGenerator {
+	if f.Type != uuidT && f.Type.Kind() != reflect.String {
+		return nil
+	}
+
+	switch def := tagOption(f.Tag.Get("sql"), "default"); {
+	case def == "uuidv7":
+		return uuidGenerator(7)
+	case def == "uuid" || def == "uuidv4":
+		return uuidGenerator(4)
+	case def == "" && f.Type == uuidT:
+		return uuidGenerator(4)
+	}
+	return nil
+}
+
+// UseIDGenerator makes Create and BulkInsert fill the zero primary keys of
+// models without a generator of their own from gen, and returns q. Keys
+// the models' types can't hold fail with ErrIDType.
+func (q *SqlTransactionAdapter) UseIDGenerator(gen IDGenerator) *SqlTransactionAdapter {
+	q.ids = gen
+	return q
+}
+
+// idGenerator returns the generator for the primary key field f of model,
+// or nil when the database assigns it.
+func (q *SqlTransactionAdapter) idGenerator(model Tabler, f reflect.StructField) IDGenerator {
+	m, own := model.(ModelIDGenerator)
+	if own {
+		if gen := m.IDGenerator(); gen != nil {
+			return gen
+		}
+	}
+	if gen := tagIDGenerator(f); gen != nil {
+		return gen
+	}
+	if own {
+		// a nil generator keeps the database's keys
+		return nil
+	}
+	return q.ids
+}
+
+// fillID sets the zero key field v, named column, to a new id from gen.
+func fillID(v reflect.Value, column string, gen IDGenerator) error {
+	if !v.IsZero() {
+		return nil
+	}
+
+	id, err := gen.NewID()
+	if err != nil {
+		return err
+	}
+
+	idv := reflect.ValueOf(id)
+	switch {
+	case idv.IsValid() && idv.Type().AssignableTo(v.Type()):
+		v.Set(idv)
+	case v.Kind() == reflect.String && idv.Kind() == reflect.String:
+		v.SetString(idv.String())
+	case v.Kind() == reflect.String && idv.Type().Implements(stringerT):
+		v.SetString(id.(fmt.Stringer).String())
+	case v.CanInt() && idv.CanInt():
+		v.SetInt(idv.Int())
+	case v.CanInt() && idv.CanUint():
+		v.SetInt(int64(idv.Uint()))
+	case v.CanUint() && idv.CanUint():
+		v.SetUint(idv.Uint())
+	case v.CanUint() && idv.CanInt() && idv.Int() >= 0:
+		v.SetUint(uint64(idv.Int()))
+	default:
+		return ErrIDType.Render(id, column)
+	}
+	return nil
+}
+
+var stringerT = reflect.TypeOf((*fmt.Stringer)(nil)).Elem()
+
+// clientKeys reports whether Create and BulkInsert write the primary key
+// field f of model themselves: when it is generated or tagged clientKey, as
+// natural keys are. Otherwise the database assigns the key, even when the
+// field is set.
+func (q *SqlTransactionAdapter) clientKeys(model Tabler, f reflect.StructField) bool {
+	return q.idGenerator(model, f) != nil || tagFlag(f.Tag.Get("sql"), "clientKey")
+}
+
+// keyedRows reports whether every model carries a key in field f.
+func keyedRows(models []Tabler, typ reflect.Type, f modelField) bool {
+	for _, m := range models {
+		v, err := modelStruct(m, false)
+		if err != nil || v.Type() != typ || v.FieldByIndex(f.Index).IsZero() {
+			return false
+		}
+	}
+	return true
+}
+
+// setInsertID stores a database-assigned id in the integer key field v.
+func setInsertID(v reflect.Value, id int64) {
+	switch {
+	case v.CanInt():
+		v.SetInt(id)
+	case v.CanUint():
+		v.SetUint(uint64(id))
+	}
+}
+
+// ULIDGenerator generates ULIDs: 26-character strings that sort by creation
+// time. IDs made within the same millisecond increment the random part, so
+// they stay ordered within one generator.
+type ULIDGenerator struct {
+	mu     sync.Mutex
+	lastMs uint64
+	last   [16]byte
+}
+
+// NewULIDGenerator returns a ULIDGenerator; share one per process.
+func NewULIDGenerator() *ULIDGenerator {
+	return &ULIDGenerator{}
+}
+
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+func (g *ULIDGenerator) NewID() (any, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	ms := uint64(time.Now().UnixMilli())
+	var id [16]byte
+	if ms <= g.lastMs {
+		// same millisecond, or the clock went back: keep ordering
+		ms, id = g.lastMs, g.last
+		i := 15
+		for ; i >= 6; i-- {
+			id[i]++
+			if id[i] != 0 {
+				break
+			}
+		}
+		if i < 6 {
+			return nil, fmt.Errorf("orm: ulid random part overflowed within one millisecond")
+		}
+	} else {
+		id[0], id[1], id[2] = byte(ms>>40), byte(ms>>32), byte(ms>>24)
+		id[3], id[4], id[5] = byte(ms>>16), byte(ms>>8), byte(ms)
+		if _, err := rand.Read(id[6:]); err != nil {
+			return nil, err
+		}
+	}
+	g.lastMs, g.last = ms, id
+
+	// 128 bits as 26 base32 digits, the first one holding the top 3 bits
+	hi, lo := binary.BigEndian.Uint64(id[:8]), binary.BigEndian.Uint64(id[8:])
+	var out [26]byte
+	for i := 25; i >= 0; i-- {
+		out[i] = crockford[lo&31]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+	return string(out[:]), nil
+}
+
+// SnowflakeEpoch is the start of Snowflake time, 2020-01-01 UTC; 41 bits of
+// milliseconds last until 2089.
+var SnowflakeEpoch = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// SnowflakeGenerator generates int64 Snowflake ids: milliseconds since
+// SnowflakeEpoch, a 10-bit node and a 12-bit sequence. Each process writing
+// to the same table needs its own node.
+type SnowflakeGenerator struct {
+	mu   sync.Mutex
+	node int64
+	ms   int64
+	seq  int64
+}
+
+// NewSnowflakeGenerator returns a SnowflakeGenerator for node, 0 to 1023.
+func NewSnowflakeGenerator(node int64) (*SnowflakeGenerator, error) {
+	if node < 0 || node > 1023 {
+		return nil, fmt.Errorf("orm: snowflake node %d outside 0..1023", node)
+	}
+	return &SnowflakeGenerator{node: node}, nil
+}
+
+func (g *SnowflakeGenerator) NewID() (any, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	ms := time.Since(SnowflakeEpoch).Milliseconds()
+	if ms < g.ms {
+		// the clock went back: keep counting in the last millisecond
+		ms = g.ms
+	}
+	if ms == g.ms {
+		g.seq = (g.seq + 1) & 4095
+		if g.seq == 0 {
+			// sequence exhausted: borrow the next millisecond
+			ms++
+		}
+	} else {
+		g.seq = 0
+	}
+	g.ms = ms
+
+	return ms<<22 | g.node<<12 | g.seq, nil
+}
@@ -0,0 +1,97 @@
+package orm
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/godev90/validator/faults"
+)
+
+type keyedInvoice struct {
+	ID     int64  `sql:"column:id;primaryKey"`
+	Number string `sql:"column:number"`
+}
+
+func (keyedInvoice) TableName() string { return "invoices" }
+
+type keyedCountry struct {
+	Code string `sql:"column:code;primaryKey;clientKey"`
+	Name string `sql:"column:name"`
+}
+
+func (keyedCountry) TableName() string { return "countries" }
+
+func TestCreateWritesOnlyClientKeys(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	SetFlavor(db, FlavorPostgres)
+
+	mock.ExpectBegin()
+	// a key the database assigns is not written, even when set
+	mock.ExpectQuery(`INSERT INTO "invoices" ("number") VALUES ($1) RETURNING "id"`).
+		WithArgs("A-1").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(41))
+	mock.ExpectExec(`INSERT INTO "countries" ("code", "name") VALUES ($1, $2)`).
+		WithArgs("NL", "Netherlands").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO "invoices" ("id", "number") VALUES ($1, $2)`).
+		WithArgs(int64(7), "A-2").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	invoice := keyedInvoice{ID: 5, Number: "A-1"}
+	generated := keyedInvoice{Number: "A-2"}
+	err = WithTransaction(context.Background(), db, func(tx *SqlTransactionAdapter) error {
+		if err := tx.Create(&invoice); err != nil {
+			return err
+		}
+		if err := tx.Create(&keyedCountry{Code: "NL", Name: "Netherlands"}); err != nil {
+			return err
+		}
+		tx.UseIDGenerator(IDGeneratorFunc(func() (any, error) { return 7, nil }))
+		return tx.Create(&generated)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if invoice.ID != 41 || generated.ID != 7 {
+		t.Errorf("keys = %d, %d; want the database's 41 and the generated 7", invoice.ID, generated.ID)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestBulkInsertRowMismatch(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	SetFlavor(db, FlavorPostgres)
+
+	mock.ExpectBegin()
+	mock.ExpectRollback()
+
+	err = WithTransaction(context.Background(), db, func(tx *SqlTransactionAdapter) error {
+		return tx.BulkInsert([]Tabler{
+			&keyedInvoice{Number: "A-1"},
+			&keyedInvoice{Number: "A-2"},
+			&keyedCountry{Code: "NL", Name: "Netherlands"},
+		})
+	})
+	if !faults.Is(err, ErrBulkRowMismatch) {
+		t.Fatalf("BulkInsert = %v, want ErrBulkRowMismatch", err)
+	}
+	if msg := err.Error(); !strings.Contains(msg, "row 2 ") || !strings.Contains(msg, "orm.keyedCountry") {
+		t.Errorf("BulkInsert error %q does not name row 2 and its type", msg)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
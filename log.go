@@ -0,0 +1,185 @@
+package orm
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"reflect"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	logSQLFormat       = "[sql] %s | %s\n"
+	logSQLErrorFormat  = "[sql] %s | %s | error: %v\n"
+	logSlowFormat      = "[sql] slow %s | %s | %s\n"
+	logSlowErrorFormat = "[sql] slow %s | %s | %s | error: %v\n"
+)
+
+// LogSampling controls which statements are logged, so SQL stays visible in
+// production without flooding the log pipeline. Errors and slow statements
+// are logged regardless of sampling.
+type LogSampling struct {
+	Every         int           // log one statement in Every; 0 defers to DebugOn
+	SlowThreshold time.Duration // always log statements at least this slow; 0 disables
+	Errors        bool          // always log failing statements
+
+	// OnSlow receives the statements at least SlowThreshold slow instead
+	// of the log, e.g. to count them in metrics or send them to a tracer.
+	// It runs on the goroutine of the statement.
+	OnSlow func(SlowQuery)
+}
+
+// SlowQuery is a statement that took at least LogSampling.SlowThreshold.
+type SlowQuery struct {
+	SQL      string // with its arguments interpolated, sensitive ones redacted
+	Duration time.Duration
+	Caller   string // file:line of the call into the orm that ran it
+	Err      error
+}
+
+var (
+	debug bool
+
+	samplingMu sync.RWMutex
+	sampling   LogSampling
+	sampled    atomic.Uint64
+)
+
+// DebugOn logs every statement (or one in LogSampling.Every, when set) of
+// the whole process. To look at one code path or request, use the Debug
+// method of its chain or WithDebug instead.
+func DebugOn() {
+	debug = true
+}
+
+type debugCtxKey struct{}
+
+// WithDebug returns a context whose statements are all logged, whatever
+// DebugOn and LogSampling say, e.g. for a request carrying a debug header:
+//
+//	if r.Header.Get("X-Debug-SQL") != "" && isAdmin(r) {
+//		r = r.WithContext(orm.WithDebug(r.Context()))
+//	}
+func WithDebug(ctx context.Context) context.Context {
+	return context.WithValue(ctx, debugCtxKey{}, true)
+}
+
+// debugging reports whether c is logged regardless of sampling.
+func debugging(c *queryCall) bool {
+	on, _ := c.ctx.Value(debugCtxKey{}).(bool)
+	return c.debug || on
+}
+
+// Debug logs every statement of the chain, whatever DebugOn and
+// LogSampling say, including those of relations it preloads:
+//
+//	adapter.UseModel(&Order{}).Debug().Where("status = ?", "late").Scan(&orders)
+func (q *SqlQueryAdapter) Debug() QueryAdapter {
+	cp := q.clone()
+	cp.debug = true
+	return cp
+}
+
+// Debug logs every statement of the chain through the orm's log, see
+// SqlQueryAdapter.Debug. gorm's own Debug is left alone.
+func (g *GormAdapter) Debug() QueryAdapter {
+	cp := g.with(g.db)
+	cp.debug = true
+	return cp
+}
+
+func (a builtAdapter) Debug() QueryAdapter {
+	return a.rewrap(a.b.Debug())
+}
+
+// SetLogSampling replaces the process-wide sampling rules.
+func SetLogSampling(s LogSampling) {
+	samplingMu.Lock()
+	defer samplingMu.Unlock()
+	sampling = s
+	sampled.Store(0)
+}
+
+func logRules() LogSampling {
+	samplingMu.RLock()
+	defer samplingMu.RUnlock()
+	return sampling
+}
+
+func shouldLog(s LogSampling, err error) bool {
+	switch {
+	case err != nil && s.Errors:
+		return true
+	case s.Every > 0:
+		return sampled.Add(1)%uint64(s.Every) == 0
+	default:
+		return debug
+	}
+}
+
+func logInterceptor(c *queryCall, next func() error) error {
+	err := next()
+	if c.dryRun {
+		return err
+	}
+	s := logRules()
+	if s.SlowThreshold > 0 && c.elapsed >= s.SlowThreshold {
+		logSlow(s, c, err)
+		return err
+	}
+	if !debugging(c) && !shouldLog(s, err) {
+		return err
+	}
+
+	rendered := interpolate(c.query, c.args, c.flavor)
+	if err != nil {
+		log.Printf(logSQLErrorFormat, rendered, c.elapsed, err)
+	} else {
+		log.Printf(logSQLFormat, rendered, c.elapsed)
+	}
+	return err
+}
+
+// logSlow reports a statement past the slow threshold, with the caller that
+// ran it, to OnSlow or the log.
+func logSlow(s LogSampling, c *queryCall, err error) {
+	q := SlowQuery{
+		SQL:      interpolate(c.query, c.args, c.flavor),
+		Duration: c.elapsed,
+		Caller:   callerLocation(),
+		Err:      err,
+	}
+	switch {
+	case s.OnSlow != nil:
+		s.OnSlow(q)
+	case err != nil:
+		log.Printf(logSlowErrorFormat, q.SQL, q.Duration, q.Caller, err)
+	default:
+		log.Printf(logSlowFormat, q.SQL, q.Duration, q.Caller)
+	}
+}
+
+// ormPackage prefixes the functions of this package in stack traces.
+var ormPackage = reflect.TypeOf(LogSampling{}).PkgPath() + "."
+
+// callerLocation returns the file:line of the innermost frame outside the
+// orm (its tests count as outside), the code that asked for the statement,
+// or "" when there is none.
+func callerLocation() string {
+	pcs := make([]uintptr, 64)
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(2, pcs)])
+	for {
+		f, more := frames.Next()
+		inOrm := strings.HasPrefix(f.Function, ormPackage) && !strings.HasSuffix(f.File, "_test.go")
+		if f.Function != "" && !inOrm && !strings.HasPrefix(f.Function, "runtime.") {
+			return fmt.Sprintf("%s:%d", f.File, f.Line)
+		}
+		if !more {
+			return ""
+		}
+	}
+}
@@ -0,0 +1,247 @@
+package orm
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// loadJoined loads a many2many relation of rows: the join table's links
+// for the parent keys, then the related rows they point at. byKey and keys
+// are the parents by key, as loadRelation collects them.
+func loadJoined(q relatedQuerier, rows []reflect.Value, rel *relation, byKey map[any][]int, keys []any, scopes []ScopeFunc, matched []bool) error {
+	localField := cachedFieldMap(rows[0].Type())[strings.ToLower(rel.localKey)]
+	remoteField := cachedFieldMap(rel.elem)[strings.ToLower(rel.remoteKey)]
+
+	for start := 0; start < len(keys); start += preloadBatch {
+		batch := keys[start:min(start+preloadBatch, len(keys))]
+
+		lq := q.linkTable(rel.joinTable)
+		var links []map[string]any
+		err := whereTrusted(lq.Select([]string{rel.joinLocal, rel.joinRemote}),
+			quoteIdent(lq.Driver(), rel.joinLocal)+" IN ?", batch).
+			Scan(&links)
+		if err != nil {
+			return err
+		}
+
+		// parent keys by related key; remoteKeys keeps the values to look up
+		parents := map[any][]any{}
+		var remoteKeys []any
+		seen := map[[2]any]bool{}
+		for _, link := range links {
+			_, lk, ok := linkKey(localField, linkValue(link, rel.joinLocal))
+			if !ok {
+				continue
+			}
+			rv, rk, ok := linkKey(remoteField, linkValue(link, rel.joinRemote))
+			if !ok || seen[[2]any{lk, rk}] {
+				continue
+			}
+			seen[[2]any{lk, rk}] = true
+
+			if _, known := parents[rk]; !known {
+				remoteKeys = append(remoteKeys, rv.Interface())
+			}
+			parents[rk] = append(parents[rk], lk)
+		}
+
+		for rs := 0; rs < len(remoteKeys); rs += preloadBatch {
+			rb := remoteKeys[rs:min(rs+preloadBatch, len(remoteKeys))]
+
+			rq := q.related(rel.model)
+			col := quoteIdent(rq.Driver(), rq.Describe().Table+"."+rel.remoteKey)
+			found := reflect.New(reflect.SliceOf(reflect.PointerTo(rel.elem)))
+			if err := whereTrusted(rq, col+" IN ?", rb).Scopes(scopes...).Scan(found.Interface()); err != nil {
+				return err
+			}
+
+			// in the order the related query returned them
+			for j := 0; j < found.Elem().Len(); j++ {
+				ptr := found.Elem().Index(j)
+				rk, ok := relationKey(ptr.Elem().FieldByIndex(remoteField.Index))
+				if !ok {
+					continue
+				}
+				for _, lk := range parents[rk] {
+					for _, i := range byKey[lk] {
+						matched[i] = true
+						setRelated(rows[i].Field(rel.field), ptr)
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// linkKey converts a join table value to the type of the model field its
+// column refers to, so it compares like the field's own values; see
+// relationKey. NULL and unconvertible values report false.
+func linkKey(field *modelField, raw any) (reflect.Value, any, bool) {
+	if raw == nil {
+		return reflect.Value{}, nil, false
+	}
+	v := reflect.New(field.Type).Elem()
+	if err := convertAssign(v, raw, assignOpts{column: field.column}); err != nil {
+		return reflect.Value{}, nil, false
+	}
+	k, ok := relationKey(v)
+	return v, k, ok
+}
+
+// linkValue returns the value of col in a scanned join table row. Drivers
+// that upper-case column names (Oracle) are matched case-insensitively.
+func linkValue(row map[string]any, col string) any {
+	if v, ok := row[col]; ok {
+		return v
+	}
+	for k, v := range row {
+		if strings.EqualFold(k, col) {
+			return v
+		}
+	}
+	return nil
+}
+
+// AppendAssociation links model to the related models through the join
+// table of its many2many relation, one row per pair; pairs already linked
+// are skipped:
+//
+//	err := tx.AppendAssociation(&user, "Roles", &admin, &editor)
+//
+// Only the join table is written. The related rows must exist and the
+// relation field of model is left as it is.
+func (q *SqlTransactionAdapter) AppendAssociation(model Tabler, relation string, related ...Tabler) error {
+	rel, local, remotes, err := associationKeys(model, relation, related)
+	if err != nil || len(remotes) == 0 {
+		return err
+	}
+
+	table := quoteIdent(q.flavor, q.tableName(joinTable(rel.joinTable)))
+	localCol := quoteIdent(q.flavor, rel.joinLocal)
+	remoteCol := quoteIdent(q.flavor, rel.joinRemote)
+
+	// the links that already exist
+	query := rebind(q.flavor, fmt.Sprintf("SELECT %s FROM %s WHERE %s = ? AND %s IN (%s)",
+		remoteCol, table, localCol, remoteCol, placeholders(len(remotes)),
+	))
+	args := append([]any{local}, remotes...)
+	linked := map[any]bool{}
+	remoteField := cachedFieldMap(rel.elem)[strings.ToLower(rel.remoteKey)]
+	err = runQuery(q.call(query, args), func(query string) error {
+		rows, err := q.tx.QueryContext(q.ctx, query, args...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var raw any
+			if err := rows.Scan(&raw); err != nil {
+				return err
+			}
+			if _, k, ok := linkKey(remoteField, raw); ok {
+				linked[k] = true
+			}
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return err
+	}
+
+	var values []string
+	args = nil
+	for _, remote := range remotes {
+		k, _ := relationKey(reflect.ValueOf(remote))
+		if linked[k] {
+			continue
+		}
+		linked[k] = true
+		values = append(values, "(?, ?)")
+		args = append(args, local, remote)
+	}
+	if len(values) == 0 {
+		return nil
+	}
+
+	query = fmt.Sprintf("INSERT INTO %s (%s, %s) VALUES %s", table, localCol, remoteCol, strings.Join(values, ", "))
+	if q.flavor == FlavorOracle {
+		// Oracle has no multi-row VALUES
+		into := fmt.Sprintf(" INTO %s (%s, %s) VALUES ", table, localCol, remoteCol)
+		query = "INSERT ALL" + into + strings.Join(values, into) + " SELECT 1 FROM DUAL"
+	}
+	return q.exec(rebind(q.flavor, query), args)
+}
+
+// RemoveAssociation deletes the join table rows linking model to the
+// related models through its many2many relation. The models themselves
+// are not deleted.
+func (q *SqlTransactionAdapter) RemoveAssociation(model Tabler, relation string, related ...Tabler) error {
+	rel, local, remotes, err := associationKeys(model, relation, related)
+	if err != nil || len(remotes) == 0 {
+		return err
+	}
+
+	query := rebind(q.flavor, fmt.Sprintf("DELETE FROM %s WHERE %s = ? AND %s IN (%s)",
+		quoteIdent(q.flavor, q.tableName(joinTable(rel.joinTable))),
+		quoteIdent(q.flavor, rel.joinLocal),
+		quoteIdent(q.flavor, rel.joinRemote),
+		placeholders(len(remotes)),
+	))
+	return q.exec(query, append([]any{local}, remotes...))
+}
+
+// associationKeys returns the many2many relation of model named relation,
+// the key of model it links by and the keys of the related models.
+func associationKeys(model Tabler, name string, related []Tabler) (*relation, any, []any, error) {
+	val, err := modelStruct(model, false)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	t := val.Type()
+	rels, err := relationsOf(t)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	rel, ok := rels[name]
+	if !ok {
+		return nil, nil, nil, ErrUnknownRelation.Render(t.Name(), name)
+	}
+	if rel.kind != "many2many" {
+		return nil, nil, nil, ErrInvalidRelation.Render(t.Name(), name, "not a many2many relation")
+	}
+
+	localField := cachedFieldMap(t)[strings.ToLower(rel.localKey)]
+	local, err := localField.value(val.FieldByIndex(localField.Index))
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	remoteField := cachedFieldMap(rel.elem)[strings.ToLower(rel.remoteKey)]
+	remotes := make([]any, 0, len(related))
+	for _, r := range related {
+		v, err := modelStruct(r, false)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		if v.Type() != rel.elem {
+			return nil, nil, nil, ErrInvalidRelation.Render(t.Name(), name, fmt.Sprintf("%s is not a %s", v.Type().Name(), rel.elem.Name()))
+		}
+		remote, err := remoteField.value(v.FieldByIndex(remoteField.Index))
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		if remote == nil {
+			return nil, nil, nil, ErrInvalidRelation.Render(t.Name(), name, fmt.Sprintf("%s has no %s", rel.elem.Name(), rel.remoteKey))
+		}
+		remotes = append(remotes, remote)
+	}
+	return rel, local, remotes, nil
+}
+
+// placeholders returns n comma-separated ? placeholders.
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
@@ -0,0 +1,299 @@
+package orm
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/godev90/validator/faults"
+)
+
+var (
+	errMigrateType = fmt.Errorf("orm: no column type")
+	ErrMigrateType = faults.New(errMigrateType, &faults.ErrAttr{
+		Code: http.StatusInternalServerError,
+		Messages: []faults.LangPackage{
+			{
+				Tag:     faults.English,
+				Message: "orm: %s.%s: no column type for %s, set one with type: in its sql tag",
+			},
+		},
+	})
+)
+
+// keyDefaults are default tag values that generate keys client-side (see
+// tagIDGenerator) rather than being column defaults.
+var keyDefaults = []string{"uuid", "uuidv4", "uuidv7"}
+
+// AutoMigrate creates the tables of models that don't exist yet and adds
+// the columns their tables lack, from the models' sql tags:
+//
+//	type User struct {
+//		ID     int64  `sql:"column:id;primaryKey"`
+//		Email  string `sql:"column:email;size:191;notNull"`
+//		Status string `sql:"column:status;type:varchar(16);default:'active'"`
+//	}
+//
+// A column's type comes from type: when set, otherwise from its field type
+// (with size: for strings) in the dialect of db. notNull (or "not null")
+// and default: add constraints; default: is SQL, so strings are quoted. A
+// single integer primary key without a sequence is generated by the
+// database. Existing columns are never changed or dropped.
+func AutoMigrate(db *sql.DB, models ...Tabler) error {
+	return AutoMigrateContext(context.Background(), db, models...)
+}
+
+// AutoMigrateContext is AutoMigrate with a context for its statements.
+func AutoMigrateContext(ctx context.Context, db *sql.DB, models ...Tabler) error {
+	flavor := detectFlavor(db)
+	for _, model := range models {
+		stmts, err := migrationStmts(ctx, db, flavor, model)
+		if err != nil {
+			return err
+		}
+		for _, stmt := range stmts {
+			err := runQuery(&queryCall{ctx: ctx, query: stmt, flavor: flavor}, func(stmt string) error {
+				_, err := db.ExecContext(ctx, stmt)
+				return err
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// migrationStmts returns the DDL bringing model's table up to date: a
+// CREATE TABLE when it doesn't exist, else one ADD COLUMN per missing
+// column.
+func migrationStmts(ctx context.Context, db *sql.DB, flavor driverFlavor, model Tabler) ([]string, error) {
+	val, err := modelStruct(model, false)
+	if err != nil {
+		return nil, err
+	}
+	table := model.TableName()
+
+	cols, err := tableColumns(ctx, db, flavor, table)
+	if err != nil {
+		// no such table, or one we can't read; CREATE reports the latter
+		stmt, err := createTableSQL(flavor, table, val.Type())
+		if err != nil {
+			return nil, err
+		}
+		return []string{stmt}, nil
+	}
+
+	existing := make(map[string]bool, len(cols))
+	for _, c := range cols {
+		existing[strings.ToLower(c.Name())] = true
+	}
+
+	var stmts []string
+	for _, f := range modelFields(val.Type()) {
+		if existing[strings.ToLower(f.column)] {
+			continue
+		}
+		def, err := columnDef(flavor, val.Type(), f, false)
+		if err != nil {
+			return nil, err
+		}
+		stmts = append(stmts, addColumnSQL(flavor, table, def))
+	}
+	return stmts, nil
+}
+
+// tableColumns returns the columns of table, in table order, read from an
+// empty SELECT so it works the same on every dialect.
+func tableColumns(ctx context.Context, db *sql.DB, flavor driverFlavor, table string) ([]*sql.ColumnType, error) {
+	query := "SELECT * FROM " + quoteIdent(flavor, table) + " WHERE 1 = 0"
+	var cols []*sql.ColumnType
+	err := runQuery(&queryCall{ctx: ctx, query: query, flavor: flavor}, func(query string) error {
+		rows, err := db.QueryContext(ctx, query)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		cols, err = rows.ColumnTypes()
+		return err
+	})
+	return cols, err
+}
+
+// createTableSQL renders the CREATE TABLE statement of the model type t.
+func createTableSQL(flavor driverFlavor, table string, t reflect.Type) (string, error) {
+	fields := modelFields(t)
+
+	var pks []string
+	for _, f := range fields {
+		if f.pk {
+			pks = append(pks, quoteIdent(flavor, f.column))
+		}
+	}
+
+	defs := make([]string, 0, len(fields)+1)
+	for _, f := range fields {
+		def, err := columnDef(flavor, t, f, len(pks) == 1)
+		if err != nil {
+			return "", err
+		}
+		defs = append(defs, def)
+	}
+	if len(pks) > 1 {
+		defs = append(defs, "PRIMARY KEY ("+strings.Join(pks, ", ")+")")
+	}
+
+	return fmt.Sprintf("CREATE TABLE %s (\n\t%s\n)", quoteIdent(flavor, table), strings.Join(defs, ",\n\t")), nil
+}
+
+// addColumnSQL renders the statement adding the column def to table.
+func addColumnSQL(flavor driverFlavor, table, def string) string {
+	if flavor == FlavorOracle {
+		return fmt.Sprintf("ALTER TABLE %s ADD (%s)", quoteIdent(flavor, table), def)
+	}
+	return fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s", quoteIdent(flavor, table), def)
+}
+
+// columnDef renders the definition of the column of f in the model type t.
+// With soloPK the column is the table's only primary key and carries the
+// PRIMARY KEY constraint itself.
+func columnDef(flavor driverFlavor, t reflect.Type, f modelField, soloPK bool) (string, error) {
+	tag := f.Tag.Get("sql")
+
+	typ := tagOption(tag, "type")
+	if typ == "" {
+		var ok bool
+		if typ, ok = columnType(flavor, f); !ok {
+			return "", ErrMigrateType.Render(t.Name(), f.Name, f.Type)
+		}
+	}
+
+	var sb strings.Builder
+	sb.WriteString(quoteIdent(flavor, f.column))
+	sb.WriteByte(' ')
+	sb.WriteString(typ)
+
+	if def := columnDefault(f); def != "" {
+		sb.WriteString(" DEFAULT ")
+		sb.WriteString(def)
+	}
+	if tagFlag(tag, "notNull") || tagFlag(tag, "not null") {
+		sb.WriteString(" NOT NULL")
+	}
+	if soloPK && f.pk {
+		if isIntegerType(f.Type) && tagOption(tag, "sequence") == "" && tagOption(tag, "type") == "" {
+			sb.WriteString(identityClause(flavor))
+		}
+		sb.WriteString(" PRIMARY KEY")
+	}
+	return sb.String(), nil
+}
+
+// columnDefault returns the DEFAULT expression f's tag declares, "" for
+// none or a key generator.
+func columnDefault(f modelField) string {
+	def := tagOption(f.Tag.Get("sql"), "default")
+	if slices.Contains(keyDefaults, def) {
+		return ""
+	}
+	return def
+}
+
+// identityClause makes an integer primary key database-generated.
+func identityClause(flavor driverFlavor) string {
+	if flavor == FlavorMySQL {
+		return " AUTO_INCREMENT"
+	}
+	return " GENERATED BY DEFAULT AS IDENTITY"
+}
+
+// columnType returns the column type of f in flavor, from its Go type.
+func columnType(flavor driverFlavor, f modelField) (string, bool) {
+	t := f.Type
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if f.timeFormat != "" {
+		// epoch counts
+		t = reflect.TypeOf(int64(0))
+	}
+
+	switch t {
+	case timeT, nullTimeT, gormDeletedAtT:
+		return pick(flavor, "DATETIME(6)", "TIMESTAMPTZ", "TIMESTAMP WITH TIME ZONE"), true
+	case nullStringT:
+		t = reflect.TypeOf("")
+	case nullIntT:
+		t = reflect.TypeOf(int64(0))
+	case nullInt32T:
+		t = reflect.TypeOf(int32(0))
+	case nullInt16T:
+		t = reflect.TypeOf(int16(0))
+	case nullFloatT:
+		t = reflect.TypeOf(float64(0))
+	case nullBoolT:
+		t = reflect.TypeOf(false)
+	case uuidT:
+		return pick(flavor, "CHAR(36)", "UUID", "VARCHAR2(36)"), true
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return pick(flavor, "BOOLEAN", "BOOLEAN", "NUMBER(1)"), true
+	case reflect.Int8, reflect.Uint8:
+		return pick(flavor, "TINYINT", "SMALLINT", "NUMBER(3)"), true
+	case reflect.Int16, reflect.Uint16:
+		return pick(flavor, "SMALLINT", "SMALLINT", "NUMBER(5)"), true
+	case reflect.Int32, reflect.Uint32:
+		return pick(flavor, "INT", "INTEGER", "NUMBER(10)"), true
+	case reflect.Int, reflect.Int64, reflect.Uint:
+		return pick(flavor, "BIGINT", "BIGINT", "NUMBER(19)"), true
+	case reflect.Uint64:
+		return pick(flavor, "BIGINT UNSIGNED", "NUMERIC(20)", "NUMBER(20)"), true
+	case reflect.Float32:
+		return pick(flavor, "FLOAT", "REAL", "BINARY_FLOAT"), true
+	case reflect.Float64:
+		return pick(flavor, "DOUBLE", "DOUBLE PRECISION", "BINARY_DOUBLE"), true
+	case reflect.String:
+		size, _ := strconv.Atoi(tagOption(f.Tag.Get("sql"), "size"))
+		if size > 0 {
+			return fmt.Sprintf("%s(%d)", pick(flavor, "VARCHAR", "VARCHAR", "VARCHAR2"), size), true
+		}
+		return pick(flavor, "VARCHAR(255)", "TEXT", "VARCHAR2(4000)"), true
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return pick(flavor, "LONGBLOB", "BYTEA", "BLOB"), true
+		}
+	}
+	return "", false
+}
+
+// isIntegerType reports whether t, or the type it points to, is an
+// integer.
+func isIntegerType(t reflect.Type) bool {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	}
+	return false
+}
+
+// pick returns the spelling of flavor.
+func pick(flavor driverFlavor, mysql, postgres, oracle string) string {
+	switch flavor {
+	case FlavorPostgres:
+		return postgres
+	case FlavorOracle:
+		return oracle
+	}
+	return mysql
+}
@@ -3,18 +3,21 @@ package orm
 import (
 	"context"
 	"database/sql"
+	"database/sql/driver"
 	"encoding/json"
 	"fmt"
-	"log"
+	"maps"
 	"net/http"
 	"reflect"
 	"regexp"
+	"slices"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/godev90/validator/faults"
-	"github.com/lib/pq"
 )
 
 type (
@@ -41,16 +44,42 @@ type (
 		offset     *int
 
 		model Tabler
+
+		dryRun   bool
+		recorder *StatementRecorder
+		stmts    *StmtCache
+		unscoped bool
+		scoped   bool // default scopes already applied, see withDefaultScopes
+		comment  string
+
+		defaultScopes []ScopeFunc // applied to every build, see WithDefaultScope
+		rowFuncs      []RowFunc   // run on every scanned row, see OnRow
+		tablePrefix   string      // put in front of model tables, see WithTablePrefix
+		emptyStrings  EmptyStringPolicy
+		lossyNumbers  bool // see AllowLossyNumbers
+		typedMaps     bool // see TypedMaps
+		preloads      []preload
+
+		planCache PlanCacheMode
+		replicas  *ReplicaSet
+		tx        *sql.Tx // set by SqlTransactionAdapter.Query
+		lock      string  // row locking clause, see ClaimJobs
+		strict    bool    // see StrictValidation
+		err       error   // first clause rejected by a strict chain
+		debug     bool    // see Debug
+		trusted   bool    // conditions skip column policies, while default scopes apply
+		filters   []policyFilter
 	}
 )
 
 const (
 	FlavorMySQL driverFlavor = iota
 	FlavorPostgres
+	FlavorOracle
 
 	// Time format constants
 	defaultTimeFormat = "2006-01-02 15:04:05"
-	logSQLFormat      = "[sql] %s | %s\n"
+	mysqlTimeFormat   = "2006-01-02 15:04:05.999999"
 	columnPrefix      = "column:"
 )
 
@@ -108,6 +137,41 @@ var (
 		Code: http.StatusNotFound,
 	})
 
+	errModelNotPointer = fmt.Errorf("orm: model must be a pointer")
+	ErrModelNotPointer = faults.New(errModelNotPointer, &faults.ErrAttr{
+		Code: http.StatusInternalServerError,
+		Messages: []faults.LangPackage{
+			{
+				Tag:     faults.English,
+				Message: "orm: %T must be passed as a pointer (&%T{})",
+			},
+		},
+	})
+
+	errModelNotStruct = fmt.Errorf("orm: model must be a struct")
+	ErrModelNotStruct = faults.New(errModelNotStruct, &faults.ErrAttr{
+		Code: http.StatusInternalServerError,
+		Messages: []faults.LangPackage{
+			{
+				Tag:     faults.English,
+				Message: "orm: model must be a struct, got [%T]",
+			},
+		},
+	})
+
+	errBulkRowMismatch = fmt.Errorf("orm: bulk insert rows differ")
+	// ErrBulkRowMismatch is a BulkInsert row whose model type, and so its
+	// columns, differs from the first row's.
+	ErrBulkRowMismatch = faults.New(errBulkRowMismatch, &faults.ErrAttr{
+		Code: http.StatusInternalServerError,
+		Messages: []faults.LangPackage{
+			{
+				Tag:     faults.English,
+				Message: "orm: bulk insert row %d is %s with %d columns, want %s with %d like row 0",
+			},
+		},
+	})
+
 	errParseFailed = fmt.Errorf("orm: parse failed")
 	ErrParseFailed = faults.New(errParseFailed, &faults.ErrAttr{
 		Code: http.StatusInternalServerError,
@@ -120,11 +184,30 @@ var (
 	})
 )
 
+// pinnedFlavors holds dialects set with SetFlavor, keyed by *sql.DB.
+var pinnedFlavors sync.Map
+
+// SetFlavor pins the dialect used for db by every adapter built on it,
+// overriding detection. Use it when the driver is wrapped (otelsql,
+// sqlhooks, proxies) and its type name no longer reveals the database.
+func SetFlavor(db *sql.DB, flavor driverFlavor) {
+	pinnedFlavors.Store(db, flavor)
+}
+
 func detectFlavor(db *sql.DB) driverFlavor {
+	if db == nil {
+		return FlavorMySQL
+	}
+	if f, ok := pinnedFlavors.Load(db); ok {
+		return f.(driverFlavor)
+	}
+
 	t := strings.TrimPrefix(reflect.TypeOf(db.Driver()).String(), "*")
 	switch {
 	case strings.Contains(t, "pq"), strings.Contains(t, "pgx"), strings.Contains(t, "postgres"), strings.Contains(t, "stdlib"):
 		return FlavorPostgres
+	case strings.Contains(t, "godror"), strings.Contains(strings.ToLower(t), "oracle"):
+		return FlavorOracle
 	default:
 		return FlavorMySQL
 	}
@@ -145,6 +228,14 @@ func NewSqlAdapter(db *sql.DB) QueryAdapter {
 	}
 }
 
+// NewSqlAdapterWithFlavor is NewSqlAdapter with an explicit dialect instead
+// of one guessed from the driver type.
+func NewSqlAdapterWithFlavor(db *sql.DB, flavor driverFlavor) QueryAdapter {
+	q := NewSqlAdapter(db).(*SqlQueryAdapter)
+	q.flavor = flavor
+	return q
+}
+
 func (q *SqlQueryAdapter) clone() *SqlQueryAdapter {
 	cp := *q
 	cp.fields = append([]string(nil), q.fields...)
@@ -155,6 +246,10 @@ func (q *SqlQueryAdapter) clone() *SqlQueryAdapter {
 	cp.orWheres = append([]string(nil), q.orWheres...)
 	cp.orArgs = append([]any(nil), q.orArgs...)
 	cp.scopes = append([]ScopeFunc(nil), q.scopes...)
+	cp.defaultScopes = append([]ScopeFunc(nil), q.defaultScopes...)
+	cp.rowFuncs = append([]RowFunc(nil), q.rowFuncs...)
+	cp.preloads = append([]preload(nil), q.preloads...)
+	cp.filters = append([]policyFilter(nil), q.filters...)
 	cp.model = q.model
 	return &cp
 }
@@ -168,7 +263,8 @@ func (q *SqlQueryAdapter) WithContext(ctx context.Context) QueryAdapter {
 func (q *SqlQueryAdapter) UseModel(m Tabler) QueryAdapter {
 	cp := q.clone()
 	cp.model = m
-	cp.table = m.TableName()
+	cp.table = q.tablePrefix + m.TableName()
+	rememberModel(m)
 	return cp
 }
 
@@ -176,7 +272,15 @@ func (q *SqlQueryAdapter) Model() Tabler {
 	return q.model
 }
 
+// Where adds an AND condition. When the query has a model implementing
+// ColumnPolicy, its columns are checked against the policies of the models
+// they belong to as the chain runs, once all joins are known; a refused one
+// fails the chain.
 func (q *SqlQueryAdapter) Where(cond any, args ...any) QueryAdapter {
+	return q.filter(fmt.Sprintf("WHERE condition %q", toString(cond)), cond).trustedWhere(cond, args...)
+}
+
+func (q *SqlQueryAdapter) trustedWhere(cond any, args ...any) QueryAdapter {
 	cp := q.clone()
 
 	// if sub, ok := cond.(*SqlQueryAdapter); ok {
@@ -258,39 +362,165 @@ func (q *SqlQueryAdapter) Where(cond any, args ...any) QueryAdapter {
 		return cp
 	}
 
-	condStr := toString(cond)
-	finalArgs := make([]any, 0, len(args))
+	condStr, finalArgs := bindWhereArgs(q.flavor, postgresDriver(q.db), toString(cond), args)
+
+	cp.wheres = append(cp.wheres, condStr)
+	cp.whereArgs = append(cp.whereArgs, finalArgs...)
+	return cp
+}
+
+var inListSuffix = regexp.MustCompile(`(?i)(\bnot\s+)?\bin\s*(\()?\s*$`)
+
+// bindWhereArgs pairs args with the placeholders of cond. Slice arguments
+// expand into "(?, ?, ...)". On Postgres a slice of basic values under
+// IN / NOT IN is bound as a single array instead ("= ANY(?)" / "<> ALL(?)"),
+// so the statement text doesn't vary with the slice length, wrapped for the
+// driver by pg.
+func bindWhereArgs(flavor driverFlavor, pg pgDriver, cond string, args []any) (string, []any) {
+	var sb strings.Builder
+	out := make([]any, 0, len(args))
+
+	argIdx := 0
+	var quote byte
+	for i := 0; i < len(cond); i++ {
+		c := cond[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+			sb.WriteByte(c)
+			continue
+		case c == '\'' || c == '"' || c == '`':
+			quote = c
+			sb.WriteByte(c)
+			continue
+		case c != '?' || argIdx >= len(args):
+			sb.WriteByte(c)
+			continue
+		}
+
+		arg := args[argIdx]
+		argIdx++
 
-	for _, arg := range args {
 		val := reflect.ValueOf(arg)
-		if val.Kind() == reflect.Slice || val.Kind() == reflect.Array {
-			// Handle slice/array
-			if val.Len() == 0 {
-				// Replace with something always false
-				condStr = "1=0"
+		if !isListArg(arg, val) {
+			sb.WriteByte('?')
+			out = append(out, arg)
+			continue
+		}
+
+		if flavor == FlavorPostgres && isArrayBindable(val.Type()) {
+			if m := inListSuffix.FindStringSubmatchIndex(sb.String()); m != nil {
+				head := sb.String()[:m[0]]
+				op := "= ANY(?)"
+				if m[2] >= 0 {
+					op = "<> ALL(?)"
+				}
+				sb.Reset()
+				sb.WriteString(head)
+				sb.WriteString(op)
+				out = append(out, pg.array(arg))
+
+				// "IN (?)": drop the caller's closing parenthesis
+				if m[4] >= 0 {
+					j := i + 1
+					for j < len(cond) && cond[j] == ' ' {
+						j++
+					}
+					if j < len(cond) && cond[j] == ')' {
+						i = j
+					}
+				}
 				continue
 			}
+		}
 
-			placeholders := make([]string, val.Len())
-			for i := 0; i < val.Len(); i++ {
-				placeholders[i] = "?"
-				finalArgs = append(finalArgs, val.Index(i).Interface())
-			}
+		if val.Len() == 0 {
+			// Replace with something always false
+			return "1=0", nil
+		}
 
-			// Replace only the first "?" occurrence with expanded list
-			condStr = strings.Replace(condStr, "?", "("+strings.Join(placeholders, ", ")+")", 1)
-		} else {
-			finalArgs = append(finalArgs, arg)
+		placeholders := make([]string, val.Len())
+		for k := 0; k < val.Len(); k++ {
+			placeholders[k] = "?"
+			out = append(out, listElemValue(flavor, val.Index(k).Interface()))
+		}
+		list := strings.Join(placeholders, ", ")
+		if !strings.HasSuffix(strings.TrimRight(sb.String(), " "), "(") {
+			list = "(" + list + ")"
 		}
+		sb.WriteString(list)
 	}
 
-	cp.wheres = append(cp.wheres, condStr)
-	cp.whereArgs = append(cp.whereArgs, finalArgs...)
-	return cp
+	// surplus args (no placeholder left) are passed through unchanged
+	out = append(out, args[argIdx:]...)
+	return sb.String(), out
+}
+
+// isListArg reports whether a Where argument expands into a value list.
+// []byte and types with their own driver encoding (pq.StringArray, JSON
+// columns) bind as one value.
+func isListArg(arg any, val reflect.Value) bool {
+	if val.Kind() != reflect.Slice && val.Kind() != reflect.Array {
+		return false
+	}
+	if val.Type().Elem().Kind() == reflect.Uint8 {
+		return false
+	}
+	_, ok := arg.(driver.Valuer)
+	return !ok
+}
+
+// listElemValue normalizes one element of an expanded list so drivers that
+// only accept driver.Value kinds get one: Valuers are resolved, pointers
+// dereferenced and, on MySQL, times sent in the DATETIME text format.
+func listElemValue(flavor driverFlavor, v any) any {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return nil
+		}
+		if _, ok := v.(driver.Valuer); !ok {
+			return listElemValue(flavor, rv.Elem().Interface())
+		}
+	}
+
+	switch x := v.(type) {
+	case driver.Valuer:
+		dv, err := x.Value()
+		if err != nil {
+			return v // let the driver report it
+		}
+		if t, ok := dv.(time.Time); ok {
+			return listElemValue(flavor, t)
+		}
+		return dv
+	case time.Time:
+		if flavor == FlavorMySQL {
+			return x.Format(mysqlTimeFormat)
+		}
+		return x
+	default:
+		return v
+	}
+}
+
+// isArrayBindable reports whether t is a slice or array of basic values
+// that Postgres can receive as one array parameter. []byte is excluded.
+func isArrayBindable(t reflect.Type) bool {
+	switch t.Elem().Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64, reflect.String, reflect.Bool:
+		return true
+	default:
+		return false
+	}
 }
 
 func (q *SqlQueryAdapter) Or(cond any, args ...any) QueryAdapter {
-	cp := q.clone()
+	cp := q.filter(fmt.Sprintf("OR condition %q", toString(cond)), cond).clone()
 	cp.orWheres = append(cp.orWheres, toString(cond))
 	cp.orArgs = append(cp.orArgs, args...)
 	return cp
@@ -309,6 +539,9 @@ func (q *SqlQueryAdapter) GroupBy(cols []string) QueryAdapter {
 }
 
 func (q *SqlQueryAdapter) Having(cols []string, args ...any) QueryAdapter {
+	for _, cond := range cols {
+		q = q.filter(fmt.Sprintf("HAVING condition %q", cond), cond)
+	}
 	return q.UnsafeHaving(cols, args...)
 }
 
@@ -351,26 +584,178 @@ func (q *SqlQueryAdapter) Clone() QueryAdapter {
 }
 
 func (q *SqlQueryAdapter) Count(target *int64) error {
+	if err := q.failure(); err != nil {
+		return err
+	}
 	sqlStr, args := q.build(true)
-	return q.db.QueryRowContext(q.ctx, sqlStr, args...).Scan(target)
+	if q.dryRun {
+		q.recorder.record(sqlStr, args)
+		*target = 0
+		return nil
+	}
+	rows, release, err := q.query(sqlStr, args)
+	if err != nil {
+		return err
+	}
+	defer release()
+	defer rows.Close()
+
+	// COUNT always yields a row unless grouped away; no row means zero
+	if !rows.Next() {
+		*target = 0
+		return rows.Err()
+	}
+	return rows.Scan(target)
+}
+
+// ToSQL returns the SELECT statement the adapter would run, with
+// placeholders in the driver's flavor, without executing it.
+func (q *SqlQueryAdapter) ToSQL() (string, []any) {
+	return q.build(false)
+}
+
+// DryRun returns an adapter whose finishers build statements and record
+// them in rec (which may be nil) instead of executing them.
+func (q *SqlQueryAdapter) DryRun(rec *StatementRecorder) QueryAdapter {
+	cp := q.clone()
+	cp.dryRun = true
+	cp.recorder = rec
+	return cp
+}
+
+// call describes a statement run by the adapter for the interceptor chain.
+func (q *SqlQueryAdapter) call(sqlStr string, args []any) *queryCall {
+	return &queryCall{ctx: q.ctx, query: sqlStr, args: args, flavor: q.flavor, prepared: q.stmts != nil, debug: q.debug}
+}
+
+// query runs a SELECT through the interceptor chain, on a replica when
+// UseReplicas is set. release must be deferred before rows.Close so that it
+// runs after it.
+func (q *SqlQueryAdapter) query(sqlStr string, args []any) (rows *sql.Rows, release func(), err error) {
+	err = runQuery(q.call(sqlStr, args), func(sqlStr string) error {
+		run := func(db *sql.DB) (*sql.Rows, func(), error) {
+			return q.queryOn(db, sqlStr, args)
+		}
+		if tx := q.activeTx(); tx != nil {
+			rows, release, err = q.queryTx(tx, sqlStr, args)
+		} else if q.replicas != nil {
+			rows, release, err = q.replicas.read(q.ctx, q.db, run)
+		} else {
+			rows, release, err = run(q.db)
+		}
+		return err
+	})
+	return rows, release, err
+}
+
+// activeTx is the transaction reads run in: the adapter's own, or one
+// carried by its context (see TxToContext) on the same database.
+func (q *SqlQueryAdapter) activeTx() *sql.Tx {
+	if q.tx != nil {
+		return q.tx
+	}
+	if outer, ok := TxFromContext(q.ctx); ok && outer.db == q.db {
+		return outer.tx
+	}
+	return nil
+}
+
+// queryOn runs a SELECT on db, using the statement cache when PrepareStmt is
+// enabled and db is the adapter's own.
+func (q *SqlQueryAdapter) queryOn(db *sql.DB, sqlStr string, args []any) (rows *sql.Rows, release func(), err error) {
+	if q.planCache != "" && q.flavor == FlavorPostgres {
+		return q.queryPlanCache(db, sqlStr, args)
+	}
+
+	if q.stmts != nil && db == q.db {
+		rows, err = q.stmts.QueryContext(q.ctx, sqlStr, args...)
+	} else {
+		rows, err = db.QueryContext(q.ctx, sqlStr, args...)
+	}
+	return rows, func() {}, err
+}
+
+// queryTx runs a SELECT in tx, with the plan cache mode set for it alone.
+// release puts the previous mode back.
+func (q *SqlQueryAdapter) queryTx(tx *sql.Tx, sqlStr string, args []any) (rows *sql.Rows, release func(), err error) {
+	release = func() {}
+	if q.planCache != "" && q.flavor == FlavorPostgres {
+		if release, err = q.setPlanCacheTx(tx); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if q.stmts != nil {
+		rows, err = q.stmts.query(q.ctx, sqlStr, func(stmt *sql.Stmt) (*sql.Rows, error) {
+			return tx.StmtContext(q.ctx, stmt).QueryContext(q.ctx, args...)
+		})
+	} else {
+		rows, err = tx.QueryContext(q.ctx, sqlStr, args...)
+	}
+	if err != nil {
+		release()
+		return nil, nil, err
+	}
+	return rows, release, nil
+}
+
+// PrepareStmt enables the shared prepared-statement cache of the adapter's
+// *sql.DB for this chain.
+func (q *SqlQueryAdapter) PrepareStmt() QueryAdapter {
+	return q.UseStmtCache(sharedStmtCache(q.db))
+}
+
+// UseStmtCache runs this chain's statements through c, e.g. a cache created
+// with NewStmtCache and a custom capacity.
+func (q *SqlQueryAdapter) UseStmtCache(c *StmtCache) QueryAdapter {
+	cp := q.clone()
+	cp.stmts = c
+	return cp
+}
+
+// Explain returns the database's query plan for the built statement.
+// With analyze the statement is actually executed to collect timings.
+func (q *SqlQueryAdapter) Explain(analyze bool) (string, error) {
+	if err := q.failure(); err != nil {
+		return "", err
+	}
+	sqlStr, args := q.build(false)
+	return explainQuery(q.ctx, q.db, q.flavor, sqlStr, args, analyze)
 }
 
 func (g *SqlQueryAdapter) Driver() driverFlavor {
 	return g.flavor
 }
 
+// DB returns the *sql.DB the adapter runs on, to drop down to database/sql
+// for what the builder doesn't cover.
 func (g *SqlQueryAdapter) DB() *sql.DB {
 	return g.db
 }
 
+// Stats returns the pool statistics of the adapter's *sql.DB. Read replicas
+// have pools of their own.
+func (g *SqlQueryAdapter) Stats() sql.DBStats {
+	if g.db == nil {
+		return sql.DBStats{}
+	}
+	return g.db.Stats()
+}
+
+// Tx returns the transaction the adapter's statements run in, for builders
+// from SqlTransactionAdapter.Query, or nil.
+func (g *SqlQueryAdapter) Tx() *sql.Tx {
+	return g.tx
+}
+
 // Enhanced security methods implementation
 func (q *SqlQueryAdapter) SafeOrder(order string) QueryAdapter {
 	// Validate the order clause first
 	if err := ValidateOrderBy(order); err != nil {
-		// Return empty adapter or handle error appropriately
-		// For now, we'll ignore invalid order clauses
-		log.Printf("WARNING: invalid ORDER BY clause %q: %v", order, err)
-		return q
+		return q.reject(fmt.Sprintf("ORDER BY clause %q", order), err)
+	}
+	if err := checkOrderPolicy(q.columnScope(), order); err != nil {
+		return q.reject(fmt.Sprintf("ORDER BY clause %q", order), err)
 	}
 	return q.Order(order)
 }
@@ -378,9 +763,7 @@ func (q *SqlQueryAdapter) SafeOrder(order string) QueryAdapter {
 func (q *SqlQueryAdapter) SafeJoin(joinClause string, args ...any) QueryAdapter {
 	// Validate the join clause first
 	if err := ValidateJoinClause(joinClause); err != nil {
-		// Return empty adapter or handle error appropriately
-		log.Printf("WARNING: invalid JOIN clause %q: %v", joinClause, err)
-		return q
+		return q.reject(fmt.Sprintf("JOIN clause %q", joinClause), err)
 	}
 	return q.Join(joinClause, args...)
 }
@@ -389,9 +772,10 @@ func (q *SqlQueryAdapter) SafeSelect(selections []string) QueryAdapter {
 	// Sanitize the select fields
 	sanitized, err := SanitizeSelectFields(selections)
 	if err != nil {
-		// Return adapter with default fields on error
-		log.Printf("WARNING: invalid SELECT fields %q: %v", selections, err)
-		return q
+		return q.reject(fmt.Sprintf("SELECT fields %q", selections), err)
+	}
+	if err := checkSelectPolicy(q.columnScope(), sanitized); err != nil {
+		return q.reject(fmt.Sprintf("SELECT fields %q", selections), err)
 	}
 	return q.Select(sanitized)
 }
@@ -400,9 +784,7 @@ func (q *SqlQueryAdapter) SafeGroupBy(groupbys []string) QueryAdapter {
 	// Sanitize the group by fields
 	sanitized, err := SanitizeColumnNames(groupbys)
 	if err != nil {
-		// Return adapter unchanged on error
-		log.Printf("WARNING: invalid GROUP BY fields %q: %v", groupbys, err)
-		return q
+		return q.reject(fmt.Sprintf("GROUP BY fields %q", groupbys), err)
 	}
 	return q.GroupBy(sanitized)
 }
@@ -410,9 +792,7 @@ func (q *SqlQueryAdapter) SafeGroupBy(groupbys []string) QueryAdapter {
 func (q *SqlQueryAdapter) SafeHaving(havings []string, args ...any) QueryAdapter {
 	// Validate the having clauses
 	if err := ValidateHavingClause(havings); err != nil {
-		// Return adapter unchanged on error
-		log.Printf("WARNING: invalid HAVING fields %q: %v", havings, err)
-		return q
+		return q.reject(fmt.Sprintf("HAVING fields %q", havings), err)
 	}
 	return q.Having(havings, args...)
 }
@@ -481,30 +861,36 @@ func isEmptyRaw(v any) bool {
 
 var scannerT = reflect.TypeOf((*sql.Scanner)(nil)).Elem()
 
-func convertAssign(field reflect.Value, raw any) error {
-	if raw == nil || isEmptyRaw(raw) {
+// convertAssign stores raw in field. Empty and blank text reads as NULL
+// unless keepEmpty is set and field holds text (see EmptyStringPolicy).
+func convertAssign(field reflect.Value, raw any, opts assignOpts) error {
+	if raw == nil || isEmptyRaw(raw) && !(opts.keepEmpty && holdsText(field.Type())) {
 		field.Set(reflect.Zero(field.Type()))
 		return nil
 	}
 
+	if opts.timeFormat != "" && (field.Type() == timeT || field.Type() == nullTimeT) {
+		return assignEpoch(field, raw, opts.timeFormat)
+	}
+
 	if isScanner(field) {
 		return assignWithScanner(field, raw)
 	}
 
 	if field.Kind() == reflect.Ptr {
 		field.Set(reflect.New(field.Type().Elem()))
-		return convertAssign(field.Elem(), raw)
+		return convertAssign(field.Elem(), raw, opts)
 	}
 
 	switch field.Kind() {
 	case reflect.String:
 		return assignString(field, raw)
 	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-		return assignInt(field, raw)
+		return assignInt(field, raw, opts)
 	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
-		return assignUint(field, raw)
+		return assignUint(field, raw, opts)
 	case reflect.Float32, reflect.Float64:
-		return assignFloat(field, raw)
+		return assignFloat(field, raw, opts)
 	case reflect.Bool:
 		return assignBool(field, raw)
 	case reflect.Struct:
@@ -513,7 +899,7 @@ func convertAssign(field reflect.Value, raw any) error {
 		}
 		return assignJSON(field, raw)
 	case reflect.Slice:
-		return assignSlice(field, raw)
+		return assignSlice(field, raw, opts.pg)
 	default:
 		return ErrUnsupportedKind.Render(field.Kind()) //fmt.Errorf("unsupported kind: %s", field.Kind())
 	}
@@ -540,59 +926,88 @@ func assignWithScanner(field reflect.Value, raw any) error {
 	return field.Addr().Interface().(sql.Scanner).Scan(val)
 }
 
-func assignInt(field reflect.Value, raw any) error {
+func assignInt(field reflect.Value, raw any, opts assignOpts) error {
 	scalar := toScalar(raw)
+	if str, ok := scalar.(string); ok {
+		n, err := parseNumber(str)
+		if err != nil {
+			return err
+		}
+		scalar = n
+	}
 
 	switch v := scalar.(type) {
 	case int64:
+		if field.OverflowInt(v) && !opts.lossy {
+			return opts.rangeError(v, field.Type())
+		}
 		field.SetInt(v)
 	case float64:
+		if !intFits(field, v) && !opts.lossy {
+			return opts.rangeError(v, field.Type())
+		}
 		field.SetInt(int64(v))
-	case string:
-		i, err := strconv.ParseInt(v, 10, 64)
-		if err != nil {
-			return err
+	case bool:
+		if v {
+			field.SetInt(1)
+		} else {
+			field.SetInt(0)
 		}
-		field.SetInt(i)
 	default:
 		return ErrParseFailed.Render(scalar, "int") //fmt.Errorf("cannot assign %T to int", scalar)
 	}
 	return nil
 }
 
-func assignUint(field reflect.Value, raw any) error {
+func assignUint(field reflect.Value, raw any, opts assignOpts) error {
 	scalar := toScalar(raw)
+	if str, ok := scalar.(string); ok {
+		n, err := parseNumber(str)
+		if err != nil {
+			return err
+		}
+		scalar = n
+	}
 
 	switch v := scalar.(type) {
 	case int64:
+		if (v < 0 || field.OverflowUint(uint64(v))) && !opts.lossy {
+			return opts.rangeError(v, field.Type())
+		}
 		field.SetUint(uint64(v))
 	case float64:
-		field.SetUint(uint64(v))
-	case string:
-		u, err := strconv.ParseUint(v, 10, 64)
-		if err != nil {
-			return err
+		if !uintFits(field, v) && !opts.lossy {
+			return opts.rangeError(v, field.Type())
 		}
-		field.SetUint(u)
+		field.SetUint(uint64(v))
 	default:
 		return ErrParseFailed.Render(scalar, "uint")
 	}
 	return nil
 }
 
-func assignFloat(field reflect.Value, raw any) error {
+func assignFloat(field reflect.Value, raw any, opts assignOpts) error {
 	scalar := toScalar(raw)
 
 	switch v := scalar.(type) {
 	case float64:
+		if field.OverflowFloat(v) && !opts.lossy {
+			return opts.rangeError(v, field.Type())
+		}
 		field.SetFloat(v)
 	case int64:
+		if !floatExact(field, v) && !opts.lossy {
+			return opts.rangeError(v, field.Type())
+		}
 		field.SetFloat(float64(v))
 	case string:
 		f, err := strconv.ParseFloat(v, 64)
 		if err != nil {
 			return err
 		}
+		if field.OverflowFloat(f) && !opts.lossy {
+			return opts.rangeError(v, field.Type())
+		}
 		field.SetFloat(f)
 	default:
 		return ErrParseFailed.Render(scalar, "float")
@@ -621,20 +1036,36 @@ func assignBool(field reflect.Value, raw any) error {
 }
 
 func assignString(field reflect.Value, raw any) error {
-	field.SetString(fmt.Sprint(toScalar(raw)))
+	switch v := toScalar(raw).(type) {
+	case string:
+		field.SetString(v)
+	case int64:
+		field.SetString(strconv.FormatInt(v, 10))
+	case float64:
+		field.SetString(strconv.FormatFloat(v, 'f', -1, 64))
+	case time.Time:
+		field.SetString(v.Format(time.RFC3339Nano))
+	default:
+		field.SetString(fmt.Sprint(v))
+	}
 	return nil
 }
 
-func assignSlice(field reflect.Value, raw any) error {
+// assignSlice parses an array column into field with pg, lib/pq's parser
+// when nil.
+func assignSlice(field reflect.Value, raw any, pg pgDriver) error {
 	switch v := raw.(type) {
 	case sql.RawBytes:
 		raw = []byte(v) // convert before scanning
 	}
+	if pg == nil {
+		pg = pqDriver{}
+	}
 
 	switch field.Type().Elem().Kind() {
 	case reflect.String:
 		var result []string
-		if err := pq.Array(&result).Scan(raw); err != nil {
+		if err := pg.scanArray(raw, &result); err != nil {
 			return err
 		}
 		field.Set(reflect.ValueOf(result))
@@ -642,7 +1073,7 @@ func assignSlice(field reflect.Value, raw any) error {
 
 	case reflect.Int:
 		var result []int64
-		if err := pq.Array(&result).Scan(raw); err != nil {
+		if err := pg.scanArray(raw, &result); err != nil {
 			return err
 		}
 		slice := reflect.MakeSlice(field.Type(), len(result), len(result))
@@ -654,7 +1085,7 @@ func assignSlice(field reflect.Value, raw any) error {
 
 	case reflect.Float64:
 		var result []float64
-		if err := pq.Array(&result).Scan(raw); err != nil {
+		if err := pg.scanArray(raw, &result); err != nil {
 			return err
 		}
 		field.Set(reflect.ValueOf(result))
@@ -728,30 +1159,105 @@ func toScalar(v any) any {
 	}
 }
 
-func (q *SqlQueryAdapter) Scan(dest any) error {
-	// notFound := true
+// withDestModel returns q bound to a model, deriving it from dest when no
+// model was set. The receiver is never mutated so a shared base adapter can
+// serve different destinations. A clause rejected by a strict chain is
+// returned here, before anything runs.
+func (q *SqlQueryAdapter) withDestModel(dest any) (*SqlQueryAdapter, error) {
+	if err := q.failure(); err != nil {
+		return nil, err
+	}
+	if q.model != nil {
+		return q, nil
+	}
 
-	if q.model == nil {
-		if t, ok := dest.(Tabler); ok {
-			q.model = t
-			q.table = q.model.TableName()
-		} else {
-			return ErrTablerNotImplemented
+	t, ok := tablerFromDest(dest)
+	if !ok {
+		return nil, ErrTablerNotImplemented
+	}
+
+	cp := q.clone()
+	cp.model = t
+	cp.table = q.tablePrefix + t.TableName()
+	return cp, nil
+}
+
+// tablerFromDest resolves the model for a scan destination: the destination
+// itself, or the element type behind pointers and slices (*[]User, *[]*User)
+// when either T or *T implements Tabler.
+func tablerFromDest(dest any) (Tabler, bool) {
+	if t, ok := dest.(Tabler); ok {
+		return t, true
+	}
+
+	typ := reflect.TypeOf(dest)
+	for typ != nil && (typ.Kind() == reflect.Ptr || typ.Kind() == reflect.Slice) {
+		typ = typ.Elem()
+	}
+	if typ == nil || typ.Kind() != reflect.Struct {
+		return nil, false
+	}
+
+	t, ok := reflect.New(typ).Interface().(Tabler)
+	return t, ok
+}
+
+// modelStruct returns the struct value behind src. Writers that report
+// generated values back to the caller pass writable so a non-pointer model
+// is rejected with a precise error instead of silently losing them.
+func modelStruct(src any, writable bool) (reflect.Value, error) {
+	val := reflect.ValueOf(src)
+	if !val.IsValid() {
+		return reflect.Value{}, ErrNilPointer
+	}
+
+	if val.Kind() == reflect.Ptr {
+		if val.IsNil() {
+			return reflect.Value{}, ErrNilPointer
 		}
+		val = val.Elem()
+	} else if writable {
+		return reflect.Value{}, ErrModelNotPointer.Render(src, src)
+	}
+
+	if val.Kind() != reflect.Struct {
+		return reflect.Value{}, ErrModelNotStruct.Render(src)
+	}
+	return val, nil
+}
+
+// Scan reads all matching rows into dest and runs AfterFind on each.
+func (q *SqlQueryAdapter) Scan(dest any) error {
+	if err := q.scanAll(dest); err != nil || q.dryRun {
+		return err
+	}
+	if err := preloadRelations(q, dest, q.preloads); err != nil {
+		return err
+	}
+	return afterFind(q.ctx, dest)
+}
+
+func (q *SqlQueryAdapter) scanAll(dest any) error {
+	q, err := q.withDestModel(dest)
+	if err != nil {
+		return err
+	}
+	if q, err = q.narrowTo(dest); err != nil {
+		return err
 	}
 
 	sqlStr, args := q.build(false)
 
-	if debug {
-		rendered := interpolate(sqlStr, args, q.flavor)
-		start := time.Now()
-		defer func() { log.Printf(logSQLFormat, rendered, time.Since(start)) }()
+	if q.dryRun {
+		q.recorder.record(sqlStr, args)
+		return nil
 	}
 
-	rows, err := q.db.QueryContext(q.ctx, sqlStr, args...)
+	rows, release, err := q.query(sqlStr, args)
 	if err != nil {
 		return err
 	}
+	defer release()
 	defer rows.Close()
 
 	cols, _ := rows.Columns()
@@ -760,138 +1266,203 @@ func (q *SqlQueryAdapter) Scan(dest any) error {
 		return ErrNilPointer
 	}
 
-	makeHolders := func() ([]any, []sql.RawBytes) {
-		rawBytes := make([]sql.RawBytes, len(cols))
-		holders := make([]any, len(cols))
-		for i := range holders {
-			holders[i] = &rawBytes[i]
-		}
-		return holders, rawBytes
+	// slices come back empty, never nil, when nothing matches
+	if target := val.Elem(); target.Kind() == reflect.Slice && target.IsNil() {
+		target.Set(reflect.MakeSlice(target.Type(), 0, 0))
 	}
 
-	switch val.Elem().Kind() {
+	if ok, err := scanGenerated(rows, val, q.rowFuncs, q.assignOpts()); ok {
+		return err
+	}
 
-	case reflect.Slice:
-		slice := val.Elem()
-		elemTyp := slice.Type().Elem()
-		fieldMap := buildFieldMap(elemTyp)
+	buf, err := acquireRowBuffer(rows)
+	if err != nil {
+		return err
+	}
+	defer buf.release()
 
-		for rows.Next() {
-			// notFound = false
-			holders, raw := makeHolders()
-			if err := rows.Scan(holders...); err != nil {
-				return err
-			}
+	if mp, ok := dest.(*[]map[string]any); ok {
+		// values as text unless TypedMaps, the way the driver sends them
+		text := make([]sql.RawBytes, len(cols))
+		holders := make([]any, len(cols))
+		for i := range holders {
+			holders[i] = &text[i]
+		}
 
-			elemPtr := reflect.New(elemTyp)
-			for ci, col := range cols {
-				if fi, ok := fieldMap[normalize(col)]; ok {
-					field := elemPtr.Elem().Field(fi)
-					if err := convertAssign(field, raw[ci]); err != nil {
-						return err
+		for rows.Next() {
+			rec := map[string]any{}
+			if q.typedMaps {
+				raw, err := buf.scan(rows)
+				if err != nil {
+					return err
+				}
+				for ci, col := range cols {
+					if b, ok := raw[ci].([]byte); ok {
+						rec[col] = string(b)
+					} else {
+						rec[col] = raw[ci]
+					}
+				}
+			} else {
+				if err := rows.Scan(holders...); err != nil {
+					return err
+				}
+				for ci, col := range cols {
+					if text[ci] == nil {
+						rec[col] = nil
+					} else {
+						rec[col] = string(text[ci])
 					}
 				}
 			}
-
-			slice = reflect.Append(slice, elemPtr.Elem())
+			if keep, err := runRowFuncs(q.rowFuncs, &rec); err != nil {
+				return err
+			} else if keep {
+				*mp = append(*mp, rec)
+			}
 		}
 
-		val.Elem().Set(slice)
+		return rows.Err()
+	}
 
-		// if rows.Err() == nil && notFound {
-		// 	return ErrNotFound
-		// }
+	switch val.Elem().Kind() {
 
-		return rows.Err()
+	case reflect.Slice:
+		slice := val.Elem()
+		elemTyp := slice.Type().Elem()
 
-	case reflect.Struct:
-		if rows.Next() {
-			// notFound = false
-			holders, raw := makeHolders()
-			if err := rows.Scan(holders...); err != nil {
-				return err
-			}
+		// Slice of scalars (e.g. Pluck): map the first column only
+		if !isStructElem(elemTyp) {
+			for rows.Next() {
+				raw, err := buf.scan(rows)
+				if err != nil {
+					return err
+				}
 
-			fieldMap := buildFieldMap(val.Elem().Type())
-			for ci, col := range cols {
-				if fi, ok := fieldMap[normalize(col)]; ok {
-					if err := convertAssign(val.Elem().Field(fi), raw[ci]); err != nil {
+				var elem reflect.Value
+				slice, elem = growOne(slice)
+				if len(raw) > 0 {
+					opts := q.assignOpts()
+					opts.column = cols[0]
+					if err := convertAssign(elem, raw[0], opts); err != nil {
 						return err
 					}
 				}
+				if err := keepLast(q.rowFuncs, slice); err != nil {
+					return err
+				}
 			}
-		}
 
-		// if rows.Err() == nil && notFound {
-		// 	return ErrNotFound
-		// }
+			val.Elem().Set(slice)
+			return rows.Err()
+		}
 
-		return rows.Err()
-	}
+		structTyp := elemTyp
+		if elemTyp.Kind() == reflect.Ptr {
+			structTyp = elemTyp.Elem()
+		}
+		colFields := columnFields(cols, structTyp)
 
-	if mp, ok := dest.(*[]map[string]any); ok {
 		for rows.Next() {
-			// notFound = false
-			holders, raw := makeHolders()
-			if err := rows.Scan(holders...); err != nil {
+			raw, err := buf.scan(rows)
+			if err != nil {
 				return err
 			}
 
-			rec := map[string]any{}
-			for ci, col := range cols {
-				if raw[ci] == nil {
-					rec[col] = nil
-				} else {
-					rec[col] = string(raw[ci])
-				}
+			// value elements are filled in place in the slice's backing
+			// array; only pointer elements need their own allocation
+			var elem reflect.Value
+			slice, elem = growOne(slice)
+			if elemTyp.Kind() == reflect.Ptr {
+				elem.Set(reflect.New(structTyp))
+				elem = elem.Elem()
+			}
+
+			if err := assignColumns(elem, colFields, raw, q.assignOpts()); err != nil {
+				return err
+			}
+			if err := keepLast(q.rowFuncs, slice); err != nil {
+				return err
 			}
-			*mp = append(*mp, rec)
 		}
 
-		// if rows.Err() == nil && notFound {
-		// 	return ErrNotFound
-		// }
+		val.Elem().Set(slice)
 
 		return rows.Err()
+
+	case reflect.Struct:
+		if !rows.Next() {
+			if err := rows.Err(); err != nil {
+				return err
+			}
+			return errRecordNotFound
+		}
+
+		raw, err := buf.scan(rows)
+		if err != nil {
+			return err
+		}
+
+		colFields := columnFields(cols, val.Elem().Type())
+		if err := assignColumns(val.Elem(), colFields, raw, q.assignOpts()); err != nil {
+			return err
+		}
+		if err := filterRows(q.rowFuncs, dest); err != nil {
+			return err
+		}
+		return rows.Err()
 	}
 
 	return ErrUnsupported
 }
 
+// First reads the first matching row into dest and runs its AfterFind.
 func (q *SqlQueryAdapter) First(dest any) error {
-	if q.model == nil {
-		if t, ok := dest.(Tabler); ok {
-			q.model = t
-			q.table = q.model.TableName()
-		} else {
-			return ErrTablerNotImplemented
-		}
+	if err := q.scanFirst(dest); err != nil || q.dryRun {
+		return err
+	}
+	if err := filterRows(q.rowFuncs, dest); err != nil {
+		return err
 	}
+	if err := preloadRelations(q, dest, q.preloads); err != nil {
+		return err
+	}
+	return afterFind(q.ctx, dest)
+}
 
-	sqlStr, args := q.build(false)
+func (q *SqlQueryAdapter) scanFirst(dest any) error {
+	q, err := q.withDestModel(dest)
+	if err != nil {
+		return err
+	}
+	if q, err = q.narrowTo(dest); err != nil {
+		return err
+	}
 
 	// Limit 1 jika belum ada
-	if !strings.Contains(strings.ToLower(sqlStr), "limit") {
-		sqlStr += " LIMIT 1"
+	if q.limit == nil {
+		q = q.Limit(1).(*SqlQueryAdapter)
 	}
 
-	if debug {
-		rendered := interpolate(sqlStr, args, q.flavor)
-		start := time.Now()
-		defer func() { log.Printf(logSQLFormat, rendered, time.Since(start)) }()
+	sqlStr, args := q.build(false)
+
+	if q.dryRun {
+		q.recorder.record(sqlStr, args)
+		return nil
 	}
 
-	rows, err := q.db.QueryContext(q.ctx, sqlStr, args...)
+	rows, release, err := q.query(sqlStr, args)
 	if err != nil {
 		return err
 	}
+	defer release()
 	defer rows.Close()
 
 	if !rows.Next() {
 		if rows.Err() != nil {
 			return rows.Err()
 		}
-		return ErrNotFound
+		return errRecordNotFound
 	}
 
 	cols, _ := rows.Columns()
@@ -900,40 +1471,33 @@ func (q *SqlQueryAdapter) First(dest any) error {
 		return ErrNilPointer
 	}
 
-	holders := make([]any, len(cols))
-	raw := make([]sql.RawBytes, len(cols))
-	for i := range holders {
-		holders[i] = &raw[i]
+	if rs, ok := dest.(RowScanner); ok && generatedExact(cols, val.Elem().Type(), q.assignOpts()) {
+		return rs.ScanRow(rows)
+	}
+
+	buf, err := acquireRowBuffer(rows)
+	if err != nil {
+		return err
 	}
+	defer buf.release()
 
-	if err := rows.Scan(holders...); err != nil {
+	raw, err := buf.scan(rows)
+	if err != nil {
 		return err
 	}
 
 	switch val.Elem().Kind() {
 	case reflect.Struct:
-		fieldMap := buildFieldMap(val.Elem().Type())
-		for ci, col := range cols {
-			if fi, ok := fieldMap[normalize(col)]; ok {
-				if err := convertAssign(val.Elem().Field(fi), raw[ci]); err != nil {
-					return err
-				}
-			}
-		}
-		return nil
+		colFields := columnFields(cols, val.Elem().Type())
+		return assignColumns(val.Elem(), colFields, raw, q.assignOpts())
 
 	case reflect.Slice:
 		// Ambil first element untuk slice
 		elemTyp := val.Elem().Type().Elem()
 		elemPtr := reflect.New(elemTyp)
-		fieldMap := buildFieldMap(elemTyp)
-
-		for ci, col := range cols {
-			if fi, ok := fieldMap[normalize(col)]; ok {
-				if err := convertAssign(elemPtr.Elem().Field(fi), raw[ci]); err != nil {
-					return err
-				}
-			}
+		colFields := columnFields(cols, elemTyp)
+		if err := assignColumns(elemPtr.Elem(), colFields, raw, q.assignOpts()); err != nil {
+			return err
 		}
 
 		slice := reflect.MakeSlice(val.Elem().Type(), 1, 1)
@@ -948,8 +1512,14 @@ func (q *SqlQueryAdapter) First(dest any) error {
 
 type SqlTransactionAdapter struct {
 	ctx    context.Context
+	db     *sql.DB
 	tx     *sql.Tx
 	flavor driverFlavor
+	ids    IDGenerator // fills zero primary keys on insert, see UseIDGenerator
+
+	tablePrefix string // see UseTablePrefix
+
+	rollbackOnly atomic.Bool // set when a joined WithTransaction call failed
 }
 
 // func (q *SqlQueryAdapter) Begin() (*SqlTransactionAdapter, error) {
@@ -964,23 +1534,52 @@ type SqlTransactionAdapter struct {
 // 	}, nil
 // }
 
-func NewSqlTransactionAdapter(ctx context.Context, db *sql.DB) (*SqlTransactionAdapter, error) {
-	tx, err := db.BeginTx(ctx, nil)
+// NewSqlTransactionAdapter begins a transaction on db. An optional TxOptions
+// sets the isolation level or makes the transaction read-only; without it
+// the driver defaults apply.
+func NewSqlTransactionAdapter(ctx context.Context, db *sql.DB, opts ...TxOptions) (*SqlTransactionAdapter, error) {
+	var txOpts *sql.TxOptions
+	if len(opts) > 0 {
+		txOpts = &opts[0]
+	}
+
+	tx, err := db.BeginTx(ctx, txOpts)
 	if err != nil {
 		return nil, err
 	}
 
 	return &SqlTransactionAdapter{
 		ctx:    ctx,
+		db:     db,
 		tx:     tx,
 		flavor: detectFlavor(db),
 	}, nil
 }
 
+// Tx returns the underlying transaction. Statements run on it directly
+// commit or roll back with the adapter's.
 func (q *SqlTransactionAdapter) Tx() *sql.Tx {
 	return q.tx
 }
 
+// DB returns the *sql.DB the transaction was begun on. Statements run on it
+// are outside the transaction.
+func (q *SqlTransactionAdapter) DB() *sql.DB {
+	return q.db
+}
+
+// Query returns a builder whose Scan, First and Count run inside the
+// transaction, so a read-modify-write sees its own uncommitted writes and
+// the rows it locked. Read replicas and PlanCache are ignored there.
+func (q *SqlTransactionAdapter) Query() QueryAdapter {
+	b := newBuilder(q.flavor)
+	b.db = q.db
+	b.ctx = q.ctx
+	b.tx = q.tx
+	b.tablePrefix = q.tablePrefix
+	return b
+}
+
 func (q *SqlTransactionAdapter) Commit() error {
 	return q.tx.Commit()
 }
@@ -990,117 +1589,149 @@ func (q *SqlTransactionAdapter) Rollback() error {
 }
 
 func (q *SqlTransactionAdapter) Create(src Tabler) error {
-	val := reflect.ValueOf(src)
-	if val.Kind() != reflect.Ptr || val.IsNil() {
-		return ErrNilPointer
+	if err := beforeCreate(q.ctx, src); err != nil {
+		return err
 	}
-	val = val.Elem()
-	if val.Kind() != reflect.Struct {
-		return ErrUnsupported
+
+	val, err := modelStruct(src, true)
+	if err != nil {
+		return err
 	}
+	val = stampCreate(val, time.Now())
+	val, exprs := applyInsertDefaults(val)
 
-	typ := val.Type()
 	cols := []string{}
 	placeholders := []string{}
 	args := []any{}
-	var pkFieldIndex int = -1
+	var pkField reflect.Value
 	var pkColumn string
 
-	for i := 0; i < typ.NumField(); i++ {
-		field := typ.Field(i)
-
-		if field.PkgPath != "" || field.Tag.Get("sql") == "-" {
-			continue
-		}
-
-		col, _ := parseColumnTag(field)
-		if col == "" {
-			col = toSnake(field.Name)
-		}
-
-		fieldVal := val.Field(i)
+	for _, field := range writeFields(val.Type()) {
+		col := field.column
+		fieldVal := val.FieldByIndex(field.Index)
 		// Skip zero value on auto increment ID (e.g., primary key)
 		if pk := strings.Contains(field.Tag.Get("sql"), "primaryKey"); pk {
-			pkFieldIndex = i
+			gen := q.idGenerator(src, field.StructField)
+			if gen != nil {
+				if err := fillID(fieldVal, col, gen); err != nil {
+					return err
+				}
+			}
+			// client-side keys (generated, or tagged clientKey) are written as is
+			if gen != nil || tagFlag(field.Tag.Get("sql"), "clientKey") {
+				value, err := field.value(fieldVal)
+				if err != nil {
+					return err
+				}
+				cols = append(cols, quoteIdent(q.flavor, col))
+				placeholders = append(placeholders, "?")
+				args = append(args, value)
+				continue
+			}
+
+			pkField = fieldVal
 			pkColumn = col
+
+			// sequence-backed keys draw their value in the INSERT itself
+			if seq := tagOption(field.Tag.Get("sql"), "sequence"); seq != "" && q.flavor != FlavorMySQL {
+				cols = append(cols, quoteIdent(q.flavor, col))
+				placeholders = append(placeholders, sequenceNextVal(q.flavor, seq))
+			}
 			continue
 		}
 
-		cols = append(cols, col)
+		cols = append(cols, quoteIdent(q.flavor, col))
+		if expr, ok := exprs[field.sourceColumn()]; ok {
+			placeholders = append(placeholders, expr)
+			continue
+		}
+		value, err := field.value(fieldVal)
+		if err != nil {
+			return err
+		}
 		placeholders = append(placeholders, "?")
-		args = append(args, fieldVal.Interface())
+		args = append(args, value)
 	}
 
 	query := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)",
-		src.TableName(),
+		quoteIdent(q.flavor, q.tableName(src)),
 		strings.Join(cols, ", "),
 		strings.Join(placeholders, ", "),
 	)
 
-	if pkFieldIndex >= 0 && q.flavor == FlavorPostgres {
-		query += fmt.Sprintf(" RETURNING %s", pkColumn)
+	if pkField.IsValid() {
+		switch q.flavor {
+		case FlavorPostgres:
+			query += fmt.Sprintf(" RETURNING %s", quoteIdent(q.flavor, pkColumn))
+		case FlavorOracle:
+			query += fmt.Sprintf(" RETURNING %s INTO ?", quoteIdent(q.flavor, pkColumn))
+			args = append(args, sql.Out{Dest: pkField.Addr().Interface()})
+		}
 	}
 
-	if debug {
-		start := time.Now()
-		defer func() {
-			log.Printf(logSQLFormat, logQueryWithValues(query, args), time.Since(start))
-		}()
-	}
+	query = rebind(q.flavor, query)
 
-	if q.flavor == FlavorPostgres {
-		query = convertPostgresPlaceholder(query)
-	}
+	err = runQuery(q.call(query, args), func(query string) error {
+		if pkField.IsValid() && q.flavor == FlavorPostgres {
+			return q.tx.QueryRowContext(q.ctx, query, args...).Scan(pkField.Addr().Interface())
+		}
 
-	var err error
-	if pkFieldIndex >= 0 && q.flavor == FlavorPostgres {
-		err = q.tx.QueryRowContext(q.ctx, query, args...).Scan(val.Field(pkFieldIndex).Addr().Interface())
-	} else {
-		result, execErr := q.tx.ExecContext(q.ctx, query, args...)
-		err = execErr
-		if execErr == nil && pkFieldIndex >= 0 {
+		result, err := q.tx.ExecContext(q.ctx, query, args...)
+		if err == nil && pkField.IsValid() && q.flavor == FlavorMySQL {
 			if lastID, idErr := result.LastInsertId(); idErr == nil {
-				val.Field(pkFieldIndex).SetInt(lastID)
+				setInsertID(pkField, lastID)
 			}
 		}
+		return err
+	})
+	if err != nil {
+		return err
 	}
-
-	return err
+	if err := q.updateParents(1, src); err != nil {
+		return err
+	}
+	if err := q.runRules(WriteInsert, nil, src); err != nil {
+		return err
+	}
+	return afterCreate(q.ctx, src)
 }
 
 func (q *SqlTransactionAdapter) Patch(src Tabler, fields map[string]any) error {
-	val := reflect.ValueOf(src)
-	if val.Kind() != reflect.Ptr || val.IsNil() {
-		return ErrNilPointer
+	if err := beforeUpdate(q.ctx, src); err != nil {
+		return err
 	}
-	val = val.Elem()
-	if val.Kind() != reflect.Struct {
-		return ErrUnsupported
+
+	val, err := modelStruct(src, false)
+	if err != nil {
+		return err
 	}
 
-	typ := val.Type()
+	// the model's autoUpdateTime columns are set unless fields sets them
+	if stamped, autoCols := stampUpdate(val, time.Now()); len(autoCols) > 0 {
+		patch := make(map[string]any, len(fields)+len(autoCols))
+		maps.Copy(patch, fields)
+		fm := cachedFieldMap(stamped.Type())
+		for _, col := range autoCols {
+			if _, ok := patch[col]; !ok {
+				patch[col] = stamped.FieldByIndex(fm[strings.ToLower(col)].Index).Interface()
+			}
+		}
+		fields = patch
+	}
 
 	var pkCol string
 	var pkVal any
-	validCols := map[string]struct{}{}
-
-	for i := 0; i < typ.NumField(); i++ {
-		field := typ.Field(i)
-		if field.PkgPath != "" || field.Tag.Get("sql") == "-" {
-			continue
-		}
+	validCols := map[string]modelField{}
 
-		col, isPK := parseColumnTag(field)
-		if col == "" {
-			col = toSnake(field.Name)
-		}
-
-		if isPK {
-			pkCol = col
-			pkVal = val.Field(i).Interface()
+	for _, field := range modelFields(val.Type()) {
+		if field.pk {
+			pkCol = field.column
+			if pkVal, err = field.value(val.FieldByIndex(field.Index)); err != nil {
+				return err
+			}
 		}
 
-		validCols[col] = struct{}{}
+		validCols[field.column] = field
 	}
 
 	if pkCol == "" {
@@ -1113,74 +1744,83 @@ func (q *SqlTransactionAdapter) Patch(src Tabler, fields map[string]any) error {
 	args := []any{}
 
 	for col, v := range fields {
-		if _, ok := validCols[col]; !ok {
+		field, ok := validCols[col]
+		if !ok {
 			return faults.New(fmt.Errorf("invalid column: %s", col), &faults.ErrAttr{
 				Code: http.StatusBadRequest,
 			})
 		}
-		cols = append(cols, fmt.Sprintf("%s = ?", col))
-		args = append(args, v)
+		value, err := bindValue(field.timeFormat, v)
+		if err != nil {
+			return err
+		}
+		cols = append(cols, fmt.Sprintf("%s = ?", quoteIdent(q.flavor, col)))
+		args = append(args, value)
+		if field.legacy != "" && !field.pk {
+			cols = append(cols, fmt.Sprintf("%s = ?", quoteIdent(q.flavor, field.legacy)))
+			args = append(args, value)
+		}
 	}
 	args = append(args, pkVal)
 
 	query := fmt.Sprintf("UPDATE %s SET %s WHERE %s = ?",
-		src.TableName(),
+		quoteIdent(q.flavor, q.tableName(src)),
 		strings.Join(cols, ", "),
-		pkCol,
+		quoteIdent(q.flavor, pkCol),
 	)
 
-	if debug {
-		start := time.Now()
-		defer func() {
-			log.Printf(logSQLFormat, logQueryWithValues(query, args), time.Since(start))
-		}()
-	}
+	query = rebind(q.flavor, query)
 
-	if q.flavor == FlavorPostgres {
-		query = convertPostgresPlaceholder(query)
+	// fields may move src to another parent its struct doesn't show yet
+	stored, err := q.storedRuleKeys(src, nil)
+	if err != nil {
+		return err
 	}
-
-	_, err := q.tx.ExecContext(q.ctx, query, args...)
-	return err
+	if err := q.exec(query, args); err != nil {
+		return err
+	}
+	if stored, err = q.storedRuleKeys(src, stored); err != nil {
+		return err
+	}
+	if err := q.updateParents(0, src); err != nil {
+		return err
+	}
+	if err := q.runRules(WriteUpdate, stored, src); err != nil {
+		return err
+	}
+	return afterUpdate(q.ctx, src)
 }
 
 func (q *SqlTransactionAdapter) Update(src Tabler) error {
-	val := reflect.ValueOf(src)
-	if val.Kind() != reflect.Ptr || val.IsNil() {
-		return ErrNilPointer
-	}
-	val = val.Elem()
-	if val.Kind() != reflect.Struct {
-		return ErrUnsupported
+	if err := beforeUpdate(q.ctx, src); err != nil {
+		return err
 	}
 
-	typ := val.Type()
+	val, err := modelStruct(src, false)
+	if err != nil {
+		return err
+	}
+	val, _ = stampUpdate(val, time.Now())
 
 	var pkCol string
 	var pkVal any
 	cols := []string{}
 	args := []any{}
 
-	for i := 0; i < typ.NumField(); i++ {
-		field := typ.Field(i)
-		if field.PkgPath != "" || field.Tag.Get("sql") == "-" {
-			continue
-		}
-
-		col, isPK := parseColumnTag(field)
-		if col == "" {
-			col = toSnake(field.Name)
+	for _, field := range writeFields(val.Type()) {
+		col := field.column
+		value, err := field.value(val.FieldByIndex(field.Index))
+		if err != nil {
+			return err
 		}
 
-		value := val.Field(i).Interface()
-
-		if isPK {
+		if field.pk {
 			pkCol = col
 			pkVal = value
 			continue // primary key tidak ikut di SET
 		}
 
-		cols = append(cols, fmt.Sprintf("%s = ?", col))
+		cols = append(cols, fmt.Sprintf("%s = ?", quoteIdent(q.flavor, col)))
 		args = append(args, value)
 	}
 
@@ -1193,70 +1833,136 @@ func (q *SqlTransactionAdapter) Update(src Tabler) error {
 	args = append(args, pkVal)
 
 	query := fmt.Sprintf("UPDATE %s SET %s WHERE %s = ?",
-		src.TableName(),
+		quoteIdent(q.flavor, q.tableName(src)),
 		strings.Join(cols, ", "),
-		pkCol,
+		quoteIdent(q.flavor, pkCol),
 	)
 
-	if debug {
-		start := time.Now()
-		defer func() {
-			log.Printf(logSQLFormat, logQueryWithValues(query, args), time.Since(start))
-		}()
+	query = rebind(q.flavor, query)
+
+	stored, err := q.storedRuleKeys(src, nil)
+	if err != nil {
+		return err
+	}
+	if err := q.exec(query, args); err != nil {
+		return err
+	}
+	if err := q.updateParents(0, src); err != nil {
+		return err
+	}
+	if err := q.runRules(WriteUpdate, stored, src); err != nil {
+		return err
 	}
+	return afterUpdate(q.ctx, src)
+}
+
+// Delete removes the row of src by primary key. Models with a soft-delete
+// column (deleted_at) only get it set to the current time, and rows deleted
+// that way before are left alone; HardDelete removes the row regardless.
+// Counter caches of src's parent are decremented when a row was actually
+// deleted.
+func (q *SqlTransactionAdapter) Delete(src Tabler) error {
+	return q.delete(src, true)
+}
 
-	if q.flavor == FlavorPostgres {
-		query = convertPostgresPlaceholder(query)
+func (q *SqlTransactionAdapter) delete(src Tabler, soft bool) error {
+	val, err := modelStruct(src, false)
+	if err != nil {
+		return err
 	}
 
-	_, err := q.tx.ExecContext(q.ctx, query, args...)
-	return err
+	pkCol := primaryKeyColumn(src)
+	fi, ok := cachedFieldMap(val.Type())[strings.ToLower(pkCol)]
+	if !ok {
+		return faults.New(fmt.Errorf("orm: primary key not found"), &faults.ErrAttr{
+			Code: http.StatusBadRequest,
+		})
+	}
+
+	query := rebind(q.flavor, fmt.Sprintf("DELETE FROM %s WHERE %s = ?",
+		quoteIdent(q.flavor, q.tableName(src)),
+		quoteIdent(q.flavor, pkCol),
+	))
+	pkVal, err := fi.value(val.FieldByIndex(fi.Index))
+	if err != nil {
+		return err
+	}
+	args := []any{pkVal}
+
+	if sd, ok := softDeleteOf(src); ok && soft {
+		query = rebind(q.flavor, fmt.Sprintf("UPDATE %s SET %s = ? WHERE %s = ? AND %s IS NULL",
+			quoteIdent(q.flavor, q.tableName(src)),
+			quoteIdent(q.flavor, sd.column),
+			quoteIdent(q.flavor, pkCol),
+			quoteIdent(q.flavor, sd.column),
+		))
+		args = []any{time.Now(), pkVal}
+	}
+
+	var affected int64
+	err = runQuery(q.call(query, args), func(query string) error {
+		res, err := q.tx.ExecContext(q.ctx, query, args...)
+		if err != nil {
+			return err
+		}
+		affected, err = res.RowsAffected()
+		return err
+	})
+	if err != nil || affected == 0 {
+		return err
+	}
+	if err := q.updateParents(-1, src); err != nil {
+		return err
+	}
+	return q.runRules(WriteDelete, nil, src)
 }
 
+// BulkInsert inserts models, all of one type, in a single statement. Their
+// primary keys are written when generated or tagged clientKey, and left to
+// the database otherwise.
 func (q *SqlTransactionAdapter) BulkInsert(models []Tabler) error {
+	return q.bulkInsert(models, false)
+}
+
+// bulkInsert is BulkInsert; with keepKeys, keys every model carries are
+// written as given, as fixtures and snapshot restores need.
+func (q *SqlTransactionAdapter) bulkInsert(models []Tabler, keepKeys bool) error {
 	if len(models) == 0 {
 		return nil
 	}
+	if err := beforeCreate(q.ctx, models...); err != nil {
+		return err
+	}
 
 	first := models[0]
-	val := reflect.ValueOf(first)
-	if val.Kind() != reflect.Ptr || val.IsNil() {
-		return ErrNilPointer
-	}
-	val = val.Elem()
-	if val.Kind() != reflect.Struct {
-		return ErrUnsupported
+	val, err := modelStruct(first, false)
+	if err != nil {
+		return err
 	}
 
 	typ := val.Type()
 	cols := []string{}
-	fieldIndexes := []int{}
-
-	// Determine columns and indexes once from first struct
-	for i := 0; i < typ.NumField(); i++ {
-		field := typ.Field(i)
-
-		if field.PkgPath != "" || field.Tag.Get("sql") == "-" {
-			continue
-		}
+	fields := []modelField{}
+	var key *modelField
 
+	// Determine columns and fields once from first struct
+	for _, field := range writeFields(typ) {
 		if strings.Contains(field.Tag.Get("sql"), "primaryKey") {
-			continue
+			if !q.clientKeys(first, field.StructField) && !(keepKeys && keyedRows(models, typ, field)) {
+				continue
+			}
+			key = &field
 		}
 
-		col, _ := parseColumnTag(field)
-		if col == "" {
-			col = toSnake(field.Name)
-		}
-		cols = append(cols, col)
-		fieldIndexes = append(fieldIndexes, i)
+		cols = append(cols, quoteIdent(q.flavor, field.column))
+		fields = append(fields, field)
 	}
 
 	if len(cols) == 0 {
 		return fmt.Errorf("orm: no insertable fields found")
 	}
 
-	table := first.TableName()
+	table := quoteIdent(q.flavor, q.tableName(first))
 	// if table == "" {
 	// 	if tabler, ok := first.(Tabler); ok {
 
@@ -1267,22 +1973,39 @@ func (q *SqlTransactionAdapter) BulkInsert(models []Tabler) error {
 
 	placeholderRows := []string{}
 	args := []any{}
+	now := time.Now()
 
-	for _, model := range models {
-		v := reflect.ValueOf(model)
-		if v.Kind() != reflect.Ptr || v.IsNil() {
-			return ErrNilPointer
+	for i, model := range models {
+		v, err := modelStruct(model, false)
+		if err != nil {
+			return err
 		}
-		v = v.Elem()
-		if v.Kind() != reflect.Struct {
-			return ErrUnsupported
+		if v.Type() != typ {
+			return ErrBulkRowMismatch.Render(i, v.Type(), len(writeFields(v.Type())), typ, len(writeFields(typ)))
+		}
+		v = stampCreate(v, now)
+		v, exprs := applyInsertDefaults(v)
+		if key != nil {
+			if gen := q.idGenerator(model, key.StructField); gen != nil {
+				v = settable(v)
+				if err := fillID(v.FieldByIndex(key.Index), key.column, gen); err != nil {
+					return err
+				}
+			}
 		}
 
 		ph := []string{}
-		for _, idx := range fieldIndexes {
-			fieldVal := v.Field(idx)
+		for _, field := range fields {
+			if expr, ok := exprs[field.sourceColumn()]; ok {
+				ph = append(ph, expr)
+				continue
+			}
+			value, err := field.value(v.FieldByIndex(field.Index))
+			if err != nil {
+				return err
+			}
 			ph = append(ph, "?")
-			args = append(args, fieldVal.Interface())
+			args = append(args, value)
 		}
 		placeholderRows = append(placeholderRows, fmt.Sprintf("(%s)", strings.Join(ph, ", ")))
 	}
@@ -1293,40 +2016,49 @@ func (q *SqlTransactionAdapter) BulkInsert(models []Tabler) error {
 		strings.Join(placeholderRows, ", "),
 	)
 
-	if debug {
-		start := time.Now()
-		defer func() {
-			log.Printf(logSQLFormat, logQueryWithValues(query, args), time.Since(start))
-		}()
+	if q.flavor == FlavorOracle {
+		// Oracle has no multi-row VALUES
+		into := fmt.Sprintf(" INTO %s (%s) VALUES ", table, strings.Join(cols, ", "))
+		query = "INSERT ALL" + into + strings.Join(placeholderRows, into) + " SELECT 1 FROM DUAL"
 	}
 
-	if q.flavor == FlavorPostgres {
-		query = convertPostgresPlaceholder(query)
-	}
+	query = rebind(q.flavor, query)
 
-	_, err := q.tx.ExecContext(q.ctx, query, args...)
-	return err
+	if err := q.exec(query, args); err != nil {
+		return err
+	}
+	if err := q.updateParents(1, models...); err != nil {
+		return err
+	}
+	if err := q.runRules(WriteInsert, nil, models...); err != nil {
+		return err
+	}
+	return afterCreate(q.ctx, models...)
 }
 
-func logQueryWithValues(query string, args []any) string {
-	var sb strings.Builder
-	argIdx := 0
+// call describes a statement run by the adapter for the interceptor chain.
+func (q *SqlTransactionAdapter) call(query string, args []any) *queryCall {
+	return &queryCall{ctx: q.ctx, query: query, args: args, flavor: q.flavor}
+}
 
-	for i := 0; i < len(query); i++ {
-		if query[i] == '?' && argIdx < len(args) {
-			sb.WriteString(formatSQLValue(args[argIdx]))
-			argIdx++
-		} else {
-			sb.WriteByte(query[i])
-		}
-	}
-	return sb.String()
+// exec runs a statement that returns no rows inside the transaction.
+func (q *SqlTransactionAdapter) exec(query string, args []any) error {
+	return runQuery(q.call(query, args), func(query string) error {
+		_, err := q.tx.ExecContext(q.ctx, query, args...)
+		return err
+	})
 }
 
 func formatSQLValue(v any) string {
 	switch val := v.(type) {
 	case nil:
 		return "NULL"
+	case driver.Valuer:
+		dv, err := val.Value()
+		if err != nil {
+			return fmt.Sprintf("%v", v)
+		}
+		return formatSQLValue(dv)
 	case *int, *int64, *int32:
 		if reflect.ValueOf(val).IsNil() {
 			return "NULL"
@@ -1355,63 +2087,158 @@ func formatSQLValue(v any) string {
 	}
 }
 
-func convertPostgresPlaceholder(query string) string {
-	var result strings.Builder
-	argIndex := 1
-	for i := 0; i < len(query); i++ {
-		if query[i] == '?' {
-			result.WriteString(fmt.Sprintf("$%d", argIndex))
-			argIndex++
-		} else {
-			result.WriteByte(query[i])
-		}
+// interpolate renders the statement exactly as sent to the driver with its
+// arguments inlined, for logs. It scans the text the way rebind wrote it:
+// placeholders inside quoted literals and identifiers are left alone, and
+// numbered ones ($2, :2) take the argument they name, not the next one.
+// Output parameters (sql.Out) keep their placeholder, and every argument of
+// a statement naming a sensitive column is replaced by [REDACTED].
+func interpolate(sqlStr string, args []any, flavor driverFlavor) string {
+	var prefix byte
+	switch flavor {
+	case FlavorPostgres:
+		prefix = '$'
+	case FlavorOracle:
+		prefix = ':'
 	}
-	return result.String()
-}
 
-func interpolate(sqlStr string, args []any, flavor driverFlavor) string {
 	var out strings.Builder
-	argIdx := 0
+	out.Grow(len(sqlStr) + 16*len(args))
+
+	redact := redacting.Load() && touchesSensitive(sqlStr)
 
-	quote := func(a any) string {
-		switch v := a.(type) {
-		case string:
-			return "'" + strings.ReplaceAll(v, "'", "''") + "'" // escape '
-		case time.Time:
-			return "'" + v.Format(defaultTimeFormat) + "'"
-		default:
-			return fmt.Sprint(v)
+	arg := func(n int, placeholder string) {
+		if n < 0 || n >= len(args) {
+			out.WriteString(placeholder)
+			return
+		}
+		if _, ok := args[n].(sql.Out); ok {
+			out.WriteString(placeholder)
+			return
 		}
+		if redact {
+			out.WriteString(redactedValue)
+			return
+		}
+		out.WriteString(formatSQLValue(args[n]))
 	}
 
-	switch flavor {
-
-	case FlavorPostgres:
-		re := regexp.MustCompile(`\$\d+`)
-		out.WriteString(re.ReplaceAllStringFunc(sqlStr, func(_ string) string {
-			if argIdx >= len(args) {
-				return "?"
+	next := 0
+	var quote byte
+	for i := 0; i < len(sqlStr); i++ {
+		c := sqlStr[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
 			}
-			val := quote(args[argIdx])
-			argIdx++
-			return val
-		}))
-		return out.String()
-
-	default:
-		for i := 0; i < len(sqlStr); i++ {
-			if sqlStr[i] == '?' && argIdx < len(args) {
-				out.WriteString(quote(args[argIdx]))
-				argIdx++
-			} else {
-				out.WriteByte(sqlStr[i])
+		case c == '\'' || c == '"' || c == '`':
+			quote = c
+		case prefix == 0 && c == '?':
+			arg(next, "?")
+			next++
+			continue
+		case prefix != 0 && c == prefix && i+1 < len(sqlStr) && isDigit(sqlStr[i+1]):
+			j := i + 1
+			for j < len(sqlStr) && isDigit(sqlStr[j]) {
+				j++
 			}
+			n, _ := strconv.Atoi(sqlStr[i+1 : j])
+			arg(n-1, sqlStr[i:j])
+			i = j - 1
+			continue
 		}
-		return out.String()
+		out.WriteByte(c)
+	}
+	return out.String()
+}
+
+// Comment appends a human-readable /* text */ to the statement, e.g. for
+// slow query logs. The text is sanitized; repeated calls are joined by "; ".
+func (q *SqlQueryAdapter) Comment(text string) QueryAdapter {
+	cp := q.clone()
+	cp.comment = joinComment(q.comment, text)
+	return cp
+}
+
+// Unscoped disables the model's DefaultScope and soft-delete filter for
+// this chain. Scopes added with WithDefaultScope, such as TenantScope, still
+// apply.
+func (q *SqlQueryAdapter) Unscoped() QueryAdapter {
+	cp := q.clone()
+	cp.unscoped = true
+	return cp
+}
+
+// WithDefaultScope adds scopes applied to every statement built from this
+// adapter and the chains derived from it, after the model's DefaultScope.
+// Unscoped doesn't skip them: they belong to the adapter, not the model.
+func (q *SqlQueryAdapter) WithDefaultScope(fs ...ScopeFunc) QueryAdapter {
+	cp := q.clone()
+	cp.defaultScopes = append(cp.defaultScopes, fs...)
+	return cp
+}
+
+// withDefaultScopes applies the model's DefaultScope and the adapter's
+// default scopes once, on a copy. Unscoped skips only the model's.
+func (q *SqlQueryAdapter) withDefaultScopes() *SqlQueryAdapter {
+	if q.scoped {
+		return q
+	}
+
+	cp := q.clone()
+	cp.scoped, cp.trusted = true, true
+	cp.groupConditions()
+	if s, ok := q.model.(DefaultScoper); ok && !q.unscoped {
+		if scoped, ok := s.DefaultScope()(cp).(*SqlQueryAdapter); ok {
+			cp = scoped
+		}
+	}
+	for _, f := range q.defaultScopes {
+		if f == nil {
+			continue
+		}
+		if scoped, ok := f(cp).(*SqlQueryAdapter); ok {
+			cp = scoped
+		}
+	}
+	cp.trusted = q.trusted
+	return cp
+}
+
+// groupConditions folds q's OR conditions and the AND conditions before them
+// into one parenthesized condition, so that conditions added by default
+// scopes restrict all of them rather than only the AND terms.
+func (q *SqlQueryAdapter) groupConditions() {
+	if len(q.orWheres) == 0 {
+		return
+	}
+
+	cond := "(" + strings.Join(q.orWheres, " OR ") + ")"
+	if len(q.wheres) > 0 {
+		cond = strings.Join(q.wheres, " AND ") + " OR " + cond
+	}
+	q.wheres = []string{"(" + cond + ")"}
+	q.whereArgs = append(q.whereArgs, q.orArgs...)
+	q.orWheres, q.orArgs = nil, nil
+}
+
+// grouped returns a copy of q whose conditions, ORs included, form one
+// parenthesized condition, so a condition added to it restricts all of them.
+func (q *SqlQueryAdapter) grouped() QueryAdapter {
+	cp := q.clone()
+	if len(cp.orWheres) > 0 {
+		cp.groupConditions()
+	} else if len(cp.wheres) > 1 {
+		cp.wheres = []string{"(" + strings.Join(cp.wheres, " AND ") + ")"}
 	}
+	return cp
 }
 
 func (q *SqlQueryAdapter) build(count bool) (string, []any) {
+	softDeleted := q.softDeleteCond()
+	q = q.withDefaultScopes()
+
 	var sb strings.Builder
 	if count {
 		sb.WriteString("SELECT COUNT(1) FROM ")
@@ -1420,7 +2247,7 @@ func (q *SqlQueryAdapter) build(count bool) (string, []any) {
 		sb.WriteString(strings.Join(q.fields, ", "))
 		sb.WriteString(" FROM ")
 	}
-	sb.WriteString(q.table)
+	sb.WriteString(quoteIdent(q.flavor, q.table))
 
 	if len(q.joins) > 0 {
 		sb.WriteByte(' ')
@@ -1432,6 +2259,9 @@ func (q *SqlQueryAdapter) build(count bool) (string, []any) {
 
 	if len(q.wheres) > 0 || len(q.orWheres) > 0 {
 		sb.WriteString(" WHERE ")
+		if softDeleted != "" {
+			sb.WriteString("(")
+		}
 		if len(q.wheres) > 0 {
 			sb.WriteString(strings.Join(q.wheres, " AND "))
 			args = append(args, q.whereArgs...)
@@ -1445,6 +2275,13 @@ func (q *SqlQueryAdapter) build(count bool) (string, []any) {
 			sb.WriteString(")")
 			args = append(args, q.orArgs...)
 		}
+		if softDeleted != "" {
+			sb.WriteString(") AND ")
+			sb.WriteString(softDeleted)
+		}
+	} else if softDeleted != "" {
+		sb.WriteString(" WHERE ")
+		sb.WriteString(softDeleted)
 	}
 
 	if len(q.groups) > 0 && !count {
@@ -1462,55 +2299,129 @@ func (q *SqlQueryAdapter) build(count bool) (string, []any) {
 		sb.WriteString(" ORDER BY ")
 		sb.WriteString(q.orderBy)
 	}
-	if q.limit != nil && !count {
-		sb.WriteString(" LIMIT ")
-		sb.WriteString(fmt.Sprint(*q.limit))
-	}
-	if q.offset != nil && !count {
-		sb.WriteString(" OFFSET ")
-		sb.WriteString(fmt.Sprint(*q.offset))
-	}
-
-	sqlStr := sb.String()
-	if q.flavor == FlavorPostgres {
-		// replace ? with $n
-		var idx int
-		var b strings.Builder
-		for i := 0; i < len(sqlStr); i++ {
-			if sqlStr[i] == '?' {
-				idx++
-				b.WriteString("$")
-				b.WriteString(fmt.Sprint(idx))
-			} else {
-				b.WriteByte(sqlStr[i])
-			}
+	if !count {
+		sb.WriteString(limitClause(q.flavor, q.limit, q.offset))
+		sb.WriteString(q.lock)
+	}
+
+	if q.comment != "" {
+		sb.WriteString(" /* ")
+		sb.WriteString(q.comment)
+		sb.WriteString(" */")
+	}
+
+	return rebind(q.flavor, sb.String()), args
+}
+
+// growOne extends the settable slice by one zero element and returns it
+// along with that element, settable in place.
+func growOne(slice reflect.Value) (reflect.Value, reflect.Value) {
+	n := slice.Len()
+	slice.Grow(1)
+	slice.SetLen(n + 1)
+
+	elem := slice.Index(n)
+	elem.SetZero()
+	return slice, elem
+}
+
+// cachedFieldMap returns buildFieldMap(t), computing it once per type.
+func cachedFieldMap(t reflect.Type) map[string]*modelField {
+	if cached, ok := fieldMapCache.scanCache.Load(t); ok {
+		return cached.(map[string]*modelField)
+	}
+
+	m := buildFieldMap(t)
+	fieldMapCache.scanCache.Store(t, m)
+	return m
+}
+
+// columnFields resolves each result column to its field of the struct type
+// t once per query, including prefix__column fields of nested structs (see
+// nestedField); unmapped columns get nil.
+func columnFields(cols []string, t reflect.Type) []*modelField {
+	fieldMap := cachedFieldMap(t)
+	fields := make([]*modelField, len(cols))
+	for ci, col := range cols {
+		col = normalize(col)
+		if fields[ci] = fieldMap[col]; fields[ci] == nil {
+			fields[ci] = nestedField(t, col)
+		}
+		if fields[ci] == nil {
+			fields[ci] = legacyFields(t)[col]
 		}
-		sqlStr = b.String()
 	}
-	return sqlStr, args
+	return fields
 }
 
-func buildFieldMap(t reflect.Type) map[string]int {
-	m := map[string]int{}
-	for i := 0; i < t.NumField(); i++ {
-		f := t.Field(i)
-		if f.PkgPath != "" {
+// assignColumns copies one scanned row into the struct value dst with the
+// chain's opts, unless a field's tag overrides its empty string policy.
+// Legacy columns of renamed fields go last and only fill fields the new
+// column left zero.
+func assignColumns(dst reflect.Value, fields []*modelField, raw []any, opts assignOpts) error {
+	for ci, f := range fields {
+		if f == nil || f.renamedTo != "" {
 			continue
 		}
-
-		if f.Tag.Get("sql") == "-" {
+		field, ok := fieldByIndex(dst, f.Index, raw[ci] != nil)
+		if !ok {
 			continue
 		}
+		if err := assignColumn(field, f, raw[ci], opts); err != nil {
+			return err
+		}
+	}
 
-		col, _ := parseColumnTag(f)
-		if col == "" {
-			col = toSnake(f.Name)
+	for ci, f := range fields {
+		if f == nil || f.renamedTo == "" || raw[ci] == nil {
+			continue
+		}
+		field, ok := fieldByIndex(dst, f.Index, true)
+		if !ok || !field.IsZero() {
+			continue
+		}
+		if err := assignColumn(field, f, raw[ci], opts); err != nil {
+			return err
 		}
-		m[strings.ToLower(col)] = i
+	}
+	return nil
+}
+
+func assignColumn(field reflect.Value, f *modelField, raw any, opts assignOpts) error {
+	opts.column, opts.timeFormat = f.column, f.timeFormat
+	if f.empty != 0 {
+		opts.keepEmpty = f.empty-1 == EmptyAsValue
+	}
+	return convertAssign(field, raw, opts)
+}
+
+// buildFieldMap maps the lower-cased columns of the struct type t to their
+// fields; see modelFields.
+func buildFieldMap(t reflect.Type) map[string]*modelField {
+	fields := modelFields(t)
+	m := make(map[string]*modelField, len(fields))
+	for i := range fields {
+		m[strings.ToLower(fields[i].column)] = &fields[i]
 	}
 	return m
 }
 
+// isStructDest reports whether dest points at a single model struct, the
+// destinations that report ErrNotFound when no row matches.
+func isStructDest(dest any) bool {
+	t := reflect.TypeOf(dest)
+	return t != nil && t.Kind() == reflect.Ptr && t.Elem().Kind() == reflect.Struct && isStructElem(t.Elem())
+}
+
+// isStructElem reports whether a slice element maps columns onto struct
+// fields (struct or pointer to struct) rather than being a scalar value.
+func isStructElem(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t.Kind() == reflect.Struct && t != reflect.TypeOf(time.Time{}) && !reflect.PointerTo(t).Implements(scannerT)
+}
+
 func parseColumnTag(f reflect.StructField) (string, bool) {
 	extract := func(tag string) (string, bool) {
 		if strings.Contains(tag, columnPrefix) {
@@ -1519,7 +2430,7 @@ func parseColumnTag(f reflect.StructField) (string, bool) {
 					return strings.TrimPrefix(p, columnPrefix), strings.Contains(tag, "primaryKey")
 				}
 			}
-		} else if !strings.Contains(tag, ":") {
+		} else if !strings.Contains(tag, ":") && !slices.ContainsFunc(flagOptions, func(f string) bool { return tagFlag(tag, f) }) {
 			return tag, false
 		}
 		return "", false
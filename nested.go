@@ -0,0 +1,106 @@
+package orm
+
+import (
+	"reflect"
+	"strings"
+)
+
+// nestedSep separates a struct field's prefix from the column in result
+// columns meant for nested structs ("profiles__bio").
+const nestedSep = "__"
+
+// NestedColumns selects the columns of model's table aliased for a nested
+// struct field, so a JOIN fills it in the same Scan:
+//
+//	type User struct {
+//		ID      int64    `sql:"column:id;primaryKey"`
+//		Profile *Profile `sql:"-"`
+//	}
+//
+//	adapter.UseModel(&User{}).
+//		Select(append([]string{"users.*"}, orm.NestedColumns(&Profile{}, "profile")...)).
+//		Join("LEFT JOIN profiles ON profiles.user_id = users.id").
+//		Scan(&users)
+//
+// gives "profiles.id AS profile__id", "profiles.bio AS profile__bio" and so
+// on; see SqlQueryAdapter.Scan for how the prefix picks the field.
+func NestedColumns(model Tabler, prefix string) []string {
+	t := reflect.Indirect(reflect.ValueOf(model)).Type()
+	table := model.TableName()
+
+	fields := modelFields(t)
+	cols := make([]string, len(fields))
+	for i, f := range fields {
+		cols[i] = table + "." + f.column + " AS " + prefix + nestedSep + f.column
+	}
+	return cols
+}
+
+// nestedField resolves a result column of the form prefix__column to a
+// field of a nested struct of t. The prefix names a struct or struct
+// pointer field of t by its column (as sql:"column:..." or the snake-cased
+// field name, even when tagged sql:"-") or by the TableName of its type;
+// the rest is a column of that struct, and may be nested further.
+func nestedField(t reflect.Type, col string) *modelField {
+	prefix, rest, ok := strings.Cut(col, nestedSep)
+	if !ok || prefix == "" || rest == "" {
+		return nil
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		ft := f.Type
+		if ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		if f.PkgPath != "" || ft.Kind() != reflect.Struct || !isStructElem(ft) || !nestedPrefix(f, ft, prefix) {
+			continue
+		}
+
+		inner := cachedFieldMap(ft)[rest]
+		if inner == nil {
+			inner = nestedField(ft, rest)
+		}
+		if inner == nil {
+			continue
+		}
+
+		nf := *inner
+		nf.Index = append([]int{i}, inner.Index...)
+		nf.column = col
+		return &nf
+	}
+	return nil
+}
+
+// nestedPrefix reports whether prefix names the struct field f of type ft.
+func nestedPrefix(f reflect.StructField, ft reflect.Type, prefix string) bool {
+	col, _ := parseColumnTag(f)
+	if col == "" || col == "-" {
+		col = toSnake(f.Name)
+	}
+	if strings.EqualFold(col, prefix) {
+		return true
+	}
+	t, ok := reflect.New(ft).Interface().(Tabler)
+	return ok && strings.EqualFold(t.TableName(), prefix)
+}
+
+// fieldByIndex is reflect.Value.FieldByIndex for nested struct pointers:
+// with alloc, nil pointers on the way are allocated, otherwise it reports
+// false, so a LEFT JOIN without a match leaves the nested struct nil.
+func fieldByIndex(v reflect.Value, index []int, alloc bool) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				if !alloc {
+					return reflect.Value{}, false
+				}
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, true
+}
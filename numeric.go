@@ -0,0 +1,89 @@
+package orm
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"reflect"
+	"strconv"
+
+	"github.com/godev90/validator/faults"
+)
+
+var (
+	errNumericRange = fmt.Errorf("orm: numeric value out of range")
+	ErrNumericRange = faults.New(errNumericRange, &faults.ErrAttr{
+		Code: http.StatusInternalServerError,
+		Messages: []faults.LangPackage{
+			{
+				Tag:     faults.English,
+				Message: "orm: column %q: %v does not fit %s",
+			},
+		},
+	})
+)
+
+// assignOpts tunes convertAssign for one column.
+type assignOpts struct {
+	column    string // named in errors
+	keepEmpty bool   // keep empty text in fields that hold text
+	lossy     bool   // truncate and wrap numbers instead of failing
+	// timeFormat is the epoch format of a time field (see epochFormats)
+	timeFormat string
+	pg         pgDriver // parses array columns; lib/pq's parser when nil
+}
+
+// assignOpts returns the chain's scan settings, for fields without
+// overrides of their own.
+func (q *SqlQueryAdapter) assignOpts() assignOpts {
+	return assignOpts{keepEmpty: q.emptyStrings == EmptyAsValue, lossy: q.lossyNumbers, pg: postgresDriver(q.db)}
+}
+
+// rangeError reports that v doesn't fit the field type t.
+func (o assignOpts) rangeError(v any, t reflect.Type) error {
+	return ErrNumericRange.Render(o.column, v, t)
+}
+
+// AllowLossyNumbers makes this chain scan numbers that don't fit their
+// field the way a Go conversion would: fractions are truncated and values
+// wrap around. By default such values fail with ErrNumericRange; the lossy
+// mode is meant for legacy data already stored that way.
+func (q *SqlQueryAdapter) AllowLossyNumbers() QueryAdapter {
+	cp := q.clone()
+	cp.lossyNumbers = true
+	return cp
+}
+
+// parseNumber parses numeric text as an int64 or, for decimals, exponents
+// and values beyond int64, a float64.
+func parseNumber(s string) (any, error) {
+	i, err := strconv.ParseInt(s, 10, 64)
+	if err == nil {
+		return i, nil
+	}
+	if f, ferr := strconv.ParseFloat(s, 64); ferr == nil {
+		return f, nil
+	}
+	return nil, err
+}
+
+// intFits reports whether the float v is a whole number the integer field
+// can hold.
+func intFits(field reflect.Value, v float64) bool {
+	return v == math.Trunc(v) && v >= math.MinInt64 && v < math.MaxInt64 && !field.OverflowInt(int64(v))
+}
+
+// uintFits is intFits for unsigned fields.
+func uintFits(field reflect.Value, v float64) bool {
+	return v == math.Trunc(v) && v >= 0 && v < math.MaxUint64 && !field.OverflowUint(uint64(v))
+}
+
+// floatExact reports whether the float field holds v without losing
+// precision.
+func floatExact(field reflect.Value, v int64) bool {
+	f := float64(v)
+	if field.Kind() == reflect.Float32 {
+		f = float64(float32(v))
+	}
+	return f >= math.MinInt64 && f < math.MaxInt64 && int64(f) == v
+}
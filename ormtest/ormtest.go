@@ -0,0 +1,168 @@
+// Package ormtest helps downstream code verify that user input reaching an
+// orm builder (sort orders, selected columns, joins, where arguments) can
+// never end up in the generated SQL text.
+//
+// Wrap the code path that turns request input into a query in a build
+// function and hand it to AssertNoInjection from a regular test, or to Fuzz
+// from a fuzz test:
+//
+//	func TestListOrdersSort(t *testing.T) {
+//		ormtest.AssertNoInjection(t, func(input string) orm.QueryAdapter {
+//			return orm.NewSqlAdapter(db).UseModel(&Order{}).Order(input)
+//		})
+//	}
+//
+// The build function must only build; nothing is executed.
+package ormtest
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/godev90/orm"
+)
+
+// Payloads is the seed corpus of injection attempts: tautologies, stacked
+// statements, comments, UNION reads, quoting tricks and time-based probes.
+var Payloads = []string{
+	"' OR '1'='1",
+	"' OR 1=1 --",
+	"\" OR \"\"=\"",
+	"1 OR 1=1",
+	"1; DROP TABLE users",
+	"name; DELETE FROM users",
+	"id DESC; DROP TABLE users --",
+	"id, (SELECT password FROM users LIMIT 1)",
+	"name /* comment */",
+	"name -- comment",
+	"name#",
+	"1 UNION SELECT username, password FROM users",
+	"id UNION ALL SELECT NULL--",
+	"CASE WHEN (1=1) THEN id ELSE name END",
+	"SLEEP(5)",
+	"pg_sleep(5)",
+	"1 AND (SELECT 1 FROM pg_sleep(5)) IS NOT NULL",
+	"WAITFOR DELAY '0:0:5'",
+	"BENCHMARK(1000000,MD5(1))",
+	"`name`; DROP TABLE users",
+	"name`) OR 1=1 -- ",
+	"users u ON 1=1; DROP TABLE users",
+	"JOIN users ON 1=1 UNION SELECT * FROM secrets",
+	"LEFT JOIN users ON users.id = orders.user_id OR 1=1",
+	"id\x00; DROP TABLE users",
+	"id\n; DROP TABLE users",
+	"0x27 OR 1=1",
+	"' || (SELECT version()) || '",
+	"%27 OR %271%27=%271",
+	"name'); INSERT INTO admins VALUES ('x",
+}
+
+// affixes combine each payload with benign-looking surroundings, since
+// validators often only inspect the start or the end of their input.
+var affixes = [][2]string{
+	{"", ""},
+	{"id ", ""},
+	{"id, ", ""},
+	{"id ASC, ", ""},
+	{"", " ASC"},
+	{"", ", name"},
+	{"name = ", ""},
+}
+
+// plainIdentifier matches inputs that may legitimately appear in SQL text:
+// an optionally qualified column, plain or quoted, or a table's wildcard
+// (u.*), with an optional direction, or a list of them.
+var plainIdentifier = func() *regexp.Regexp {
+	part := "(?:[A-Za-z_][A-Za-z0-9_]*|\"[A-Za-z_][A-Za-z0-9_]*\"|`[A-Za-z_][A-Za-z0-9_]*`)"
+	col := part + `(?:\.(?:` + part + `|\*))?(?:\s+(?i:asc|desc))?\s*`
+	return regexp.MustCompile(`^\s*` + col + `(?:,\s*` + col + `)*$`)
+}()
+
+// Inputs returns every payload combined with every affix.
+func Inputs() []string {
+	out := make([]string, 0, len(Payloads)*len(affixes))
+	for _, p := range Payloads {
+		for _, a := range affixes {
+			out = append(out, a[0]+p+a[1])
+		}
+	}
+	return out
+}
+
+// CheckSQL reports whether input leaked into sqlStr, the statement built
+// from it. Input that is a plain column list is allowed through; anything
+// else must have been rejected by the validators or bound as an argument.
+// Besides the whole input, the part after its first quote or semicolon is
+// checked, which catches payloads whose harmless prefix was kept.
+func CheckSQL(sqlStr, input string) error {
+	return checkSQL(sqlStr, "", input)
+}
+
+// checkSQL is CheckSQL for a statement whose builder writes base when given
+// no input: text already in base, such as a quote or a keyword, only leaks
+// when it appears more often.
+func checkSQL(sqlStr, base, input string) error {
+	if strings.TrimSpace(input) == "" || plainIdentifier.MatchString(input) {
+		return nil
+	}
+	added := func(s string) bool {
+		return strings.Count(sqlStr, s) > strings.Count(base, s)
+	}
+	if added(input) {
+		return fmt.Errorf("ormtest: input %q appears verbatim in %q", input, sqlStr)
+	}
+
+	if i := strings.IndexAny(input, "'\";`"); i >= 0 {
+		tail := strings.TrimSpace(input[i+1:])
+		if len(tail) >= 4 && !plainIdentifier.MatchString(tail) && added(tail) {
+			return fmt.Errorf("ormtest: %q from input %q appears in %q", tail, input, sqlStr)
+		}
+	}
+	return nil
+}
+
+// AssertNoInjection builds a query for every input of Inputs and fails t
+// for each one whose text leaks into the SQL, or that makes build panic.
+func AssertNoInjection(t testing.TB, build func(input string) orm.QueryAdapter) {
+	t.Helper()
+	for _, input := range Inputs() {
+		if err := check(build, input); err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+// Fuzz seeds f with Inputs and fuzzes build with them, failing on any
+// input that leaks into the SQL or makes build panic.
+func Fuzz(f *testing.F, build func(input string) orm.QueryAdapter) {
+	for _, input := range Inputs() {
+		f.Add(input)
+	}
+	f.Fuzz(func(t *testing.T, input string) {
+		if err := check(build, input); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
+
+func check(build func(input string) orm.QueryAdapter, input string) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("ormtest: building with input %q panicked: %v", input, p)
+		}
+	}()
+
+	q := build(input)
+	if q == nil {
+		return nil
+	}
+	sqlStr, _ := q.ToSQL()
+
+	var base string
+	if q := build(""); q != nil {
+		base, _ = q.ToSQL()
+	}
+	return checkSQL(sqlStr, base, input)
+}
@@ -0,0 +1,108 @@
+package ormtest
+
+import (
+	"database/sql"
+	"fmt"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/godev90/orm"
+)
+
+type order struct {
+	ID     int64  `sql:"column:id;primaryKey"`
+	UserID int64  `sql:"column:user_id"`
+	Status string `sql:"column:status"`
+}
+
+func (order) TableName() string { return "orders" }
+
+// openDB returns a database the adapters can build against; nothing runs.
+func openDB(t testing.TB) *sql.DB {
+	t.Helper()
+	db, _, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	orm.SetFlavor(db, orm.FlavorPostgres)
+	return db
+}
+
+// builders are the SqlQueryAdapter entry points that take request input.
+// SafeJoin is left out: it checks a join's syntax, not what it joins on, so
+// a request must pick joins through JoinModel instead.
+func builders(db *sql.DB) map[string]func(input string) orm.QueryAdapter {
+	orders := func() orm.QueryAdapter { return orm.NewSqlAdapter(db).UseModel(&order{}) }
+	return map[string]func(input string) orm.QueryAdapter{
+		"SafeOrder":   func(input string) orm.QueryAdapter { return orders().SafeOrder(input) },
+		"SafeSelect":  func(input string) orm.QueryAdapter { return orders().SafeSelect([]string{"id", input}) },
+		"SafeGroupBy": func(input string) orm.QueryAdapter { return orders().SafeGroupBy([]string{input}) },
+		"Where arg":   func(input string) orm.QueryAdapter { return orders().Where("status = ?", input) },
+	}
+}
+
+func TestAssertNoInjection(t *testing.T) {
+	for name, build := range builders(openDB(t)) {
+		t.Run(name, func(t *testing.T) {
+			AssertNoInjection(t, build)
+		})
+	}
+}
+
+// recorder is a testing.TB that counts failures instead of reporting them.
+type recorder struct {
+	testing.TB
+	failures int
+}
+
+func (r *recorder) Helper()           {}
+func (r *recorder) Error(args ...any) { r.failures++ }
+
+func TestAssertNoInjectionCatchesLeaks(t *testing.T) {
+	db := openDB(t)
+	r := &recorder{TB: t}
+	AssertNoInjection(r, func(input string) orm.QueryAdapter {
+		return orm.NewSqlAdapter(db).UseModel(&order{}).UnsafeOrder(input)
+	})
+	if r.failures == 0 {
+		t.Error("AssertNoInjection passed UnsafeOrder, which writes its input into the SQL")
+	}
+
+	r.failures = 0
+	AssertNoInjection(r, func(input string) orm.QueryAdapter { panic(input) })
+	if want := len(Inputs()); r.failures != want {
+		t.Errorf("AssertNoInjection reported %d panicking builds, want %d", r.failures, want)
+	}
+}
+
+func TestCheckSQL(t *testing.T) {
+	tests := []struct {
+		sqlStr, input string
+		leaked        bool
+	}{
+		{`SELECT * FROM "orders" ORDER BY status DESC`, "status DESC", false},
+		{`SELECT * FROM "orders" ORDER BY o.status, "id"`, `o.status, "id"`, false},
+		{`SELECT * FROM "orders" WHERE status = $1`, "' OR '1'='1", false},
+		{`SELECT * FROM "orders" ORDER BY id; DROP TABLE users`, "id; DROP TABLE users", true},
+		{`SELECT * FROM "orders" ORDER BY id; DROP TABLE users`, "id'; DROP TABLE users", true},
+	}
+	for _, tt := range tests {
+		err := CheckSQL(tt.sqlStr, tt.input)
+		if leaked := err != nil; leaked != tt.leaked {
+			t.Errorf("CheckSQL(%q, %q) = %v, want leaked %t", tt.sqlStr, tt.input, err, tt.leaked)
+		}
+	}
+}
+
+func FuzzSafeOrder(f *testing.F) {
+	db := openDB(f)
+	Fuzz(f, func(input string) orm.QueryAdapter {
+		return orm.NewSqlAdapter(db).UseModel(&order{}).SafeOrder(input)
+	})
+}
+
+func ExampleCheckSQL() {
+	fmt.Println(CheckSQL(`SELECT * FROM "orders" ORDER BY id`, "id"))
+	// Output: <nil>
+}
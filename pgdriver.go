@@ -0,0 +1,98 @@
+package orm
+
+import (
+	"database/sql"
+	"errors"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/lib/pq"
+)
+
+// pgDriver is what the native adapter needs from a PostgreSQL driver beyond
+// database/sql: binding a slice as one array parameter, parsing array
+// columns and reading SQLSTATE codes. lib/pq and pgx's stdlib do each
+// differently, so moving a *sql.DB from one to the other doesn't change how
+// queries bind or scan.
+type pgDriver interface {
+	// array wraps the slice v to bind as a single array parameter.
+	array(v any) any
+	// scanArray parses raw, an array column in its text form, into dest, a
+	// pointer to a slice.
+	scanArray(raw any, dest any) error
+	// sqlState returns the SQLSTATE of one of the driver's errors, or "".
+	sqlState(err error) string
+}
+
+// pqDriver is github.com/lib/pq.
+type pqDriver struct{}
+
+func (pqDriver) array(v any) any {
+	return pq.Array(v)
+}
+
+func (pqDriver) scanArray(raw any, dest any) error {
+	return pq.Array(dest).Scan(raw)
+}
+
+func (pqDriver) sqlState(err error) string {
+	var pe *pq.Error
+	if errors.As(err, &pe) {
+		return string(pe.Code)
+	}
+	return ""
+}
+
+// pgxDriver is github.com/jackc/pgx/v5/stdlib, which encodes Go slices
+// itself.
+type pgxDriver struct{}
+
+// pgxTypes holds pgtype.Maps for parsing arrays; a Map caches scan plans
+// and isn't safe for concurrent use.
+var pgxTypes = sync.Pool{New: func() any { return pgtype.NewMap() }}
+
+func (pgxDriver) array(v any) any {
+	return v
+}
+
+func (pgxDriver) scanArray(raw any, dest any) error {
+	m := pgxTypes.Get().(*pgtype.Map)
+	defer pgxTypes.Put(m)
+	return m.SQLScanner(dest).Scan(raw)
+}
+
+func (pgxDriver) sqlState(err error) string {
+	var pe *pgconn.PgError
+	if errors.As(err, &pe) {
+		return pe.Code
+	}
+	return ""
+}
+
+// pgDrivers are tried in turn on errors, whose type tells the driver apart.
+var pgDrivers = []pgDriver{pqDriver{}, pgxDriver{}}
+
+var pgDriverCache sync.Map // reflect.Type of the sql driver -> pgDriver
+
+// postgresDriver returns the pgDriver of db: pgx when its driver is pgx's
+// stdlib, lib/pq otherwise. Builders without a *sql.DB, those of
+// PgxAdapter, get pgx.
+func postgresDriver(db *sql.DB) pgDriver {
+	if db == nil {
+		return pgxDriver{}
+	}
+	t := reflect.TypeOf(db.Driver())
+	if d, ok := pgDriverCache.Load(t); ok {
+		return d.(pgDriver)
+	}
+
+	var d pgDriver = pqDriver{}
+	if name := t.String(); strings.Contains(name, "pgx") || strings.Contains(name, "stdlib") {
+		d = pgxDriver{}
+	}
+	pgDriverCache.Store(t, d)
+	return d
+}
@@ -0,0 +1,425 @@
+package orm
+
+import (
+	"database/sql"
+	"reflect"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/lib/pq"
+)
+
+// PgxAdapter runs queries on a pgxpool.Pool instead of database/sql. Queries
+// are built exactly like SqlQueryAdapter's Postgres flavor; results use the
+// binary protocol and pgx's own decoding, so arrays scan into slices and
+// jsonb into maps or structs without an intermediate []byte.
+//
+// Generated RowScanner methods read *sql.Rows and are not used here, and DB
+// returns nil: the pool is available through Pool.
+type PgxAdapter struct {
+	builtAdapter
+	pool *pgxpool.Pool
+}
+
+func NewPgxAdapter(pool *pgxpool.Pool) QueryAdapter {
+	p := &PgxAdapter{pool: pool}
+	return p.with(newBuilder(FlavorPostgres))
+}
+
+func (p *PgxAdapter) with(b *SqlQueryAdapter) QueryAdapter {
+	cp := &PgxAdapter{pool: p.pool}
+	cp.builtAdapter = builtAdapter{b: b, wrap: cp.with}
+	return cp
+}
+
+// Pool returns the underlying connection pool.
+func (p *PgxAdapter) Pool() *pgxpool.Pool {
+	return p.pool
+}
+
+// PrepareStmt is a no-op: pgx already prepares and caches statements per
+// connection.
+func (p *PgxAdapter) PrepareStmt() QueryAdapter {
+	return p
+}
+
+func (p *PgxAdapter) Driver() driverFlavor {
+	return FlavorPostgres
+}
+
+// DB returns nil: the adapter runs on a pgx pool, not database/sql. Use
+// Pool to drop down to pgx.
+func (p *PgxAdapter) DB() *sql.DB {
+	return nil
+}
+
+// Stats reports the pgx pool in sql.DBStats terms: its maximum, total,
+// acquired and idle connections, the acquires that had to wait for one and
+// how long acquiring took, and the connections destroyed for being idle or
+// old. Pool().Stat() has the rest.
+func (p *PgxAdapter) Stats() sql.DBStats {
+	if p.pool == nil {
+		return sql.DBStats{}
+	}
+	s := p.pool.Stat()
+	return sql.DBStats{
+		MaxOpenConnections: int(s.MaxConns()),
+		OpenConnections:    int(s.TotalConns()),
+		InUse:              int(s.AcquiredConns()),
+		Idle:               int(s.IdleConns()),
+		WaitCount:          s.EmptyAcquireCount(),
+		WaitDuration:       s.AcquireDuration(),
+		MaxIdleTimeClosed:  s.MaxIdleDestroyCount(),
+		MaxLifetimeClosed:  s.MaxLifetimeDestroyCount(),
+	}
+}
+
+// query runs a SELECT. release must be deferred before rows.Close so that
+// it runs after it.
+func (p *PgxAdapter) query(q *SqlQueryAdapter, sqlStr string, args []any) (rows pgx.Rows, release func(), err error) {
+	if p.pool == nil {
+		return nil, nil, ErrNilPointer
+	}
+
+	release = func() {}
+	err = runQuery(q.call(sqlStr, args), func(sqlStr string) error {
+		if q.planCache != "" {
+			rows, release, err = p.queryPlanCache(q.ctx, q.planCache, sqlStr, pgxArgs(args))
+		} else {
+			rows, err = p.pool.Query(q.ctx, sqlStr, pgxArgs(args)...)
+		}
+		return err
+	})
+	return rows, release, err
+}
+
+// pgxArgs hands the arrays Where wraps for lib/pq (= ANY(?)) back to pgx as
+// plain slices, which it encodes natively.
+func pgxArgs(args []any) []any {
+	var out []any
+	for i, a := range args {
+		var v any
+		switch t := a.(type) {
+		case *pq.BoolArray:
+			v = []bool(*t)
+		case *pq.Float64Array:
+			v = []float64(*t)
+		case *pq.Float32Array:
+			v = []float32(*t)
+		case *pq.Int64Array:
+			v = []int64(*t)
+		case *pq.Int32Array:
+			v = []int32(*t)
+		case *pq.StringArray:
+			v = []string(*t)
+		case *pq.ByteaArray:
+			v = [][]byte(*t)
+		case pq.GenericArray:
+			v = t.A
+		default:
+			continue
+		}
+
+		if out == nil {
+			out = append([]any(nil), args...)
+		}
+		out[i] = v
+	}
+
+	if out == nil {
+		return args
+	}
+	return out
+}
+
+func (p *PgxAdapter) Count(target *int64) error {
+	if err := p.b.failure(); err != nil {
+		return err
+	}
+	sqlStr, args := p.b.build(true)
+	if p.b.dryRun {
+		p.b.recorder.record(sqlStr, args)
+		*target = 0
+		return nil
+	}
+
+	rows, release, err := p.query(p.b, sqlStr, args)
+	if err != nil {
+		return err
+	}
+	defer release()
+	defer rows.Close()
+
+	// COUNT always yields a row unless grouped away; no row means zero
+	if !rows.Next() {
+		*target = 0
+		return rows.Err()
+	}
+	return rows.Scan(target)
+}
+
+func (p *PgxAdapter) Scan(dest any) error {
+	q, err := p.b.withDestModel(dest)
+	if err != nil {
+		return err
+	}
+	if q, err = q.narrowTo(dest); err != nil {
+		return err
+	}
+	if err := p.scan(q, dest); err != nil || q.dryRun {
+		return err
+	}
+	if err := filterRows(q.rowFuncs, dest); err != nil {
+		return err
+	}
+	if err := preloadRelations(p, dest, q.preloads); err != nil {
+		return err
+	}
+	return afterFind(q.ctx, dest)
+}
+
+func (p *PgxAdapter) First(dest any) error {
+	q, err := p.b.withDestModel(dest)
+	if err != nil {
+		return err
+	}
+	if q, err = q.narrowTo(dest); err != nil {
+		return err
+	}
+	if q.limit == nil {
+		q = q.Limit(1).(*SqlQueryAdapter)
+	}
+
+	if err := p.scan(q, dest); err != nil {
+		return err
+	}
+
+	if q.dryRun {
+		return nil
+	}
+	if err := filterRows(q.rowFuncs, dest); err != nil {
+		return err
+	}
+	if err := preloadRelations(p, dest, q.preloads); err != nil {
+		return err
+	}
+	if target := reflect.ValueOf(dest).Elem(); target.Kind() == reflect.Slice && target.Len() == 0 {
+		return errRecordNotFound
+	}
+	return afterFind(q.ctx, dest)
+}
+
+func (p *PgxAdapter) scan(q *SqlQueryAdapter, dest any) error {
+	sqlStr, args := q.build(false)
+
+	if q.dryRun {
+		q.recorder.record(sqlStr, args)
+		return nil
+	}
+
+	val := reflect.ValueOf(dest)
+	if val.Kind() != reflect.Ptr || val.IsNil() {
+		return ErrNilPointer
+	}
+
+	rows, release, err := p.query(q, sqlStr, args)
+	if err != nil {
+		return err
+	}
+	defer release()
+	defer rows.Close()
+
+	fds := rows.FieldDescriptions()
+	cols := make([]string, len(fds))
+	for i, fd := range fds {
+		cols[i] = fd.Name
+	}
+
+	if mp, ok := dest.(*[]map[string]any); ok {
+		for rows.Next() {
+			vals, err := rows.Values()
+			if err != nil {
+				return err
+			}
+
+			rec := make(map[string]any, len(cols))
+			for ci, col := range cols {
+				rec[col] = vals[ci]
+			}
+			*mp = append(*mp, rec)
+		}
+		return rows.Err()
+	}
+
+	target := val.Elem()
+	switch target.Kind() {
+	case reflect.Slice:
+		// slices come back empty, never nil, when nothing matches
+		if target.IsNil() {
+			target.Set(reflect.MakeSlice(target.Type(), 0, 0))
+		}
+
+		slice := target
+		elemTyp := slice.Type().Elem()
+		isPtr := elemTyp.Kind() == reflect.Ptr && isStructElem(elemTyp)
+
+		var colFields []*modelField
+		if isStructElem(elemTyp) {
+			structTyp := elemTyp
+			if isPtr {
+				structTyp = elemTyp.Elem()
+			}
+			colFields = columnFields(cols, structTyp)
+		}
+
+		for rows.Next() {
+			var elem reflect.Value
+			slice, elem = growOne(slice)
+
+			if colFields == nil {
+				// slice of scalars (e.g. Pluck): map the first column only
+				if err := scanPgxRow(rows, []reflect.Value{elem}); err != nil {
+					return err
+				}
+				continue
+			}
+
+			if isPtr {
+				elem.Set(reflect.New(elemTyp.Elem()))
+				elem = elem.Elem()
+			}
+			fields, legacy := pgxFields(elem, colFields)
+			if err := scanPgxRow(rows, fields); err != nil {
+				return err
+			}
+			fillLegacy(elem, colFields, legacy)
+		}
+
+		target.Set(slice)
+		return rows.Err()
+
+	case reflect.Struct:
+		if !rows.Next() {
+			if err := rows.Err(); err != nil {
+				return err
+			}
+			return errRecordNotFound
+		}
+
+		colFields := columnFields(cols, target.Type())
+		fields, legacy := pgxFields(target, colFields)
+		if err := scanPgxRow(rows, fields); err != nil {
+			return err
+		}
+		fillLegacy(target, colFields, legacy)
+		rows.Close()
+		return rows.Err()
+	}
+
+	return ErrUnsupported
+}
+
+// pgxFields returns the struct field each result column scans into; an
+// invalid Value skips the column. Legacy columns of renamed fields scan into
+// values of their own, returned in legacy for fillLegacy.
+func pgxFields(dst reflect.Value, colFields []*modelField) (fields, legacy []reflect.Value) {
+	fields = make([]reflect.Value, len(colFields))
+	legacy = make([]reflect.Value, len(colFields))
+	for ci, f := range colFields {
+		if f == nil {
+			continue
+		}
+		if f.renamedTo != "" {
+			legacy[ci] = reflect.New(f.Type).Elem()
+			fields[ci] = legacy[ci]
+		} else {
+			fields[ci], _ = fieldByIndex(dst, f.Index, true)
+		}
+		if f.timeFormat != "" {
+			// pgx won't scan integers into times; convert them ourselves
+			s := &epochScanner{field: fields[ci], opts: assignOpts{column: f.column, timeFormat: f.timeFormat}}
+			fields[ci] = reflect.ValueOf(s).Elem()
+		}
+	}
+	return fields, legacy
+}
+
+// fillLegacy copies the legacy columns pgxFields scanned into the fields
+// their new column left zero.
+func fillLegacy(dst reflect.Value, colFields []*modelField, legacy []reflect.Value) {
+	for ci, v := range legacy {
+		if !v.IsValid() || v.IsZero() {
+			continue
+		}
+		if field, ok := fieldByIndex(dst, colFields[ci].Index, true); ok && field.IsZero() {
+			field.Set(v)
+		}
+	}
+}
+
+// scanPgxRow scans the current row into fields. pgx refuses NULL for plain
+// values, so those go through a **T holder and NULL leaves the zero value,
+// as in the other adapters. Pointers and sql.Scanners take NULL directly.
+func scanPgxRow(rows pgx.Rows, fields []reflect.Value) error {
+	n := len(rows.FieldDescriptions())
+	targets := make([]any, n)
+	holders := make([]reflect.Value, n)
+
+	for i := 0; i < n && i < len(fields); i++ {
+		f := fields[i]
+		if !f.IsValid() {
+			continue
+		}
+
+		if f.Kind() == reflect.Ptr || f.Addr().Type().Implements(scannerT) {
+			targets[i] = f.Addr().Interface()
+			continue
+		}
+		holders[i] = reflect.New(reflect.PointerTo(f.Type()))
+		targets[i] = holders[i].Interface()
+	}
+
+	if err := rows.Scan(targets...); err != nil {
+		return err
+	}
+
+	for i, h := range holders {
+		if h.IsValid() {
+			if v := h.Elem(); !v.IsNil() {
+				fields[i].Set(v.Elem())
+			} else {
+				fields[i].SetZero()
+			}
+		}
+	}
+	return nil
+}
+
+func (p *PgxAdapter) Explain(analyze bool) (string, error) {
+	if err := p.b.failure(); err != nil {
+		return "", err
+	}
+	sqlStr, args := p.b.build(false)
+	query := explainPrefix(FlavorPostgres, analyze) + sqlStr
+
+	rows, release, err := p.query(p.b, query, args)
+	if err != nil {
+		return "", err
+	}
+	defer release()
+	defer rows.Close()
+
+	var lines []string
+	for rows.Next() {
+		var line string
+		if err := rows.Scan(&line); err != nil {
+			return "", err
+		}
+		lines = append(lines, line)
+	}
+	if err := rows.Err(); err != nil {
+		return "", err
+	}
+	return strings.Join(lines, "\n"), nil
+}
@@ -0,0 +1,167 @@
+package orm
+
+import (
+	"context"
+	"database/sql"
+	"log"
+
+	"github.com/jackc/pgx/v5"
+	"gorm.io/gorm"
+)
+
+// PlanCacheMode is a value for Postgres' plan_cache_mode setting.
+type PlanCacheMode string
+
+const (
+	// PlanCacheAuto lets Postgres choose, the server default.
+	PlanCacheAuto PlanCacheMode = "auto"
+	// PlanForceCustom re-plans a prepared statement on every execution with
+	// the actual parameters, for queries whose generic plan is bad for
+	// skewed values.
+	PlanForceCustom PlanCacheMode = "force_custom_plan"
+	// PlanForceGeneric always uses the cached generic plan.
+	PlanForceGeneric PlanCacheMode = "force_generic_plan"
+)
+
+func (m PlanCacheMode) valid() bool {
+	switch m {
+	case PlanCacheAuto, PlanForceCustom, PlanForceGeneric:
+		return true
+	}
+	return false
+}
+
+// setLocal is the statement that scopes the mode to the enclosing
+// transaction; the mode is one of the constants above, never caller text.
+func (m PlanCacheMode) setLocal() string {
+	return "SET LOCAL plan_cache_mode = " + string(m)
+}
+
+const (
+	currentPlanCache = "SELECT current_setting('plan_cache_mode')"
+	restorePlanCache = "SELECT set_config('plan_cache_mode', ?, true)"
+)
+
+// PlanCache runs the query with plan_cache_mode set for it alone. SET LOCAL
+// only lasts for a transaction, so the query runs in a short read-only
+// transaction; in one the adapter already runs in, the mode is set for the
+// query and the previous one put back after it. Other dialects ignore it.
+func (q *SqlQueryAdapter) PlanCache(mode PlanCacheMode) QueryAdapter {
+	if !mode.valid() {
+		log.Printf("WARNING: invalid plan_cache_mode %q", mode)
+		return q
+	}
+
+	cp := q.clone()
+	cp.planCache = mode
+	return cp
+}
+
+func (g *GormAdapter) PlanCache(mode PlanCacheMode) QueryAdapter {
+	if !mode.valid() {
+		log.Printf("WARNING: invalid plan_cache_mode %q", mode)
+		return g
+	}
+
+	cp := g.with(g.db)
+	cp.planCache = mode
+	return cp
+}
+
+// queryPlanCache runs sqlStr on db in a read-only transaction with the plan
+// cache mode applied. release ends the transaction once the rows are closed.
+func (q *SqlQueryAdapter) queryPlanCache(db *sql.DB, sqlStr string, args []any) (*sql.Rows, func(), error) {
+	tx, err := db.BeginTx(q.ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, nil, err
+	}
+	release := func() { _ = tx.Rollback() }
+
+	if _, err := tx.ExecContext(q.ctx, q.planCache.setLocal()); err != nil {
+		release()
+		return nil, nil, err
+	}
+
+	var rows *sql.Rows
+	if q.stmts != nil && db == q.db {
+		var stmt *sql.Stmt
+		if stmt, err = q.stmts.Prepare(q.ctx, sqlStr); err == nil {
+			rows, err = tx.StmtContext(q.ctx, stmt).QueryContext(q.ctx, args...)
+		}
+	} else {
+		rows, err = tx.QueryContext(q.ctx, sqlStr, args...)
+	}
+	if err != nil {
+		release()
+		return nil, nil, err
+	}
+	return rows, release, nil
+}
+
+// setPlanCacheTx applies the plan cache mode in the caller's transaction tx,
+// where SET LOCAL would outlast the query. restore puts the previous mode
+// back; run it once the rows are closed.
+func (q *SqlQueryAdapter) setPlanCacheTx(tx *sql.Tx) (restore func(), err error) {
+	var prev string
+	if err := tx.QueryRowContext(q.ctx, currentPlanCache).Scan(&prev); err != nil {
+		return nil, err
+	}
+	if _, err := tx.ExecContext(q.ctx, q.planCache.setLocal()); err != nil {
+		return nil, err
+	}
+
+	return func() {
+		_, err := tx.ExecContext(context.WithoutCancel(q.ctx), rebind(q.flavor, restorePlanCache), prev)
+		// a failed statement aborted the transaction, setting and all
+		if err != nil && sqlState(err) != "25P02" {
+			log.Printf("WARNING: restoring plan_cache_mode %s: %v", prev, err)
+		}
+	}, nil
+}
+
+// queryPlanCache is the pgx counterpart of SqlQueryAdapter.queryPlanCache.
+func (p *PgxAdapter) queryPlanCache(ctx context.Context, mode PlanCacheMode, sqlStr string, args []any) (pgx.Rows, func(), error) {
+	tx, err := p.pool.BeginTx(ctx, pgx.TxOptions{AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return nil, nil, err
+	}
+	release := func() { _ = tx.Rollback(context.WithoutCancel(ctx)) }
+
+	if _, err := tx.Exec(ctx, mode.setLocal()); err != nil {
+		release()
+		return nil, nil, err
+	}
+
+	rows, err := tx.Query(ctx, sqlStr, args...)
+	if err != nil {
+		release()
+		return nil, nil, err
+	}
+	return rows, release, nil
+}
+
+// planCacheTx runs fn in a transaction with the plan cache mode applied. In
+// the caller's own transaction the previous mode is put back after fn.
+func (g *GormAdapter) planCacheTx(db *gorm.DB, fn func(tx *gorm.DB) error) error {
+	if _, inTx := db.Statement.ConnPool.(gorm.TxCommitter); inTx {
+		raw := db.Session(&gorm.Session{NewDB: true})
+		var prev string
+		if err := raw.Raw(currentPlanCache).Scan(&prev).Error; err != nil {
+			return err
+		}
+		if err := raw.Exec(g.planCache.setLocal()).Error; err != nil {
+			return err
+		}
+		if err := fn(db); err != nil {
+			return err
+		}
+		return raw.Exec(restorePlanCache, prev).Error
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Session(&gorm.Session{NewDB: true}).Exec(g.planCache.setLocal()).Error; err != nil {
+			return err
+		}
+		return fn(tx)
+	}, &sql.TxOptions{ReadOnly: true})
+}
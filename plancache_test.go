@@ -0,0 +1,47 @@
+package orm
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestPlanCacheInTransactionRestoresMode(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	SetFlavor(db, FlavorPostgres)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(currentPlanCache).
+		WillReturnRows(sqlmock.NewRows([]string{"current_setting"}).AddRow("auto"))
+	mock.ExpectExec("SET LOCAL plan_cache_mode = force_custom_plan").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(`SELECT * FROM "orders" WHERE status = $1`).
+		WithArgs("paid").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "status"}).AddRow(1, "paid"))
+	mock.ExpectExec("SELECT set_config('plan_cache_mode', $1, true)").
+		WithArgs("auto").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	var got []batchOrder
+	err = WithTransaction(context.Background(), db, func(tx *SqlTransactionAdapter) error {
+		return tx.Query().(*SqlQueryAdapter).PlanCache(PlanForceCustom).
+			UseModel(&batchOrder{}).
+			Where("status = ?", "paid").
+			Scan(&got)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 {
+		t.Errorf("got %d rows, want 1", len(got))
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,565 @@
+package orm
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// ColumnUse is the role a user-supplied column plays in a query.
+type ColumnUse string
+
+const (
+	ColumnFilter ColumnUse = "filter"
+	ColumnOrder  ColumnUse = "order"
+	ColumnSelect ColumnUse = "select"
+)
+
+// ColumnPolicy is implemented by models that restrict which of their
+// columns user input may reference, beyond the column existing, e.g. to
+// keep sorting to indexed columns or secrets out of dynamic selects.
+// AllowColumn returns an error saying why column can't be used.
+type ColumnPolicy interface {
+	AllowColumn(use ColumnUse, column string) error
+}
+
+// ErrColumnNotAllowed is returned when a user-supplied column is refused
+// by its model's ColumnPolicy, names a table the query doesn't join, or,
+// with RequireKnownColumns, isn't mapped by the model.
+var ErrColumnNotAllowed = errors.New("orm: column not allowed")
+
+// requireKnownColumns is set by RequireKnownColumns.
+var requireKnownColumns atomic.Bool
+
+// RequireKnownColumns makes column policies also refuse columns the model
+// doesn't map, and columns of joined tables no model is known for. It is
+// off by default: orders and filters may name select aliases or columns
+// of sub-selects, which no model describes.
+func RequireKnownColumns(require bool) {
+	requireKnownColumns.Store(require)
+}
+
+// tableModels maps table names to the models seen for them by UseModel,
+// JoinModel, Joins and RegisterModels, so columns qualified with a joined
+// table are checked against that table's policy.
+var tableModels sync.Map
+
+// rememberModel records model for its table. A model implementing
+// ColumnPolicy replaces one that doesn't, e.g. a result struct scanning
+// the same table.
+func rememberModel(model Tabler) {
+	if model == nil {
+		return
+	}
+	table := strings.ToLower(model.TableName())
+	if known, loaded := tableModels.LoadOrStore(table, model); loaded {
+		if _, ok := known.(ColumnPolicy); !ok {
+			if _, ok := model.(ColumnPolicy); ok {
+				tableModels.Store(table, model)
+			}
+		}
+	}
+}
+
+func modelForTable(table string) Tabler {
+	if m, ok := tableModels.Load(strings.ToLower(table)); ok {
+		return m.(Tabler)
+	}
+	return nil
+}
+
+// modelPolicy is the policy returned by ModelColumnPolicy.
+type modelPolicy struct {
+	model Tabler
+	typ   reflect.Type
+}
+
+// ModelColumnPolicy returns the policy for columns of model taken from user
+// input: when the model implements ColumnPolicy the column must be allowed
+// by it and, with RequireKnownColumns, exist on the model. Columns
+// qualified with another table are refused; queries resolve those against
+// the models they join. Request binders building filters should call it
+// for every column they accept.
+func ModelColumnPolicy(model Tabler) ColumnPolicy {
+	t := reflect.TypeOf(model)
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return modelPolicy{model: model, typ: t}
+}
+
+func (p modelPolicy) AllowColumn(use ColumnUse, column string) error {
+	if p.typ == nil || p.typ.Kind() != reflect.Struct {
+		return nil
+	}
+
+	col := column
+	if table, name, ok := splitQualified(column); ok {
+		if !strings.EqualFold(table, p.model.TableName()) {
+			return fmt.Errorf("%w: %s is not a column of %s", ErrColumnNotAllowed, column, p.model.TableName())
+		}
+		col = name
+	}
+
+	if requireKnownColumns.Load() && !hasColumn(p.typ, col) {
+		return fmt.Errorf("%w: %s has no column %s", ErrColumnNotAllowed, p.typ.Name(), col)
+	}
+	if custom, ok := p.model.(ColumnPolicy); ok {
+		if err := custom.AllowColumn(use, col); err != nil {
+			return fmt.Errorf("%w: %s as %s: %v", ErrColumnNotAllowed, col, use, err)
+		}
+	}
+	return nil
+}
+
+// splitQualified splits a possibly quoted column reference into the table
+// it is qualified with, schema left out, and the column. ok is false for
+// unqualified columns, which are returned unquoted as column.
+func splitQualified(ref string) (table, column string, ok bool) {
+	i := strings.LastIndexByte(ref, '.')
+	if i < 0 {
+		return "", strings.Trim(ref, "`\""), false
+	}
+	table = ref[:i]
+	if j := strings.LastIndexByte(table, '.'); j >= 0 {
+		table = table[j+1:]
+	}
+	return strings.Trim(table, "`\""), strings.Trim(ref[i+1:], "`\""), true
+}
+
+// hasColumn reports whether the struct type t maps column, through sql
+// tags or, for gorm models, gorm column tags.
+func hasColumn(t reflect.Type, column string) bool {
+	if _, ok := cachedFieldMap(t)[strings.ToLower(column)]; ok {
+		return true
+	}
+	for i := 0; i < t.NumField(); i++ {
+		if c := extractColumnFromTag(t.Field(i).Tag.Get("gorm"), columnTagPrefix); strings.EqualFold(c, column) {
+			return true
+		}
+	}
+	return false
+}
+
+// columnScope resolves the columns a chain is given to the policies of the
+// models they belong to: unqualified ones and those qualified with the
+// chain's table to its model, the others to the table a join of the chain
+// introduces under that name.
+type columnScope struct {
+	model  Tabler
+	table  string   // the chain's table, prefix included
+	prefix string   // see WithTablePrefix
+	joins  []string // the chain's JOIN clauses
+}
+
+func (s columnScope) allow(use ColumnUse, column string) error {
+	qualifier, col, ok := splitQualified(column)
+	if !ok || s.ownTable(qualifier) {
+		if s.model == nil {
+			return nil
+		}
+		return ModelColumnPolicy(s.model).AllowColumn(use, col)
+	}
+
+	table, joined := joinedTables(s.joins)[strings.ToLower(qualifier)]
+	if !joined {
+		return fmt.Errorf("%w: %s: the query has no table %s", ErrColumnNotAllowed, column, qualifier)
+	}
+	model := modelForTable(strings.TrimPrefix(table, strings.ToLower(s.prefix)))
+	if model == nil {
+		if requireKnownColumns.Load() {
+			return fmt.Errorf("%w: %s: no model is known for %s", ErrColumnNotAllowed, column, qualifier)
+		}
+		return nil
+	}
+	return ModelColumnPolicy(model).AllowColumn(use, col)
+}
+
+// guarded reports whether conditions need checking in scope: a model of
+// the query implements ColumnPolicy, or RequireKnownColumns is on.
+// Otherwise filters are left alone, whatever tables they name.
+func (s columnScope) guarded() bool {
+	if requireKnownColumns.Load() {
+		return true
+	}
+	if _, ok := s.model.(ColumnPolicy); ok {
+		return true
+	}
+	for _, table := range joinedTables(s.joins) {
+		if _, ok := modelForTable(strings.TrimPrefix(table, strings.ToLower(s.prefix))).(ColumnPolicy); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (s columnScope) ownTable(name string) bool {
+	if s.table != "" && strings.EqualFold(name, s.table) {
+		return true
+	}
+	return s.model != nil && strings.EqualFold(name, s.model.TableName())
+}
+
+// joinedTables maps the aliases and table names the JOIN clauses introduce,
+// lower-cased, to the table joined; sub-selects and gorm association joins
+// map to "".
+func joinedTables(joins []string) map[string]string {
+	tables := map[string]string{}
+	for _, clause := range joins {
+		toks := refTokens(clause)
+		if len(toks) == 1 && isRefName(toks[0]) {
+			// a gorm association, aliased after its name
+			tables[strings.ToLower(strings.Trim(toks[0], "`\""))] = ""
+			continue
+		}
+		for i := 0; i+1 < len(toks); i++ {
+			if !strings.EqualFold(toks[i], "JOIN") {
+				continue
+			}
+			if i++; strings.EqualFold(toks[i], "LATERAL") && i+1 < len(toks) {
+				i++
+			}
+			table := ""
+			if toks[i] == "(" {
+				for depth := 0; i < len(toks); i++ {
+					if toks[i] == "(" {
+						depth++
+					} else if toks[i] == ")" {
+						if depth--; depth == 0 {
+							break
+						}
+					}
+				}
+			} else if isRefName(toks[i]) {
+				_, table, _ = splitQualified("." + toks[i])
+				table = strings.ToLower(table)
+				tables[table] = table
+			}
+			if i+1 < len(toks) && strings.EqualFold(toks[i+1], "AS") {
+				i++
+			}
+			if i+1 < len(toks) && isRefName(toks[i+1]) && !exprKeywords[strings.ToUpper(toks[i+1])] {
+				i++
+				tables[strings.ToLower(strings.Trim(toks[i], "`\""))] = table
+			}
+		}
+	}
+	return tables
+}
+
+// refTokens splits a raw SQL fragment into names, qualified and quoted ones
+// whole, numbers, :: and single punctuation characters. String literals and
+// placeholders are left out.
+func refTokens(s string) []string {
+	var toks []string
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '\'':
+			for i++; i < len(s); i++ {
+				if s[i] == '\'' {
+					if i+1 < len(s) && s[i+1] == '\'' {
+						i++
+						continue
+					}
+					break
+				}
+			}
+			i++
+		case c == ':' && i+1 < len(s) && s[i+1] == ':':
+			toks = append(toks, "::")
+			i += 2
+		case c == ':' || c == '@' || c == '$':
+			// a named or numbered placeholder
+			for i++; i < len(s) && isWordByte(s[i]); i++ {
+			}
+		case isWordByte(c) || c == '"' || c == '`':
+			start := i
+			for i < len(s) && (isWordByte(s[i]) || s[i] == '.' || s[i] == '"' || s[i] == '`') {
+				if q := s[i]; q == '"' || q == '`' {
+					if end := strings.IndexByte(s[i+1:], q); end >= 0 {
+						i += end + 1
+					}
+				}
+				i++
+			}
+			toks = append(toks, s[start:i])
+		default:
+			toks = append(toks, s[i:i+1])
+			i++
+		}
+	}
+	return toks
+}
+
+// isRefName reports whether a refTokens token is a name rather than a
+// number or punctuation.
+func isRefName(tok string) bool {
+	return tok[0] == '"' || tok[0] == '`' || isIdentStart(tok[0])
+}
+
+// conditionKeywords are the words of conditions that aren't columns, on
+// top of exprKeywords.
+var conditionKeywords = map[string]bool{
+	"ANY": true, "ALL": true, "SOME": true, "ESCAPE": true, "COLLATE": true,
+	"SIMILAR": true, "TO": true, "REGEXP": true, "RLIKE": true, "GLOB": true,
+	"DIV": true, "MOD": true, "XOR": true, "UNKNOWN": true, "SYMMETRIC": true,
+	"ASC": true, "DESC": true, "NULLS": true, "FIRST": true, "LAST": true,
+	"INTERVAL": true, "DATE": true, "TIME": true, "TIMESTAMP": true,
+	"AT": true, "ZONE": true, "YEAR": true, "MONTH": true, "WEEK": true,
+	"DAY": true, "HOUR": true, "MINUTE": true, "SECOND": true, "EPOCH": true,
+	"CURRENT_DATE": true, "CURRENT_TIME": true, "CURRENT_TIMESTAMP": true,
+	"LOCALTIME": true, "LOCALTIMESTAMP": true, "CURRENT_USER": true,
+	"OVER": true, "PARTITION": true, "FILTER": true, "WITHIN": true,
+	"OFFSET": true, "FETCH": true, "NEXT": true, "ROWS": true, "ONLY": true,
+}
+
+// conditionColumns returns the columns a WHERE or HAVING condition
+// references: the names, qualified or not, that aren't keywords, functions,
+// cast types or the tables of its sub-selects. Columns qualified with a
+// sub-select's own tables are left out.
+func conditionColumns(cond string) []string {
+	var cols []string
+	local := map[string]bool{}
+	toks := refTokens(cond)
+	prev, selects := "", 0
+	for i, tok := range toks {
+		if !isRefName(tok) {
+			switch {
+			case tok == "::":
+				prev = "::"
+			case tok == "," && prev == "table":
+				prev = "FROM" // FROM a, b
+			default:
+				prev = ""
+			}
+			continue
+		}
+
+		up := strings.ToUpper(tok)
+		switch {
+		case exprKeywords[up] || conditionKeywords[up]:
+			if up == "SELECT" {
+				selects++
+			}
+			if up == "FROM" && (prev == "DISTINCT" || selects == 0) {
+				up = "" // IS DISTINCT FROM, EXTRACT(YEAR FROM col)
+			}
+			prev = up
+		case i+1 < len(toks) && toks[i+1] == "(":
+			prev = "" // a function
+		case prev == "FROM" || prev == "JOIN":
+			_, table, _ := splitQualified("." + tok)
+			local[strings.ToLower(table)] = true
+			prev = "table"
+		case prev == "table" || prev == "AS":
+			local[strings.ToLower(strings.Trim(tok, "`\""))] = true
+			prev = ""
+		case prev == "::":
+			prev = ""
+		default:
+			cols = append(cols, tok)
+			prev = ""
+		}
+	}
+
+	return slices.DeleteFunc(cols, func(col string) bool {
+		table, _, ok := splitQualified(col)
+		return ok && local[strings.ToLower(table)]
+	})
+}
+
+// checkOrderPolicy applies the column policies of scope to each column of
+// a validated ORDER BY clause.
+func checkOrderPolicy(scope columnScope, order string) error {
+	for _, item := range strings.Split(order, ",") {
+		if fields := strings.Fields(item); len(fields) > 0 {
+			if err := scope.allow(ColumnOrder, fields[0]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// checkSelectPolicy applies the column policies of scope to sanitized
+// select fields; wildcards are left alone.
+func checkSelectPolicy(scope columnScope, fields []string) error {
+	for _, f := range fields {
+		if f == "*" || strings.HasSuffix(f, ".*") {
+			continue
+		}
+		if err := scope.allow(ColumnSelect, f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkFilterPolicy applies the column policies of scope to the columns of
+// a WHERE or HAVING condition: those of a SQL string, or the keys of a
+// gorm map condition. Other conditions, such as sub-queries whose own
+// conditions were checked, are left alone.
+func checkFilterPolicy(scope columnScope, cond any) error {
+	var cols []string
+	switch c := cond.(type) {
+	case string:
+		cols = conditionColumns(c)
+	case map[string]any:
+		for col := range c {
+			cols = append(cols, col)
+		}
+	}
+	for _, col := range cols {
+		if err := scope.allow(ColumnFilter, col); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// policyFilter is a WHERE or HAVING condition given to a chain. Its columns
+// are checked when the chain runs, once all its joins are known, so a
+// condition may name a table joined after it.
+type policyFilter struct {
+	what string // the clause, for the error
+	cond any
+}
+
+// checkFilters applies the column policies of scope to filters, returning
+// the first refused one as an ErrInvalidClause.
+func checkFilters(scope columnScope, filters []policyFilter) error {
+	if len(filters) == 0 || !scope.guarded() {
+		return nil
+	}
+	for _, f := range filters {
+		if err := checkFilterPolicy(scope, f.cond); err != nil {
+			return clauseError(f.what, err)
+		}
+	}
+	return nil
+}
+
+// trustedFilterer is implemented by the adapters for conditions the
+// library builds itself from keys and tags, which column policies don't
+// apply to.
+type trustedFilterer interface {
+	trustedWhere(cond any, args ...any) QueryAdapter
+}
+
+// whereTrusted adds a condition built by the library to q, bypassing the
+// column policies Where applies.
+func whereTrusted(q QueryAdapter, cond any, args ...any) QueryAdapter {
+	if t, ok := q.(trustedFilterer); ok {
+		return t.trustedWhere(cond, args...)
+	}
+	return q.Where(cond, args...)
+}
+
+// OrderByJSONField returns the ORDER BY clause sorting model by the field
+// whose json name is jsonName, e.g. a ?sort=createdAt query parameter:
+//
+//	order, err := orm.OrderByJSONField(r.URL.Query().Get("sort"), desc, &User{})
+//	if err != nil {
+//		return err // errors.Is(err, orm.ErrColumnNotAllowed)
+//	}
+//	q = q.Order(order) // "created_at DESC"
+//
+// The name is resolved through the sql tags of model
+// (DefaultSqlTablerAllowedFields), or its gorm tags, to the column; names
+// the model doesn't map, or that its ColumnPolicy refuses for sorting, are
+// rejected.
+func OrderByJSONField(jsonName string, desc bool, model Tabler) (string, error) {
+	column, ok := CachedSqlTablerAllowedFields(model)[jsonName]
+	if !ok {
+		column, ok = CachedGormTablerAllowedFields(model)[jsonName]
+	}
+	if !ok || jsonName == "" {
+		return "", fmt.Errorf("%w: %s has no field %q", ErrColumnNotAllowed, reflect.Indirect(reflect.ValueOf(model)).Type().Name(), jsonName)
+	}
+
+	order := column + " ASC"
+	if desc {
+		order = column + " DESC"
+	}
+	if err := ValidateOrderBy(order); err != nil {
+		return "", err
+	}
+	if err := checkOrderPolicy(columnScope{model: model}, order); err != nil {
+		return "", err
+	}
+	return order, nil
+}
+
+// columnScope returns the scope resolving the columns q is given.
+func (q *SqlQueryAdapter) columnScope() columnScope {
+	return columnScope{model: q.model, table: q.table, prefix: q.tablePrefix, joins: q.joins}
+}
+
+// filter returns q with cond to be checked against the column policies when
+// it runs, unless it comes from a default scope. Conditions of a
+// sub-query adapter are carried over.
+func (q *SqlQueryAdapter) filter(what string, cond any) *SqlQueryAdapter {
+	if q.trusted {
+		return q
+	}
+	cp := q.clone()
+	if sub, ok := cond.(*SqlQueryAdapter); ok {
+		cp.filters = append(cp.filters, sub.filters...)
+	} else {
+		cp.filters = append(cp.filters, policyFilter{what: what, cond: cond})
+	}
+	return cp
+}
+
+// failure returns what fails the chain before it runs: a clause a strict
+// chain rejected, or a condition its column policies refuse.
+func (q *SqlQueryAdapter) failure() error {
+	if q.err != nil {
+		return q.err
+	}
+	return checkFilters(q.columnScope(), q.filters)
+}
+
+// columnScope returns the scope resolving the columns g is given.
+func (g *GormAdapter) columnScope() columnScope {
+	scope := columnScope{model: g.model, table: g.db.Statement.Table, prefix: g.tablePrefix}
+	if g.model != nil {
+		scope.table = g.tablePrefix + g.model.TableName()
+	}
+	for _, j := range g.db.Statement.Joins {
+		scope.joins = append(scope.joins, j.Name)
+	}
+	return scope
+}
+
+// filter returns g with cond to be checked against the column policies when
+// it runs; see SqlQueryAdapter.filter.
+func (g *GormAdapter) filter(what string, cond any) *GormAdapter {
+	if g.trusted {
+		return g
+	}
+	cp := g.with(g.db)
+	cp.filters = append([]policyFilter(nil), g.filters...)
+	if sub, ok := cond.(*GormAdapter); ok {
+		cp.filters = append(cp.filters, sub.filters...)
+	} else {
+		cp.filters = append(cp.filters, policyFilter{what: what, cond: cond})
+	}
+	return cp
+}
+
+// failure returns what fails the chain before it runs; see
+// SqlQueryAdapter.failure.
+func (g *GormAdapter) failure() error {
+	if g.err != nil {
+		return g.err
+	}
+	return checkFilters(g.columnScope(), g.filters)
+}
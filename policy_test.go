@@ -0,0 +1,208 @@
+package orm
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+type policyAccount struct {
+	ID           int64  `sql:"column:id;primaryKey"`
+	Email        string `sql:"column:email"`
+	PasswordHash string `sql:"column:password_hash"`
+}
+
+func (policyAccount) TableName() string { return "accounts" }
+
+func (policyAccount) AllowColumn(use ColumnUse, column string) error {
+	switch {
+	case column == "password_hash":
+		return errors.New("never exposed")
+	case use == ColumnOrder && column != "id" && column != "email":
+		return errors.New("not indexed")
+	}
+	return nil
+}
+
+type policyPurchase struct {
+	ID        int64 `sql:"column:id;primaryKey"`
+	AccountID int64 `sql:"column:account_id"`
+	Total     int64 `sql:"column:total"`
+}
+
+func (policyPurchase) TableName() string { return "purchases" }
+
+func TestConditionColumns(t *testing.T) {
+	tests := []struct {
+		cond string
+		want []string
+	}{
+		{"LOWER(email) = ? AND a.password_hash IS NOT NULL", []string{"email", "a.password_hash"}},
+		{"status IN ('a', 'it''s') OR created_at::date = $1", []string{"status", "created_at"}},
+		{"id IN (SELECT p.account_id FROM purchases p WHERE p.total > ?)", []string{"id"}},
+		{"email IS DISTINCT FROM backup_email", []string{"email", "backup_email"}},
+		{"EXTRACT(YEAR FROM created_at) = ?", []string{"created_at"}},
+		{`"accounts"."email" = :email AND total > @min`, []string{`"accounts"."email"`, "total"}},
+	}
+	for _, tt := range tests {
+		if got := conditionColumns(tt.cond); !slices.Equal(got, tt.want) {
+			t.Errorf("conditionColumns(%q) = %q, want %q", tt.cond, got, tt.want)
+		}
+	}
+}
+
+func TestColumnPolicyFilters(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	SetFlavor(db, FlavorPostgres)
+
+	q := NewSqlAdapter(db).UseModel(&policyAccount{})
+	refused := map[string]QueryAdapter{
+		"Where":          q.Where("password_hash = ?", "x"),
+		"Where function": q.Where("LOWER(password_hash) = ?", "x"),
+		"Or":             q.Where("id = ?", 1).Or("? = accounts.password_hash", "x"),
+		"Having":         q.GroupBy([]string{"email"}).Having([]string{"MAX(password_hash) > ?"}, "x"),
+	}
+	for name, chain := range refused {
+		var got []policyAccount
+		err := chain.Scan(&got)
+		if !errors.Is(err, ErrColumnNotAllowed) || !errors.Is(err, ErrInvalidClause) {
+			t.Errorf("%s: Scan = %v, want ErrColumnNotAllowed", name, err)
+		}
+	}
+
+	// default scopes are written by the application, not taken from requests
+	mock.ExpectQuery(`SELECT * FROM "accounts" WHERE email = $1 AND password_hash <> ''`).
+		WithArgs("a@example.com").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "email"}).AddRow(1, "a@example.com"))
+	var got []policyAccount
+	err = q.WithDefaultScope(func(q QueryAdapter) QueryAdapter {
+		return q.Where("password_hash <> ''")
+	}).Where("email = ?", "a@example.com").Scan(&got)
+	if err != nil || len(got) != 1 {
+		t.Errorf("default scope: Scan = %v, %d rows; want 1 row", err, len(got))
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestColumnPolicyQualifiedColumns(t *testing.T) {
+	db, _, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	SetFlavor(db, FlavorPostgres)
+
+	q := NewSqlAdapter(db).StrictValidation().UseModel(&policyPurchase{}).
+		JoinModel(&policyAccount{}, On(Col(&policyPurchase{}, "account_id"), Col(&policyAccount{}, "id")))
+	aliased := NewSqlAdapter(db).StrictValidation().UseModel(&policyPurchase{}).
+		UnsafeJoin("JOIN accounts a ON a.id = purchases.account_id")
+
+	tests := []struct {
+		name    string
+		chain   QueryAdapter
+		allowed bool
+	}{
+		{"order by joined column", q.SafeOrder("accounts.email DESC"), true},
+		{"order by refused joined column", q.SafeOrder("accounts.password_hash"), false},
+		{"order by alias", aliased.SafeOrder("a.email"), true},
+		{"order by refused aliased column", aliased.SafeOrder("a.password_hash"), false},
+		{"filter on aliased column", aliased.Where("a.password_hash = ?", "x"), false},
+		{"select aliased column", aliased.SafeSelect([]string{"purchases.id", "a.password_hash"}), false},
+		{"table not in the query", q.SafeOrder("x.secret"), false},
+		{"own table", q.SafeOrder("purchases.total"), true},
+	}
+	for _, tt := range tests {
+		err := tt.chain.Error()
+		if tt.allowed && err != nil {
+			t.Errorf("%s: %v, want nil", tt.name, err)
+		}
+		if !tt.allowed && !errors.Is(err, ErrColumnNotAllowed) {
+			t.Errorf("%s: %v, want ErrColumnNotAllowed", tt.name, err)
+		}
+	}
+
+	if err := ModelColumnPolicy(&policyAccount{}).AllowColumn(ColumnOrder, "x.id"); !errors.Is(err, ErrColumnNotAllowed) {
+		t.Errorf("ModelColumnPolicy on another table's column = %v, want ErrColumnNotAllowed", err)
+	}
+}
+
+func TestRequireKnownColumns(t *testing.T) {
+	db, _, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	q := NewSqlAdapter(db).StrictValidation().UseModel(&policyPurchase{}).
+		UnsafeJoin("JOIN (SELECT account_id, COUNT(*) AS n FROM purchases GROUP BY account_id) c ON c.account_id = purchases.account_id")
+	chains := map[string]func() QueryAdapter{
+		"unmapped column":     func() QueryAdapter { return q.SafeOrder("total_cents") },
+		"sub-select's column": func() QueryAdapter { return q.SafeOrder("c.n DESC") },
+	}
+
+	for name, chain := range chains {
+		if err := chain().Error(); err != nil {
+			t.Errorf("%s: %v, want nil by default", name, err)
+		}
+	}
+
+	RequireKnownColumns(true)
+	defer RequireKnownColumns(false)
+	for name, chain := range chains {
+		if err := chain().Error(); !errors.Is(err, ErrColumnNotAllowed) {
+			t.Errorf("%s with RequireKnownColumns: %v, want ErrColumnNotAllowed", name, err)
+		}
+	}
+}
+
+type policyOrder struct {
+	ID     int64 `sql:"column:id;primaryKey"`
+	UserID int64 `sql:"column:user_id"`
+}
+
+func (policyOrder) TableName() string { return "orders" }
+
+func TestColumnPolicyFiltersAtBuildTime(t *testing.T) {
+	db, _, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	q := NewSqlAdapter(db)
+	tests := []struct {
+		name    string
+		chain   QueryAdapter
+		allowed bool
+	}{
+		// without a ColumnPolicy in the query, filters are not checked
+		{"no model", q.Where("users.id = ?", 1), true},
+		{"where before its join", q.UseModel(&policyOrder{}).Where("users.active = ?", true).
+			Join("JOIN users ON users.id = orders.user_id"), true},
+		{"table never joined", q.UseModel(&policyOrder{}).Where("users.active = ?", true), true},
+
+		// a policy of a joined model applies once the join is added
+		{"refused column joined later", q.UseModel(&policyPurchase{}).Where("accounts.password_hash = ?", "x").
+			JoinModel(&policyAccount{}, On(Col(&policyPurchase{}, "account_id"), Col(&policyAccount{}, "id"))), false},
+		{"allowed column joined later", q.UseModel(&policyPurchase{}).Where("accounts.email = ?", "a@example.com").
+			JoinModel(&policyAccount{}, On(Col(&policyPurchase{}, "account_id"), Col(&policyAccount{}, "id"))), true},
+		{"policy model, table never joined", q.UseModel(&policyAccount{}).Where("x.secret = ?", 1), false},
+	}
+	for _, tt := range tests {
+		err := tt.chain.Error()
+		if tt.allowed && err != nil {
+			t.Errorf("%s: %v, want nil", tt.name, err)
+		}
+		if !tt.allowed && !errors.Is(err, ErrColumnNotAllowed) {
+			t.Errorf("%s: %v, want ErrColumnNotAllowed", tt.name, err)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package orm
+
+import (
+	"log"
+
+	"gorm.io/gorm"
+)
+
+// validTablePrefix reports whether prefix can go in front of table names:
+// a schema ("analytics.") or a name prefix ("app_").
+func validTablePrefix(prefix string) bool {
+	return prefix == "" || plainIdent.MatchString(prefix+"t")
+}
+
+// WithTablePrefix puts prefix, a schema ("analytics.") or a name prefix
+// ("app_"), in front of the TableName of the adapter's models and of
+// JoinModel targets. Raw Join clauses are used as written. An invalid
+// prefix is logged and ignored.
+func (q *SqlQueryAdapter) WithTablePrefix(prefix string) QueryAdapter {
+	if !validTablePrefix(prefix) {
+		log.Printf("WARNING: invalid table prefix %q", prefix)
+		return q
+	}
+
+	cp := q.clone()
+	cp.tablePrefix = prefix
+	if cp.model != nil {
+		cp.table = prefix + cp.model.TableName()
+	}
+	return cp
+}
+
+func (a builtAdapter) WithTablePrefix(prefix string) QueryAdapter {
+	return a.rewrap(a.b.WithTablePrefix(prefix))
+}
+
+// WithTablePrefix is SqlQueryAdapter.WithTablePrefix for gorm; it sets the
+// statement's table, which takes precedence over gorm's naming strategy.
+func (g *GormAdapter) WithTablePrefix(prefix string) QueryAdapter {
+	if !validTablePrefix(prefix) {
+		log.Printf("WARNING: invalid table prefix %q", prefix)
+		return g
+	}
+
+	cp := g.with(g.db)
+	cp.tablePrefix = prefix
+	if cp.model != nil {
+		cp.db = cp.db.Table(prefix + cp.model.TableName())
+	}
+	return cp
+}
+
+// withTable names the table of dest's model on the statement when a prefix
+// is set and no model was given, as gorm would otherwise derive the bare
+// name from dest.
+func (g *GormAdapter) withTable(dest any) *GormAdapter {
+	if g.tablePrefix == "" || g.model != nil || g.db.Statement.Table != "" {
+		return g
+	}
+	t, ok := tablerFromDest(dest)
+	if !ok {
+		return g
+	}
+	return g.with(g.db.Session(&gorm.Session{}).Table(g.tablePrefix + t.TableName()))
+}
+
+// UseTablePrefix makes the transaction's writes, and queries from Query,
+// use prefix in front of table names (see SqlQueryAdapter.WithTablePrefix),
+// and returns q. An invalid prefix is logged and ignored.
+func (q *SqlTransactionAdapter) UseTablePrefix(prefix string) *SqlTransactionAdapter {
+	if !validTablePrefix(prefix) {
+		log.Printf("WARNING: invalid table prefix %q", prefix)
+		return q
+	}
+	q.tablePrefix = prefix
+	return q
+}
+
+// tableName is the table the transaction writes m to.
+func (q *SqlTransactionAdapter) tableName(m Tabler) string {
+	return q.tablePrefix + m.TableName()
+}
@@ -0,0 +1,144 @@
+package orm
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// QueryStats accumulates the statements run on behalf of one request: how
+// many, the database time they took and the slowest of them, to spot
+// chatty endpoints. Attach it with WithQueryStats, or let
+// QueryStatsMiddleware do it.
+type QueryStats struct {
+	mu         sync.Mutex
+	queries    int
+	elapsed    time.Duration
+	slowest    time.Duration
+	slowestSQL string
+}
+
+// QuerySummary is a snapshot of QueryStats.
+type QuerySummary struct {
+	Queries    int
+	Elapsed    time.Duration // cumulative database time
+	Slowest    time.Duration
+	SlowestSQL string // with placeholders, not values
+}
+
+func (s QuerySummary) String() string {
+	if s.Queries == 0 {
+		return "0 queries"
+	}
+	return fmt.Sprintf("%d queries in %s, slowest %s: %s", s.Queries, s.Elapsed, s.Slowest, s.SlowestSQL)
+}
+
+type statsCtxKey struct{}
+
+// WithQueryStats returns a context whose statements are recorded in s.
+func WithQueryStats(ctx context.Context, s *QueryStats) context.Context {
+	return context.WithValue(ctx, statsCtxKey{}, s)
+}
+
+// QueryStatsFromContext returns the stats attached to ctx, if any.
+func QueryStatsFromContext(ctx context.Context) *QueryStats {
+	if ctx == nil {
+		return nil
+	}
+	s, _ := ctx.Value(statsCtxKey{}).(*QueryStats)
+	return s
+}
+
+// Summary reports the statements recorded so far.
+func (s *QueryStats) Summary() QuerySummary {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return QuerySummary{Queries: s.queries, Elapsed: s.elapsed, Slowest: s.slowest, SlowestSQL: s.slowestSQL}
+}
+
+func (s *QueryStats) record(query string, d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.queries++
+	s.elapsed += d
+	if s.queries == 1 || d > s.slowest {
+		s.slowest, s.slowestSQL = d, query
+	}
+}
+
+func statsInterceptor(c *queryCall, next func() error) error {
+	s := QueryStatsFromContext(c.ctx)
+	if s == nil || c.dryRun {
+		return next()
+	}
+
+	err := next()
+	s.record(c.query, c.elapsed)
+	return err
+}
+
+// QueryStatsMiddleware records the statements of each request run with its
+// context and reports them in the response headers
+//
+//	X-DB-Queries: 7
+//	X-DB-Time: 42ms
+//
+// as they stand when the handler starts writing the response, and to
+// report, when not nil, once the handler returns:
+//
+//	mux := orm.QueryStatsMiddleware(func(r *http.Request, s orm.QuerySummary) {
+//		if s.Queries > 20 {
+//			log.Printf("chatty endpoint %s: %s", r.URL.Path, s)
+//		}
+//	})(router)
+func QueryStatsMiddleware(report func(r *http.Request, s QuerySummary)) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			stats := &QueryStats{}
+			r = r.WithContext(WithQueryStats(r.Context(), stats))
+			sw := &statsWriter{ResponseWriter: w, stats: stats}
+
+			next.ServeHTTP(sw, r)
+			sw.writeStats()
+			if report != nil {
+				report(r, stats.Summary())
+			}
+		})
+	}
+}
+
+// statsWriter sets the stats headers before the first byte of a response.
+type statsWriter struct {
+	http.ResponseWriter
+	stats *QueryStats
+	wrote bool
+}
+
+func (w *statsWriter) writeStats() {
+	if w.wrote {
+		return
+	}
+	w.wrote = true
+	s := w.stats.Summary()
+	w.Header().Set("X-DB-Queries", strconv.Itoa(s.Queries))
+	w.Header().Set("X-DB-Time", strconv.FormatInt(s.Elapsed.Milliseconds(), 10)+"ms")
+}
+
+func (w *statsWriter) WriteHeader(code int) {
+	w.writeStats()
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *statsWriter) Write(b []byte) (int, error) {
+	w.writeStats()
+	return w.ResponseWriter.Write(b)
+}
+
+// Unwrap gives http.ResponseController the underlying writer, for Flush
+// and deadlines.
+func (w *statsWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,93 @@
+package orm
+
+import (
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// redactedValue stands in for the value of a sensitive column in logged
+// statements.
+const redactedValue = "[REDACTED]"
+
+var (
+	sensitiveColumns sync.Map // lower-case column -> struct{}, from sensitive tags
+
+	redactorMu sync.RWMutex
+	redactor   func(column string) bool
+
+	// redacting is set once any column can be sensitive, so statements are
+	// only searched for them when it matters.
+	redacting atomic.Bool
+)
+
+// SetLogRedactor replaces the process-wide rule that, besides sensitive
+// tags, decides which columns have their values left out of logged
+// statements. fn gets the lower-cased column name; nil leaves only the
+// tags:
+//
+//	orm.SetLogRedactor(func(column string) bool {
+//		return strings.Contains(column, "password") || strings.HasSuffix(column, "_token")
+//	})
+func SetLogRedactor(fn func(column string) bool) {
+	redactorMu.Lock()
+	defer redactorMu.Unlock()
+	redactor = fn
+	if fn != nil {
+		redacting.Store(true)
+	}
+}
+
+// markSensitive records the column of a field tagged sensitive. Columns are
+// matched by name, whatever the table.
+func markSensitive(column string) {
+	sensitiveColumns.Store(strings.ToLower(column), struct{}{})
+	redacting.Store(true)
+}
+
+// sensitiveColumn reports whether the values bound to column are left out
+// of logs.
+func sensitiveColumn(column string) bool {
+	if column == "" {
+		return false
+	}
+	column = strings.ToLower(column)
+	if _, ok := sensitiveColumns.Load(column); ok {
+		return true
+	}
+	redactorMu.RLock()
+	fn := redactor
+	redactorMu.RUnlock()
+	return fn != nil && fn(column)
+}
+
+// touchesSensitive reports whether sqlStr names a sensitive column anywhere
+// outside string literals, quoted or not. Which placeholder binds which
+// column can't be told reliably from the text (LOWER(password) = ?,
+// ? = password, expressions, sub-selects), so interpolate redacts every
+// argument of such a statement.
+func touchesSensitive(sqlStr string) bool {
+	for i := 0; i < len(sqlStr); {
+		c := sqlStr[i]
+		switch {
+		case c == '\'':
+			j := strings.IndexByte(sqlStr[i+1:], '\'')
+			if j < 0 {
+				return false
+			}
+			i += j + 2
+		case isWordByte(c):
+			j := i + 1
+			for j < len(sqlStr) && isWordByte(sqlStr[j]) {
+				j++
+			}
+			if !isDigit(c) && sensitiveColumn(sqlStr[i:j]) {
+				return true
+			}
+			i = j
+		default:
+			i++ // quotes of identifiers too, so their names are checked
+		}
+	}
+	return false
+}
@@ -0,0 +1,32 @@
+package orm
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestInterpolateRedactsSensitiveStatements(t *testing.T) {
+	markSensitive("password")
+
+	redacted := []struct {
+		sql  string
+		args []any
+	}{
+		{`SELECT * FROM "users" WHERE LOWER(password) = $1`, []any{"hunter2"}},
+		{`SELECT * FROM "users" WHERE $1 = password`, []any{"hunter2"}},
+		{`UPDATE "users" SET "password" = $1 WHERE "id" = $2`, []any{"hunter2", 7}},
+		{`INSERT INTO "users" ("name", "password") VALUES ($1, $2)`, []any{"ann", "hunter2"}},
+		{`SELECT * FROM "users" WHERE u.password IN ($1, $2)`, []any{"hunter2", "hunter3"}},
+	}
+	for _, tt := range redacted {
+		got := interpolate(tt.sql, tt.args, FlavorPostgres)
+		if strings.Contains(got, "hunter") || !strings.Contains(got, redactedValue) {
+			t.Errorf("interpolate(%q) = %q, want the values redacted", tt.sql, got)
+		}
+	}
+
+	got := interpolate(`SELECT * FROM "users" WHERE name = ? AND note = 'password'`, []any{"ann"}, FlavorMySQL)
+	if want := `SELECT * FROM "users" WHERE name = 'ann' AND note = 'password'`; got != want {
+		t.Errorf("interpolate = %q, want %q", got, want)
+	}
+}
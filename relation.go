@@ -0,0 +1,540 @@
+package orm
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"net/http"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/godev90/validator/faults"
+	"gorm.io/gorm"
+)
+
+var (
+	errUnknownRelation = fmt.Errorf("orm: unknown relation")
+	ErrUnknownRelation = faults.New(errUnknownRelation, &faults.ErrAttr{
+		Code: http.StatusInternalServerError,
+		Messages: []faults.LangPackage{
+			{
+				Tag:     faults.English,
+				Message: "orm: %s has no relation %q",
+			},
+		},
+	})
+
+	errInvalidRelation = fmt.Errorf("orm: invalid relation")
+	ErrInvalidRelation = faults.New(errInvalidRelation, &faults.ErrAttr{
+		Code: http.StatusInternalServerError,
+		Messages: []faults.LangPackage{
+			{
+				Tag:     faults.English,
+				Message: "orm: relation %s.%s: %s",
+			},
+		},
+	})
+)
+
+// relationKinds are the sql tag options declaring a relation field. Such
+// fields are not columns of their model.
+var relationKinds = []string{"hasMany", "hasOne", "belongsTo", "many2many"}
+
+// preloadBatch caps the parent keys loaded by one IN query.
+const preloadBatch = 1000
+
+// relation is a relation field of a model, read from its sql tag:
+//
+//	Orders   []Order  `sql:"hasMany;foreignKey:user_id;references:id"`
+//	Profile  *Profile `sql:"hasOne;foreignKey:user_id"`
+//	Customer Customer `sql:"belongsTo;foreignKey:customer_id;references:id"`
+//	Roles    []Role   `sql:"many2many:user_roles"`
+//
+// foreignKey is the column holding the key of the other side: on the
+// related model for hasMany and hasOne, on the model itself for belongsTo.
+// A many2many relation links both sides through rows of a join table (see
+// readRelations for its options).
+type relation struct {
+	name  string
+	kind  string
+	field int          // index of the relation field in the model struct
+	elem  reflect.Type // the related struct type
+	model Tabler       // a zero related model, for queries
+
+	localKey  string // the model's column whose values are looked up
+	remoteKey string // the related model's column matched against them
+
+	// many2many only: the join table and its columns holding the localKey
+	// and remoteKey values
+	joinTable  string
+	joinLocal  string
+	joinRemote string
+}
+
+// relationCache maps a struct type to its relations by name, or the error
+// found reading them.
+var relationCache sync.Map // reflect.Type -> map[string]*relation | error
+
+// preload is a relation path to load with the parents, see Preload.
+type preload struct {
+	path   string
+	scopes []ScopeFunc
+}
+
+// isRelationTag reports whether a field's sql tag declares a relation.
+func isRelationTag(tag string) bool {
+	return slices.ContainsFunc(relationKinds, func(kind string) bool {
+		return tagFlag(tag, kind) || tagOption(tag, kind) != ""
+	})
+}
+
+// relationsOf returns the relations of the struct type t by name. A
+// relation is named after its field unless the tag names it
+// (hasMany:Purchases). For hasMany and hasOne the foreign key defaults to
+// <model>_id and the referenced column to the model's primary key; for
+// belongsTo to <field>_id and the related model's primary key.
+//
+// A many2many relation names its join table (many2many:user_roles).
+// foreignKey and references are the columns of the model and the related
+// model its rows link, by default their primary keys; joinForeignKey and
+// joinReferences are the join table's columns holding them, by default
+// <model>_id and <related model>_id.
+func relationsOf(t reflect.Type) (map[string]*relation, error) {
+	if cached, ok := relationCache.Load(t); ok {
+		if err, ok := cached.(error); ok {
+			return nil, err
+		}
+		return cached.(map[string]*relation), nil
+	}
+
+	rels, err := readRelations(t)
+	if err != nil {
+		relationCache.Store(t, err)
+		return nil, err
+	}
+	relationCache.Store(t, rels)
+	return rels, nil
+}
+
+func readRelations(t reflect.Type) (map[string]*relation, error) {
+	rels := map[string]*relation{}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("sql")
+		if !isRelationTag(tag) {
+			continue
+		}
+
+		invalid := func(reason string) error {
+			return ErrInvalidRelation.Render(t.Name(), f.Name, reason)
+		}
+
+		rel := &relation{name: f.Name, field: i}
+		for _, kind := range relationKinds {
+			if tagFlag(tag, kind) || tagOption(tag, kind) != "" {
+				rel.kind = kind
+				if name := tagOption(tag, kind); name != "" && kind != "many2many" {
+					rel.name = name
+				}
+				break
+			}
+		}
+
+		elem := f.Type
+		if rel.kind == "hasMany" || rel.kind == "many2many" {
+			if elem.Kind() != reflect.Slice {
+				return nil, invalid(rel.kind + " needs a slice field")
+			}
+			elem = elem.Elem()
+		}
+		if elem.Kind() == reflect.Ptr {
+			elem = elem.Elem()
+		}
+		model, ok := reflect.New(elem).Interface().(Tabler)
+		if elem.Kind() != reflect.Struct || !ok {
+			return nil, invalid(elem.String() + " is not a model")
+		}
+		rel.elem, rel.model = elem, model
+
+		// the key of the side that owns the foreign key defaults to its
+		// primary key
+		foreignKey, references := tagOption(tag, "foreignKey"), tagOption(tag, "references")
+		switch rel.kind {
+		case "many2many":
+			rel.joinTable = tagOption(tag, "many2many")
+			if rel.joinTable == "" {
+				return nil, invalid("many2many needs a join table")
+			}
+			if foreignKey == "" {
+				foreignKey = "id"
+				if parent, ok := reflect.New(t).Interface().(Tabler); ok {
+					foreignKey = primaryKeyColumn(parent)
+				}
+			}
+			if references == "" {
+				references = primaryKeyColumn(model)
+			}
+			rel.localKey, rel.remoteKey = foreignKey, references
+
+			rel.joinLocal = tagOption(tag, "joinForeignKey")
+			if rel.joinLocal == "" {
+				rel.joinLocal = toSnake(t.Name()) + "_id"
+			}
+			rel.joinRemote = tagOption(tag, "joinReferences")
+			if rel.joinRemote == "" {
+				rel.joinRemote = toSnake(elem.Name()) + "_id"
+			}
+		case "belongsTo":
+			if foreignKey == "" {
+				foreignKey = toSnake(f.Name) + "_id"
+			}
+			if references == "" {
+				references = primaryKeyColumn(model)
+			}
+			rel.localKey, rel.remoteKey = foreignKey, references
+		default:
+			if foreignKey == "" {
+				foreignKey = toSnake(t.Name()) + "_id"
+			}
+			if references == "" {
+				references = "id"
+				if parent, ok := reflect.New(t).Interface().(Tabler); ok {
+					references = primaryKeyColumn(parent)
+				}
+			}
+			rel.localKey, rel.remoteKey = references, foreignKey
+		}
+
+		if cachedFieldMap(t)[strings.ToLower(rel.localKey)] == nil {
+			return nil, invalid(fmt.Sprintf("%s has no column %q", t.Name(), rel.localKey))
+		}
+		if cachedFieldMap(elem)[strings.ToLower(rel.remoteKey)] == nil {
+			return nil, invalid(fmt.Sprintf("%s has no column %q", elem.Name(), rel.remoteKey))
+		}
+		rels[rel.name] = rel
+	}
+	return rels, nil
+}
+
+// Preload loads the named relation of the rows Scan and First read, with
+// one batched "key IN (...)" query per relation instead of one per row,
+// and stitches the related rows onto them (hasMany, hasOne, belongsTo or,
+// with a second query on the join table, many2many):
+//
+//	type User struct {
+//		ID     int64   `sql:"column:id;primaryKey"`
+//		Orders []Order `sql:"hasMany;foreignKey:user_id"`
+//	}
+//
+//	adapter.UseModel(&User{}).
+//		Preload("Orders", func(q orm.QueryAdapter) orm.QueryAdapter {
+//			return q.Where("status = ?", "paid").Order("id")
+//		}).
+//		Scan(&users)
+//
+// Nested relations are loaded with a dotted path ("Orders.Items"); scopes
+// apply to the last relation of the path. Relation queries share the
+// chain's context, default scopes and table prefix, but none of its
+// conditions. Preloading runs before AfterFind hooks.
+func (q *SqlQueryAdapter) Preload(relation string, scopes ...ScopeFunc) QueryAdapter {
+	cp := q.clone()
+	cp.preloads = append(cp.preloads, preload{path: relation, scopes: scopes})
+	return cp
+}
+
+func (a builtAdapter) Preload(relation string, scopes ...ScopeFunc) QueryAdapter {
+	return a.rewrap(a.b.Preload(relation, scopes...))
+}
+
+// Preload loads the named relation of the rows Scan and First read; see
+// SqlQueryAdapter.Preload. The relations are read from sql tags, not
+// gorm's.
+func (g *GormAdapter) Preload(relation string, scopes ...ScopeFunc) QueryAdapter {
+	cp := g.with(g.db)
+	cp.preloads = append(slices.Clip(g.preloads), preload{path: relation, scopes: scopes})
+	return cp
+}
+
+// related returns a query for model sharing q's connection, context,
+// default scopes and table prefix, but none of its conditions.
+func (q *SqlQueryAdapter) related(model Tabler) QueryAdapter {
+	root := &SqlQueryAdapter{
+		db:            q.db,
+		ctx:           q.ctx,
+		flavor:        q.flavor,
+		fields:        []string{"*"},
+		stmts:         q.stmts,
+		comment:       q.comment,
+		defaultScopes: slices.Clone(q.defaultScopes),
+		tablePrefix:   q.tablePrefix,
+		emptyStrings:  q.emptyStrings,
+		lossyNumbers:  q.lossyNumbers,
+		typedMaps:     q.typedMaps,
+		planCache:     q.planCache,
+		replicas:      q.replicas,
+		tx:            q.tx,
+		debug:         q.debug,
+	}
+	return root.UseModel(model)
+}
+
+func (a builtAdapter) related(model Tabler) QueryAdapter {
+	return a.rewrap(a.b.related(model))
+}
+
+func (g *GormAdapter) related(model Tabler) QueryAdapter {
+	root := &GormAdapter{
+		db:            g.db.Session(&gorm.Session{NewDB: true, Context: g.db.Statement.Context}),
+		planCache:     g.planCache,
+		defaultScopes: slices.Clone(g.defaultScopes),
+		tablePrefix:   g.tablePrefix,
+		debug:         g.debug,
+	}
+	return root.UseModel(model)
+}
+
+// joinTable is the Tabler of a many2many join table, which has no model.
+type joinTable string
+
+func (t joinTable) TableName() string {
+	return string(t)
+}
+
+// linkTable returns a query on the join table name like related, without
+// default scopes.
+func (q *SqlQueryAdapter) linkTable(name string) QueryAdapter {
+	return q.related(joinTable(name)).Unscoped()
+}
+
+func (a builtAdapter) linkTable(name string) QueryAdapter {
+	return a.rewrap(a.b.linkTable(name))
+}
+
+func (g *GormAdapter) linkTable(name string) QueryAdapter {
+	return &GormAdapter{
+		db:          g.db.Session(&gorm.Session{NewDB: true, Context: g.db.Statement.Context}).Table(g.tablePrefix + name),
+		planCache:   g.planCache,
+		tablePrefix: g.tablePrefix,
+	}
+}
+
+// relatedQuerier is implemented by the adapters, for preloading.
+type relatedQuerier interface {
+	related(model Tabler) QueryAdapter
+	linkTable(name string) QueryAdapter
+}
+
+// preloadRelations loads preloads onto the rows scanned into dest.
+func preloadRelations(q relatedQuerier, dest any, preloads []preload) error {
+	if len(preloads) == 0 {
+		return nil
+	}
+	parents := relationRows(reflect.ValueOf(dest))
+	if len(parents) == 0 {
+		return nil
+	}
+
+	// rows loaded per path, so "Orders" and "Orders.Items" load orders once
+	loaded := map[string][]reflect.Value{"": parents}
+	for _, p := range preloads {
+		path := ""
+		segments := strings.Split(p.path, ".")
+		for i, name := range segments {
+			rows := loaded[path]
+			if path != "" {
+				path += "."
+			}
+			path += name
+
+			last := i == len(segments)-1
+			if _, ok := loaded[path]; ok && !last {
+				continue
+			}
+			var scopes []ScopeFunc
+			if last {
+				scopes = p.scopes
+			}
+
+			children, err := loadRelation(q, rows, name, scopes)
+			if err != nil {
+				return err
+			}
+			loaded[path] = children
+		}
+	}
+	return nil
+}
+
+// loadRelation loads the relation name of rows, all of one struct type,
+// and returns the related rows as stitched onto them.
+func loadRelation(q relatedQuerier, rows []reflect.Value, name string, scopes []ScopeFunc) ([]reflect.Value, error) {
+	if len(rows) == 0 {
+		return nil, nil
+	}
+	t := rows[0].Type()
+	rels, err := relationsOf(t)
+	if err != nil {
+		return nil, err
+	}
+	rel, ok := rels[name]
+	if !ok {
+		return nil, ErrUnknownRelation.Render(t.Name(), name)
+	}
+
+	// rows by key; keys keeps the values as the rows hold them
+	localField := cachedFieldMap(t)[strings.ToLower(rel.localKey)]
+	byKey := map[any][]int{}
+	var keys []any
+	for i, row := range rows {
+		row.Field(rel.field).SetZero()
+		v := row.FieldByIndex(localField.Index)
+		k, ok := relationKey(v)
+		if !ok {
+			continue
+		}
+		if _, seen := byKey[k]; !seen {
+			keys = append(keys, v.Interface())
+		}
+		byKey[k] = append(byKey[k], i)
+	}
+
+	matched := make([]bool, len(rows))
+	if rel.kind == "many2many" {
+		if err := loadJoined(q, rows, rel, byKey, keys, scopes, matched); err != nil {
+			return nil, err
+		}
+		return matchedRows(rows, rel, matched), nil
+	}
+
+	remoteField := cachedFieldMap(rel.elem)[strings.ToLower(rel.remoteKey)]
+	for start := 0; start < len(keys); start += preloadBatch {
+		batch := keys[start:min(start+preloadBatch, len(keys))]
+
+		rq := q.related(rel.model)
+		col := quoteIdent(rq.Driver(), rq.Describe().Table+"."+rel.remoteKey)
+		found := reflect.New(reflect.SliceOf(reflect.PointerTo(rel.elem)))
+		if err := whereTrusted(rq, col+" IN ?", batch).Scopes(scopes...).Scan(found.Interface()); err != nil {
+			return nil, err
+		}
+
+		for j := 0; j < found.Elem().Len(); j++ {
+			ptr := found.Elem().Index(j)
+			k, ok := relationKey(ptr.Elem().FieldByIndex(remoteField.Index))
+			if !ok {
+				continue
+			}
+			for _, i := range byKey[k] {
+				if rel.kind != "hasMany" && matched[i] {
+					// hasOne: the first row wins
+					continue
+				}
+				matched[i] = true
+				setRelated(rows[i].Field(rel.field), ptr)
+			}
+		}
+	}
+
+	return matchedRows(rows, rel, matched), nil
+}
+
+// matchedRows returns the related rows stitched onto the matched rows, each
+// once: many2many rows held as pointers are shared between parents.
+func matchedRows(rows []reflect.Value, rel *relation, matched []bool) []reflect.Value {
+	var related []reflect.Value
+	seen := map[uintptr]bool{}
+	for i, row := range rows {
+		if !matched[i] {
+			continue
+		}
+		for _, r := range relationRows(row.Field(rel.field).Addr()) {
+			if addr := r.Addr().Pointer(); !seen[addr] {
+				seen[addr] = true
+				related = append(related, r)
+			}
+		}
+	}
+	return related
+}
+
+// setRelated stores the related row ptr in the relation field: appended
+// to a slice, or set as the struct or struct pointer.
+func setRelated(field, ptr reflect.Value) {
+	switch t := field.Type(); {
+	case t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Ptr:
+		field.Set(reflect.Append(field, ptr))
+	case t.Kind() == reflect.Slice:
+		field.Set(reflect.Append(field, ptr.Elem()))
+	case t.Kind() == reflect.Ptr:
+		field.Set(ptr)
+	default:
+		field.Set(ptr.Elem())
+	}
+}
+
+// relationRows returns the addressable structs held by v: a pointer to a
+// struct, or to a slice of structs or struct pointers.
+func relationRows(v reflect.Value) []reflect.Value {
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		if v.CanAddr() {
+			return []reflect.Value{v}
+		}
+	case reflect.Slice:
+		rows := make([]reflect.Value, 0, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			e := v.Index(i)
+			if e.Kind() == reflect.Ptr {
+				if e.IsNil() {
+					continue
+				}
+				e = e.Elem()
+			}
+			if e.Kind() == reflect.Struct {
+				rows = append(rows, e)
+			}
+		}
+		return rows
+	}
+	return nil
+}
+
+// relationKey returns the key value v as a comparable map key, so that a
+// parent's int64 key matches a child's int32, *int64 or sql.NullInt64
+// foreign key. NULL keys report false.
+func relationKey(v reflect.Value) (any, bool) {
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil, false
+		}
+		v = v.Elem()
+	}
+	if valuer, ok := v.Interface().(driver.Valuer); ok {
+		x, err := valuer.Value()
+		if err != nil || x == nil {
+			return nil, false
+		}
+		v = reflect.ValueOf(x)
+	}
+
+	switch {
+	case v.CanInt():
+		return v.Int(), true
+	case v.CanUint():
+		return int64(v.Uint()), true
+	case v.Kind() == reflect.String:
+		return v.String(), true
+	case v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Uint8:
+		return string(v.Bytes()), true
+	case v.Comparable():
+		return v.Interface(), true
+	}
+	return nil, false
+}
@@ -0,0 +1,181 @@
+package orm
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// parentLink is a hasMany relation seen from the child: writes of a child
+// whose fkField points at a parent keep the parent's counter column and/or
+// updated_at column current.
+type parentLink struct {
+	parent   Tabler
+	parentPK string
+	fkField  []int
+	counter  string // counterCache column, "" when not cached
+	touch    bool   // touch:parent
+}
+
+// parentLinks maps a child struct type to the parents it maintains.
+var parentLinks sync.Map // reflect.Type -> []parentLink
+
+// RegisterRelations reads the hasMany fields of each parent model and
+// starts maintaining the parent side of those tagged with counterCache or
+// touch:parent:
+//
+//	type User struct {
+//		ID          int64     `sql:"column:id;primaryKey"`
+//		OrdersCount int       `sql:"column:orders_count"`
+//		UpdatedAt   time.Time `sql:"column:updated_at"`
+//		Orders      []Order   `sql:"-" orm:"counterCache:orders_count;touch:parent"`
+//	}
+//
+// From then on SqlTransactionAdapter.Create, BulkInsert and Delete of an
+// Order add to or subtract from users.orders_count, and every write of an
+// Order (Update and Patch too) sets the user's updated_at, in the same
+// transaction. The child's foreign key defaults to <parent>_id (user_id)
+// and can be set with `orm:"foreignKey:owner_id;..."`.
+func RegisterRelations(parents ...Tabler) error {
+	for _, parent := range parents {
+		pt := reflect.TypeOf(parent)
+		for pt.Kind() == reflect.Ptr {
+			pt = pt.Elem()
+		}
+		if pt.Kind() != reflect.Struct {
+			return ErrModelNotStruct.Render(parent)
+		}
+
+		for i := 0; i < pt.NumField(); i++ {
+			f := pt.Field(i)
+			tag := f.Tag.Get("orm")
+			column := tagOption(tag, "counterCache")
+			touch := tagOption(tag, "touch") == "parent"
+			if column == "" && !touch {
+				continue
+			}
+
+			ct := f.Type
+			if ct.Kind() != reflect.Slice {
+				return fmt.Errorf("orm: %s.%s: counterCache and touch need a hasMany (slice) field", pt.Name(), f.Name)
+			}
+			ct = ct.Elem()
+			if ct.Kind() == reflect.Ptr {
+				ct = ct.Elem()
+			}
+
+			fk := tagOption(tag, "foreignKey")
+			if fk == "" {
+				fk = toSnake(pt.Name()) + "_id"
+			}
+			fkField, ok := cachedFieldMap(ct)[strings.ToLower(fk)]
+			if !ok {
+				return fmt.Errorf("orm: %s.%s: %s has no column %q", pt.Name(), f.Name, ct.Name(), fk)
+			}
+			if column != "" {
+				if err := ValidateColumnName(column); err != nil {
+					return fmt.Errorf("orm: %s.%s: %w", pt.Name(), f.Name, err)
+				}
+			}
+			if _, ok := cachedFieldMap(pt)[updatedAtColumn]; touch && !ok {
+				return fmt.Errorf("orm: %s.%s: touch needs an %s column on %s", pt.Name(), f.Name, updatedAtColumn, pt.Name())
+			}
+
+			link := parentLink{
+				parent:   parent,
+				parentPK: primaryKeyColumn(parent),
+				fkField:  fkField.Index,
+				counter:  column,
+				touch:    touch,
+			}
+
+			existing, _ := parentLinks.Load(ct)
+			list, _ := existing.([]parentLink)
+			if slices.ContainsFunc(list, link.same) {
+				continue // registered before
+			}
+			parentLinks.Store(ct, append(append([]parentLink(nil), list...), link))
+		}
+	}
+	return nil
+}
+
+// RegisterCounterCaches is the name RegisterRelations had before it
+// handled touch:parent; it registers the same relations.
+func RegisterCounterCaches(parents ...Tabler) error {
+	return RegisterRelations(parents...)
+}
+
+// same reports whether l and other maintain the same parent through the
+// same foreign key.
+func (l parentLink) same(other parentLink) bool {
+	return reflect.Indirect(reflect.ValueOf(l.parent)).Type() == reflect.Indirect(reflect.ValueOf(other.parent)).Type() &&
+		slices.Equal(l.fkField, other.fkField) &&
+		l.counter == other.counter && l.touch == other.touch
+}
+
+// updateParents runs after a write of children: counters move by delta per
+// child and touched parents get a new updated_at, one statement per parent
+// (or per relation for Touch). Children with a zero foreign key belong to
+// no parent and are skipped.
+func (q *SqlTransactionAdapter) updateParents(delta int, children ...Tabler) error {
+	if len(children) == 0 {
+		return nil
+	}
+
+	val, err := modelStruct(children[0], false)
+	if err != nil {
+		return err
+	}
+	cached, ok := parentLinks.Load(val.Type())
+	if !ok {
+		return nil
+	}
+
+	for _, link := range cached.([]parentLink) {
+		if link.counter == "" && !link.touch {
+			continue
+		}
+
+		// parent keys in first-seen order
+		var keys []any
+		counts := map[any]int{}
+		for _, child := range children {
+			v, err := modelStruct(child, false)
+			if err != nil {
+				return err
+			}
+
+			fk := reflect.Indirect(v.FieldByIndex(link.fkField))
+			if !fk.IsValid() || fk.IsZero() {
+				continue
+			}
+			key := fk.Interface()
+			if _, seen := counts[key]; !seen {
+				keys = append(keys, key)
+			}
+			counts[key] += delta
+		}
+
+		if link.counter != "" && delta != 0 {
+			col := quoteIdent(q.flavor, link.counter)
+			query := rebind(q.flavor, fmt.Sprintf("UPDATE %s SET %s = COALESCE(%s, 0) + ? WHERE %s = ?",
+				quoteIdent(q.flavor, q.tableName(link.parent)), col, col, quoteIdent(q.flavor, link.parentPK)))
+
+			for _, key := range keys {
+				if err := q.exec(query, []any{counts[key], key}); err != nil {
+					return err
+				}
+			}
+		}
+
+		if link.touch && len(keys) > 0 {
+			if err := q.Touch(link.parent, keys...); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
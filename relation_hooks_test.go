@@ -0,0 +1,54 @@
+package orm
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+type cachedAuthor struct {
+	ID         int64        `sql:"column:id;primaryKey"`
+	PostsCount int          `sql:"column:posts_count"`
+	Posts      []cachedPost `sql:"-" orm:"counterCache:posts_count"`
+}
+
+func (cachedAuthor) TableName() string { return "authors" }
+
+type cachedPost struct {
+	ID             int64 `sql:"column:id;primaryKey"`
+	CachedAuthorID int64 `sql:"column:cached_author_id"`
+}
+
+func (cachedPost) TableName() string { return "posts" }
+
+func TestRegisterCounterCachesOnce(t *testing.T) {
+	if err := RegisterCounterCaches(&cachedAuthor{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := RegisterRelations(cachedAuthor{}); err != nil {
+		t.Fatal(err)
+	}
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	SetFlavor(db, FlavorPostgres)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO "posts"`).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectExec(`UPDATE "authors" SET "posts_count"`).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	err = WithTransaction(context.Background(), db, func(tx *SqlTransactionAdapter) error {
+		return tx.Create(&cachedPost{CachedAuthorID: 3})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
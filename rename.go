@@ -0,0 +1,65 @@
+package orm
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// legacyFieldCache maps a struct type to its legacyFields.
+var legacyFieldCache sync.Map // reflect.Type -> map[string]*modelField
+
+// legacyFields maps the lower-cased legacy columns of the struct type t to
+// copies of the fields renamed from them, which read and write the legacy
+// column in place of the field's own:
+//
+//	Email string `sql:"column:email_address;legacyColumn:email"`
+//
+// While a column is being renamed both exist: writes fill both and reads
+// fall back on the legacy one where the new one is NULL or zero, as in rows
+// not backfilled yet. Primary keys can't be renamed this way.
+func legacyFields(t reflect.Type) map[string]*modelField {
+	if cached, ok := legacyFieldCache.Load(t); ok {
+		return cached.(map[string]*modelField)
+	}
+
+	m := map[string]*modelField{}
+	for _, f := range modelFields(t) {
+		if f.legacy == "" || f.pk {
+			continue
+		}
+		c := f
+		c.column, c.renamedTo, c.legacy = f.legacy, f.column, ""
+		m[strings.ToLower(c.column)] = &c
+	}
+	legacyFieldCache.Store(t, m)
+	return m
+}
+
+// writeFields returns modelFields(t) with each renamed field followed by
+// its copy under the legacy column, the columns INSERT and UPDATE write.
+func writeFields(t reflect.Type) []modelField {
+	legacy := legacyFields(t)
+	fields := modelFields(t)
+	if len(legacy) == 0 {
+		return fields
+	}
+
+	out := make([]modelField, 0, len(fields)+len(legacy))
+	for _, f := range fields {
+		out = append(out, f)
+		if f.legacy != "" && !f.pk {
+			out = append(out, *legacy[strings.ToLower(f.legacy)])
+		}
+	}
+	return out
+}
+
+// sourceColumn is the column whose value f carries: its own, or for the
+// copy of a renamed field, the new one.
+func (f *modelField) sourceColumn() string {
+	if f.renamedTo != "" {
+		return f.renamedTo
+	}
+	return f.column
+}
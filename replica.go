@@ -0,0 +1,118 @@
+package orm
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"log"
+	"math/rand/v2"
+	"net"
+	"sync/atomic"
+	"time"
+
+	"github.com/godev90/validator/faults"
+)
+
+const (
+	defaultReplicaBaseDelay = 10 * time.Millisecond
+	defaultReplicaMaxDelay  = 200 * time.Millisecond
+)
+
+// ReplicaSet routes reads to read replicas, round robin. A read that fails
+// on one replica because its connection did (ErrConnClosed, driver.ErrBadConn,
+// network errors) is retried on the next after a jittered backoff, and on
+// the primary once MaxAttempts replicas have failed, so a single unhealthy
+// replica doesn't surface errors. Other errors, such as a bad statement, and
+// cancellation of the caller's context are returned at once.
+type ReplicaSet struct {
+	MaxAttempts int           // replicas tried per read; all of them when zero
+	BaseDelay   time.Duration // first backoff, doubled per retry; 10ms when zero
+	MaxDelay    time.Duration // backoff cap; 200ms when zero
+
+	replicas []*sql.DB
+	next     atomic.Uint32
+}
+
+// NewReplicaSet returns a set routing reads over replicas.
+func NewReplicaSet(replicas ...*sql.DB) *ReplicaSet {
+	return &ReplicaSet{replicas: replicas}
+}
+
+// UseReplicas sends Scan, First and Count to rs, falling back to the
+// adapter's own database. Transactions and writes always use the primary.
+func (q *SqlQueryAdapter) UseReplicas(rs *ReplicaSet) QueryAdapter {
+	cp := q.clone()
+	cp.replicas = rs
+	return cp
+}
+
+// read runs fn on replicas until one succeeds, then on primary.
+func (rs *ReplicaSet) read(ctx context.Context, primary *sql.DB, fn func(db *sql.DB) (*sql.Rows, func(), error)) (*sql.Rows, func(), error) {
+	n := len(rs.replicas)
+	attempts := rs.MaxAttempts
+	if attempts <= 0 || attempts > n {
+		attempts = n
+	}
+
+	start := int(rs.next.Add(1) - 1)
+	for i := 0; i < attempts; i++ {
+		idx := (start + i) % n
+		rows, release, err := fn(rs.replicas[idx])
+		if err == nil {
+			return rows, release, nil
+		}
+		if ctx.Err() != nil || errors.Is(err, context.Canceled) || !connFailed(ctx, err) {
+			return nil, nil, err
+		}
+
+		target := "primary"
+		if i+1 < attempts {
+			target = "next replica"
+		}
+		log.Printf("WARNING: read replica %d failed, retrying on %s: %v", idx, target, err)
+
+		if err := rs.sleep(ctx, i); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return fn(primary)
+}
+
+// connFailed reports whether err is a failure of the connection rather than
+// of the statement, which another database may run fine.
+func connFailed(ctx context.Context, err error) bool {
+	var netErr net.Error
+	if errors.Is(err, ErrConnClosed) || errors.Is(err, driver.ErrBadConn) || errors.As(err, &netErr) {
+		return true
+	}
+	return faults.Is(errorClass(ctx, err), ErrConnClosed)
+}
+
+// sleep waits out the backoff before retry i: the capped exponential delay
+// with full jitter over its upper half.
+func (rs *ReplicaSet) sleep(ctx context.Context, retry int) error {
+	base, max := rs.BaseDelay, rs.MaxDelay
+	if base <= 0 {
+		base = defaultReplicaBaseDelay
+	}
+	if max <= 0 {
+		max = defaultReplicaMaxDelay
+	}
+
+	d := base << retry
+	if d <= 0 || d > max {
+		d = max
+	}
+	d = d/2 + rand.N(d/2+1)
+
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
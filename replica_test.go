@@ -0,0 +1,55 @@
+package orm
+
+import (
+	"database/sql"
+	"errors"
+	"net"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestReplicaSetRetriesOnlyConnectionErrors(t *testing.T) {
+	newDB := func() (*sql.DB, sqlmock.Sqlmock) {
+		db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+		if err != nil {
+			t.Fatal(err)
+		}
+		SetFlavor(db, FlavorPostgres)
+		return db, mock
+	}
+	primary, primaryMock := newDB()
+	first, firstMock := newDB()
+	second, secondMock := newDB()
+	defer primary.Close()
+	defer first.Close()
+	defer second.Close()
+
+	const query = `SELECT * FROM "orders"`
+	rs := NewReplicaSet(first, second)
+	rs.BaseDelay, rs.MaxDelay = time.Microsecond, time.Microsecond
+	q := NewSqlAdapter(primary).(*SqlQueryAdapter).UseReplicas(rs).UseModel(&batchOrder{})
+
+	// a lost connection moves on to the next replica
+	firstMock.ExpectQuery(query).WillReturnError(&net.OpError{Op: "read", Net: "tcp", Err: syscall.ECONNRESET})
+	secondMock.ExpectQuery(query).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	var got []batchOrder
+	if err := q.Scan(&got); err != nil || len(got) != 1 {
+		t.Fatalf("Scan after a lost connection = %v, %d rows; want the next replica's row", err, len(got))
+	}
+
+	// the next read starts on the second replica; a failing statement fails
+	// the same way everywhere
+	secondMock.ExpectQuery(query).WillReturnError(errors.New(`relation "orders" does not exist`))
+	if err := q.Scan(&got); err == nil {
+		t.Error("Scan = nil, want the replica's error")
+	}
+
+	for _, mock := range []sqlmock.Sqlmock{primaryMock, firstMock, secondMock} {
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Error(err)
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package orm
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/godev90/validator/faults"
+)
+
+// PurgeInterval is the pause between two PurgeExpired batches, leaving room
+// for regular traffic and replication to catch up.
+var PurgeInterval = 500 * time.Millisecond
+
+var (
+	errNoRetention = fmt.Errorf("orm: no retention tag")
+	ErrNoRetention = faults.New(errNoRetention, &faults.ErrAttr{
+		Code: http.StatusInternalServerError,
+		Messages: []faults.LangPackage{
+			{
+				Tag:     faults.English,
+				Message: "orm: %s has no valid retention tag (sql:\"retention:90d,column:created_at\")",
+			},
+		},
+	})
+)
+
+// retentionPolicy is the parsed retention tag of a model.
+type retentionPolicy struct {
+	column string
+	keep   time.Duration
+}
+
+// retentionOf finds the retention tag on model. It may sit on the timestamp
+// field itself (sql:"column:created_at;retention:90d") or on a blank field
+// naming the column (_ struct{} `sql:"retention:90d,column:created_at"`).
+func retentionOf(model Tabler) (retentionPolicy, bool) {
+	t := reflect.TypeOf(model)
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return retentionPolicy{}, false
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		var p retentionPolicy
+		var hasKeep bool
+
+		for _, opt := range strings.FieldsFunc(f.Tag.Get("sql"), func(r rune) bool { return r == ';' || r == ',' }) {
+			key, val, _ := strings.Cut(strings.TrimSpace(opt), ":")
+			switch key {
+			case "retention":
+				d, err := parseRetention(val)
+				if err != nil {
+					return retentionPolicy{}, false
+				}
+				p.keep, hasKeep = d, true
+			case "column":
+				p.column = val
+			}
+		}
+		if !hasKeep {
+			continue
+		}
+
+		if p.column == "" && f.Name != "_" {
+			p.column = toSnake(f.Name)
+		}
+		if ValidateColumnName(p.column) != nil {
+			return retentionPolicy{}, false
+		}
+		return p, true
+	}
+	return retentionPolicy{}, false
+}
+
+// parseRetention accepts a day count ("90d") or a time.Duration ("720h").
+func parseRetention(s string) (time.Duration, error) {
+	var d time.Duration
+	var err error
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		var n int
+		n, err = strconv.Atoi(days)
+		d = time.Duration(n) * 24 * time.Hour
+	} else {
+		d, err = time.ParseDuration(s)
+	}
+
+	if err == nil && d <= 0 {
+		err = fmt.Errorf("orm: retention must be positive, got %q", s)
+	}
+	return d, err
+}
+
+// PurgeExpired deletes the rows of model older than its retention tag, in
+// batches of batchSize with PurgeInterval between them, and returns how
+// many rows were deleted. Each batch commits on its own, so a long purge
+// never holds locks on the whole range; the cutoff is fixed when the purge
+// starts. It stops early, returning the count so far, when the adapter's
+// context is done.
+func (q *SqlQueryAdapter) PurgeExpired(model Tabler, batchSize int) (int64, error) {
+	p, ok := retentionOf(model)
+	if !ok {
+		return 0, ErrNoRetention.Render(reflect.TypeOf(model).String())
+	}
+	if batchSize <= 0 {
+		batchSize = 1000
+	}
+
+	query := purgeBatchSQL(q.flavor, q.tablePrefix+model.TableName(), p.column, batchSize)
+	cutoff := time.Now().Add(-p.keep)
+
+	var total int64
+	for {
+		var affected int64
+		args := []any{cutoff}
+		err := runQuery(q.call(query, args), func(query string) error {
+			res, err := q.db.ExecContext(q.ctx, query, args...)
+			if err != nil {
+				return err
+			}
+			affected, err = res.RowsAffected()
+			return err
+		})
+		total += affected
+		if err != nil || affected < int64(batchSize) {
+			return total, err
+		}
+
+		select {
+		case <-time.After(PurgeInterval):
+		case <-q.ctx.Done():
+			return total, q.ctx.Err()
+		}
+	}
+}
+
+// purgeBatchSQL deletes at most n rows with column before the bound cutoff.
+func purgeBatchSQL(flavor driverFlavor, table, column string, n int) string {
+	t, c := quoteIdent(flavor, table), quoteIdent(flavor, column)
+
+	var query string
+	switch flavor {
+	case FlavorPostgres:
+		query = fmt.Sprintf("DELETE FROM %s WHERE ctid IN (SELECT ctid FROM %s WHERE %s < ? LIMIT %d)", t, t, c, n)
+	case FlavorOracle:
+		query = fmt.Sprintf("DELETE FROM %s WHERE %s < ? AND ROWNUM <= %d", t, c, n)
+	default:
+		query = fmt.Sprintf("DELETE FROM %s WHERE %s < ? LIMIT %d", t, c, n)
+	}
+	return rebind(flavor, query)
+}
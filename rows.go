@@ -0,0 +1,111 @@
+package orm
+
+import (
+	"errors"
+	"reflect"
+	"slices"
+)
+
+// ErrSkipRow is returned by an OnRow function to leave the row out of the
+// result.
+var ErrSkipRow = errors.New("orm: skip row")
+
+// RowFunc post-processes one scanned row; see OnRow.
+type RowFunc func(dest any) error
+
+// OnRow registers fn to run on every row Scan and First read, once its
+// columns are assigned: dest is a pointer to the element (the struct, the
+// scalar or the map[string]any). fn may change the row, return ErrSkipRow
+// to drop it, or fail the scan with any other error. Functions run in the
+// order they were registered, before AfterFind hooks.
+func (q *SqlQueryAdapter) OnRow(fn RowFunc) QueryAdapter {
+	cp := q.clone()
+	cp.rowFuncs = append(cp.rowFuncs, fn)
+	return cp
+}
+
+func (a builtAdapter) OnRow(fn RowFunc) QueryAdapter {
+	return a.rewrap(a.b.OnRow(fn))
+}
+
+// OnRow registers fn to run on every row Scan and First read; see
+// SqlQueryAdapter.OnRow. gorm scans all rows at once, so the functions run
+// over the result afterwards.
+func (g *GormAdapter) OnRow(fn RowFunc) QueryAdapter {
+	cp := g.with(g.db)
+	cp.rowFuncs = append(slices.Clip(g.rowFuncs), fn)
+	return cp
+}
+
+// runRowFuncs runs fns on the row at dest and reports whether to keep it.
+func runRowFuncs(fns []RowFunc, dest any) (bool, error) {
+	for _, fn := range fns {
+		if err := fn(dest); err != nil {
+			if errors.Is(err, ErrSkipRow) {
+				return false, nil
+			}
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// keepLast runs fns on the element just appended to slice and drops it
+// when skipped.
+func keepLast(fns []RowFunc, slice reflect.Value) error {
+	if len(fns) == 0 {
+		return nil
+	}
+	n := slice.Len() - 1
+	elem := slice.Index(n)
+	if elem.Kind() != reflect.Ptr {
+		elem = elem.Addr()
+	}
+	keep, err := runRowFuncs(fns, elem.Interface())
+	if err == nil && !keep {
+		slice.SetLen(n)
+	}
+	return err
+}
+
+// filterRows runs fns over dest once it was scanned in full, for adapters
+// that can't hook into their scan loop: slices are compacted in place, and
+// a skipped single row reports ErrNotFound.
+func filterRows(fns []RowFunc, dest any) error {
+	if len(fns) == 0 {
+		return nil
+	}
+	val := reflect.ValueOf(dest)
+	if val.Kind() != reflect.Ptr || val.IsNil() {
+		return nil
+	}
+
+	target := val.Elem()
+	if target.Kind() != reflect.Slice {
+		keep, err := runRowFuncs(fns, dest)
+		if err == nil && !keep {
+			return errRecordNotFound
+		}
+		return err
+	}
+
+	kept := 0
+	for i := 0; i < target.Len(); i++ {
+		elem := target.Index(i)
+		if elem.Kind() != reflect.Ptr {
+			elem = elem.Addr()
+		}
+		keep, err := runRowFuncs(fns, elem.Interface())
+		if err != nil {
+			return err
+		}
+		if keep {
+			if kept != i {
+				target.Index(kept).Set(target.Index(i))
+			}
+			kept++
+		}
+	}
+	target.SetLen(kept)
+	return nil
+}
@@ -0,0 +1,176 @@
+package orm
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/godev90/validator/faults"
+)
+
+var (
+	errNoStatement = fmt.Errorf("orm: no running statement")
+	ErrNoStatement = faults.New(errNoStatement, &faults.ErrAttr{
+		Code: http.StatusNotFound,
+		Messages: []faults.LangPackage{
+			{
+				Tag:     faults.English,
+				Message: "orm: no running statement on session %d",
+			},
+		},
+	})
+)
+
+// RunningStatement is a statement executing on the database, as listed by
+// RunningStatements.
+type RunningStatement struct {
+	ID      int64  // backend PID (PostgreSQL), connection ID (MySQL) or SID (Oracle)
+	Serial  int64  // Oracle's SERIAL#, 0 elsewhere
+	Comment string // the text the statement was tagged with by Comment
+	Query   string
+	State   string
+	Elapsed time.Duration // since the statement started
+}
+
+// runningQueries list the active sessions other than the caller's as ID,
+// serial, elapsed seconds, state and statement text, longest-running first.
+var runningQueries = map[driverFlavor]string{
+	FlavorPostgres: `SELECT pid, 0, COALESCE(EXTRACT(EPOCH FROM now() - query_start), 0)::float8, COALESCE(state, ''), query
+FROM pg_stat_activity
+WHERE pid <> pg_backend_pid() AND state <> 'idle'
+ORDER BY query_start`,
+
+	FlavorMySQL: `SELECT ID, 0, TIME, COALESCE(STATE, ''), INFO
+FROM information_schema.PROCESSLIST
+WHERE ID <> CONNECTION_ID() AND COMMAND <> 'Sleep' AND INFO IS NOT NULL
+ORDER BY TIME DESC`,
+
+	FlavorOracle: `SELECT s.sid, s.serial#, s.last_call_et, s.status, q.sql_text
+FROM v$session s JOIN v$sql q ON q.sql_id = s.sql_id AND q.child_number = s.sql_child_number
+WHERE s.status = 'ACTIVE' AND s.sid <> SYS_CONTEXT('USERENV', 'SID')
+ORDER BY s.last_call_et DESC`,
+}
+
+// RunningStatements lists the statements running on db that were tagged
+// with Comment, longest-running first; with match, only those whose
+// comment contains it. Statements without a comment are not listed, so
+// tag the queries you may need to find:
+//
+//	adapter.Comment("report:monthly").Scan(&rows)
+//
+//	stmts, _ := orm.RunningStatements(ctx, db, "report:")
+//	for _, s := range stmts {
+//		if s.Elapsed > 10*time.Minute {
+//			orm.CancelStatement(ctx, db, s.ID)
+//		}
+//	}
+//
+// The database user needs to see other sessions: pg_read_all_stats on
+// PostgreSQL, PROCESS on MySQL, SELECT on v$session and v$sql on Oracle.
+func RunningStatements(ctx context.Context, db *sql.DB, match string) ([]RunningStatement, error) {
+	if db == nil {
+		return nil, ErrNilPointer
+	}
+	flavor := detectFlavor(db)
+	query := runningQueries[flavor]
+
+	var stmts []RunningStatement
+	err := runQuery(&queryCall{ctx: ctx, query: query, flavor: flavor}, func(query string) error {
+		rows, err := db.QueryContext(ctx, query)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var s RunningStatement
+			var seconds float64
+			if err := rows.Scan(&s.ID, &s.Serial, &seconds, &s.State, &s.Query); err != nil {
+				return err
+			}
+			s.Comment = statementComment(s.Query)
+			if s.Comment == "" || !strings.Contains(s.Comment, match) {
+				continue
+			}
+			s.Elapsed = time.Duration(seconds * float64(time.Second))
+			stmts = append(stmts, s)
+		}
+		return rows.Err()
+	})
+	return stmts, err
+}
+
+// CancelStatement cancels the statement running on the session id (a
+// RunningStatement.ID); the session itself stays open and its caller gets
+// a cancellation error. A session without a running statement fails with
+// ErrNoStatement on PostgreSQL and Oracle.
+func CancelStatement(ctx context.Context, db *sql.DB, id int64) error {
+	if db == nil {
+		return ErrNilPointer
+	}
+	flavor := detectFlavor(db)
+
+	switch flavor {
+	case FlavorPostgres:
+		query := "SELECT pg_cancel_backend($1)"
+		var ok bool
+		err := runQuery(&queryCall{ctx: ctx, query: query, args: []any{id}, flavor: flavor}, func(query string) error {
+			return db.QueryRowContext(ctx, query, id).Scan(&ok)
+		})
+		if err == nil && !ok {
+			err = ErrNoStatement.Render(id)
+		}
+		return err
+
+	case FlavorOracle:
+		lookup := "SELECT serial# FROM v$session WHERE sid = :1 AND status = 'ACTIVE'"
+		var serial int64
+		err := runQuery(&queryCall{ctx: ctx, query: lookup, args: []any{id}, flavor: flavor}, func(lookup string) error {
+			return db.QueryRowContext(ctx, lookup, id).Scan(&serial)
+		})
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrNoStatement.Render(id)
+		}
+		if err != nil {
+			return err
+		}
+		// ALTER SYSTEM takes no binds; both values are integers
+		return execAdmin(ctx, db, flavor, fmt.Sprintf("ALTER SYSTEM CANCEL SQL '%d, %d'", id, serial))
+
+	default:
+		// KILL takes no binds; id is an integer
+		return execAdmin(ctx, db, flavor, fmt.Sprintf("KILL QUERY %d", id))
+	}
+}
+
+// execAdmin runs an administrative statement without arguments.
+func execAdmin(ctx context.Context, db *sql.DB, flavor driverFlavor, query string) error {
+	return runQuery(&queryCall{ctx: ctx, query: query, flavor: flavor}, func(query string) error {
+		_, err := db.ExecContext(ctx, query)
+		return err
+	})
+}
+
+// statementComment returns the text of the first /* comment */ in query,
+// skipping optimizer hints (/*+ ... */), or "".
+func statementComment(query string) string {
+	for {
+		start := strings.Index(query, "/*")
+		if start < 0 {
+			return ""
+		}
+		end := strings.Index(query[start+2:], "*/")
+		if end < 0 {
+			return ""
+		}
+		text := query[start+2 : start+2+end]
+		if !strings.HasPrefix(text, "+") {
+			return strings.TrimSpace(text)
+		}
+		query = query[start+2+end+2:]
+	}
+}
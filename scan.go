@@ -0,0 +1,306 @@
+package orm
+
+import (
+	"database/sql"
+	"reflect"
+	"sync"
+	"time"
+)
+
+type (
+	// rowBuffer holds one typed scan destination per result column. Holders
+	// are chosen from the driver's column types so integers, floats, booleans
+	// and timestamps arrive already decoded; anything else is kept as bytes.
+	// Buffers are pooled: acquire one per query and release it when done.
+	rowBuffer struct {
+		kinds []holderKind
+		dest  []any
+		vals  []any
+	}
+
+	holderKind uint8
+
+	// rawHolder is the fallback destination. Byte values are borrowed from
+	// the driver and only valid until the next Scan; every consumer
+	// (convertAssign, map rows) copies them before that.
+	rawHolder struct {
+		v any
+	}
+
+	// timeHolder takes time.Time values as-is and keeps anything else (a
+	// DATETIME sent as text, e.g. MySQL without parseTime) for assignTime.
+	timeHolder struct {
+		rawHolder
+	}
+)
+
+const (
+	holderRaw holderKind = iota
+	holderInt
+	holderFloat
+	holderBool
+	holderTime
+)
+
+var (
+	timeT      = reflect.TypeOf(time.Time{})
+	nullTimeT  = reflect.TypeOf(sql.NullTime{})
+	nullIntT   = reflect.TypeOf(sql.NullInt64{})
+	nullInt32T = reflect.TypeOf(sql.NullInt32{})
+	nullInt16T = reflect.TypeOf(sql.NullInt16{})
+	nullFloatT = reflect.TypeOf(sql.NullFloat64{})
+	nullBoolT  = reflect.TypeOf(sql.NullBool{})
+
+	rowBufferPool = sync.Pool{New: func() any { return &rowBuffer{} }}
+
+	rowScannerT = reflect.TypeOf((*RowScanner)(nil)).Elem()
+)
+
+func (h *rawHolder) Scan(src any) error {
+	h.v = src
+	return nil
+}
+
+func (h *timeHolder) Scan(src any) error {
+	h.v = src
+	return nil
+}
+
+// TypedMaps makes this chain scan []map[string]any rows into the values
+// structs get: int64, float64, bool and time.Time where the column types
+// allow, strings otherwise. By default every non-NULL value is the string
+// the driver sent.
+func (q *SqlQueryAdapter) TypedMaps() QueryAdapter {
+	cp := q.clone()
+	cp.typedMaps = true
+	return cp
+}
+
+// acquireRowBuffer takes a buffer from the pool and fits it to the column
+// types of rows, reusing holders whose kind hasn't changed.
+func acquireRowBuffer(rows *sql.Rows) (*rowBuffer, error) {
+	types, err := rows.ColumnTypes()
+	if err != nil {
+		return nil, err
+	}
+
+	b := rowBufferPool.Get().(*rowBuffer)
+	n := len(types)
+	if cap(b.dest) < n {
+		b.kinds = make([]holderKind, n)
+		b.dest = make([]any, n)
+		b.vals = make([]any, n)
+	} else {
+		b.kinds, b.dest, b.vals = b.kinds[:n], b.dest[:n], b.vals[:n]
+	}
+
+	for i, ct := range types {
+		k := holderKindOf(ct)
+		if b.dest[i] == nil || b.kinds[i] != k {
+			b.kinds[i], b.dest[i] = k, newHolder(k)
+		}
+	}
+	return b, nil
+}
+
+// release drops references to row data and returns b to the pool.
+func (b *rowBuffer) release() {
+	for i, d := range b.dest {
+		switch h := d.(type) {
+		case *rawHolder:
+			h.v = nil
+		case *timeHolder:
+			h.v = nil
+		}
+		b.vals[i] = nil
+	}
+	rowBufferPool.Put(b)
+}
+
+// holderKindOf picks the scan destination for one column. Unsigned and
+// decimal types stay as bytes so no precision is lost on the way in.
+func holderKindOf(ct *sql.ColumnType) holderKind {
+	t := ct.ScanType()
+	if t == nil {
+		return holderRaw
+	}
+
+	switch t {
+	case timeT, nullTimeT:
+		return holderTime
+	case nullIntT, nullInt32T, nullInt16T:
+		return holderInt
+	case nullFloatT:
+		return holderFloat
+	case nullBoolT:
+		return holderBool
+	}
+
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return holderInt
+	case reflect.Float32, reflect.Float64:
+		return holderFloat
+	case reflect.Bool:
+		return holderBool
+	default:
+		return holderRaw
+	}
+}
+
+func newHolder(k holderKind) any {
+	switch k {
+	case holderInt:
+		return &sql.NullInt64{}
+	case holderFloat:
+		return &sql.NullFloat64{}
+	case holderBool:
+		return &sql.NullBool{}
+	case holderTime:
+		return &timeHolder{}
+	default:
+		return &rawHolder{}
+	}
+}
+
+// scan reads the current row and returns its values as int64, float64,
+// bool, time.Time, []byte, a driver-specific value, or nil for NULL. The
+// returned slice is reused by the next call.
+func (b *rowBuffer) scan(rows *sql.Rows) ([]any, error) {
+	if err := rows.Scan(b.dest...); err != nil {
+		return nil, err
+	}
+
+	for i, d := range b.dest {
+		b.vals[i] = holderValue(d)
+	}
+	return b.vals, nil
+}
+
+func holderValue(d any) any {
+	switch h := d.(type) {
+	case *sql.NullInt64:
+		if h.Valid {
+			return h.Int64
+		}
+	case *sql.NullFloat64:
+		if h.Valid {
+			return h.Float64
+		}
+	case *sql.NullBool:
+		if h.Valid {
+			return h.Bool
+		}
+	case *timeHolder:
+		return h.v
+	case *rawHolder:
+		return h.v
+	}
+	return nil
+}
+
+// scanGenerated fills dest (a pointer to a struct or slice) through
+// RowScanner when the model has a generated scanner that reads the result
+// exactly as reflection would (see generatedExact), running fns on each
+// row. It reports false when the caller has to fall back to reflection.
+func scanGenerated(rows *sql.Rows, dest reflect.Value, fns []RowFunc, opts assignOpts) (bool, error) {
+	target := dest.Elem()
+	cols, err := rows.Columns()
+	if err != nil {
+		return false, nil
+	}
+
+	switch target.Kind() {
+	case reflect.Struct:
+		rs, ok := dest.Interface().(RowScanner)
+		if !ok || !generatedExact(cols, target.Type(), opts) {
+			return false, nil
+		}
+		if !rows.Next() {
+			if err := rows.Err(); err != nil {
+				return true, err
+			}
+			return true, errRecordNotFound
+		}
+		if err := rs.ScanRow(rows); err != nil {
+			return true, err
+		}
+		if err := filterRows(fns, dest.Interface()); err != nil {
+			return true, err
+		}
+		return true, rows.Err()
+
+	case reflect.Slice:
+		elemTyp := target.Type().Elem()
+		structTyp := elemTyp
+		if elemTyp.Kind() == reflect.Ptr {
+			structTyp = elemTyp.Elem()
+		}
+		if structTyp.Kind() != reflect.Struct || !reflect.PointerTo(structTyp).Implements(rowScannerT) ||
+			!generatedExact(cols, structTyp, opts) {
+			return false, nil
+		}
+
+		slice := target
+		for rows.Next() {
+			var elem reflect.Value
+			slice, elem = growOne(slice)
+			if elemTyp.Kind() == reflect.Ptr {
+				elem.Set(reflect.New(structTyp))
+				elem = elem.Elem()
+			}
+
+			if err := elem.Addr().Interface().(RowScanner).ScanRow(rows); err != nil {
+				return true, err
+			}
+			if err := keepLast(fns, slice); err != nil {
+				return true, err
+			}
+		}
+		return true, rows.Err()
+	}
+
+	return false, nil
+}
+
+// generatedExact reports whether a generated scanner reads cols into the
+// struct type t exactly as the reflective scan would. Generated scanners
+// convert every field through Nullable, that is convertAssign with default
+// options, so the chain's options must be at their defaults and each column
+// must map to a top-level field without epoch, empty string or legacy column
+// settings of its own; array fields also need lib/pq's array parser.
+func generatedExact(cols []string, t reflect.Type, opts assignOpts) bool {
+	if opts.keepEmpty || opts.lossy {
+		return false
+	}
+	pq := opts.pg == nil || opts.pg == pgDriver(pqDriver{})
+
+	for _, f := range columnFields(cols, t) {
+		if f == nil {
+			continue
+		}
+		if len(f.Index) != 1 || f.timeFormat != "" || f.empty != 0 || f.renamedTo != "" {
+			return false
+		}
+		if ft := f.Type; !pq && ft.Kind() == reflect.Slice && ft.Elem().Kind() != reflect.Uint8 {
+			return false
+		}
+	}
+	return true
+}
+
+type nullable[T any] struct {
+	p *T
+}
+
+// Nullable wraps a scan destination so it converts like a reflective scan
+// with default options: NULL and empty text leave the zero value, and
+// times, JSON structs, arrays and sql.Scanner fields are read the same
+// way. Generated scanners wrap every field in it.
+func Nullable[T any](p *T) sql.Scanner {
+	return nullable[T]{p: p}
+}
+
+func (n nullable[T]) Scan(src any) error {
+	return convertAssign(reflect.ValueOf(n.p).Elem(), src, assignOpts{})
+}
@@ -0,0 +1,41 @@
+package orm
+
+import (
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestScanMapsKeepsTextByDefault(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	SetFlavor(db, FlavorPostgres)
+
+	for range 2 {
+		mock.ExpectQuery(`SELECT * FROM "orders"`).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "status", "note"}).AddRow(int64(7), "paid", nil))
+	}
+
+	q := NewSqlAdapter(db).UseModel(&batchOrder{})
+	var text []map[string]any
+	if err := q.Scan(&text); err != nil {
+		t.Fatal(err)
+	}
+	if got := text[0]; got["id"] != "7" || got["status"] != "paid" || got["note"] != nil {
+		t.Errorf("default scan = %#v, want string values and a nil note", got)
+	}
+
+	var typed []map[string]any
+	if err := q.(*SqlQueryAdapter).TypedMaps().Scan(&typed); err != nil {
+		t.Fatal(err)
+	}
+	if got := typed[0]; got["id"] != int64(7) || got["status"] != "paid" || got["note"] != nil {
+		t.Errorf("TypedMaps scan = %#v, want the driver's int64", got)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,228 @@
+package orm
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ChangeKind classifies a SchemaChange.
+type ChangeKind string
+
+const (
+	// ChangeCreateTable is a model whose table doesn't exist.
+	ChangeCreateTable ChangeKind = "create table"
+	// ChangeAddColumn is a model field without a column.
+	ChangeAddColumn ChangeKind = "add column"
+	// ChangeDropColumn is a column without a model field.
+	ChangeDropColumn ChangeKind = "drop column"
+)
+
+// SchemaChange is one difference between a model and its live table, with
+// the statements applying and reverting it.
+type SchemaChange struct {
+	Kind   ChangeKind
+	Table  string
+	Column string // "" for ChangeCreateTable
+	Up     string
+	Down   string
+
+	// Destructive changes lose data when applied. The Down of a dropped
+	// column recreates it from the driver's type name, empty and nullable.
+	Destructive bool
+}
+
+func (c SchemaChange) String() string {
+	if c.Column == "" {
+		return fmt.Sprintf("%s %s", c.Kind, c.Table)
+	}
+	return fmt.Sprintf("%s %s.%s", c.Kind, c.Table, c.Column)
+}
+
+// DiffSchema compares models with their tables on db and returns the
+// changes that would bring the schema in line with the models, table by
+// table in the order of models:
+//
+//	changes, err := orm.DiffSchema(ctx, db, &User{}, &Order{})
+//	for _, c := range changes {
+//		if c.Destructive {
+//			log.Printf("schema drift: %s", c)
+//		}
+//	}
+//
+// Missing tables and columns are created as AutoMigrate would create them;
+// columns no model field maps to are dropped, which is destructive. Column
+// types, constraints and tables without a model are not compared; for
+// defaults see DiffDefaults.
+func DiffSchema(ctx context.Context, db *sql.DB, models ...Tabler) ([]SchemaChange, error) {
+	if db == nil {
+		return nil, ErrNilPointer
+	}
+	flavor := detectFlavor(db)
+
+	var changes []SchemaChange
+	for _, model := range models {
+		c, err := diffTable(ctx, db, flavor, model)
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, c...)
+	}
+	return changes, nil
+}
+
+// diffTable returns the changes between model and its table.
+func diffTable(ctx context.Context, db *sql.DB, flavor driverFlavor, model Tabler) ([]SchemaChange, error) {
+	val, err := modelStruct(model, false)
+	if err != nil {
+		return nil, err
+	}
+	t := val.Type()
+	table := model.TableName()
+
+	cols, err := tableColumns(ctx, db, flavor, table)
+	if err != nil {
+		create, err := createTableSQL(flavor, table, t)
+		if err != nil {
+			return nil, err
+		}
+		return []SchemaChange{{
+			Kind:  ChangeCreateTable,
+			Table: table,
+			Up:    create,
+			Down:  "DROP TABLE " + quoteIdent(flavor, table),
+		}}, nil
+	}
+
+	existing := make(map[string]bool, len(cols))
+	for _, c := range cols {
+		existing[strings.ToLower(c.Name())] = true
+	}
+
+	var changes []SchemaChange
+	fields := map[string]bool{}
+	for _, f := range modelFields(t) {
+		fields[strings.ToLower(f.column)] = true
+		if f.legacy != "" {
+			fields[strings.ToLower(f.legacy)] = true // dropped once the rename is done
+		}
+		if existing[strings.ToLower(f.column)] {
+			continue
+		}
+		def, err := columnDef(flavor, t, f, false)
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, SchemaChange{
+			Kind:   ChangeAddColumn,
+			Table:  table,
+			Column: f.column,
+			Up:     addColumnSQL(flavor, table, def),
+			Down:   dropColumnSQL(flavor, table, f.column),
+		})
+	}
+
+	for _, c := range cols {
+		if fields[strings.ToLower(c.Name())] {
+			continue
+		}
+		changes = append(changes, SchemaChange{
+			Kind:        ChangeDropColumn,
+			Table:       table,
+			Column:      c.Name(),
+			Up:          dropColumnSQL(flavor, table, c.Name()),
+			Down:        addColumnSQL(flavor, table, quoteIdent(flavor, c.Name())+" "+liveColumnType(c)),
+			Destructive: true,
+		})
+	}
+	return changes, nil
+}
+
+// dropColumnSQL renders the statement dropping column from table.
+func dropColumnSQL(flavor driverFlavor, table, column string) string {
+	return fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s", quoteIdent(flavor, table), quoteIdent(flavor, column))
+}
+
+// liveColumnType spells the type of an existing column from what the
+// driver reports: its type name, with the length or precision when known.
+func liveColumnType(c *sql.ColumnType) string {
+	typ := c.DatabaseTypeName()
+	if typ == "" {
+		typ = "TEXT"
+	}
+	if p, s, ok := c.DecimalSize(); ok && p > 0 {
+		return fmt.Sprintf("%s(%d, %d)", typ, p, s)
+	}
+	// unbounded types report huge lengths
+	if n, ok := c.Length(); ok && n > 0 && n < 1<<16 {
+		return fmt.Sprintf("%s(%d)", typ, n)
+	}
+	return typ
+}
+
+// WriteMigration writes changes to dir as a pair of migration files,
+// <version>_<name>.up.sql and <version>_<name>.down.sql, with a UTC
+// timestamp version as golang-migrate and similar tools expect:
+//
+//	changes, _ := orm.DiffSchema(ctx, db, models...)
+//	up, down, err := orm.WriteMigration("migrations", "add_user_email", changes)
+//
+// The down file reverts the changes in reverse order. Destructive changes
+// are preceded by a DESTRUCTIVE comment in the up file so they stand out
+// in review. Without changes no files are written and both paths are "".
+func WriteMigration(dir, name string, changes []SchemaChange) (up, down string, err error) {
+	if len(changes) == 0 {
+		return "", "", nil
+	}
+
+	base := time.Now().UTC().Format("20060102150405") + "_" + migrationName(name)
+	up = filepath.Join(dir, base+".up.sql")
+	down = filepath.Join(dir, base+".down.sql")
+
+	var upSQL, downSQL strings.Builder
+	for i, c := range changes {
+		if i > 0 {
+			upSQL.WriteByte('\n')
+		}
+		if c.Destructive {
+			fmt.Fprintf(&upSQL, "-- DESTRUCTIVE: %s loses its data\n", c)
+		}
+		upSQL.WriteString(c.Up + ";\n")
+	}
+	for i := len(changes) - 1; i >= 0; i-- {
+		if downSQL.Len() > 0 {
+			downSQL.WriteByte('\n')
+		}
+		downSQL.WriteString(changes[i].Down + ";\n")
+	}
+
+	if err := os.WriteFile(up, []byte(upSQL.String()), 0o644); err != nil {
+		return "", "", err
+	}
+	if err := os.WriteFile(down, []byte(downSQL.String()), 0o644); err != nil {
+		return "", "", err
+	}
+	return up, down, nil
+}
+
+// migrationName makes name safe for a file name: lower case, with runs of
+// anything but letters and digits replaced by one underscore.
+func migrationName(name string) string {
+	var sb strings.Builder
+	for _, r := range strings.ToLower(name) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			sb.WriteRune(r)
+		case sb.Len() > 0 && !strings.HasSuffix(sb.String(), "_"):
+			sb.WriteByte('_')
+		}
+	}
+	if s := strings.TrimSuffix(sb.String(), "_"); s != "" {
+		return s
+	}
+	return "migration"
+}
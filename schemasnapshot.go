@@ -0,0 +1,353 @@
+package orm
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Schema is the canonical description of a database schema SchemaSnapshot
+// serializes: tables, columns and indexes by lower-cased name, tables and
+// indexes sorted by name, columns in table order.
+type Schema struct {
+	Tables []SchemaTable `json:"tables"`
+}
+
+// SchemaTable is a table of a Schema.
+type SchemaTable struct {
+	Name    string         `json:"name"`
+	Columns []SchemaColumn `json:"columns"`
+	Indexes []SchemaIndex  `json:"indexes,omitempty"`
+}
+
+// SchemaColumn is a column of a SchemaTable.
+type SchemaColumn struct {
+	Name     string  `json:"name"`
+	Type     string  `json:"type"` // with length, or precision and scale
+	Nullable bool    `json:"nullable"`
+	Default  *string `json:"default,omitempty"`
+}
+
+// SchemaIndex is an index of a SchemaTable, primary keys included.
+type SchemaIndex struct {
+	Name    string   `json:"name"`
+	Unique  bool     `json:"unique,omitempty"`
+	Columns []string `json:"columns"` // in index order; expressions left out
+}
+
+// schemaColumnsQueries list the columns of the base tables of the current
+// schema, table by table in column order.
+var schemaColumnsQueries = map[driverFlavor]string{
+	FlavorPostgres: `SELECT c.table_name, c.column_name, c.data_type, c.character_maximum_length,
+	c.numeric_precision, c.numeric_scale, c.is_nullable = 'YES', c.column_default
+FROM information_schema.columns c
+JOIN information_schema.tables t ON t.table_schema = c.table_schema AND t.table_name = c.table_name
+WHERE c.table_schema = current_schema() AND t.table_type = 'BASE TABLE'
+ORDER BY c.table_name, c.ordinal_position`,
+
+	FlavorMySQL: `SELECT c.TABLE_NAME, c.COLUMN_NAME, c.DATA_TYPE, c.CHARACTER_MAXIMUM_LENGTH,
+	c.NUMERIC_PRECISION, c.NUMERIC_SCALE, c.IS_NULLABLE = 'YES', c.COLUMN_DEFAULT
+FROM information_schema.COLUMNS c
+JOIN information_schema.TABLES t ON t.TABLE_SCHEMA = c.TABLE_SCHEMA AND t.TABLE_NAME = c.TABLE_NAME
+WHERE c.TABLE_SCHEMA = DATABASE() AND t.TABLE_TYPE = 'BASE TABLE'
+ORDER BY c.TABLE_NAME, c.ORDINAL_POSITION`,
+
+	FlavorOracle: `SELECT c.table_name, c.column_name, c.data_type, c.char_length,
+	c.data_precision, c.data_scale, CASE WHEN c.nullable = 'Y' THEN 1 ELSE 0 END, c.data_default
+FROM user_tab_columns c
+JOIN user_tables t ON t.table_name = c.table_name
+ORDER BY c.table_name, c.column_id`,
+}
+
+// schemaIndexesQueries list the columns of the indexes of the current
+// schema, index by index in index order.
+var schemaIndexesQueries = map[driverFlavor]string{
+	FlavorPostgres: `SELECT t.relname, i.relname, ix.indisunique, a.attname
+FROM pg_index ix
+JOIN pg_class i ON i.oid = ix.indexrelid
+JOIN pg_class t ON t.oid = ix.indrelid
+JOIN pg_namespace n ON n.oid = t.relnamespace
+JOIN LATERAL unnest(ix.indkey) WITH ORDINALITY AS k(attnum, ord) ON true
+JOIN pg_attribute a ON a.attrelid = t.oid AND a.attnum = k.attnum
+WHERE n.nspname = current_schema()
+ORDER BY t.relname, i.relname, k.ord`,
+
+	FlavorMySQL: `SELECT TABLE_NAME, INDEX_NAME, NON_UNIQUE = 0, COLUMN_NAME
+FROM information_schema.STATISTICS
+WHERE TABLE_SCHEMA = DATABASE() AND COLUMN_NAME IS NOT NULL
+ORDER BY TABLE_NAME, INDEX_NAME, SEQ_IN_INDEX`,
+
+	FlavorOracle: `SELECT i.table_name, i.index_name, CASE WHEN i.uniqueness = 'UNIQUE' THEN 1 ELSE 0 END, c.column_name
+FROM user_indexes i
+JOIN user_ind_columns c ON c.index_name = i.index_name
+ORDER BY i.table_name, i.index_name, c.column_position`,
+}
+
+// SchemaSnapshot reads the tables of db's current schema, or only those
+// named by tables, and returns them as canonical, indented JSON (see
+// Schema), to commit next to the migrations and compare in a release
+// pipeline with CompareSnapshots:
+//
+//	live, err := orm.SchemaSnapshot(ctx, db)
+//	want, _ := os.ReadFile("schema.json")
+//	report, err := orm.CompareSnapshots(want, live)
+//	if report != "" {
+//		log.Fatalf("schema drift:\n%s", report)
+//	}
+//
+// The same schema always gives the same bytes. Views, and index
+// expressions other than plain columns, are left out.
+func SchemaSnapshot(ctx context.Context, db *sql.DB, tables ...string) ([]byte, error) {
+	if db == nil {
+		return nil, ErrNilPointer
+	}
+	flavor := detectFlavor(db)
+
+	only := map[string]bool{}
+	for _, t := range tables {
+		only[strings.ToLower(t)] = true
+	}
+	byName := map[string]*SchemaTable{}
+	var found []*SchemaTable
+
+	err := catalogRows(ctx, db, flavor, schemaColumnsQueries[flavor], func(rows *sql.Rows) error {
+		var table, column, typ string
+		var length, precision, scale sql.NullInt64
+		var nullable bool
+		var def sql.NullString
+		if err := rows.Scan(&table, &column, &typ, &length, &precision, &scale, &nullable, &def); err != nil {
+			return err
+		}
+		table = strings.ToLower(table)
+		if len(only) > 0 && !only[table] {
+			return nil
+		}
+
+		t, ok := byName[table]
+		if !ok {
+			t = &SchemaTable{Name: table}
+			byName[table] = t
+			found = append(found, t)
+		}
+		c := SchemaColumn{Name: strings.ToLower(column), Type: snapshotType(typ, length, precision, scale), Nullable: nullable}
+		if d := strings.TrimSpace(def.String); def.Valid && d != "" && !strings.EqualFold(d, "NULL") {
+			c.Default = &d
+		}
+		t.Columns = append(t.Columns, c)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = catalogRows(ctx, db, flavor, schemaIndexesQueries[flavor], func(rows *sql.Rows) error {
+		var table, index, column string
+		var unique bool
+		if err := rows.Scan(&table, &index, &unique, &column); err != nil {
+			return err
+		}
+		t, ok := byName[strings.ToLower(table)]
+		if !ok {
+			return nil
+		}
+		index = strings.ToLower(index)
+		if n := len(t.Indexes); n == 0 || t.Indexes[n-1].Name != index {
+			t.Indexes = append(t.Indexes, SchemaIndex{Name: index, Unique: unique})
+		}
+		idx := &t.Indexes[len(t.Indexes)-1]
+		idx.Columns = append(idx.Columns, strings.ToLower(column))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	schema := Schema{Tables: []SchemaTable{}}
+	for _, t := range found {
+		slices.SortStableFunc(t.Indexes, func(a, b SchemaIndex) int { return strings.Compare(a.Name, b.Name) })
+		schema.Tables = append(schema.Tables, *t)
+	}
+	slices.SortFunc(schema.Tables, func(a, b SchemaTable) int { return strings.Compare(a.Name, b.Name) })
+
+	out, err := json.MarshalIndent(schema, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(out, '\n'), nil
+}
+
+// catalogRows runs a catalog query without arguments and calls scan on
+// each of its rows.
+func catalogRows(ctx context.Context, db *sql.DB, flavor driverFlavor, query string, scan func(rows *sql.Rows) error) error {
+	return runQuery(&queryCall{ctx: ctx, query: query, flavor: flavor}, func(query string) error {
+		rows, err := db.QueryContext(ctx, query)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			if err := scan(rows); err != nil {
+				return err
+			}
+		}
+		return rows.Err()
+	})
+}
+
+// snapshotType spells a catalog type with its length, or its precision and
+// scale where they are chosen per column rather than fixed by the type.
+func snapshotType(typ string, length, precision, scale sql.NullInt64) string {
+	typ = strings.ToLower(typ)
+	switch {
+	case length.Valid && length.Int64 > 0 && length.Int64 < 1<<16:
+		return fmt.Sprintf("%s(%d)", typ, length.Int64)
+	case (typ == "numeric" || typ == "decimal" || typ == "number") && precision.Valid:
+		return fmt.Sprintf("%s(%d,%d)", typ, precision.Int64, scale.Int64)
+	}
+	return typ
+}
+
+// CompareSnapshots compares two SchemaSnapshot outputs, the expected one
+// first, and returns a report of their differences, one per line, or ""
+// when the schemas are the same:
+//
+//	table audit_log: unexpected
+//	table orders: column discount missing
+//	table orders: column total type numeric(10,2), found numeric(12,2)
+//	table users: index idx_users_email columns (email), found (email, tenant_id)
+//
+// Column order is not compared.
+func CompareSnapshots(want, got []byte) (string, error) {
+	var a, b Schema
+	if err := json.Unmarshal(want, &a); err != nil {
+		return "", fmt.Errorf("orm: expected schema snapshot: %w", err)
+	}
+	if err := json.Unmarshal(got, &b); err != nil {
+		return "", fmt.Errorf("orm: schema snapshot: %w", err)
+	}
+
+	var report []string
+	diff := func(table, format string, args ...any) {
+		report = append(report, fmt.Sprintf("table %s: ", table)+fmt.Sprintf(format, args...))
+	}
+
+	wantTables := map[string]SchemaTable{}
+	for _, t := range a.Tables {
+		wantTables[t.Name] = t
+	}
+	gotTables := map[string]SchemaTable{}
+	for _, t := range b.Tables {
+		gotTables[t.Name] = t
+	}
+
+	for _, name := range sortedKeys(wantTables, gotTables) {
+		wt, inWant := wantTables[name]
+		gt, inGot := gotTables[name]
+		switch {
+		case !inGot:
+			report = append(report, fmt.Sprintf("table %s: missing", name))
+			continue
+		case !inWant:
+			report = append(report, fmt.Sprintf("table %s: unexpected", name))
+			continue
+		}
+
+		wantCols := map[string]SchemaColumn{}
+		for _, c := range wt.Columns {
+			wantCols[c.Name] = c
+		}
+		gotCols := map[string]SchemaColumn{}
+		for _, c := range gt.Columns {
+			gotCols[c.Name] = c
+		}
+		for _, col := range sortedKeys(wantCols, gotCols) {
+			wc, inWant := wantCols[col]
+			gc, inGot := gotCols[col]
+			switch {
+			case !inGot:
+				diff(name, "column %s missing", col)
+				continue
+			case !inWant:
+				diff(name, "column %s unexpected", col)
+				continue
+			}
+			if wc.Type != gc.Type {
+				diff(name, "column %s type %s, found %s", col, wc.Type, gc.Type)
+			}
+			if wc.Nullable != gc.Nullable {
+				diff(name, "column %s %s, found %s", col, nullability(wc.Nullable), nullability(gc.Nullable))
+			}
+			if wd, gd := defaultText(wc.Default), defaultText(gc.Default); wd != gd {
+				diff(name, "column %s default %s, found %s", col, wd, gd)
+			}
+		}
+
+		wantIdx := map[string]SchemaIndex{}
+		for _, i := range wt.Indexes {
+			wantIdx[i.Name] = i
+		}
+		gotIdx := map[string]SchemaIndex{}
+		for _, i := range gt.Indexes {
+			gotIdx[i.Name] = i
+		}
+		for _, idx := range sortedKeys(wantIdx, gotIdx) {
+			wi, inWant := wantIdx[idx]
+			gi, inGot := gotIdx[idx]
+			switch {
+			case !inGot:
+				diff(name, "index %s missing", idx)
+				continue
+			case !inWant:
+				diff(name, "index %s unexpected", idx)
+				continue
+			}
+			if !slices.Equal(wi.Columns, gi.Columns) {
+				diff(name, "index %s columns (%s), found (%s)", idx, strings.Join(wi.Columns, ", "), strings.Join(gi.Columns, ", "))
+			}
+			if wi.Unique != gi.Unique {
+				diff(name, "index %s %s, found %s", idx, uniqueness(wi.Unique), uniqueness(gi.Unique))
+			}
+		}
+	}
+	return strings.Join(report, "\n"), nil
+}
+
+// sortedKeys returns the keys of a and b, sorted and without duplicates.
+func sortedKeys[V any](a, b map[string]V) []string {
+	keys := make([]string, 0, len(a)+len(b))
+	for k := range a {
+		keys = append(keys, k)
+	}
+	for k := range b {
+		if _, ok := a[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+func nullability(nullable bool) string {
+	if nullable {
+		return "nullable"
+	}
+	return "not null"
+}
+
+func uniqueness(unique bool) string {
+	if unique {
+		return "unique"
+	}
+	return "not unique"
+}
+
+func defaultText(def *string) string {
+	if def == nil {
+		return "none"
+	}
+	return *def
+}
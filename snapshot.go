@@ -0,0 +1,178 @@
+package orm
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/godev90/validator/faults"
+)
+
+var (
+	errSnapshot = fmt.Errorf("orm: invalid snapshot")
+	ErrSnapshot = faults.New(errSnapshot, &faults.ErrAttr{
+		Code: http.StatusBadRequest,
+		Messages: []faults.LangPackage{
+			{
+				Tag:     faults.English,
+				Message: "orm: snapshot line %d: %s",
+			},
+		},
+	})
+)
+
+// DumpModel writes the rows of model's table matching the chain's
+// conditions to w as NDJSON, one object per row keyed by column, and
+// returns how many it wrote:
+//
+//	var buf bytes.Buffer
+//	n, err := adapter.(*orm.SqlQueryAdapter).Order("id").DumpModel(&Country{}, &buf)
+//
+// Values are the JSON of the model's field types (time.Time as RFC 3339
+// with nanoseconds, sql.Null* as objects), so RestoreModel reads them back
+// into the same types unchanged. Rows are written as they are read.
+// Fields tagged anonymize are rewritten on the way out; see Anonymizers.
+func (q *SqlQueryAdapter) DumpModel(model Tabler, w io.Writer) (int, error) {
+	return dumpModel(q.UseModel(model), model, w, nil)
+}
+
+// DumpModel writes the rows of model's table as NDJSON; see
+// SqlQueryAdapter.DumpModel. gorm reads all rows before the first is
+// written.
+func (g *GormAdapter) DumpModel(model Tabler, w io.Writer) (int, error) {
+	return dumpModel(g.UseModel(model), model, w, nil)
+}
+
+func dumpModel(q QueryAdapter, model Tabler, w io.Writer, policy AnonymizePolicy) (int, error) {
+	val, err := modelStruct(model, false)
+	if err != nil {
+		return 0, err
+	}
+	fields := modelFields(val.Type())
+	rules, err := anonymizers(val.Type(), fields, policy)
+	if err != nil {
+		return 0, err
+	}
+
+	n := 0
+	var line bytes.Buffer
+	write := func(dest any) error {
+		line.Reset()
+		v := reflect.Indirect(reflect.ValueOf(dest))
+		line.WriteByte('{')
+		for i, f := range fields {
+			if i > 0 {
+				line.WriteByte(',')
+			}
+			col, _ := json.Marshal(f.column)
+			out := v.FieldByIndex(f.Index).Interface()
+			if rules[i] != nil {
+				var err error
+				if out, err = anonymized(val.Type(), f, v.FieldByIndex(f.Index), rules[i]); err != nil {
+					return err
+				}
+			}
+			b, err := json.Marshal(out)
+			if err != nil {
+				return fmt.Errorf("orm: dump %s.%s: %w", val.Type().Name(), f.Name, err)
+			}
+			line.Write(col)
+			line.WriteByte(':')
+			line.Write(b)
+		}
+		line.WriteString("}\n")
+		if _, err := w.Write(line.Bytes()); err != nil {
+			return err
+		}
+		n++
+		// written; keep it out of the result
+		return ErrSkipRow
+	}
+
+	rows := reflect.New(reflect.SliceOf(val.Type()))
+	err = q.OnRow(write).Scan(rows.Interface())
+	return n, err
+}
+
+// RestoreModel inserts the rows of an NDJSON snapshot written by
+// DumpModel into model's table, in chunks through BulkInsert, and returns
+// how many it inserted. Columns the snapshot lacks keep their zero value,
+// columns the model lacks fail with ErrSnapshot. The rows keep the keys
+// they were dumped with, so restore into an empty table, or one without
+// those keys.
+func (q *SqlTransactionAdapter) RestoreModel(model Tabler, r io.Reader) (int, error) {
+	val, err := modelStruct(model, false)
+	if err != nil {
+		return 0, err
+	}
+	t := val.Type()
+	fields := cachedFieldMap(t)
+
+	n := 0
+	var chunk []Tabler
+	flush := func() error {
+		if len(chunk) == 0 {
+			return nil
+		}
+		if err := q.bulkInsert(chunk, true); err != nil {
+			return err
+		}
+		n += len(chunk)
+		chunk = nil
+		return nil
+	}
+
+	br := bufio.NewReader(r)
+	for lineNo := 1; ; lineNo++ {
+		line, err := br.ReadBytes('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return n, err
+		}
+		if len(bytes.TrimSpace(line)) > 0 {
+			row, rerr := snapshotRow(t, fields, line)
+			if rerr != nil {
+				return n, ErrSnapshot.Render(lineNo, rerr)
+			}
+			if chunk = append(chunk, row); len(chunk) == defaultBulkChunk {
+				if err := flush(); err != nil {
+					return n, err
+				}
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			break
+		}
+	}
+	return n, flush()
+}
+
+// snapshotRow decodes one snapshot line into a new model of type t.
+func snapshotRow(t reflect.Type, fields map[string]*modelField, line []byte) (Tabler, error) {
+	var cols map[string]json.RawMessage
+	if err := json.Unmarshal(line, &cols); err != nil {
+		return nil, err
+	}
+
+	ptr := reflect.New(t)
+	for col, raw := range cols {
+		f, ok := fields[strings.ToLower(col)]
+		if !ok {
+			return nil, fmt.Errorf("%s has no column %q", t.Name(), col)
+		}
+		if err := json.Unmarshal(raw, ptr.Elem().FieldByIndex(f.Index).Addr().Interface()); err != nil {
+			return nil, fmt.Errorf("%s: %v", col, err)
+		}
+	}
+
+	model, ok := ptr.Interface().(Tabler)
+	if !ok {
+		return nil, ErrTablerNotImplemented
+	}
+	return model, nil
+}
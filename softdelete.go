@@ -0,0 +1,80 @@
+package orm
+
+import (
+	"reflect"
+	"sync"
+
+	"gorm.io/gorm"
+)
+
+// deletedAtColumn is the column marking soft-deleted rows.
+const deletedAtColumn = "deleted_at"
+
+var (
+	softDeleteCache sync.Map // reflect.Type -> softDelete
+
+	gormDeletedAtT = reflect.TypeOf(gorm.DeletedAt{})
+)
+
+// softDelete describes the soft-delete column of a model, if it has one.
+type softDelete struct {
+	column string
+	// gormManaged is set for gorm.DeletedAt fields, which gorm filters
+	// itself.
+	gormManaged bool
+}
+
+// softDeleteOf finds model's soft-delete column: the field mapped to
+// deleted_at, or one tagged softDelete.
+func softDeleteOf(model any) (softDelete, bool) {
+	t := reflect.TypeOf(model)
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return softDelete{}, false
+	}
+
+	if cached, ok := softDeleteCache.Load(t); ok {
+		sd := cached.(softDelete)
+		return sd, sd.column != ""
+	}
+
+	var sd softDelete
+	for _, f := range modelFields(t) {
+		col := f.column
+		if col == deletedAtColumn || tagFlag(f.Tag.Get("sql"), "softDelete") {
+			sd = softDelete{column: col, gormManaged: f.Type == gormDeletedAtT}
+			break
+		}
+	}
+
+	softDeleteCache.Store(t, sd)
+	return sd, sd.column != ""
+}
+
+// softDeleteCond is the condition hiding q's soft-deleted rows, or "" when
+// the model has no soft-delete column or the chain is Unscoped. The column
+// is qualified with the table, schema included, once joins could make it
+// ambiguous.
+func (q *SqlQueryAdapter) softDeleteCond() string {
+	if q.unscoped {
+		return ""
+	}
+	sd, ok := softDeleteOf(q.model)
+	if !ok {
+		return ""
+	}
+
+	col := quoteIdent(q.flavor, sd.column)
+	if len(q.joins) > 0 && plainIdent.MatchString(q.table) {
+		col = quoteIdent(q.flavor, q.table) + "." + col
+	}
+	return col + " IS NULL"
+}
+
+// HardDelete removes the row of src by primary key even when the model has
+// a soft-delete column. Counter caches are updated as by Delete.
+func (q *SqlTransactionAdapter) HardDelete(src Tabler) error {
+	return q.delete(src, false)
+}
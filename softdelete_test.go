@@ -0,0 +1,44 @@
+package orm
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+type deletedEvent struct {
+	ID        int64      `sql:"column:id;primaryKey"`
+	UserID    int64      `sql:"column:user_id"`
+	DeletedAt *time.Time `sql:"column:deleted_at"`
+}
+
+func (deletedEvent) TableName() string { return "events" }
+
+func TestSoftDeleteQualifiesPrefixedTable(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	SetFlavor(db, FlavorPostgres)
+
+	q := NewSqlAdapter(db).WithTablePrefix("analytics.").UseModel(&deletedEvent{})
+
+	mock.ExpectQuery(`SELECT * FROM "analytics"."events" WHERE "deleted_at" IS NULL`).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectQuery(`SELECT * FROM "analytics"."events" JOIN users ON users.id = events.user_id ` +
+		`WHERE "analytics"."events"."deleted_at" IS NULL`).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	var got []deletedEvent
+	if err := q.Scan(&got); err != nil {
+		t.Fatal(err)
+	}
+	if err := q.UnsafeJoin("JOIN users ON users.id = events.user_id").Scan(&got); err != nil {
+		t.Fatal(err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
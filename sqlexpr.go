@@ -0,0 +1,616 @@
+package orm
+
+import (
+	"fmt"
+	"strings"
+	"sync/atomic"
+)
+
+// A small lexer and parser for the raw SQL callers hand the builder as
+// HAVING conditions and JOIN clauses. Instead of scanning for blacklisted
+// words, the input has to fit a grammar that only knows columns, numbers,
+// ? placeholders, whitelisted functions, comparison, arithmetic and logical
+// operators (and, for joins, table sources and, if enabled, simple
+// sub-selects), so string literals, comments and statement separators
+// can't get through.
+
+type sqlTokenKind uint8
+
+const (
+	tokIdent sqlTokenKind = iota
+	tokNumber
+	tokPlaceholder
+	tokOp
+	tokLParen
+	tokRParen
+	tokComma
+	tokStar
+	tokEOF
+)
+
+type sqlToken struct {
+	kind sqlTokenKind
+	text string
+	pos  int
+}
+
+// upper is the token text in upper case, for keyword comparisons.
+func (t sqlToken) upper() string {
+	return strings.ToUpper(t.text)
+}
+
+// lexSQLExpr splits s into tokens, rejecting any character outside the
+// expression grammar, quotes and comment markers included.
+func lexSQLExpr(s string) ([]sqlToken, error) {
+	var toks []sqlToken
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case isIdentStart(c):
+			j := i + 1
+			for j < len(s) && (isIdentStart(s[j]) || isDigit(s[j]) || s[j] == '$') {
+				j++
+			}
+			toks = append(toks, sqlToken{tokIdent, s[i:j], i})
+			i = j
+		case isDigit(c):
+			j, dot := i+1, false
+			for j < len(s) && (isDigit(s[j]) || s[j] == '.' && !dot) {
+				dot = dot || s[j] == '.'
+				j++
+			}
+			toks = append(toks, sqlToken{tokNumber, s[i:j], i})
+			i = j
+		case c == '"' || c == '`':
+			// a quoted identifier, which must still be a plain name
+			j := i + 1
+			for j < len(s) && (isIdentStart(s[j]) || isDigit(s[j]) || s[j] == '$') {
+				j++
+			}
+			if j == i+1 || j == len(s) || s[j] != c || !isIdentStart(s[i+1]) {
+				return nil, fmt.Errorf("unexpected %q at %d", c, i)
+			}
+			toks = append(toks, sqlToken{tokIdent, s[i : j+1], i})
+			i = j + 1
+		case c == '?':
+			toks = append(toks, sqlToken{tokPlaceholder, "?", i})
+			i++
+		case c == '(':
+			toks = append(toks, sqlToken{tokLParen, "(", i})
+			i++
+		case c == ')':
+			toks = append(toks, sqlToken{tokRParen, ")", i})
+			i++
+		case c == ',':
+			toks = append(toks, sqlToken{tokComma, ",", i})
+			i++
+		case c == '.':
+			toks = append(toks, sqlToken{tokOp, ".", i})
+			i++
+		case c == '*':
+			toks = append(toks, sqlToken{tokStar, "*", i})
+			i++
+		case strings.HasPrefix(s[i:], "--") || strings.HasPrefix(s[i:], "/*"):
+			return nil, fmt.Errorf("comment at %d", i)
+		case strings.HasPrefix(s[i:], "<=") || strings.HasPrefix(s[i:], ">=") ||
+			strings.HasPrefix(s[i:], "<>") || strings.HasPrefix(s[i:], "!="):
+			toks = append(toks, sqlToken{tokOp, s[i : i+2], i})
+			i += 2
+		case strings.IndexByte("=<>+-/%", c) >= 0:
+			toks = append(toks, sqlToken{tokOp, s[i : i+1], i})
+			i++
+		default:
+			return nil, fmt.Errorf("unexpected %q at %d", c, i)
+		}
+	}
+	return append(toks, sqlToken{kind: tokEOF, pos: len(s)}), nil
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// exprKeywords can't be used as column names in an expression.
+var exprKeywords = map[string]bool{
+	"AND": true, "OR": true, "NOT": true, "IS": true, "NULL": true, "IN": true,
+	"LIKE": true, "ILIKE": true, "BETWEEN": true, "DISTINCT": true, "TRUE": true,
+	"FALSE": true, "SELECT": true, "FROM": true, "WHERE": true, "UNION": true,
+	"JOIN": true, "ON": true, "USING": true, "AS": true, "CASE": true,
+	"WHEN": true, "THEN": true, "ELSE": true, "END": true, "EXISTS": true,
+	"INTO": true, "ORDER": true, "GROUP": true, "HAVING": true, "LIMIT": true,
+	"BY": true, "INNER": true, "LEFT": true, "RIGHT": true, "FULL": true,
+	"OUTER": true, "CROSS": true, "LATERAL": true, "NATURAL": true,
+}
+
+// aggregateFuncs are the functions allowed in HAVING conditions.
+var aggregateFuncs = map[string]bool{
+	"COUNT": true, "SUM": true, "AVG": true, "MIN": true, "MAX": true,
+	"COALESCE": true, "ABS": true, "ROUND": true, "LOWER": true, "UPPER": true,
+}
+
+// joinFuncs are the functions allowed in join conditions.
+var joinFuncs = map[string]bool{
+	"COALESCE": true, "ABS": true, "ROUND": true, "LOWER": true, "UPPER": true,
+}
+
+// exprParser checks a token stream against the expression grammar:
+//
+//	expr      = and { OR and }
+//	and       = not { AND not }
+//	not       = NOT not | predicate
+//	predicate = operand [ cmp operand | IS [NOT] NULL
+//	          | [NOT] BETWEEN operand AND operand
+//	          | [NOT] IN "(" operand { "," operand } ")"
+//	          | [NOT] (LIKE|ILIKE) operand ]
+//	operand   = term { (+|-|*|/|%) term }
+//	term      = "(" expr ")" | func "(" [DISTINCT] ( "*" | expr { "," expr } ) ")"
+//	          | column | number | ? | TRUE | FALSE | NULL | - term
+type exprParser struct {
+	toks       []sqlToken
+	pos        int
+	funcs      map[string]bool
+	subselects bool // joins may join a sub-select
+}
+
+// validateSQLExpr reports whether s is a single expression of the grammar,
+// calling only funcs.
+func validateSQLExpr(s string, funcs map[string]bool) error {
+	toks, err := lexSQLExpr(s)
+	if err != nil {
+		return err
+	}
+	p := &exprParser{toks: toks, funcs: funcs}
+	if err := p.expr(); err != nil {
+		return err
+	}
+	if t := p.peek(); t.kind != tokEOF {
+		return p.unexpected()
+	}
+	return nil
+}
+
+func (p *exprParser) peek() sqlToken {
+	return p.toks[p.pos]
+}
+
+func (p *exprParser) next() sqlToken {
+	t := p.toks[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+// keyword consumes the next token if it is the keyword kw.
+func (p *exprParser) keyword(kw string) bool {
+	if t := p.peek(); t.kind == tokIdent && t.upper() == kw {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *exprParser) expect(kind sqlTokenKind) error {
+	if p.peek().kind != kind {
+		return p.unexpected()
+	}
+	p.pos++
+	return nil
+}
+
+func (p *exprParser) unexpected() error {
+	t := p.peek()
+	if t.kind == tokEOF {
+		return fmt.Errorf("unexpected end of expression")
+	}
+	return fmt.Errorf("unexpected %q at %d", t.text, t.pos)
+}
+
+func (p *exprParser) expr() error {
+	if err := p.and(); err != nil {
+		return err
+	}
+	for p.keyword("OR") {
+		if err := p.and(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *exprParser) and() error {
+	if err := p.not(); err != nil {
+		return err
+	}
+	for p.keyword("AND") {
+		if err := p.not(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *exprParser) not() error {
+	if p.keyword("NOT") {
+		return p.not()
+	}
+	return p.predicate()
+}
+
+func (p *exprParser) predicate() error {
+	if err := p.operand(); err != nil {
+		return err
+	}
+
+	if t := p.peek(); t.kind == tokOp {
+		switch t.text {
+		case "=", "!=", "<>", "<", ">", "<=", ">=":
+			p.pos++
+			return p.operand()
+		}
+	}
+
+	if p.keyword("IS") {
+		p.keyword("NOT")
+		if !p.keyword("NULL") {
+			return p.unexpected()
+		}
+		return nil
+	}
+
+	negated := p.keyword("NOT")
+	switch {
+	case p.keyword("BETWEEN"):
+		if err := p.operand(); err != nil {
+			return err
+		}
+		if !p.keyword("AND") {
+			return p.unexpected()
+		}
+		return p.operand()
+	case p.keyword("IN"):
+		if err := p.expect(tokLParen); err != nil {
+			return err
+		}
+		for {
+			if err := p.operand(); err != nil {
+				return err
+			}
+			if p.peek().kind != tokComma {
+				break
+			}
+			p.pos++
+		}
+		return p.expect(tokRParen)
+	case p.keyword("LIKE"), p.keyword("ILIKE"):
+		return p.operand()
+	}
+
+	// a NOT here must have been followed by one of the above
+	if negated {
+		return p.unexpected()
+	}
+	return nil
+}
+
+func (p *exprParser) operand() error {
+	if err := p.term(); err != nil {
+		return err
+	}
+	for {
+		t := p.peek()
+		if t.kind == tokStar || t.kind == tokOp && strings.Contains("+-/%", t.text) {
+			p.pos++
+			if err := p.term(); err != nil {
+				return err
+			}
+			continue
+		}
+		return nil
+	}
+}
+
+func (p *exprParser) term() error {
+	if p.peek().kind == tokEOF {
+		return p.unexpected()
+	}
+	t := p.next()
+	switch t.kind {
+	case tokNumber, tokPlaceholder:
+		return nil
+	case tokLParen:
+		if err := p.expr(); err != nil {
+			return err
+		}
+		return p.expect(tokRParen)
+	case tokOp:
+		if t.text == "-" || t.text == "+" {
+			return p.term()
+		}
+	case tokIdent:
+		kw := t.upper()
+		switch {
+		case kw == "TRUE" || kw == "FALSE" || kw == "NULL":
+			return nil
+		case p.peek().kind == tokLParen:
+			if !p.funcs[kw] {
+				return fmt.Errorf("function %s is not allowed", t.text)
+			}
+			p.pos++
+			return p.args()
+		case exprKeywords[kw]:
+			p.pos--
+			return p.unexpected()
+		}
+		return p.qualified()
+	}
+	p.pos--
+	return p.unexpected()
+}
+
+// qualified finishes a column reference after its first part.
+func (p *exprParser) qualified() error {
+	for parts := 1; p.peek().kind == tokOp && p.peek().text == "."; parts++ {
+		p.pos++
+		if t := p.peek(); t.kind != tokIdent || exprKeywords[t.upper()] || parts == 3 {
+			return p.unexpected()
+		}
+		p.pos++
+	}
+	return nil
+}
+
+// args parses a function's argument list after its "(".
+func (p *exprParser) args() error {
+	p.keyword("DISTINCT")
+	if p.peek().kind == tokStar {
+		p.pos++
+		return p.expect(tokRParen)
+	}
+	for {
+		if err := p.expr(); err != nil {
+			return err
+		}
+		if p.peek().kind != tokComma {
+			break
+		}
+		p.pos++
+	}
+	return p.expect(tokRParen)
+}
+
+// joinSubselects is set by AllowJoinSubselects.
+var joinSubselects atomic.Bool
+
+// AllowJoinSubselects lets validated joins (SafeJoin, Join, Fragment.Join)
+// join a sub-select, which is off by default: a sub-select reads whatever
+// table it names, so only enable it where join clauses never come from
+// user input. System catalogs stay rejected either way.
+func AllowJoinSubselects(allow bool) {
+	joinSubselects.Store(allow)
+}
+
+// systemSchemas are the schemas of the databases' own catalogs.
+var systemSchemas = map[string]bool{
+	"information_schema": true, "pg_catalog": true, "pg_toast": true,
+	"mysql": true, "performance_schema": true, "sys": true,
+}
+
+// validateJoinSQL reports whether s is a sequence of joins:
+//
+//	joins  = join { join }
+//	join   = [ INNER | CROSS | (LEFT|RIGHT|FULL) [OUTER] ] JOIN source [ [AS] alias ]
+//	         ( ON expr | USING "(" column { "," column } ")" )
+//	source = table [ "." table ] | "(" select ")"
+//	select = SELECT [DISTINCT] item { "," item } FROM table [ [AS] alias ]
+//	         [ WHERE expr ] [ GROUP BY column { "," column } ] [ HAVING expr ]
+//	item   = "*" | table ".*" | expr [ [AS] alias ]
+//
+// A CROSS JOIN takes no condition. Conditions may call joinFuncs; a
+// sub-select, accepted only after AllowJoinSubselects, may also aggregate.
+// No name anywhere in s may refer to a system catalog.
+func validateJoinSQL(s string) error {
+	toks, err := lexSQLExpr(s)
+	if err != nil {
+		return err
+	}
+	if err := checkSystemNames(toks); err != nil {
+		return err
+	}
+	p := &exprParser{toks: toks, funcs: joinFuncs, subselects: joinSubselects.Load()}
+	for {
+		if err := p.join(); err != nil {
+			return err
+		}
+		if p.peek().kind == tokEOF {
+			return nil
+		}
+	}
+}
+
+// checkSystemNames rejects names of system catalogs: pg_* relations and
+// schemas, information_schema, and anything qualified by a system schema
+// (mysql.user, sys.objects).
+func checkSystemNames(toks []sqlToken) error {
+	for i, t := range toks {
+		if t.kind != tokIdent {
+			continue
+		}
+		name := strings.ToLower(strings.Trim(t.text, "\"`"))
+		qualifier := i+1 < len(toks) && toks[i+1].kind == tokOp && toks[i+1].text == "."
+		if strings.HasPrefix(name, "pg_") || name == "information_schema" || qualifier && systemSchemas[name] {
+			return fmt.Errorf("system catalog %s at %d", t.text, t.pos)
+		}
+	}
+	return nil
+}
+
+func (p *exprParser) join() error {
+	cross := false
+	switch {
+	case p.keyword("INNER"):
+	case p.keyword("CROSS"):
+		cross = true
+	case p.keyword("LEFT"), p.keyword("RIGHT"), p.keyword("FULL"):
+		p.keyword("OUTER")
+	}
+	if !p.keyword("JOIN") {
+		return p.unexpected()
+	}
+
+	if err := p.source(); err != nil {
+		return err
+	}
+	if cross {
+		return nil
+	}
+
+	switch {
+	case p.keyword("ON"):
+		return p.expr()
+	case p.keyword("USING"):
+		return p.columnList(true)
+	}
+	return p.unexpected()
+}
+
+// source parses a joined table or sub-select and its optional alias.
+func (p *exprParser) source() error {
+	if p.peek().kind == tokLParen {
+		if !p.subselects {
+			return fmt.Errorf("sub-select at %d needs AllowJoinSubselects", p.peek().pos)
+		}
+		p.pos++
+		if err := p.subselect(); err != nil {
+			return err
+		}
+		if err := p.expect(tokRParen); err != nil {
+			return err
+		}
+	} else if err := p.name(); err != nil {
+		return err
+	} else if err := p.qualified(); err != nil {
+		return err
+	}
+	return p.alias()
+}
+
+func (p *exprParser) subselect() error {
+	if !p.keyword("SELECT") {
+		return p.unexpected()
+	}
+	outer := p.funcs
+	p.funcs = aggregateFuncs
+	defer func() { p.funcs = outer }()
+
+	p.keyword("DISTINCT")
+	for {
+		if err := p.selectItem(); err != nil {
+			return err
+		}
+		if p.peek().kind != tokComma {
+			break
+		}
+		p.pos++
+	}
+
+	if !p.keyword("FROM") {
+		return p.unexpected()
+	}
+	if err := p.name(); err != nil {
+		return err
+	}
+	if err := p.qualified(); err != nil {
+		return err
+	}
+	if err := p.alias(); err != nil {
+		return err
+	}
+
+	if p.keyword("WHERE") {
+		if err := p.expr(); err != nil {
+			return err
+		}
+	}
+	if p.keyword("GROUP") {
+		if !p.keyword("BY") {
+			return p.unexpected()
+		}
+		if err := p.columnList(false); err != nil {
+			return err
+		}
+	}
+	if p.keyword("HAVING") {
+		return p.expr()
+	}
+	return nil
+}
+
+func (p *exprParser) selectItem() error {
+	if p.peek().kind == tokStar {
+		p.pos++
+		return nil
+	}
+	// table.*
+	if p.peek().kind == tokIdent && p.pos+2 < len(p.toks) &&
+		p.toks[p.pos+1].text == "." && p.toks[p.pos+2].kind == tokStar {
+		p.pos += 3
+		return nil
+	}
+	if err := p.expr(); err != nil {
+		return err
+	}
+	return p.alias()
+}
+
+// alias consumes an optional [AS] name.
+func (p *exprParser) alias() error {
+	if p.keyword("AS") {
+		return p.name()
+	}
+	if t := p.peek(); t.kind == tokIdent && !exprKeywords[t.upper()] {
+		p.pos++
+	}
+	return nil
+}
+
+// name consumes an identifier that isn't a keyword.
+func (p *exprParser) name() error {
+	if t := p.peek(); t.kind != tokIdent || exprKeywords[t.upper()] {
+		return p.unexpected()
+	}
+	p.pos++
+	return nil
+}
+
+// columnList parses column { "," column }, in parentheses when paren is
+// set.
+func (p *exprParser) columnList(paren bool) error {
+	if paren {
+		if err := p.expect(tokLParen); err != nil {
+			return err
+		}
+	}
+	for {
+		if err := p.name(); err != nil {
+			return err
+		}
+		if err := p.qualified(); err != nil {
+			return err
+		}
+		if p.peek().kind != tokComma {
+			break
+		}
+		p.pos++
+	}
+	if paren {
+		return p.expect(tokRParen)
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package orm
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateJoinClauseSystemCatalogs(t *testing.T) {
+	rejected := []string{
+		"JOIN (SELECT usename, passwd FROM pg_shadow) s ON TRUE",
+		"LEFT JOIN pg_authid a ON TRUE",
+		"JOIN pg_catalog.pg_user u ON TRUE",
+		"JOIN mysql.user u ON u.id = users.id",
+		"JOIN sys.objects o ON o.id = users.id",
+		`JOIN "pg_authid" a ON TRUE`,
+		"JOIN orders o ON o.id = (information_schema.x)",
+	}
+	for _, subselects := range []bool{false, true} {
+		AllowJoinSubselects(subselects)
+		for _, clause := range rejected {
+			if err := ValidateJoinClause(clause); err == nil {
+				t.Errorf("subselects=%v: ValidateJoinClause(%q) = nil, want an error", subselects, clause)
+			}
+		}
+	}
+	AllowJoinSubselects(false)
+}
+
+func TestValidateJoinClauseSubselectOptIn(t *testing.T) {
+	clauses := []string{
+		"JOIN (SELECT * FROM admins) a ON 1=1",
+		"JOIN (SELECT user_id, COUNT(*) AS n FROM orders WHERE status = ? GROUP BY user_id) o ON o.user_id = users.id",
+	}
+
+	AllowJoinSubselects(false)
+	for _, clause := range clauses {
+		err := ValidateJoinClause(clause)
+		if err == nil || !strings.Contains(err.Error(), "AllowJoinSubselects") {
+			t.Errorf("ValidateJoinClause(%q) = %v, want a sub-select error", clause, err)
+		}
+	}
+
+	AllowJoinSubselects(true)
+	defer AllowJoinSubselects(false)
+	for _, clause := range clauses {
+		if err := ValidateJoinClause(clause); err != nil {
+			t.Errorf("with AllowJoinSubselects: ValidateJoinClause(%q) = %v, want nil", clause, err)
+		}
+	}
+}
+
+func TestValidateJoinClauseTables(t *testing.T) {
+	accepted := []string{
+		"LEFT JOIN profiles p ON (p.user_id = users.id AND p.active = ?) OR p.id IS NULL",
+		"INNER JOIN orders USING (tenant_id, user_id)",
+		"JOIN sales.orders o ON o.user_id = users.id",
+		"CROSS JOIN regions",
+	}
+	for _, clause := range accepted {
+		if err := ValidateJoinClause(clause); err != nil {
+			t.Errorf("ValidateJoinClause(%q) = %v, want nil", clause, err)
+		}
+	}
+}
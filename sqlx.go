@@ -0,0 +1,183 @@
+package orm
+
+import (
+	"database/sql"
+	"log"
+	"reflect"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// SqlxAdapter runs queries on an *sqlx.DB so code moving between sqlx and
+// this package can share one connection pool. Queries are built like
+// SqlQueryAdapter's; Scan and First go through sqlx's StructScan and the
+// DB's mapper, so `db` tags and sqlx's matching rules apply to destinations.
+// Count, Explain and PrepareStmt work as on SqlQueryAdapter.
+//
+// Rows are read through the interceptor chain rather than sqlx's Queryx, so
+// a DB switched to Unsafe() still rejects columns missing from the
+// destination.
+type SqlxAdapter struct {
+	builtAdapter
+	x *sqlx.DB
+}
+
+func NewSqlxAdapter(db *sqlx.DB) QueryAdapter {
+	b := newBuilder(detectFlavor(db.DB))
+	b.db = db.DB
+	return (&SqlxAdapter{x: db}).with(b)
+}
+
+func (s *SqlxAdapter) with(b *SqlQueryAdapter) QueryAdapter {
+	cp := &SqlxAdapter{x: s.x}
+	cp.builtAdapter = builtAdapter{b: b, wrap: cp.with}
+	return cp
+}
+
+// Sqlx returns the wrapped *sqlx.DB.
+func (s *SqlxAdapter) Sqlx() *sqlx.DB {
+	return s.x
+}
+
+// WhereNamed adds a condition with sqlx named parameters (:name) bound from
+// arg, a struct or map[string]any. Slice values expand as in Where.
+func (s *SqlxAdapter) WhereNamed(cond string, arg any) QueryAdapter {
+	query, args, err := sqlx.Named(cond, arg)
+	if err != nil {
+		log.Printf("WARNING: invalid named WHERE clause %q: %v", cond, err)
+		return s
+	}
+	return s.Where(query, args...)
+}
+
+func (s *SqlxAdapter) Count(target *int64) error {
+	return s.b.Count(target)
+}
+
+func (s *SqlxAdapter) Explain(analyze bool) (string, error) {
+	return s.b.Explain(analyze)
+}
+
+func (s *SqlxAdapter) PrepareStmt() QueryAdapter {
+	return s.rewrap(s.b.PrepareStmt())
+}
+
+func (s *SqlxAdapter) Driver() driverFlavor {
+	return s.b.Driver()
+}
+
+// DB returns the *sql.DB under the wrapped *sqlx.DB.
+func (s *SqlxAdapter) DB() *sql.DB {
+	return s.x.DB
+}
+
+func (s *SqlxAdapter) Stats() sql.DBStats {
+	return s.x.DB.Stats()
+}
+
+func (s *SqlxAdapter) Scan(dest any) error {
+	q, err := s.b.withDestModel(dest)
+	if err != nil {
+		return err
+	}
+	if err := s.scan(q, dest, false); err != nil || q.dryRun {
+		return err
+	}
+	if err := filterRows(q.rowFuncs, dest); err != nil {
+		return err
+	}
+	if err := preloadRelations(s, dest, q.preloads); err != nil {
+		return err
+	}
+	return afterFind(q.ctx, dest)
+}
+
+func (s *SqlxAdapter) First(dest any) error {
+	q, err := s.b.withDestModel(dest)
+	if err != nil {
+		return err
+	}
+	if q.limit == nil {
+		q = q.Limit(1).(*SqlQueryAdapter)
+	}
+	if err := s.scan(q, dest, true); err != nil || q.dryRun {
+		return err
+	}
+	if err := filterRows(q.rowFuncs, dest); err != nil {
+		return err
+	}
+	if err := preloadRelations(s, dest, q.preloads); err != nil {
+		return err
+	}
+	return afterFind(q.ctx, dest)
+}
+
+func (s *SqlxAdapter) scan(q *SqlQueryAdapter, dest any, first bool) error {
+	sqlStr, args := q.build(false)
+
+	if q.dryRun {
+		q.recorder.record(sqlStr, args)
+		return nil
+	}
+
+	val := reflect.ValueOf(dest)
+	if val.Kind() != reflect.Ptr || val.IsNil() {
+		return ErrNilPointer
+	}
+
+	raw, release, err := q.query(sqlStr, args)
+	if err != nil {
+		return err
+	}
+	defer release()
+	defer raw.Close()
+
+	rows := &sqlx.Rows{Rows: raw, Mapper: s.x.Mapper}
+
+	if mp, ok := dest.(*[]map[string]any); ok {
+		for rows.Next() {
+			rec := map[string]any{}
+			if err := rows.MapScan(rec); err != nil {
+				return err
+			}
+			for k, v := range rec {
+				if b, ok := v.([]byte); ok {
+					rec[k] = string(b)
+				}
+			}
+			*mp = append(*mp, rec)
+		}
+		return rows.Err()
+	}
+
+	target := val.Elem()
+	if target.Kind() == reflect.Slice {
+		// slices come back empty, never nil, when nothing matches
+		if target.IsNil() {
+			target.Set(reflect.MakeSlice(target.Type(), 0, 0))
+		}
+		if err := sqlx.StructScan(rows, dest); err != nil {
+			return err
+		}
+		if first && target.Len() == 0 {
+			return errRecordNotFound
+		}
+		return nil
+	}
+
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		return errRecordNotFound
+	}
+	if isStructElem(target.Type()) {
+		err = rows.StructScan(dest)
+	} else {
+		err = rows.Scan(dest)
+	}
+	if err != nil {
+		return err
+	}
+	return rows.Err()
+}
@@ -0,0 +1,106 @@
+package orm
+
+import (
+	"context"
+	"maps"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// StatementTagger returns tags for the statements run with ctx, e.g. the
+// application name or the trace id of the span in ctx.
+type StatementTagger func(ctx context.Context) map[string]string
+
+var (
+	taggerMu sync.RWMutex
+	tagger   StatementTagger
+)
+
+// SetStatementTagger replaces the process-wide StatementTagger. Tags set on
+// the context with WithStatementTags win over its own.
+func SetStatementTagger(fn StatementTagger) {
+	taggerMu.Lock()
+	defer taggerMu.Unlock()
+	tagger = fn
+}
+
+type statementTagsKey struct{}
+
+// WithStatementTags returns a context whose statements carry tags, added to
+// those of ctx, in a trailing comment in the sqlcommenter format, so the
+// database's own views of running and slow statements tell which endpoint
+// sent them:
+//
+//	ctx = orm.WithStatementTags(r.Context(), map[string]string{"route": "/orders"})
+//	// SELECT * FROM "orders" WHERE user_id = $1 /*app='checkout',route='%2Forders'*/
+//
+// Keys and values are URL-encoded and sorted by key. Statements run from the
+// prepared-statement cache are not tagged, and the gorm adapter places the
+// comment after the SELECT list.
+func WithStatementTags(ctx context.Context, tags map[string]string) context.Context {
+	merged := maps.Clone(statementTags(ctx))
+	if merged == nil {
+		merged = make(map[string]string, len(tags))
+	}
+	maps.Copy(merged, tags)
+	return context.WithValue(ctx, statementTagsKey{}, merged)
+}
+
+// statementTags returns the tags set on ctx, without the tagger's.
+func statementTags(ctx context.Context) map[string]string {
+	tags, _ := ctx.Value(statementTagsKey{}).(map[string]string)
+	return tags
+}
+
+// statementTagComment renders the tags of ctx as the text of an sqlcommenter
+// comment, or "" when there are none.
+func statementTagComment(ctx context.Context) string {
+	taggerMu.RLock()
+	fn := tagger
+	taggerMu.RUnlock()
+
+	var tags map[string]string
+	if fn != nil {
+		tags = maps.Clone(fn(ctx))
+	}
+	if own := statementTags(ctx); len(own) > 0 {
+		if tags == nil {
+			tags = make(map[string]string, len(own))
+		}
+		maps.Copy(tags, own)
+	}
+	if len(tags) == 0 {
+		return ""
+	}
+
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, 0, len(keys))
+	for _, k := range keys {
+		pairs = append(pairs, tagEscape(k)+"='"+tagEscape(tags[k])+"'")
+	}
+	return strings.Join(pairs, ",")
+}
+
+// tagEscape percent-encodes s, leaving no quotes, comment delimiters or
+// placeholder characters (?, $, :) a driver might trip over.
+func tagEscape(s string) string {
+	return strings.ReplaceAll(url.QueryEscape(s), "+", "%20")
+}
+
+// tagStatement appends the statement tags of ctx to query.
+func tagStatement(ctx context.Context, query string) string {
+	if query == "" {
+		return query
+	}
+	if c := statementTagComment(ctx); c != "" {
+		return query + " /*" + c + "*/"
+	}
+	return query
+}
@@ -0,0 +1,120 @@
+package orm
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/godev90/validator/faults"
+)
+
+var (
+	errNoTableStats = fmt.Errorf("orm: no table statistics")
+	ErrNoTableStats = faults.New(errNoTableStats, &faults.ErrAttr{
+		Code: http.StatusNotFound,
+		Messages: []faults.LangPackage{
+			{
+				Tag:     faults.English,
+				Message: "orm: no statistics for table %q",
+			},
+		},
+	})
+)
+
+// TableStatistics is what the database catalog knows about a table's size.
+// The figures come from planner statistics and may lag behind the table by
+// up to the last ANALYZE (or auto-analyze).
+type TableStatistics struct {
+	Table      string
+	Rows       int64 // estimated row count; -1 when never analyzed
+	TotalBytes int64 // table, indexes and out-of-line storage
+	IndexBytes int64
+
+	// Bloat hints: DeadRows (PostgreSQL) are rows awaiting vacuum,
+	// FreeBytes (MySQL) space allocated to the table but unused.
+	// BloatRatio is their share of the table, 0 when unknown (Oracle).
+	DeadRows   int64
+	FreeBytes  int64
+	BloatRatio float64
+}
+
+// tableStatsQueries read TableStatistics fields from the catalog, as rows,
+// total bytes, index bytes, dead rows and free bytes, for the table named
+// by the single argument.
+var tableStatsQueries = map[driverFlavor]string{
+	FlavorPostgres: `SELECT c.reltuples::bigint, pg_total_relation_size(c.oid), pg_indexes_size(c.oid),
+	COALESCE(s.n_dead_tup, 0), 0
+FROM pg_class c LEFT JOIN pg_stat_user_tables s ON s.relid = c.oid
+WHERE c.oid = to_regclass(?)`,
+
+	FlavorMySQL: `SELECT COALESCE(TABLE_ROWS, -1), DATA_LENGTH + INDEX_LENGTH, INDEX_LENGTH, 0, DATA_FREE
+FROM information_schema.TABLES
+WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ?`,
+
+	FlavorOracle: `SELECT NVL(t.num_rows, -1),
+	NVL((SELECT SUM(s.bytes) FROM user_segments s WHERE s.segment_name = t.table_name), 0) +
+	NVL((SELECT SUM(s.bytes) FROM user_segments s JOIN user_indexes i ON i.index_name = s.segment_name WHERE i.table_name = t.table_name), 0),
+	NVL((SELECT SUM(s.bytes) FROM user_segments s JOIN user_indexes i ON i.index_name = s.segment_name WHERE i.table_name = t.table_name), 0),
+	0, 0
+FROM user_tables t
+WHERE t.table_name = ?`,
+}
+
+// TableStats returns the catalog's row estimate, size and bloat hints for
+// model's table, for admin dashboards and capacity alerts:
+//
+//	st, err := adapter.(*orm.SqlQueryAdapter).TableStats(&Order{})
+//	if st.BloatRatio > 0.3 { ... }
+//
+// A table the catalog doesn't know fails with ErrNoTableStats.
+func (q *SqlQueryAdapter) TableStats(model Tabler) (TableStatistics, error) {
+	return tableStats(q.ctx, q.db, q.flavor, q.tablePrefix+model.TableName())
+}
+
+// TableStats returns the catalog statistics of model's table; see
+// SqlQueryAdapter.TableStats.
+func (g *GormAdapter) TableStats(model Tabler) (TableStatistics, error) {
+	return tableStats(g.db.Statement.Context, g.DB(), g.Driver(), g.tablePrefix+model.TableName())
+}
+
+func tableStats(ctx context.Context, db *sql.DB, flavor driverFlavor, table string) (TableStatistics, error) {
+	st := TableStatistics{Table: table}
+	if db == nil {
+		return st, ErrNilPointer
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	name := table
+	if flavor == FlavorOracle {
+		name = strings.ToUpper(table)
+	}
+	query := rebind(flavor, tableStatsQueries[flavor])
+	args := []any{name}
+
+	err := runQuery(&queryCall{ctx: ctx, query: query, args: args, flavor: flavor}, func(query string) error {
+		return db.QueryRowContext(ctx, query, args...).
+			Scan(&st.Rows, &st.TotalBytes, &st.IndexBytes, &st.DeadRows, &st.FreeBytes)
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return st, ErrNoTableStats.Render(table)
+	}
+	if err != nil {
+		return st, err
+	}
+
+	if st.Rows < 0 {
+		st.Rows = -1
+	}
+	switch {
+	case st.DeadRows > 0 && st.Rows > 0:
+		st.BloatRatio = float64(st.DeadRows) / float64(st.Rows+st.DeadRows)
+	case st.FreeBytes > 0:
+		st.BloatRatio = float64(st.FreeBytes) / float64(st.TotalBytes-st.IndexBytes+st.FreeBytes)
+	}
+	return st, nil
+}
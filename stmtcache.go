@@ -0,0 +1,305 @@
+package orm
+
+import (
+	"container/list"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// DefaultStmtCacheSize is the capacity of the caches created by PrepareStmt.
+const DefaultStmtCacheSize = 256
+
+type (
+	// StmtCache keeps prepared statements for one *sql.DB keyed by their
+	// normalized SQL text, evicting the least recently used beyond capacity.
+	// database/sql re-prepares a cached *sql.Stmt transparently on every
+	// pooled connection it runs on.
+	StmtCache struct {
+		db       *sql.DB
+		capacity int
+
+		mu    sync.Mutex
+		ll    *list.List
+		items map[string]*list.Element
+	}
+
+	// stmtEntry is a cached statement. An evicted entry is closed once the
+	// last query running it has released it.
+	stmtEntry struct {
+		key     string
+		stmt    *sql.Stmt
+		refs    int
+		evicted bool
+	}
+)
+
+// sharedStmtCaches holds the cache of each *sql.DB PrepareStmt was used on;
+// caches of closed DBs are dropped (see pruneStmtCaches).
+var sharedStmtCaches sync.Map // *sql.DB -> *StmtCache
+
+// NewStmtCache creates a statement cache for db holding at most capacity
+// statements (DefaultStmtCacheSize when capacity <= 0).
+func NewStmtCache(db *sql.DB, capacity int) *StmtCache {
+	if capacity <= 0 {
+		capacity = DefaultStmtCacheSize
+	}
+
+	return &StmtCache{
+		db:       db,
+		capacity: capacity,
+		ll:       list.New(),
+		items:    make(map[string]*list.Element),
+	}
+}
+
+// sharedStmtCache returns the process-wide cache for db. Adding one first
+// drops those of DBs closed since.
+func sharedStmtCache(db *sql.DB) *StmtCache {
+	if c, ok := sharedStmtCaches.Load(db); ok {
+		return c.(*StmtCache)
+	}
+
+	pruneStmtCaches()
+	c, _ := sharedStmtCaches.LoadOrStore(db, NewStmtCache(db, DefaultStmtCacheSize))
+	return c.(*StmtCache)
+}
+
+// pruneStmtCaches closes and forgets the shared caches of closed DBs.
+func pruneStmtCaches() {
+	sharedStmtCaches.Range(func(key, value any) bool {
+		if dbClosed(key.(*sql.DB)) {
+			dropSharedStmtCache(value.(*StmtCache))
+		}
+		return true
+	})
+}
+
+// dropSharedStmtCache closes c and removes it from the shared caches.
+func dropSharedStmtCache(c *StmtCache) {
+	if sharedStmtCaches.CompareAndDelete(c.db, c) {
+		c.Close()
+	}
+}
+
+// dbClosed reports whether db.Close has been called. With a canceled
+// context, DB.Conn fails before touching the pool: with errDBClosed on a
+// closed DB, with the context's error otherwise.
+func dbClosed(db *sql.DB) bool {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	conn, err := db.Conn(ctx)
+	if err == nil {
+		conn.Close()
+		return false
+	}
+	return isDBClosedErr(err)
+}
+
+func isDBClosedErr(err error) bool {
+	return err != nil && err.Error() == "sql: database is closed"
+}
+
+// Len returns the number of cached statements.
+func (c *StmtCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.ll.Len()
+}
+
+// Prepare returns the cached statement for query, preparing it on a miss.
+// The statement is closed when it is evicted, so hold on to it only
+// briefly; QueryContext keeps it open while it runs.
+func (c *StmtCache) Prepare(ctx context.Context, query string) (*sql.Stmt, error) {
+	stmt, release, err := c.acquire(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	release()
+	return stmt, nil
+}
+
+// acquire returns the cached statement for query, preparing it on a miss,
+// and keeps it from being closed by eviction until release is called.
+func (c *StmtCache) acquire(ctx context.Context, query string) (stmt *sql.Stmt, release func(), err error) {
+	key := normalizeSQL(query)
+
+	c.mu.Lock()
+	if el, ok := c.items[key]; ok {
+		c.ll.MoveToFront(el)
+		entry := el.Value.(*stmtEntry)
+		entry.refs++
+		c.mu.Unlock()
+		return entry.stmt, c.releaser(entry), nil
+	}
+	c.mu.Unlock()
+
+	// prepare outside the lock so a slow prepare doesn't block hits
+	stmt, err = c.db.PrepareContext(ctx, query)
+	if err != nil {
+		if isDBClosedErr(err) {
+			dropSharedStmtCache(c)
+		}
+		return nil, nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.items[key]; ok {
+		// lost a race with another goroutine preparing the same text
+		stmt.Close()
+		c.ll.MoveToFront(el)
+		entry := el.Value.(*stmtEntry)
+		entry.refs++
+		return entry.stmt, c.releaser(entry), nil
+	}
+
+	entry := &stmtEntry{key: key, stmt: stmt, refs: 1}
+	c.items[key] = c.ll.PushFront(entry)
+	for c.ll.Len() > c.capacity {
+		c.removeElement(c.ll.Back())
+	}
+	return stmt, c.releaser(entry), nil
+}
+
+// releaser returns the function dropping one reference to entry, closing
+// it when it was evicted meanwhile.
+func (c *StmtCache) releaser(entry *stmtEntry) func() {
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			c.mu.Lock()
+			defer c.mu.Unlock()
+			if entry.refs--; entry.refs == 0 && entry.evicted {
+				entry.stmt.Close()
+			}
+		})
+	}
+}
+
+// Invalidate drops and closes the cached statement for query, if any.
+func (c *StmtCache) Invalidate(query string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.items[normalizeSQL(query)]; ok {
+		c.removeElement(el)
+	}
+}
+
+// Close closes every cached statement and empties the cache. Statements
+// still running are closed when they finish.
+func (c *StmtCache) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var firstErr error
+	for el := c.ll.Front(); el != nil; el = el.Next() {
+		entry := el.Value.(*stmtEntry)
+		entry.evicted = true
+		if entry.refs > 0 {
+			continue // closed by its last release
+		}
+		if err := entry.stmt.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	c.ll.Init()
+	c.items = make(map[string]*list.Element)
+	return firstErr
+}
+
+// removeElement evicts el, closing its statement unless a query is still
+// running it.
+func (c *StmtCache) removeElement(el *list.Element) {
+	entry := el.Value.(*stmtEntry)
+	c.ll.Remove(el)
+	delete(c.items, entry.key)
+	entry.evicted = true
+	if entry.refs == 0 {
+		entry.stmt.Close()
+	}
+}
+
+// QueryContext runs query through its cached statement. A statement the
+// server no longer accepts (dropped connection, changed result type after
+// DDL) is evicted and re-prepared once.
+func (c *StmtCache) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	rows, err := c.query(ctx, query, func(stmt *sql.Stmt) (*sql.Rows, error) {
+		return stmt.QueryContext(ctx, args...)
+	})
+	if err == nil || !isStaleStmtErr(err) {
+		return rows, err
+	}
+
+	c.Invalidate(query)
+	return c.query(ctx, query, func(stmt *sql.Stmt) (*sql.Rows, error) {
+		return stmt.QueryContext(ctx, args...)
+	})
+}
+
+// query runs fn with the cached statement for query, held for the call.
+// Rows keep their statement open themselves until they are closed.
+func (c *StmtCache) query(ctx context.Context, query string, fn func(stmt *sql.Stmt) (*sql.Rows, error)) (*sql.Rows, error) {
+	stmt, release, err := c.acquire(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	rows, err := fn(stmt)
+	if isDBClosedErr(err) {
+		dropSharedStmtCache(c)
+	}
+	return rows, err
+}
+
+func isStaleStmtErr(err error) bool {
+	if errors.Is(err, driver.ErrBadConn) || errors.Is(err, sql.ErrConnDone) {
+		return true
+	}
+
+	msg := strings.ToLower(err.Error())
+	return strings.Contains(msg, "cached plan must not change result type") ||
+		strings.Contains(msg, "needs to be re-prepared") ||
+		(strings.Contains(msg, "prepared statement") && strings.Contains(msg, "does not exist"))
+}
+
+// normalizeSQL collapses whitespace outside string literals so statements
+// that differ only in formatting share a cache entry.
+func normalizeSQL(query string) string {
+	var sb strings.Builder
+	sb.Grow(len(query))
+
+	var quote rune
+	space := false
+	for _, r := range strings.TrimSpace(query) {
+		if quote != 0 {
+			sb.WriteRune(r)
+			if r == quote {
+				quote = 0
+			}
+			continue
+		}
+
+		if unicode.IsSpace(r) {
+			space = true
+			continue
+		}
+		if space {
+			sb.WriteByte(' ')
+			space = false
+		}
+
+		if r == '\'' || r == '"' || r == '`' {
+			quote = r
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
@@ -0,0 +1,71 @@
+package orm
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestStmtCacheEvictionWaitsForRunningQuery(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	mock.MatchExpectationsInOrder(false)
+
+	mock.ExpectPrepare("SELECT a").WillBeClosed().
+		ExpectQuery().WillReturnRows(sqlmock.NewRows([]string{"a"}).AddRow(1))
+	mock.ExpectPrepare("SELECT b")
+
+	ctx := context.Background()
+	c := NewStmtCache(db, 1)
+	stmt, release, err := c.acquire(ctx, "SELECT a")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// evicts SELECT a while it is still held
+	if _, err := c.Prepare(ctx, "SELECT b"); err != nil {
+		t.Fatal(err)
+	}
+	rows, err := stmt.QueryContext(ctx)
+	if err != nil {
+		t.Fatalf("evicted statement closed while in use: %v", err)
+	}
+	rows.Close()
+	release()
+
+	if _, err := stmt.QueryContext(ctx); err == nil {
+		t.Error("evicted statement still open after its last release")
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestSharedStmtCacheDroppedWithClosedDB(t *testing.T) {
+	db, _, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sharedStmtCache(db)
+	db.Close()
+
+	other, _, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer other.Close()
+	sharedStmtCache(other)
+
+	if _, ok := sharedStmtCaches.Load(db); ok {
+		t.Error("cache of a closed DB kept")
+	}
+	if _, ok := sharedStmtCaches.Load(other); !ok {
+		t.Error("cache of an open DB dropped")
+	}
+	other.Close()
+	pruneStmtCaches()
+}
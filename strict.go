@@ -0,0 +1,102 @@
+package orm
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/godev90/validator/faults"
+)
+
+var (
+	errInvalidClause = fmt.Errorf("orm: invalid clause")
+	ErrInvalidClause = faults.New(errInvalidClause, &faults.ErrAttr{
+		Code: http.StatusBadRequest,
+		Messages: []faults.LangPackage{
+			{
+				Tag:     faults.English,
+				Message: "orm: invalid %s: %v",
+			},
+		},
+	})
+)
+
+// clauseError is the error a strict chain records for a clause that failed
+// validation. It is ErrInvalidClause and matches cause too, e.g.
+// ErrInvalidOrderBy or ErrColumnNotAllowed.
+func clauseError(what string, cause error) error {
+	return faultError{err: ErrInvalidClause.Render(what, cause), aliases: []error{cause}}
+}
+
+// StrictValidation makes the chain keep the first clause that fails
+// validation instead of logging and dropping it: SafeOrder, SafeJoin,
+// SafeSelect, SafeGroupBy, SafeHaving, JoinModel and Joins then record an
+// ErrInvalidClause, which Error reports and Scan, First, Count and Explain
+// return without running anything.
+//
+//	err := adapter.StrictValidation().UseModel(&User{}).SafeOrder(sort).Scan(&users)
+//	if errors.Is(err, orm.ErrInvalidClause) { ... } // a 400
+func (q *SqlQueryAdapter) StrictValidation() QueryAdapter {
+	cp := q.clone()
+	cp.strict = true
+	return cp
+}
+
+// Error returns the clause a strict chain rejected, or a condition the
+// column policies refuse, or nil.
+func (q *SqlQueryAdapter) Error() error {
+	return q.failure()
+}
+
+// reject drops a clause that failed validation: logged and left out of the
+// chain, or recorded on a strict one.
+func (q *SqlQueryAdapter) reject(what string, cause error) QueryAdapter {
+	if !q.strict {
+		log.Printf("WARNING: invalid %s: %v", what, cause)
+		return q
+	}
+	if q.err != nil {
+		return q
+	}
+	cp := q.clone()
+	cp.err = clauseError(what, cause)
+	return cp
+}
+
+// StrictValidation makes the chain record the first clause that fails
+// validation, including those Order, Join, Select, GroupBy and Having check;
+// see SqlQueryAdapter.StrictValidation.
+func (g *GormAdapter) StrictValidation() QueryAdapter {
+	cp := g.with(g.db)
+	cp.strict = true
+	return cp
+}
+
+// Error returns the clause a strict chain rejected, or a condition the
+// column policies refuse, or nil.
+func (g *GormAdapter) Error() error {
+	return g.failure()
+}
+
+// reject drops a clause that failed validation: logged and left out of the
+// chain, or recorded on a strict one.
+func (g *GormAdapter) reject(what string, cause error) QueryAdapter {
+	if !g.strict {
+		log.Printf("WARNING: invalid %s: %v", what, cause)
+		return g
+	}
+	if g.err != nil {
+		return g
+	}
+	cp := g.with(g.db)
+	cp.err = clauseError(what, cause)
+	return cp
+}
+
+func (a builtAdapter) StrictValidation() QueryAdapter {
+	return a.rewrap(a.b.StrictValidation())
+}
+
+func (a builtAdapter) Error() error {
+	return a.b.Error()
+}
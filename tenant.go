@@ -0,0 +1,68 @@
+package orm
+
+import (
+	"context"
+	"log"
+	"strings"
+)
+
+// tenantKey carries the current tenant in a context.
+type tenantKey struct{}
+
+// WithTenant returns a copy of ctx carrying the tenant id, for TenantScope.
+func WithTenant(ctx context.Context, id any) context.Context {
+	return context.WithValue(ctx, tenantKey{}, id)
+}
+
+// TenantFromContext returns the tenant id carried by ctx, if any.
+func TenantFromContext(ctx context.Context) (any, bool) {
+	id := ctx.Value(tenantKey{})
+	return id, id != nil
+}
+
+// TenantScope restricts queries to the tenant of their context (see
+// WithTenant) through column = ?. It is meant for WithDefaultScope:
+//
+//	adapter = adapter.WithDefaultScope(orm.TenantScope("tenant_id"))
+//	adapter.WithContext(orm.WithTenant(ctx, tenantID)).UseModel(&Invoice{}).Scan(&invoices)
+//
+// It fails closed: a query whose context carries no tenant, or a column
+// that is not a valid identifier, matches no rows. The column is qualified
+// with the model's table, so joined tables with the same column don't make
+// it ambiguous.
+func TenantScope(column string) ScopeFunc {
+	if err := ValidateColumnName(column); err != nil {
+		log.Printf("WARNING: invalid tenant column %q: %v", column, err)
+		return func(q QueryAdapter) QueryAdapter {
+			return whereTrusted(q, "1 = 0")
+		}
+	}
+
+	return func(q QueryAdapter) QueryAdapter {
+		var ctx context.Context
+		var table string
+		switch a := q.(type) {
+		case *SqlQueryAdapter:
+			ctx, table = a.ctx, a.table
+		case *GormAdapter:
+			ctx, table = a.db.Statement.Context, a.db.Statement.Table
+			if table == "" && a.model != nil {
+				table = a.model.TableName()
+			}
+		}
+		if ctx == nil {
+			return whereTrusted(q, "1 = 0")
+		}
+
+		id, ok := TenantFromContext(ctx)
+		if !ok {
+			return whereTrusted(q, "1 = 0")
+		}
+
+		col := column
+		if table != "" && !strings.Contains(column, ".") && plainIdent.MatchString(table) {
+			col = table + "." + column
+		}
+		return whereTrusted(q, quoteIdent(q.Driver(), col)+" = ?", id)
+	}
+}
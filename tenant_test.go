@@ -0,0 +1,65 @@
+package orm
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+type tenantInvoice struct {
+	ID        int64      `sql:"column:id;primaryKey"`
+	TenantID  int64      `sql:"column:tenant_id"`
+	Status    string     `sql:"column:status"`
+	DeletedAt *time.Time `sql:"column:deleted_at"`
+}
+
+func (tenantInvoice) TableName() string { return "invoices" }
+
+func (tenantInvoice) DefaultScope() ScopeFunc {
+	return func(q QueryAdapter) QueryAdapter {
+		return q.Where("status <> ?", "draft")
+	}
+}
+
+func TestTenantScopeSurvivesUnscoped(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	SetFlavor(db, FlavorPostgres)
+
+	ctx := WithTenant(context.Background(), int64(7))
+	q := NewSqlAdapter(db).WithDefaultScope(TenantScope("tenant_id")).
+		WithContext(ctx).UseModel(&tenantInvoice{})
+
+	mock.ExpectQuery(`SELECT * FROM "invoices" WHERE (status <> $1 AND "invoices"."tenant_id" = $2) AND "deleted_at" IS NULL`).
+		WithArgs("draft", int64(7)).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	var got []tenantInvoice
+	if err := q.Scan(&got); err != nil {
+		t.Fatalf("Scan: %v", err)
+	}
+
+	// Unscoped lifts the model's scope and soft delete, not the tenant
+	mock.ExpectQuery(`SELECT * FROM "invoices" WHERE "invoices"."tenant_id" = $1`).
+		WithArgs(int64(7)).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	if err := q.Unscoped().Scan(&got); err != nil {
+		t.Fatalf("Unscoped Scan: %v", err)
+	}
+
+	var n int64
+	mock.ExpectQuery(`SELECT COUNT(1) FROM "invoices" WHERE "invoices"."tenant_id" = $1`).
+		WithArgs(int64(7)).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	if err := q.Unscoped().Count(&n); err != nil {
+		t.Fatalf("Unscoped Count: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,128 @@
+package orm
+
+import (
+	"database/sql"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// autoTimeFields are a model's automatically managed timestamp fields.
+type autoTimeFields struct {
+	create []modelField
+	update []modelField
+}
+
+var autoTimeCache sync.Map // reflect.Type -> autoTimeFields
+
+// autoTimeFieldsOf finds the fields tagged autoCreateTime or autoUpdateTime,
+// and the CreatedAt and UpdatedAt time fields by convention. Fields are
+// time.Time, *time.Time, sql.NullTime or, when tagged, an integer holding
+// Unix seconds.
+func autoTimeFieldsOf(t reflect.Type) autoTimeFields {
+	if cached, ok := autoTimeCache.Load(t); ok {
+		return cached.(autoTimeFields)
+	}
+
+	var fs autoTimeFields
+	for _, f := range modelFields(t) {
+		tag := f.Tag.Get("sql")
+		onCreate, onUpdate := tagFlag(tag, "autoCreateTime"), tagFlag(tag, "autoUpdateTime")
+		if !onCreate && !onUpdate && isTimeType(f.Type) {
+			onCreate, onUpdate = f.Name == "CreatedAt", f.Name == "UpdatedAt"
+		}
+		if !isTimeType(f.Type) && !isUnixType(f.Type) {
+			continue
+		}
+
+		if onCreate {
+			fs.create = append(fs.create, f)
+		}
+		if onUpdate {
+			fs.update = append(fs.update, f)
+		}
+	}
+
+	autoTimeCache.Store(t, fs)
+	return fs
+}
+
+func isTimeType(t reflect.Type) bool {
+	return t == timeT || t == nullTimeT || t.Kind() == reflect.Ptr && t.Elem() == timeT
+}
+
+func isUnixType(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Int, reflect.Int64, reflect.Uint64:
+		return true
+	}
+	return false
+}
+
+// setAutoTime sets the fields of val to now; with onlyZero, fields already
+// holding a value are kept, so callers can backfill timestamps.
+func setAutoTime(val reflect.Value, fields []modelField, now time.Time, onlyZero bool) {
+	for _, field := range fields {
+		f := val.FieldByIndex(field.Index)
+		if onlyZero && !f.IsZero() {
+			continue
+		}
+
+		switch {
+		case f.Type() == timeT:
+			f.Set(reflect.ValueOf(now))
+		case f.Type() == nullTimeT:
+			f.Set(reflect.ValueOf(sql.NullTime{Time: now, Valid: true}))
+		case f.Kind() == reflect.Ptr:
+			t := now
+			f.Set(reflect.ValueOf(&t))
+		case f.Kind() == reflect.Uint64:
+			f.SetUint(uint64(now.Unix()))
+		default:
+			f.SetInt(now.Unix())
+		}
+	}
+}
+
+// settable returns val itself when it can be modified, else a copy; value
+// models passed to BulkInsert or Update are stamped on the copy.
+func settable(val reflect.Value) reflect.Value {
+	if val.CanSet() {
+		return val
+	}
+	cp := reflect.New(val.Type()).Elem()
+	cp.Set(val)
+	return cp
+}
+
+// stampCreate fills the create and update timestamps of the model struct
+// val that don't have a value yet.
+func stampCreate(val reflect.Value, now time.Time) reflect.Value {
+	fs := autoTimeFieldsOf(val.Type())
+	if len(fs.create) == 0 && len(fs.update) == 0 {
+		return val
+	}
+
+	val = settable(val)
+	setAutoTime(val, fs.create, now, true)
+	setAutoTime(val, fs.update, now, true)
+	return val
+}
+
+// stampUpdate sets the update timestamps of the model struct val and
+// returns their columns.
+func stampUpdate(val reflect.Value, now time.Time) (reflect.Value, []string) {
+	fs := autoTimeFieldsOf(val.Type())
+	if len(fs.update) == 0 {
+		return val, nil
+	}
+
+	val = settable(val)
+	setAutoTime(val, fs.update, now, false)
+
+	cols := make([]string, 0, len(fs.update))
+	for _, f := range fs.update {
+		cols = append(cols, f.column)
+	}
+	return val, cols
+}
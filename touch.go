@@ -0,0 +1,38 @@
+package orm
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// updatedAtColumn is the column Touch sets.
+const updatedAtColumn = "updated_at"
+
+// Touch sets updated_at to the current time on the rows of model with the
+// given primary keys, in one statement. Caches keyed on a parent's
+// timestamp use it to invalidate after changes to child rows; relations
+// tagged touch:parent call it automatically (see RegisterRelations).
+func (q *SqlTransactionAdapter) Touch(model Tabler, ids ...any) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	val, err := modelStruct(model, false)
+	if err != nil {
+		return err
+	}
+	if _, ok := cachedFieldMap(val.Type())[updatedAtColumn]; !ok {
+		return fmt.Errorf("orm: %s has no %s column", val.Type().Name(), updatedAtColumn)
+	}
+
+	query := rebind(q.flavor, fmt.Sprintf("UPDATE %s SET %s = ? WHERE %s IN (%s)",
+		quoteIdent(q.flavor, q.tableName(model)),
+		quoteIdent(q.flavor, updatedAtColumn),
+		quoteIdent(q.flavor, primaryKeyColumn(model)),
+		strings.TrimSuffix(strings.Repeat("?, ", len(ids)), ", "),
+	))
+
+	args := append([]any{time.Now()}, ids...)
+	return q.exec(query, args)
+}
@@ -0,0 +1,111 @@
+package orm
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// TxOptions sets the isolation level (e.g. sql.LevelSerializable) and
+// read-only mode of a transaction. Drivers reject levels they don't support
+// when the transaction begins.
+type TxOptions = sql.TxOptions
+
+// ErrRollbackOnly is returned by the outermost WithTransaction when it
+// rolled back because a joined call failed.
+var ErrRollbackOnly = errors.New("orm: transaction rolled back: a joined WithTransaction call failed")
+
+// txKey carries the running transaction in a context.
+type txKey struct{}
+
+// TxToContext returns a copy of ctx carrying tx. Adapters on the same
+// *sql.DB given that context through WithContext run their reads in tx, and
+// WithTransaction joins it, so repository code needs no separate tx and
+// non-tx variants of its methods.
+func TxToContext(ctx context.Context, tx *SqlTransactionAdapter) context.Context {
+	return context.WithValue(ctx, txKey{}, tx)
+}
+
+// ContextWithTx is TxToContext, named after context.WithValue and friends.
+func ContextWithTx(ctx context.Context, tx *SqlTransactionAdapter) context.Context {
+	return TxToContext(ctx, tx)
+}
+
+// TxFromContext returns the transaction carried by ctx, if any.
+func TxFromContext(ctx context.Context) (*SqlTransactionAdapter, bool) {
+	tx, ok := ctx.Value(txKey{}).(*SqlTransactionAdapter)
+	return tx, ok && tx != nil
+}
+
+// WithTransaction runs fn in a transaction on db. The transaction commits
+// when fn returns nil and rolls back when it returns an error or panics; the
+// panic is re-raised after the rollback. fn must not call Commit or
+// Rollback itself.
+//
+// Calls nest: when ctx comes from tx.Context() of a running transaction on
+// the same db, fn joins that transaction instead of beginning another, and
+// only the outermost call commits. A failing inner call marks the
+// transaction rollback-only, so the outer call rolls back even if it
+// ignores the error.
+func WithTransaction(ctx context.Context, db *sql.DB, fn func(tx *SqlTransactionAdapter) error) error {
+	return WithTransactionOptions(ctx, db, TxOptions{}, fn)
+}
+
+// WithTransactionOptions is WithTransaction with an isolation level or a
+// read-only transaction. A joined transaction keeps the options it was
+// begun with.
+func WithTransactionOptions(ctx context.Context, db *sql.DB, opts TxOptions, fn func(tx *SqlTransactionAdapter) error) (err error) {
+	if outer, ok := TxFromContext(ctx); ok && outer.db == db {
+		return outer.join(fn)
+	}
+
+	tx, err := NewSqlTransactionAdapter(ctx, db, opts)
+	if err != nil {
+		return err
+	}
+	tx.ctx = TxToContext(tx.ctx, tx)
+
+	defer func() {
+		if p := recover(); p != nil {
+			_ = tx.Rollback()
+			panic(p)
+		}
+	}()
+
+	if err := fn(tx); err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil && !errors.Is(rbErr, sql.ErrTxDone) {
+			return fmt.Errorf("%w (rollback failed: %v)", err, rbErr)
+		}
+		return err
+	}
+
+	if tx.rollbackOnly.Load() {
+		_ = tx.Rollback()
+		return ErrRollbackOnly
+	}
+	return tx.Commit()
+}
+
+// join runs fn inside q for a nested WithTransaction call.
+func (q *SqlTransactionAdapter) join(fn func(tx *SqlTransactionAdapter) error) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			q.rollbackOnly.Store(true)
+			panic(p)
+		}
+	}()
+
+	if err := fn(q); err != nil {
+		q.rollbackOnly.Store(true)
+		return err
+	}
+	return nil
+}
+
+// Context returns the transaction's context. Passing it to WithTransaction
+// joins this transaction instead of starting a new one, and passing it to an
+// adapter's WithContext runs the adapter's reads in it.
+func (q *SqlTransactionAdapter) Context() context.Context {
+	return q.ctx
+}
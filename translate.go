@@ -0,0 +1,157 @@
+package orm
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"sync"
+
+	"github.com/godev90/validator/faults"
+	"gorm.io/gorm"
+)
+
+// The constraint and concurrency faults a failed statement is translated
+// to, so handlers can tell a taken email from a missing parent without
+// reading driver messages. Each also matches its class (ErrConstraint for
+// the violations) and ErrQueryFailed.
+var (
+	errDuplicateKey = fmt.Errorf("orm: duplicate key")
+	ErrDuplicateKey = faults.New(errDuplicateKey, &faults.ErrAttr{
+		Code: http.StatusConflict,
+		Messages: []faults.LangPackage{
+			{
+				Tag:     faults.English,
+				Message: "orm: duplicate key: %[3]v",
+			},
+		},
+	})
+
+	errForeignKeyViolation = fmt.Errorf("orm: foreign key violation")
+	ErrForeignKeyViolation = faults.New(errForeignKeyViolation, &faults.ErrAttr{
+		Code: http.StatusConflict,
+		Messages: []faults.LangPackage{
+			{
+				Tag:     faults.English,
+				Message: "orm: foreign key violation: %[3]v",
+			},
+		},
+	})
+
+	errCheckViolation = fmt.Errorf("orm: check constraint violation")
+	ErrCheckViolation = faults.New(errCheckViolation, &faults.ErrAttr{
+		Code: http.StatusUnprocessableEntity,
+		Messages: []faults.LangPackage{
+			{
+				Tag:     faults.English,
+				Message: "orm: check constraint violation: %[3]v",
+			},
+		},
+	})
+
+	errDeadlock = fmt.Errorf("orm: deadlock")
+	ErrDeadlock = faults.New(errDeadlock, &faults.ErrAttr{
+		Code: http.StatusConflict,
+		Messages: []faults.LangPackage{
+			{
+				Tag:     faults.English,
+				Message: "orm: deadlock detected, retry the transaction: %[3]v",
+			},
+		},
+	})
+)
+
+// ErrorTranslator maps a driver error to the fault a failed statement
+// returns instead of the orm's own, or reports false to pass. The fault is
+// returned as it is, still unwrapping to the driver error and matching the
+// error's class.
+type ErrorTranslator func(err error) (faults.Error, bool)
+
+var (
+	translatorsMu sync.RWMutex
+	translators   []ErrorTranslator
+)
+
+// RegisterErrorTranslator adds t ahead of the translators registered
+// before it and of the built-in one (see ErrDuplicateKey), e.g. to name the
+// constraint a user hit:
+//
+//	orm.RegisterErrorTranslator(func(err error) (faults.Error, bool) {
+//		var pe *pq.Error
+//		if errors.As(err, &pe) && pe.Constraint == "users_email_key" {
+//			return ErrEmailTaken, true
+//		}
+//		return faults.Error{}, false
+//	})
+func RegisterErrorTranslator(t ErrorTranslator) {
+	translatorsMu.Lock()
+	defer translatorsMu.Unlock()
+	translators = append(translators, t)
+}
+
+// translateError runs the registered translators on err, latest first.
+func translateError(err error) (faults.Error, bool) {
+	translatorsMu.RLock()
+	ts := slices.Clone(translators)
+	translatorsMu.RUnlock()
+
+	for i := len(ts) - 1; i >= 0; i-- {
+		if f, ok := ts[i](err); ok {
+			return f, true
+		}
+	}
+	return faults.Error{}, false
+}
+
+// driverFault returns the fault of a lib/pq, pgx, go-sql-driver/mysql,
+// Oracle or gorm error code, to be rendered like a class.
+func driverFault(err error) (faults.Error, bool) {
+	switch {
+	case errors.Is(err, gorm.ErrDuplicatedKey):
+		return ErrDuplicateKey, true
+	case errors.Is(err, gorm.ErrForeignKeyViolated):
+		return ErrForeignKeyViolation, true
+	case errors.Is(err, gorm.ErrCheckConstraintViolated):
+		return ErrCheckViolation, true
+	}
+
+	if state := sqlState(err); state != "" {
+		switch state {
+		case "23505":
+			return ErrDuplicateKey, true
+		case "23503":
+			return ErrForeignKeyViolation, true
+		case "23514":
+			return ErrCheckViolation, true
+		case "40P01":
+			return ErrDeadlock, true
+		}
+		return faults.Error{}, false
+	}
+	if code, ok := mysqlErrorNumber(err); ok {
+		switch code {
+		case 1062, 1586:
+			return ErrDuplicateKey, true
+		case 1216, 1217, 1451, 1452:
+			return ErrForeignKeyViolation, true
+		case 3819:
+			return ErrCheckViolation, true
+		case 1213:
+			return ErrDeadlock, true
+		}
+		return faults.Error{}, false
+	}
+	if code, ok := oracleErrorCode(err); ok {
+		switch code {
+		case 1:
+			return ErrDuplicateKey, true
+		case 2291, 2292:
+			return ErrForeignKeyViolation, true
+		case 2290:
+			return ErrCheckViolation, true
+		case 60:
+			return ErrDeadlock, true
+		}
+	}
+	return faults.Error{}, false
+}
@@ -0,0 +1,137 @@
+package orm
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/godev90/validator/faults"
+	"gorm.io/gorm"
+)
+
+var (
+	errWarmUp = fmt.Errorf("orm: warm-up failed")
+	ErrWarmUp = faults.New(errWarmUp, &faults.ErrAttr{
+		Code: http.StatusInternalServerError,
+		Messages: []faults.LangPackage{
+			{
+				Tag:     faults.English,
+				Message: "orm: warm-up of query %d: %v",
+			},
+		},
+	})
+)
+
+// preparer is implemented by adapters that can prepare their statement
+// without running it.
+type preparer interface {
+	prepare() error
+}
+
+// WarmUp prepares the statements of queries at startup, so the first
+// requests running them don't pay for parsing and planning:
+//
+//	activeUsers := adapter.UseModel(&User{}).Where("status = ?", "active").Order("id")
+//	if err := orm.WarmUp(activeUsers, recentOrders); err != nil {
+//		log.Fatal(err)
+//	}
+//
+// Statements go into the cache PrepareStmt uses: the adapter's own (see
+// UseStmtCache) or the one shared by its *sql.DB, so only queries run with
+// PrepareStmt benefit. The statement is the one Scan runs with a model
+// destination; First and Count build others, as does a DTO destination.
+// GormAdapter fills gorm's prepared statement cache with the statement of
+// ToSQL. PgxAdapter prepares statements on each connection's first use
+// and is left alone.
+//
+// The first failure stops the warm-up with an ErrWarmUp naming the query
+// by its position, from 1, and wrapping the cause.
+func WarmUp(queries ...QueryAdapter) error {
+	for i, q := range queries {
+		p, ok := q.(preparer)
+		if !ok {
+			continue
+		}
+		if err := p.prepare(); err != nil {
+			return warmUpError(i, err)
+		}
+	}
+	return nil
+}
+
+// WarmUpExplain is WarmUp that also EXPLAINs every query, which plans it
+// and loads the catalog data its tables need, and returns the plans in
+// the order of queries for the startup log.
+func WarmUpExplain(queries ...QueryAdapter) ([]string, error) {
+	if err := WarmUp(queries...); err != nil {
+		return nil, err
+	}
+
+	plans := make([]string, len(queries))
+	for i, q := range queries {
+		plan, err := q.Explain(false)
+		if err != nil {
+			return nil, warmUpError(i, err)
+		}
+		plans[i] = plan
+	}
+	return plans, nil
+}
+
+func warmUpError(i int, err error) error {
+	return queryError{
+		faultError: faultError{err: ErrWarmUp.Render(i+1, err)},
+		cause:      err,
+	}
+}
+
+// prepare prepares the statement Scan would run in the adapter's statement
+// cache. Builders without a *sql.DB (PgxAdapter's) have nothing to prepare.
+func (q *SqlQueryAdapter) prepare() error {
+	if q.db == nil || q.dryRun {
+		return nil
+	}
+	stmts := q.stmts
+	if stmts == nil {
+		stmts = sharedStmtCache(q.db)
+	}
+
+	sqlStr, args := q.build(false)
+	return runQuery(&queryCall{ctx: q.ctx, query: sqlStr, args: args, flavor: q.flavor, prepared: true}, func(sqlStr string) error {
+		_, err := stmts.Prepare(q.ctx, sqlStr)
+		return err
+	})
+}
+
+func (a builtAdapter) prepare() error {
+	return a.b.prepare()
+}
+
+// prepare prepares the statement of ToSQL in gorm's prepared statement
+// cache, the one a PrepareStmt session on the same *gorm.DB uses.
+func (g *GormAdapter) prepare() error {
+	if g.db.DryRun {
+		return nil
+	}
+	db := g.db
+	if _, ok := db.Statement.ConnPool.(*gorm.PreparedStmtDB); !ok {
+		db = db.Session(&gorm.Session{PrepareStmt: true})
+	}
+	pdb, ok := db.Statement.ConnPool.(*gorm.PreparedStmtDB)
+	if !ok {
+		return nil
+	}
+
+	ctx := g.db.Statement.Context
+	sqlStr, args := g.ToSQL()
+	return runQuery(&queryCall{ctx: ctx, query: sqlStr, args: args, flavor: g.Driver(), prepared: true}, func(sqlStr string) error {
+		// as gorm's own prepare does: New releases the lock once the entry
+		// is in place, so concurrent users wait for this preparation
+		pdb.Mux.Lock()
+		if _, ok := pdb.Stmts.Get(sqlStr); ok {
+			pdb.Mux.Unlock()
+			return nil
+		}
+		_, err := pdb.Stmts.New(ctx, sqlStr, false, pdb.ConnPool, pdb.Mux)
+		return err
+	})
+}